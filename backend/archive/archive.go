@@ -0,0 +1,258 @@
+// Package archive 提供单个公司数据的备份、恢复和跨实例迁移
+// 归档为 zip 文件：manifest.json（格式版本和统计）、data.json（公司数据）、assets/（本系统存储中的 Logo 和奖品图片）
+// 导入时为所有记录分配新 ID 并重建关联；不包含管理员密码、参与者密码、兑换码等密钥类数据
+package archive
+
+import (
+	"errors"
+	"time"
+)
+
+// 归档格式
+const (
+	FormatName = "lottery-company-archive"
+	Version    = 1 // 导入支持的最高版本，格式不兼容变更时递增
+
+	manifestFile = "manifest.json"
+	dataFile     = "data.json"
+	assetsDir    = "assets/"
+)
+
+// 导入时公司代码冲突的处理方式
+const (
+	ConflictFail   = "fail"   // 返回 ErrCodeConflict
+	ConflictRename = "rename" // 自动追加序号，如 acme-2
+)
+
+// 归档错误
+var (
+	ErrInvalidArchive     = errors.New("invalid archive")
+	ErrUnsupportedVersion = errors.New("unsupported archive version")
+	ErrCodeConflict       = errors.New("company code already exists")
+)
+
+// excludedData 归档中不包含的数据（密钥类数据和临时数据）
+var excludedData = []string{
+	"admin_passwords",  // 管理员需在目标实例重置密码
+	"user_passwords",   // 参与者密码
+	"prize_codes",      // 兑换码使用实例密钥加密，需在目标实例重新导入
+	"display_sessions", // 大屏配对会话
+	"otp_codes",        // 短信验证码
+	"export_jobs",      // 导出任务
+}
+
+// Manifest 归档说明
+type Manifest struct {
+	Format      string         `json:"format"`
+	Version     int            `json:"version"`
+	ExportedAt  time.Time      `json:"exported_at"`
+	CompanyCode string         `json:"company_code"`
+	CompanyName string         `json:"company_name"`
+	Counts      map[string]int `json:"counts"`
+	Assets      []string       `json:"assets"`   // 包含的文件（存储对象键）
+	Excluded    []string       `json:"excluded"` // 未包含的数据
+}
+
+// Data 归档数据（ID 均为源实例中的 ID，仅用于归档内部关联）
+type Data struct {
+	Company             CompanyRecord     `json:"company"`
+	Admins              []AdminRecord     `json:"admins"`
+	PrizeLevels         []LevelRecord     `json:"prize_levels"`
+	Prizes              []PrizeRecord     `json:"prizes"`
+	PrizeVariants       []VariantRecord   `json:"prize_variants"`
+	RegistrationFields  []FieldRecord     `json:"registration_fields"`
+	Users               []UserRecord      `json:"users"`
+	DrawRecords         []DrawRecord      `json:"draw_records"`
+	PrizeRollovers      []RolloverRecord  `json:"prize_rollovers"`
+	DuplicateDismissals []DismissalRecord `json:"duplicate_dismissals"`
+	OperationLogs       []LogRecord       `json:"operation_logs"`
+}
+
+// CompanyRecord 公司设置
+type CompanyRecord struct {
+	Code           string `json:"code"`
+	Name           string `json:"name"`
+	Logo           string `json:"logo"`
+	ThemeColor     string `json:"theme_color"`
+	BgColor        string `json:"bg_color"`
+	Title          string `json:"title"`
+	Subtitle       string `json:"subtitle"`
+	WelcomeText    string `json:"welcome_text"`
+	RulesText      string `json:"rules_text"`
+	DrawButtonText string `json:"draw_button_text"`
+	SuccessText    string `json:"success_text"`
+	ContactName    string `json:"contact_name"`
+	ContactPhone   string `json:"contact_phone"`
+	ContactEmail   string `json:"contact_email"`
+	DrawOrder      string `json:"draw_order"`
+
+	PrizeBudget    float64 `json:"prize_budget"`
+	BudgetCurrency string  `json:"budget_currency"`
+	TaxBrackets    string  `json:"tax_brackets"`
+
+	CheckInRequired      bool       `json:"check_in_required"`
+	CheckInCutoffAt      *time.Time `json:"check_in_cutoff_at,omitempty"`
+	RegistrationOpensAt  *time.Time `json:"registration_opens_at,omitempty"`
+	RegistrationClosesAt *time.Time `json:"registration_closes_at,omitempty"`
+	RegistrationClosed   bool       `json:"registration_closed"`
+	MaxParticipants      int        `json:"max_participants"`
+	WaitlistEnabled      bool       `json:"waitlist_enabled"`
+	PhoneOTPRequired     bool       `json:"phone_otp_required"`
+	ChallengeMode        string     `json:"challenge_mode"`
+
+	IsActive      bool       `json:"is_active"`
+	EventClosedAt *time.Time `json:"event_closed_at,omitempty"`
+	RetentionDays int        `json:"retention_days"`
+	PIIPurgedAt   *time.Time `json:"pii_purged_at,omitempty"`
+	CreatedAt     time.Time  `json:"created_at"`
+}
+
+// AdminRecord 公司管理员（不含密码）
+type AdminRecord struct {
+	ID        int       `json:"id"`
+	Username  string    `json:"username"`
+	Role      string    `json:"role"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// LevelRecord 奖项等级
+type LevelRecord struct {
+	ID              int       `json:"id"`
+	Name            string    `json:"name"`
+	Description     string    `json:"description"`
+	Probability     float64   `json:"probability"`
+	TotalStock      int       `json:"total_stock"`
+	UsedStock       int       `json:"used_stock"`
+	SortOrder       int       `json:"sort_order"`
+	IsActive        bool      `json:"is_active"`
+	RolloverLevelID *int      `json:"rollover_level_id,omitempty"`
+	DrawState       string    `json:"draw_state"`
+	CreatedAt       time.Time `json:"created_at"`
+}
+
+// PrizeRecord 奖品
+type PrizeRecord struct {
+	ID                 int       `json:"id"`
+	LevelID            int       `json:"level_id"`
+	Name               string    `json:"name"`
+	Image              string    `json:"image"`
+	TotalStock         int       `json:"total_stock"`
+	UsedStock          int       `json:"used_stock"`
+	Value              float64   `json:"value"`
+	Currency           string    `json:"currency"`
+	CodePool           bool      `json:"code_pool"`
+	CodeAlertThreshold int       `json:"code_alert_threshold"`
+	HasVariants        bool      `json:"has_variants"`
+	VariantPickHours   int       `json:"variant_pick_hours"`
+	CreatedAt          time.Time `json:"created_at"`
+}
+
+// VariantRecord 奖品规格
+type VariantRecord struct {
+	ID         int       `json:"id"`
+	PrizeID    int       `json:"prize_id"`
+	Name       string    `json:"name"`
+	TotalStock int       `json:"total_stock"`
+	UsedStock  int       `json:"used_stock"`
+	SortOrder  int       `json:"sort_order"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+// FieldRecord 报名表单字段
+type FieldRecord struct {
+	Key       string    `json:"key"`
+	Label     string    `json:"label"`
+	Type      string    `json:"type"`
+	Required  bool      `json:"required"`
+	Pattern   string    `json:"pattern,omitempty"`
+	Options   []string  `json:"options,omitempty"`
+	MaxLength int       `json:"max_length"`
+	SortOrder int       `json:"sort_order"`
+	IsActive  bool      `json:"is_active"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// UserRecord 参与者（不含密码和设备标识）
+type UserRecord struct {
+	ID            int               `json:"id"`
+	Username      string            `json:"username"`
+	Role          string            `json:"role"`
+	Name          string            `json:"name"`
+	Phone         string            `json:"phone"`
+	HasDrawn      bool              `json:"has_drawn"`
+	CheckedInAt   *time.Time        `json:"checked_in_at,omitempty"`
+	CheckInMethod string            `json:"check_in_method,omitempty"`
+	CheckedInBy   *int              `json:"checked_in_by,omitempty"` // 管理员 ID
+	Waitlisted    bool              `json:"waitlisted"`
+	RegisteredIP  string            `json:"registered_ip,omitempty"`
+	ReviewStatus  string            `json:"review_status,omitempty"`
+	ReviewReason  string            `json:"review_reason,omitempty"`
+	Fields        map[string]string `json:"fields,omitempty"`
+	CreatedAt     time.Time         `json:"created_at"`
+}
+
+// DrawRecord 抽奖记录
+type DrawRecord struct {
+	ID         int        `json:"id"`
+	UserID     int        `json:"user_id"`
+	LevelID    int        `json:"level_id"`
+	PrizeID    int        `json:"prize_id"`
+	IP         string     `json:"ip"`
+	Status     string     `json:"status"`
+	ClaimedAt  *time.Time `json:"claimed_at,omitempty"`
+	RolledOver bool       `json:"rolled_over"`
+
+	RecipientName   string     `json:"recipient_name,omitempty"`
+	RecipientPhone  string     `json:"recipient_phone,omitempty"`
+	ShippingAddress string     `json:"shipping_address,omitempty"`
+	Courier         string     `json:"courier,omitempty"`
+	TrackingNumber  string     `json:"tracking_number,omitempty"`
+	FulfillmentNote string     `json:"fulfillment_note,omitempty"`
+	ShippedAt       *time.Time `json:"shipped_at,omitempty"`
+	CompletedAt     *time.Time `json:"completed_at,omitempty"`
+
+	RedemptionNonce string     `json:"redemption_nonce,omitempty"`
+	RedeemedAt      *time.Time `json:"redeemed_at,omitempty"`
+	RedeemedBy      *int       `json:"redeemed_by,omitempty"` // 管理员 ID
+
+	VariantID           *int       `json:"variant_id,omitempty"`
+	VariantDeadline     *time.Time `json:"variant_deadline,omitempty"`
+	VariantAutoAssigned bool       `json:"variant_auto_assigned"`
+
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// RolloverRecord 奖品回流记录
+type RolloverRecord struct {
+	DrawRecordID int       `json:"draw_record_id"`
+	UserID       int       `json:"user_id"`
+	FromLevelID  int       `json:"from_level_id"`
+	FromPrizeID  int       `json:"from_prize_id"`
+	ToLevelID    int       `json:"to_level_id"`
+	ToPrizeID    int       `json:"to_prize_id"`
+	PrizeName    string    `json:"prize_name"`
+	Reason       string    `json:"reason"`
+	OperatorID   uint      `json:"operator_id"` // 管理员 ID
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+// DismissalRecord 已确认不重复的参与者对
+type DismissalRecord struct {
+	UserAID     int       `json:"user_a_id"`
+	UserBID     int       `json:"user_b_id"`
+	DismissedBy int       `json:"dismissed_by"` // 管理员 ID
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+// LogRecord 操作日志
+type LogRecord struct {
+	AdminID    uint      `json:"admin_id"`
+	AdminName  string    `json:"admin_name"`
+	Action     string    `json:"action"`
+	Resource   string    `json:"resource"`
+	ResourceID *uint     `json:"resource_id,omitempty"`
+	Details    string    `json:"details"`
+	IPAddress  string    `json:"ip_address"`
+	UserAgent  string    `json:"user_agent"`
+	CreatedAt  time.Time `json:"created_at"`
+}
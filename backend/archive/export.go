@@ -0,0 +1,310 @@
+package archive
+
+import (
+	"archive/zip"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"lottery-system/models"
+	"lottery-system/storage"
+
+	"gorm.io/gorm"
+)
+
+// maxAssetBytes 单个文件大小上限
+const maxAssetBytes = 20 << 20
+
+// Options 归档文件处理选项
+type Options struct {
+	Store       storage.Storage // 读取/保存 Logo 和奖品图片，为空时不处理文件
+	AssetPrefix string          // 本系统上传文件的 URL 前缀（如 /api/uploads/），其他 URL 原样保留
+}
+
+// assetKey 返回本系统存储中文件的对象键，外部 URL 返回空字符串
+func (o Options) assetKey(url string) string {
+	if o.Store == nil || o.AssetPrefix == "" || !strings.HasPrefix(url, o.AssetPrefix) {
+		return ""
+	}
+	key := strings.TrimPrefix(url, o.AssetPrefix)
+	if storage.ValidateKey(key) != nil {
+		return ""
+	}
+	return key
+}
+
+// Export 导出公司数据为 zip 归档写入 w
+func Export(ctx context.Context, db *gorm.DB, companyID int, w io.Writer, opts Options) (*Manifest, error) {
+	data, err := load(db, companyID)
+	if err != nil {
+		return nil, err
+	}
+
+	manifest := &Manifest{
+		Format:      FormatName,
+		Version:     Version,
+		ExportedAt:  time.Now(),
+		CompanyCode: data.Company.Code,
+		CompanyName: data.Company.Name,
+		Counts:      data.counts(),
+		Excluded:    excludedData,
+	}
+
+	// 收集引用的文件（同一文件只保存一次，读取失败时跳过，导入后保留原 URL）
+	seen := map[string]bool{}
+	assets := map[string][]byte{}
+	urls := []string{data.Company.Logo}
+	for _, prize := range data.Prizes {
+		urls = append(urls, prize.Image)
+	}
+	for _, url := range urls {
+		key := opts.assetKey(url)
+		if key == "" || seen[key] {
+			continue
+		}
+		seen[key] = true
+		content, err := readAsset(ctx, opts.Store, key)
+		if err != nil {
+			if errors.Is(err, storage.ErrNotFound) {
+				continue
+			}
+			return nil, fmt.Errorf("read asset %s: %w", key, err)
+		}
+		assets[key] = content
+		manifest.Assets = append(manifest.Assets, key)
+	}
+
+	zw := zip.NewWriter(w)
+	if err := writeJSON(zw, manifestFile, manifest); err != nil {
+		return nil, err
+	}
+	if err := writeJSON(zw, dataFile, data); err != nil {
+		return nil, err
+	}
+	for _, key := range manifest.Assets {
+		f, err := zw.CreateHeader(&zip.FileHeader{Name: assetsDir + key, Method: zip.Store, Modified: manifest.ExportedAt})
+		if err != nil {
+			return nil, err
+		}
+		if _, err := f.Write(assets[key]); err != nil {
+			return nil, err
+		}
+	}
+	if err := zw.Close(); err != nil {
+		return nil, err
+	}
+	return manifest, nil
+}
+
+// load 从数据库读取公司的全部归档数据
+func load(db *gorm.DB, companyID int) (*Data, error) {
+	var company models.Company
+	if err := db.First(&company, companyID).Error; err != nil {
+		return nil, err
+	}
+	data := &Data{Company: companyRecord(company)}
+
+	var admins []models.Admin
+	if err := db.Where("company_id = ?", companyID).Order("id ASC").Find(&admins).Error; err != nil {
+		return nil, err
+	}
+	for _, a := range admins {
+		data.Admins = append(data.Admins, AdminRecord{ID: a.ID, Username: a.Username, Role: a.Role, CreatedAt: a.CreatedAt})
+	}
+
+	var levels []models.PrizeLevel
+	if err := db.Where("company_id = ?", companyID).Order("id ASC").Find(&levels).Error; err != nil {
+		return nil, err
+	}
+	levelIDs := make([]int, 0, len(levels))
+	for _, l := range levels {
+		levelIDs = append(levelIDs, l.ID)
+		data.PrizeLevels = append(data.PrizeLevels, LevelRecord{
+			ID: l.ID, Name: l.Name, Description: l.Description, Probability: l.Probability,
+			TotalStock: l.TotalStock, UsedStock: l.UsedStock, SortOrder: l.SortOrder, IsActive: l.IsActive,
+			RolloverLevelID: l.RolloverLevelID, DrawState: l.DrawState, CreatedAt: l.CreatedAt,
+		})
+	}
+
+	var prizes []models.Prize
+	if len(levelIDs) > 0 {
+		if err := db.Where("level_id IN ?", levelIDs).Order("id ASC").Find(&prizes).Error; err != nil {
+			return nil, err
+		}
+	}
+	prizeIDs := make([]int, 0, len(prizes))
+	for _, p := range prizes {
+		prizeIDs = append(prizeIDs, p.ID)
+		data.Prizes = append(data.Prizes, PrizeRecord{
+			ID: p.ID, LevelID: p.LevelID, Name: p.Name, Image: p.Image, TotalStock: p.TotalStock, UsedStock: p.UsedStock,
+			Value: p.Value, Currency: p.Currency, CodePool: p.CodePool, CodeAlertThreshold: p.CodeAlertThreshold,
+			HasVariants: p.HasVariants, VariantPickHours: p.VariantPickHours, CreatedAt: p.CreatedAt,
+		})
+	}
+
+	var variants []models.PrizeVariant
+	if len(prizeIDs) > 0 {
+		if err := db.Where("prize_id IN ?", prizeIDs).Order("id ASC").Find(&variants).Error; err != nil {
+			return nil, err
+		}
+	}
+	for _, v := range variants {
+		data.PrizeVariants = append(data.PrizeVariants, VariantRecord{
+			ID: v.ID, PrizeID: v.PrizeID, Name: v.Name, TotalStock: v.TotalStock, UsedStock: v.UsedStock,
+			SortOrder: v.SortOrder, CreatedAt: v.CreatedAt,
+		})
+	}
+
+	var fields []models.RegistrationField
+	if err := db.Where("company_id = ?", companyID).Order("sort_order ASC, id ASC").Find(&fields).Error; err != nil {
+		return nil, err
+	}
+	for _, f := range fields {
+		data.RegistrationFields = append(data.RegistrationFields, FieldRecord{
+			Key: f.Key, Label: f.Label, Type: f.Type, Required: f.Required, Pattern: f.Pattern, Options: f.Options,
+			MaxLength: f.MaxLength, SortOrder: f.SortOrder, IsActive: f.IsActive, CreatedAt: f.CreatedAt,
+		})
+	}
+
+	var values []models.UserFieldValue
+	if err := db.Where("company_id = ?", companyID).Find(&values).Error; err != nil {
+		return nil, err
+	}
+	userFields := map[int]map[string]string{}
+	for _, v := range values {
+		if userFields[v.UserID] == nil {
+			userFields[v.UserID] = map[string]string{}
+		}
+		userFields[v.UserID][v.FieldKey] = v.Value
+	}
+
+	var users []models.User
+	if err := db.Where("company_id = ?", companyID).Order("id ASC").Find(&users).Error; err != nil {
+		return nil, err
+	}
+	for _, u := range users {
+		data.Users = append(data.Users, UserRecord{
+			ID: u.ID, Username: u.Username, Role: u.Role, Name: u.Name, Phone: u.Phone, HasDrawn: u.HasDrawn,
+			CheckedInAt: u.CheckedInAt, CheckInMethod: u.CheckInMethod, CheckedInBy: u.CheckedInBy,
+			Waitlisted: u.Waitlisted, RegisteredIP: u.RegisteredIP, ReviewStatus: u.ReviewStatus,
+			ReviewReason: u.ReviewReason, Fields: userFields[u.ID], CreatedAt: u.CreatedAt,
+		})
+	}
+
+	var records []models.DrawRecord
+	if err := db.Where("company_id = ?", companyID).Order("id ASC").Find(&records).Error; err != nil {
+		return nil, err
+	}
+	for _, r := range records {
+		data.DrawRecords = append(data.DrawRecords, DrawRecord{
+			ID: r.ID, UserID: r.UserID, LevelID: r.LevelID, PrizeID: r.PrizeID, IP: r.IP, Status: r.Status,
+			ClaimedAt: r.ClaimedAt, RolledOver: r.RolledOver,
+			RecipientName: r.RecipientName, RecipientPhone: r.RecipientPhone, ShippingAddress: r.ShippingAddress,
+			Courier: r.Courier, TrackingNumber: r.TrackingNumber, FulfillmentNote: r.FulfillmentNote,
+			ShippedAt: r.ShippedAt, CompletedAt: r.CompletedAt,
+			RedemptionNonce: r.RedemptionNonce, RedeemedAt: r.RedeemedAt, RedeemedBy: r.RedeemedBy,
+			VariantID: r.VariantID, VariantDeadline: r.VariantDeadline, VariantAutoAssigned: r.VariantAutoAssigned,
+			CreatedAt: r.CreatedAt,
+		})
+	}
+
+	var rollovers []models.PrizeRollover
+	if err := db.Where("company_id = ?", companyID).Order("id ASC").Find(&rollovers).Error; err != nil {
+		return nil, err
+	}
+	for _, r := range rollovers {
+		data.PrizeRollovers = append(data.PrizeRollovers, RolloverRecord{
+			DrawRecordID: r.DrawRecordID, UserID: r.UserID, FromLevelID: r.FromLevelID, FromPrizeID: r.FromPrizeID,
+			ToLevelID: r.ToLevelID, ToPrizeID: r.ToPrizeID, PrizeName: r.PrizeName, Reason: r.Reason,
+			OperatorID: r.OperatorID, CreatedAt: r.CreatedAt,
+		})
+	}
+
+	var dismissals []models.DuplicateDismissal
+	if err := db.Where("company_id = ?", companyID).Order("id ASC").Find(&dismissals).Error; err != nil {
+		return nil, err
+	}
+	for _, d := range dismissals {
+		data.DuplicateDismissals = append(data.DuplicateDismissals, DismissalRecord{
+			UserAID: d.UserAID, UserBID: d.UserBID, DismissedBy: d.DismissedBy, CreatedAt: d.CreatedAt,
+		})
+	}
+
+	var logs []models.OperationLog
+	if err := db.Where("company_id = ?", companyID).Order("id ASC").Find(&logs).Error; err != nil {
+		return nil, err
+	}
+	for _, l := range logs {
+		data.OperationLogs = append(data.OperationLogs, LogRecord{
+			AdminID: l.AdminID, AdminName: l.AdminName, Action: l.Action, Resource: l.Resource, ResourceID: l.ResourceID,
+			Details: l.Details, IPAddress: l.IPAddress, UserAgent: l.UserAgent, CreatedAt: l.CreatedAt,
+		})
+	}
+
+	return data, nil
+}
+
+// companyRecord 公司设置转换为归档记录
+func companyRecord(c models.Company) CompanyRecord {
+	return CompanyRecord{
+		Code: c.Code, Name: c.Name, Logo: c.Logo, ThemeColor: c.ThemeColor, BgColor: c.BgColor,
+		Title: c.Title, Subtitle: c.Subtitle, WelcomeText: c.WelcomeText, RulesText: c.RulesText,
+		DrawButtonText: c.DrawButtonText, SuccessText: c.SuccessText,
+		ContactName: c.ContactName, ContactPhone: c.ContactPhone, ContactEmail: c.ContactEmail,
+		DrawOrder: c.DrawOrder, PrizeBudget: c.PrizeBudget, BudgetCurrency: c.BudgetCurrency, TaxBrackets: c.TaxBrackets,
+		CheckInRequired: c.CheckInRequired, CheckInCutoffAt: c.CheckInCutoffAt,
+		RegistrationOpensAt: c.RegistrationOpensAt, RegistrationClosesAt: c.RegistrationClosesAt,
+		RegistrationClosed: c.RegistrationClosed, MaxParticipants: c.MaxParticipants, WaitlistEnabled: c.WaitlistEnabled,
+		PhoneOTPRequired: c.PhoneOTPRequired, ChallengeMode: c.ChallengeMode,
+		IsActive: c.IsActive, EventClosedAt: c.EventClosedAt, RetentionDays: c.RetentionDays, PIIPurgedAt: c.PIIPurgedAt,
+		CreatedAt: c.CreatedAt,
+	}
+}
+
+// counts 各类数据的条数
+func (d *Data) counts() map[string]int {
+	return map[string]int{
+		"admins":               len(d.Admins),
+		"prize_levels":         len(d.PrizeLevels),
+		"prizes":               len(d.Prizes),
+		"prize_variants":       len(d.PrizeVariants),
+		"registration_fields":  len(d.RegistrationFields),
+		"users":                len(d.Users),
+		"draw_records":         len(d.DrawRecords),
+		"prize_rollovers":      len(d.PrizeRollovers),
+		"duplicate_dismissals": len(d.DuplicateDismissals),
+		"operation_logs":       len(d.OperationLogs),
+	}
+}
+
+// readAsset 从存储读取文件
+func readAsset(ctx context.Context, store storage.Storage, key string) ([]byte, error) {
+	reader, _, err := store.Get(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+	defer reader.Close()
+	content, err := io.ReadAll(io.LimitReader(reader, maxAssetBytes+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(content) > maxAssetBytes {
+		return nil, fmt.Errorf("asset too large")
+	}
+	return content, nil
+}
+
+// writeJSON 写入 zip 中的 JSON 文件
+func writeJSON(zw *zip.Writer, name string, v interface{}) error {
+	f, err := zw.Create(name)
+	if err != nil {
+		return err
+	}
+	enc := json.NewEncoder(f)
+	enc.SetIndent("", "  ")
+	return enc.Encode(v)
+}
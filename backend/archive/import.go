@@ -0,0 +1,565 @@
+package archive
+
+import (
+	"archive/zip"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"strings"
+
+	"lottery-system/models"
+	"lottery-system/storage"
+	"lottery-system/utils"
+
+	"gorm.io/gorm"
+)
+
+// maxDataBytes data.json 解压后的大小上限
+const maxDataBytes = 512 << 20
+
+// ImportOptions 导入选项
+type ImportOptions struct {
+	Options
+	Code       string // 导入后的公司代码，为空时使用归档中的代码
+	OnConflict string // 公司代码已存在时的处理方式：fail（默认）、rename
+}
+
+// Result 导入结果
+type Result struct {
+	CompanyID int            `json:"company_id"`
+	Code      string         `json:"code"`
+	Counts    map[string]int `json:"counts"`
+	Warnings  []string       `json:"warnings,omitempty"`
+}
+
+// ReadManifest 读取归档说明并检查格式版本（不导入数据）
+func ReadManifest(r io.ReaderAt, size int64) (*Manifest, error) {
+	zr, err := zip.NewReader(r, size)
+	if err != nil {
+		return nil, ErrInvalidArchive
+	}
+	return readManifest(zr)
+}
+
+// Import 导入归档为新公司：所有记录分配新 ID 并重建关联，在同一事务中完成
+// 管理员不含密码，导入后需重置密码；目标实例已存在同名管理员时跳过（不会关联到新公司）
+func Import(ctx context.Context, db *gorm.DB, r io.ReaderAt, size int64, opts ImportOptions) (*Result, error) {
+	zr, err := zip.NewReader(r, size)
+	if err != nil {
+		return nil, ErrInvalidArchive
+	}
+	if _, err := readManifest(zr); err != nil {
+		return nil, err
+	}
+
+	files := map[string]*zip.File{}
+	for _, f := range zr.File {
+		files[f.Name] = f
+	}
+	var data Data
+	if err := decodeFile(files[dataFile], maxDataBytes, &data); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidArchive, err)
+	}
+	if err := data.validate(); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidArchive, err)
+	}
+
+	code := opts.Code
+	if code == "" {
+		code = data.Company.Code
+		if err := utils.ValidateCompanyCode(code); err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidArchive, err)
+		}
+	} else if err := utils.ValidateCompanyCode(code); err != nil {
+		return nil, err
+	}
+	code, err = resolveCode(db, code, opts.OnConflict)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &Result{Code: code, Counts: data.counts()}
+	if err := restoreAssets(ctx, files, &data, opts.Options); err != nil {
+		return nil, err
+	}
+	for _, prize := range data.Prizes {
+		if prize.CodePool {
+			result.Warnings = append(result.Warnings, fmt.Sprintf("奖品 %s 的兑换码未包含在归档中，需重新导入兑换码", prize.Name))
+		}
+	}
+
+	err = db.Transaction(func(tx *gorm.DB) error {
+		im := &importer{tx: tx, data: &data, result: result,
+			admins: map[int]int{}, levels: map[int]int{}, prizes: map[int]int{}, variants: map[int]int{},
+			users: map[int]int{}, records: map[int]int{}}
+		return im.run(code)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// readManifest 读取并校验归档说明
+func readManifest(zr *zip.Reader) (*Manifest, error) {
+	var manifest Manifest
+	for _, f := range zr.File {
+		if f.Name == manifestFile {
+			if err := decodeFile(f, 1<<20, &manifest); err != nil {
+				return nil, ErrInvalidArchive
+			}
+			break
+		}
+	}
+	if manifest.Format != FormatName || manifest.Version < 1 {
+		return nil, ErrInvalidArchive
+	}
+	if manifest.Version > Version {
+		return nil, fmt.Errorf("%w: %d", ErrUnsupportedVersion, manifest.Version)
+	}
+	return &manifest, nil
+}
+
+// decodeFile 解析 zip 中的 JSON 文件
+func decodeFile(f *zip.File, limit int64, v interface{}) error {
+	if f == nil {
+		return errors.New("missing file")
+	}
+	rc, err := f.Open()
+	if err != nil {
+		return err
+	}
+	defer rc.Close()
+	return json.NewDecoder(io.LimitReader(rc, limit)).Decode(v)
+}
+
+// resolveCode 处理公司代码冲突，rename 时依次尝试 code-2、code-3…
+func resolveCode(db *gorm.DB, code, onConflict string) (string, error) {
+	exists := func(c string) (bool, error) {
+		var count int64
+		err := db.Model(&models.Company{}).Where("code = ?", c).Count(&count).Error
+		return count > 0, err
+	}
+
+	taken, err := exists(code)
+	if err != nil || !taken {
+		return code, err
+	}
+	if onConflict != ConflictRename {
+		return "", fmt.Errorf("%w: %s", ErrCodeConflict, code)
+	}
+	for i := 2; i < 100; i++ {
+		suffix := fmt.Sprintf("-%d", i)
+		base := code
+		if len(base)+len(suffix) > 50 {
+			base = base[:50-len(suffix)]
+		}
+		candidate := base + suffix
+		if taken, err := exists(candidate); err != nil || !taken {
+			return candidate, err
+		}
+	}
+	return "", fmt.Errorf("%w: %s", ErrCodeConflict, code)
+}
+
+// restoreAssets 保存归档中被公司 Logo 或奖品图片引用的文件（对象键基于内容哈希，已存在时不覆盖）
+func restoreAssets(ctx context.Context, files map[string]*zip.File, data *Data, opts Options) error {
+	urls := []string{data.Company.Logo}
+	for _, prize := range data.Prizes {
+		urls = append(urls, prize.Image)
+	}
+
+	for _, url := range urls {
+		key := opts.assetKey(url)
+		f := files[assetsDir+key]
+		if key == "" || f == nil || path.Clean(f.Name) != f.Name {
+			continue
+		}
+		if reader, _, err := opts.Store.Get(ctx, key); err == nil {
+			reader.Close()
+			continue
+		} else if !errors.Is(err, storage.ErrNotFound) {
+			return err
+		}
+
+		rc, err := f.Open()
+		if err != nil {
+			return fmt.Errorf("%w: %v", ErrInvalidArchive, err)
+		}
+		content, err := io.ReadAll(io.LimitReader(rc, maxAssetBytes+1))
+		rc.Close()
+		if err != nil || len(content) > maxAssetBytes {
+			return fmt.Errorf("%w: asset %s", ErrInvalidArchive, key)
+		}
+		contentType, err := utils.SniffImageType(content)
+		if err != nil {
+			continue // 只恢复图片文件
+		}
+		if err := opts.Store.Put(ctx, key, content, contentType); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// validate 检查归档内部关联是否完整
+func (d *Data) validate() error {
+	if d.Company.Code == "" || strings.TrimSpace(d.Company.Name) == "" {
+		return errors.New("missing company")
+	}
+	ids := func(n int, id func(int) int) map[int]bool {
+		set := make(map[int]bool, n)
+		for i := 0; i < n; i++ {
+			set[id(i)] = true
+		}
+		return set
+	}
+	levels := ids(len(d.PrizeLevels), func(i int) int { return d.PrizeLevels[i].ID })
+	prizes := ids(len(d.Prizes), func(i int) int { return d.Prizes[i].ID })
+	variants := ids(len(d.PrizeVariants), func(i int) int { return d.PrizeVariants[i].ID })
+	users := ids(len(d.Users), func(i int) int { return d.Users[i].ID })
+	records := ids(len(d.DrawRecords), func(i int) int { return d.DrawRecords[i].ID })
+
+	for _, p := range d.Prizes {
+		if !levels[p.LevelID] {
+			return fmt.Errorf("prize %d references unknown level %d", p.ID, p.LevelID)
+		}
+	}
+	for _, v := range d.PrizeVariants {
+		if !prizes[v.PrizeID] {
+			return fmt.Errorf("variant %d references unknown prize %d", v.ID, v.PrizeID)
+		}
+	}
+	for _, r := range d.DrawRecords {
+		if !users[r.UserID] || !levels[r.LevelID] || !prizes[r.PrizeID] || (r.VariantID != nil && !variants[*r.VariantID]) {
+			return fmt.Errorf("draw record %d has dangling references", r.ID)
+		}
+	}
+	for _, r := range d.PrizeRollovers {
+		if !records[r.DrawRecordID] || !users[r.UserID] || !levels[r.FromLevelID] || !levels[r.ToLevelID] ||
+			!prizes[r.FromPrizeID] || !prizes[r.ToPrizeID] {
+			return fmt.Errorf("rollover of draw record %d has dangling references", r.DrawRecordID)
+		}
+	}
+	return nil
+}
+
+// importer 导入过程中的 ID 映射（归档 ID → 新 ID）
+type importer struct {
+	tx     *gorm.DB
+	data   *Data
+	result *Result
+
+	companyID int
+	admins    map[int]int
+	levels    map[int]int
+	prizes    map[int]int
+	variants  map[int]int
+	users     map[int]int
+	records   map[int]int
+}
+
+// run 按依赖顺序写入所有数据
+func (im *importer) run(code string) error {
+	steps := []func() error{
+		func() error { return im.importCompany(code) },
+		im.importAdmins,
+		im.importPrizes,
+		im.importFields,
+		im.importUsers,
+		im.importDrawRecords,
+		im.importLogs,
+	}
+	for _, step := range steps {
+		if err := step(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (im *importer) importCompany(code string) error {
+	c := im.data.Company
+	company := models.Company{
+		Code: code, Name: c.Name, Logo: c.Logo, ThemeColor: c.ThemeColor, BgColor: c.BgColor,
+		Title: c.Title, Subtitle: c.Subtitle, WelcomeText: c.WelcomeText, RulesText: c.RulesText,
+		DrawButtonText: c.DrawButtonText, SuccessText: c.SuccessText,
+		ContactName: c.ContactName, ContactPhone: c.ContactPhone, ContactEmail: c.ContactEmail,
+		DrawOrder: c.DrawOrder, PrizeBudget: c.PrizeBudget, BudgetCurrency: c.BudgetCurrency, TaxBrackets: c.TaxBrackets,
+		CheckInRequired: c.CheckInRequired, CheckInCutoffAt: c.CheckInCutoffAt,
+		RegistrationOpensAt: c.RegistrationOpensAt, RegistrationClosesAt: c.RegistrationClosesAt,
+		RegistrationClosed: c.RegistrationClosed, MaxParticipants: c.MaxParticipants, WaitlistEnabled: c.WaitlistEnabled,
+		PhoneOTPRequired: c.PhoneOTPRequired, ChallengeMode: c.ChallengeMode,
+		IsActive: c.IsActive, EventClosedAt: c.EventClosedAt, RetentionDays: c.RetentionDays, PIIPurgedAt: c.PIIPurgedAt,
+		CreatedAt: c.CreatedAt,
+	}
+	if company.DrawOrder == "" || !models.DrawOrderIsValid(company.DrawOrder) {
+		company.DrawOrder = models.DrawOrderNone
+	}
+	if err := im.tx.Create(&company).Error; err != nil {
+		return err
+	}
+	// 带默认值 true 的字段为 false 时 Create 会被忽略，需单独更新
+	if !c.IsActive {
+		if err := im.tx.Model(&company).Update("is_active", false).Error; err != nil {
+			return err
+		}
+	}
+	im.companyID = company.ID
+	im.result.CompanyID = company.ID
+	return nil
+}
+
+func (im *importer) importAdmins() error {
+	created := 0
+	for _, a := range im.data.Admins {
+		var count int64
+		if err := im.tx.Model(&models.Admin{}).Where("username = ?", a.Username).Count(&count).Error; err != nil {
+			return err
+		}
+		if count > 0 || a.Username == "" {
+			im.result.Warnings = append(im.result.Warnings, fmt.Sprintf("管理员 %s 已存在，未导入", a.Username))
+			continue
+		}
+		companyID := im.companyID
+		admin := models.Admin{Username: a.Username, Password: "", Role: a.Role, CompanyID: &companyID, CreatedAt: a.CreatedAt}
+		if admin.Role == "" || admin.Role == models.RoleSuperAdmin {
+			admin.Role = models.RoleAdmin
+		}
+		if err := im.tx.Create(&admin).Error; err != nil {
+			return err
+		}
+		im.admins[a.ID] = admin.ID
+		created++
+	}
+	if created > 0 {
+		im.result.Warnings = append(im.result.Warnings, fmt.Sprintf("已导入 %d 个管理员，需重置密码后才能登录", created))
+	}
+	return nil
+}
+
+func (im *importer) importPrizes() error {
+	var inactive []int
+	for _, l := range im.data.PrizeLevels {
+		level := models.PrizeLevel{
+			CompanyID: im.companyID, Name: l.Name, Description: l.Description, Probability: l.Probability,
+			TotalStock: l.TotalStock, UsedStock: l.UsedStock, SortOrder: l.SortOrder, IsActive: l.IsActive,
+			DrawState: l.DrawState, CreatedAt: l.CreatedAt,
+		}
+		if err := im.tx.Create(&level).Error; err != nil {
+			return err
+		}
+		im.levels[l.ID] = level.ID
+		if !l.IsActive {
+			inactive = append(inactive, level.ID)
+		}
+	}
+	if len(inactive) > 0 {
+		if err := im.tx.Model(&models.PrizeLevel{}).Where("id IN ?", inactive).Update("is_active", false).Error; err != nil {
+			return err
+		}
+	}
+	// 回流目标需在所有奖项创建后再关联
+	for _, l := range im.data.PrizeLevels {
+		if target := im.mapID(im.levels, l.RolloverLevelID); target != nil {
+			if err := im.tx.Model(&models.PrizeLevel{}).Where("id = ?", im.levels[l.ID]).Update("rollover_level_id", *target).Error; err != nil {
+				return err
+			}
+		}
+	}
+
+	for _, p := range im.data.Prizes {
+		prize := models.Prize{
+			LevelID: im.levels[p.LevelID], Name: p.Name, Image: p.Image, TotalStock: p.TotalStock, UsedStock: p.UsedStock,
+			Value: p.Value, Currency: p.Currency, CodePool: p.CodePool, CodeAlertThreshold: p.CodeAlertThreshold,
+			HasVariants: p.HasVariants, VariantPickHours: p.VariantPickHours, CreatedAt: p.CreatedAt,
+		}
+		if prize.Currency == "" {
+			prize.Currency = "CNY"
+		}
+		if err := im.tx.Create(&prize).Error; err != nil {
+			return err
+		}
+		im.prizes[p.ID] = prize.ID
+	}
+
+	for _, v := range im.data.PrizeVariants {
+		variant := models.PrizeVariant{
+			PrizeID: im.prizes[v.PrizeID], Name: v.Name, TotalStock: v.TotalStock, UsedStock: v.UsedStock,
+			SortOrder: v.SortOrder, CreatedAt: v.CreatedAt,
+		}
+		if err := im.tx.Create(&variant).Error; err != nil {
+			return err
+		}
+		im.variants[v.ID] = variant.ID
+	}
+	return nil
+}
+
+func (im *importer) importFields() error {
+	var inactive []int
+	for _, f := range im.data.RegistrationFields {
+		field := models.RegistrationField{
+			CompanyID: im.companyID, Key: f.Key, Label: f.Label, Type: f.Type, Required: f.Required, Pattern: f.Pattern,
+			Options: f.Options, MaxLength: f.MaxLength, SortOrder: f.SortOrder, IsActive: f.IsActive, CreatedAt: f.CreatedAt,
+		}
+		if err := utils.ValidateFieldDefinition(&field); err != nil {
+			return fmt.Errorf("%w: registration field %s: %v", ErrInvalidArchive, f.Key, err)
+		}
+		if err := im.tx.Create(&field).Error; err != nil {
+			return err
+		}
+		if !f.IsActive {
+			inactive = append(inactive, field.ID)
+		}
+	}
+	if len(inactive) > 0 {
+		return im.tx.Model(&models.RegistrationField{}).Where("id IN ?", inactive).Update("is_active", false).Error
+	}
+	return nil
+}
+
+func (im *importer) importUsers() error {
+	const batchSize = 200
+	for start := 0; start < len(im.data.Users); start += batchSize {
+		end := start + batchSize
+		if end > len(im.data.Users) {
+			end = len(im.data.Users)
+		}
+		records := im.data.Users[start:end]
+
+		users := make([]models.User, len(records))
+		for i, u := range records {
+			users[i] = models.User{
+				CompanyID: im.companyID, Username: u.Username, Password: "", Role: u.Role, Name: u.Name, Phone: u.Phone,
+				HasDrawn: u.HasDrawn, CheckedInAt: u.CheckedInAt, CheckInMethod: u.CheckInMethod,
+				CheckedInBy: im.mapID(im.admins, u.CheckedInBy), Waitlisted: u.Waitlisted, RegisteredIP: u.RegisteredIP,
+				ReviewStatus: u.ReviewStatus, ReviewReason: u.ReviewReason, CreatedAt: u.CreatedAt,
+			}
+			if users[i].Role == "" {
+				users[i].Role = models.RoleUser
+			}
+			// 旧版归档中以手机号作为用户名的参与者，创建后改为系统生成的用户名
+			if models.IsPhoneUsername(u.Username, u.Phone) {
+				users[i].Username = ""
+			}
+		}
+		if err := im.tx.Create(&users).Error; err != nil {
+			return err
+		}
+
+		var values []models.UserFieldValue
+		for i, u := range records {
+			im.users[u.ID] = users[i].ID
+			for key, value := range u.Fields {
+				values = append(values, models.UserFieldValue{CompanyID: im.companyID, UserID: users[i].ID, FieldKey: key, Value: value})
+			}
+		}
+		if len(values) > 0 {
+			if err := im.tx.CreateInBatches(&values, 500).Error; err != nil {
+				return err
+			}
+		}
+	}
+
+	for _, d := range im.data.DuplicateDismissals {
+		a, b := im.users[d.UserAID], im.users[d.UserBID]
+		if a == 0 || b == 0 {
+			continue
+		}
+		if a > b {
+			a, b = b, a
+		}
+		dismissal := models.DuplicateDismissal{CompanyID: im.companyID, UserAID: a, UserBID: b, CreatedAt: d.CreatedAt}
+		if by := im.admins[d.DismissedBy]; by > 0 {
+			dismissal.DismissedBy = by
+		}
+		if err := im.tx.Create(&dismissal).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (im *importer) importDrawRecords() error {
+	for _, r := range im.data.DrawRecords {
+		record := models.DrawRecord{
+			CompanyID: im.companyID, UserID: im.users[r.UserID], LevelID: im.levels[r.LevelID], PrizeID: im.prizes[r.PrizeID],
+			IP: r.IP, Status: r.Status, ClaimedAt: r.ClaimedAt, RolledOver: r.RolledOver,
+			RecipientName: r.RecipientName, RecipientPhone: r.RecipientPhone, ShippingAddress: r.ShippingAddress,
+			Courier: r.Courier, TrackingNumber: r.TrackingNumber, FulfillmentNote: r.FulfillmentNote,
+			ShippedAt: r.ShippedAt, CompletedAt: r.CompletedAt,
+			RedemptionNonce: r.RedemptionNonce, RedeemedAt: r.RedeemedAt, RedeemedBy: im.mapID(im.admins, r.RedeemedBy),
+			VariantID: im.mapID(im.variants, r.VariantID), VariantDeadline: r.VariantDeadline,
+			VariantAutoAssigned: r.VariantAutoAssigned, CreatedAt: r.CreatedAt,
+		}
+		if !models.DrawStatusIsValid(record.Status) {
+			record.Status = models.DrawStatusWon
+		}
+		if err := im.tx.Create(&record).Error; err != nil {
+			return err
+		}
+		im.records[r.ID] = record.ID
+	}
+
+	for _, r := range im.data.PrizeRollovers {
+		rollover := models.PrizeRollover{
+			CompanyID: im.companyID, DrawRecordID: im.records[r.DrawRecordID], UserID: im.users[r.UserID],
+			FromLevelID: im.levels[r.FromLevelID], FromPrizeID: im.prizes[r.FromPrizeID],
+			ToLevelID: im.levels[r.ToLevelID], ToPrizeID: im.prizes[r.ToPrizeID],
+			PrizeName: r.PrizeName, Reason: r.Reason, OperatorID: uint(im.admins[int(r.OperatorID)]), CreatedAt: r.CreatedAt,
+		}
+		if err := im.tx.Create(&rollover).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// importLogs 导入操作日志：操作人和资源 ID 映射为新 ID，无法映射的资源 ID 置空（操作人名称保留）
+func (im *importer) importLogs() error {
+	companyID := uint(im.companyID)
+	resourceMaps := map[string]map[int]int{
+		"admin":       im.admins,
+		"prize_level": im.levels,
+		"prize":       im.prizes,
+		"user":        im.users,
+		"draw_record": im.records,
+	}
+
+	logs := make([]models.OperationLog, 0, len(im.data.OperationLogs))
+	for _, l := range im.data.OperationLogs {
+		var resourceID *uint
+		if l.ResourceID != nil {
+			if l.Resource == "company" {
+				resourceID = &companyID
+			} else if id := resourceMaps[l.Resource][int(*l.ResourceID)]; id > 0 {
+				rid := uint(id)
+				resourceID = &rid
+			}
+		}
+		logs = append(logs, models.OperationLog{
+			AdminID: uint(im.admins[int(l.AdminID)]), AdminName: l.AdminName, CompanyID: &companyID,
+			Action: l.Action, Resource: l.Resource, ResourceID: resourceID, Details: l.Details,
+			IPAddress: l.IPAddress, UserAgent: l.UserAgent, CreatedAt: l.CreatedAt,
+		})
+	}
+	if len(logs) == 0 {
+		return nil
+	}
+	return im.tx.CreateInBatches(&logs, 500).Error
+}
+
+// mapID 映射可选 ID，无法映射时返回 nil
+func (im *importer) mapID(m map[int]int, id *int) *int {
+	if id == nil {
+		return nil
+	}
+	if mapped, ok := m[*id]; ok {
+		return &mapped
+	}
+	return nil
+}
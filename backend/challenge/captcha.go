@@ -0,0 +1,189 @@
+package challenge
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/base64"
+	"image"
+	"image/color"
+	"image/png"
+	"math/big"
+	mathrand "math/rand"
+	"time"
+)
+
+// TypeCaptcha 图形验证码挑战
+const TypeCaptcha = "captcha"
+
+// 验证码图片参数
+const (
+	captchaLength = 5   // 数字位数
+	captchaWidth  = 160 // 图片宽度（像素）
+	captchaHeight = 60  // 图片高度（像素）
+	captchaScale  = 4   // 字形点阵放大倍数
+)
+
+// captchaFont 5x7 数字点阵字形（每行低 5 位有效，高位在左）
+var captchaFont = [10][7]uint8{
+	{0b01110, 0b10001, 0b10011, 0b10101, 0b11001, 0b10001, 0b01110}, // 0
+	{0b00100, 0b01100, 0b00100, 0b00100, 0b00100, 0b00100, 0b01110}, // 1
+	{0b01110, 0b10001, 0b00001, 0b00010, 0b00100, 0b01000, 0b11111}, // 2
+	{0b11111, 0b00010, 0b00100, 0b00010, 0b00001, 0b10001, 0b01110}, // 3
+	{0b00010, 0b00110, 0b01010, 0b10010, 0b11111, 0b00010, 0b00010}, // 4
+	{0b11111, 0b10000, 0b11110, 0b00001, 0b00001, 0b10001, 0b01110}, // 5
+	{0b00110, 0b01000, 0b10000, 0b11110, 0b10001, 0b10001, 0b01110}, // 6
+	{0b11111, 0b00001, 0b00010, 0b00100, 0b01000, 0b01000, 0b01000}, // 7
+	{0b01110, 0b10001, 0b10001, 0b01110, 0b10001, 0b10001, 0b01110}, // 8
+	{0b01110, 0b10001, 0b10001, 0b01111, 0b00001, 0b00010, 0b01100}, // 9
+}
+
+// CaptchaProvider 内置数字图形验证码（纯 Go 绘制，不依赖字体文件和第三方服务）
+// 令牌中只保存答案的 HMAC 摘要，不保存明文
+type CaptchaProvider struct {
+	*Signer
+}
+
+// NewCaptchaProvider 创建图形验证码提供者
+func NewCaptchaProvider(key []byte, ttl time.Duration) *CaptchaProvider {
+	return &CaptchaProvider{Signer: NewSigner(key, ttl)}
+}
+
+// Type 挑战类型
+func (p *CaptchaProvider) Type() string {
+	return TypeCaptcha
+}
+
+// Issue 生成随机数字并绘制验证码图片
+func (p *CaptchaProvider) Issue(now time.Time) (*Challenge, error) {
+	id, err := newChallengeID()
+	if err != nil {
+		return nil, err
+	}
+
+	digits := make([]int, captchaLength)
+	answer := make([]byte, captchaLength)
+	for i := range digits {
+		n, err := rand.Int(rand.Reader, big.NewInt(10))
+		if err != nil {
+			return nil, err
+		}
+		digits[i] = int(n.Int64())
+		answer[i] = byte('0' + digits[i])
+	}
+
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, renderCaptcha(digits)); err != nil {
+		return nil, err
+	}
+
+	token, expiresAt := p.Sign(TypeCaptcha, id, p.answerMAC(id, string(answer)), now)
+	return &Challenge{
+		Type:      TypeCaptcha,
+		Token:     token,
+		ExpiresAt: expiresAt,
+		Image:     "data:image/png;base64," + base64.StdEncoding.EncodeToString(buf.Bytes()),
+	}, nil
+}
+
+// CheckAnswer 校验用户输入的验证码
+func (p *CaptchaProvider) CheckAnswer(claims *Claims, answer string) bool {
+	if len(answer) != captchaLength {
+		return false
+	}
+	return p.answerMAC(claims.ID, answer) == claims.Param
+}
+
+// answerMAC 验证码答案摘要（绑定挑战ID）
+func (p *CaptchaProvider) answerMAC(id, answer string) string {
+	return p.MAC("captcha-answer:" + id + ":" + answer)
+}
+
+// renderCaptcha 绘制验证码：浅色随机背景、每个数字随机偏移和倾斜，叠加干扰线和噪点
+func renderCaptcha(digits []int) image.Image {
+	img := image.NewRGBA(image.Rect(0, 0, captchaWidth, captchaHeight))
+	bg := color.RGBA{uint8(225 + mathrand.Intn(30)), uint8(225 + mathrand.Intn(30)), uint8(225 + mathrand.Intn(30)), 255}
+	for y := 0; y < captchaHeight; y++ {
+		for x := 0; x < captchaWidth; x++ {
+			img.SetRGBA(x, y, bg)
+		}
+	}
+
+	// 干扰线（在数字下层）
+	for i := 0; i < 4; i++ {
+		drawLine(img, mathrand.Intn(captchaWidth), mathrand.Intn(captchaHeight),
+			mathrand.Intn(captchaWidth), mathrand.Intn(captchaHeight), randomInk(120))
+	}
+
+	glyphWidth := 5 * captchaScale
+	glyphHeight := 7 * captchaScale
+	step := (captchaWidth - 20) / len(digits)
+	for i, d := range digits {
+		ink := randomInk(90)
+		originX := 10 + i*step + (step-glyphWidth)/2 + mathrand.Intn(7) - 3
+		originY := (captchaHeight-glyphHeight)/2 + mathrand.Intn(11) - 5
+		shear := mathrand.Intn(3) - 1 // 每行水平偏移像素，形成倾斜
+		for row := 0; row < 7; row++ {
+			for col := 0; col < 5; col++ {
+				if captchaFont[d][row]&(1<<(4-col)) == 0 {
+					continue
+				}
+				for dy := 0; dy < captchaScale; dy++ {
+					y := originY + row*captchaScale + dy
+					x0 := originX + col*captchaScale + (3-row)*shear
+					for dx := 0; dx < captchaScale; dx++ {
+						img.SetRGBA(x0+dx, y, ink)
+					}
+				}
+			}
+		}
+	}
+
+	// 干扰线和噪点（在数字上层）
+	for i := 0; i < 3; i++ {
+		drawLine(img, 0, mathrand.Intn(captchaHeight), captchaWidth-1, mathrand.Intn(captchaHeight), randomInk(140))
+	}
+	for i := 0; i < captchaWidth*captchaHeight/12; i++ {
+		img.SetRGBA(mathrand.Intn(captchaWidth), mathrand.Intn(captchaHeight), randomInk(200))
+	}
+	return img
+}
+
+// randomInk 随机深色（各分量不超过 max）
+func randomInk(max int) color.RGBA {
+	return color.RGBA{uint8(mathrand.Intn(max)), uint8(mathrand.Intn(max)), uint8(mathrand.Intn(max)), 255}
+}
+
+// drawLine 绘制直线（Bresenham 算法）
+func drawLine(img *image.RGBA, x0, y0, x1, y1 int, c color.RGBA) {
+	dx, dy := abs(x1-x0), -abs(y1-y0)
+	sx, sy := 1, 1
+	if x0 > x1 {
+		sx = -1
+	}
+	if y0 > y1 {
+		sy = -1
+	}
+	e := dx + dy
+	for {
+		img.SetRGBA(x0, y0, c)
+		if x0 == x1 && y0 == y1 {
+			return
+		}
+		e2 := 2 * e
+		if e2 >= dy {
+			e += dy
+			x0 += sx
+		}
+		if e2 <= dx {
+			e += dx
+			y0 += sy
+		}
+	}
+}
+
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
@@ -0,0 +1,190 @@
+// Package challenge 提供公开报名接口的人机验证，内置图形验证码和工作量证明两种挑战，无需第三方服务
+// 挑战凭证为无状态的 HMAC 签名令牌，提交时通过 ReplayGuard 记录已使用的挑战，每个挑战只能提交一次
+package challenge
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultTTL 挑战默认有效期
+const DefaultTTL = 5 * time.Minute
+
+// 挑战校验错误
+var (
+	ErrUnknownType  = errors.New("challenge type not registered")
+	ErrInvalidToken = errors.New("invalid challenge token")
+	ErrExpired      = errors.New("challenge expired")
+	ErrWrongAnswer  = errors.New("wrong challenge answer")
+	ErrAlreadyUsed  = errors.New("challenge already used")
+)
+
+// Challenge 下发给客户端的挑战
+type Challenge struct {
+	Type      string    `json:"type"`
+	Token     string    `json:"token"` // 提交答案时原样带回
+	ExpiresAt time.Time `json:"expires_at"`
+
+	Image string `json:"image,omitempty"` // 图形验证码（data URL）
+
+	Nonce      string `json:"nonce,omitempty"`      // 工作量证明：计算 sha256(nonce + ":" + answer)
+	Difficulty int    `json:"difficulty,omitempty"` // 工作量证明：摘要需要的前导零比特数
+	Algorithm  string `json:"algorithm,omitempty"`
+}
+
+// Claims 挑战令牌中的签名声明
+type Claims struct {
+	Type      string
+	ID        string
+	ExpiresAt time.Time
+	Param     string // 挑战类型自定义参数（如验证码答案摘要、难度）
+}
+
+// Provider 挑战提供者，可注册自定义实现（如接入第三方验证服务）
+type Provider interface {
+	// Type 挑战类型名称（公司配置 challenge_mode 的取值）
+	Type() string
+	// Issue 签发新挑战
+	Issue(now time.Time) (*Challenge, error)
+	// Parse 校验令牌签名和有效期
+	Parse(token string, now time.Time) (*Claims, error)
+	// CheckAnswer 校验客户端提交的答案
+	CheckAnswer(claims *Claims, answer string) bool
+}
+
+// ReplayGuard 记录已使用的挑战（otp.Store 满足该接口）
+type ReplayGuard interface {
+	Incr(ctx context.Context, key string, window time.Duration) (int, time.Duration, error)
+}
+
+var (
+	providers  = map[string]Provider{}
+	providerMu sync.RWMutex
+)
+
+// Register 注册挑战提供者，同名提供者会被替换
+func Register(p Provider) {
+	providerMu.Lock()
+	defer providerMu.Unlock()
+	providers[p.Type()] = p
+}
+
+// Get 获取挑战提供者，未注册时返回 nil
+func Get(typ string) Provider {
+	providerMu.RLock()
+	defer providerMu.RUnlock()
+	return providers[typ]
+}
+
+// Types 返回已注册的挑战类型
+func Types() []string {
+	providerMu.RLock()
+	defer providerMu.RUnlock()
+	types := make([]string, 0, len(providers))
+	for typ := range providers {
+		types = append(types, typ)
+	}
+	sort.Strings(types)
+	return types
+}
+
+// Verify 校验挑战答案：令牌必须是 typ 类型、签名有效且未过期
+// 答案校验前先记录挑战已使用，无论答案对错每个挑战只能提交一次，防止对同一验证码反复猜测
+func Verify(ctx context.Context, guard ReplayGuard, typ, token, answer string, now time.Time) error {
+	p := Get(typ)
+	if p == nil {
+		return ErrUnknownType
+	}
+
+	claims, err := p.Parse(token, now)
+	if err != nil {
+		return err
+	}
+	if claims.Type != typ {
+		return ErrInvalidToken
+	}
+
+	count, _, err := guard.Incr(ctx, "challenge:"+claims.ID, claims.ExpiresAt.Sub(now)+time.Minute)
+	if err != nil {
+		return fmt.Errorf("record challenge: %w", err)
+	}
+	if count > 1 {
+		return ErrAlreadyUsed
+	}
+
+	if !p.CheckAnswer(claims, strings.TrimSpace(answer)) {
+		return ErrWrongAnswer
+	}
+	return nil
+}
+
+// Signer 签发和解析挑战令牌：<类型>.<挑战ID>.<过期时间戳>.<参数>.<HMAC签名>
+// 内置提供者共用，自定义提供者也可以使用
+type Signer struct {
+	key []byte
+	ttl time.Duration
+}
+
+// NewSigner 创建令牌签名器，ttl <= 0 时使用 DefaultTTL
+func NewSigner(key []byte, ttl time.Duration) *Signer {
+	if ttl <= 0 {
+		ttl = DefaultTTL
+	}
+	return &Signer{key: key, ttl: ttl}
+}
+
+// Sign 签发令牌，param 不能包含 "."
+func (s *Signer) Sign(typ, id, param string, now time.Time) (string, time.Time) {
+	expiresAt := now.Add(s.ttl)
+	payload := fmt.Sprintf("%s.%s.%d.%s", typ, id, expiresAt.Unix(), param)
+	return payload + "." + s.MAC(payload), expiresAt
+}
+
+// Parse 校验令牌签名和有效期
+func (s *Signer) Parse(token string, now time.Time) (*Claims, error) {
+	parts := strings.Split(strings.TrimSpace(token), ".")
+	if len(parts) != 5 || parts[0] == "" || parts[1] == "" {
+		return nil, ErrInvalidToken
+	}
+
+	payload := strings.Join(parts[:4], ".")
+	if !hmac.Equal([]byte(parts[4]), []byte(s.MAC(payload))) {
+		return nil, ErrInvalidToken
+	}
+
+	exp, err := strconv.ParseInt(parts[2], 10, 64)
+	if err != nil {
+		return nil, ErrInvalidToken
+	}
+	expiresAt := time.Unix(exp, 0)
+	if now.After(expiresAt) {
+		return nil, ErrExpired
+	}
+	return &Claims{Type: parts[0], ID: parts[1], ExpiresAt: expiresAt, Param: parts[3]}, nil
+}
+
+// MAC 计算 HMAC-SHA256（base64url 编码）
+func (s *Signer) MAC(data string) string {
+	mac := hmac.New(sha256.New, s.key)
+	mac.Write([]byte(data))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// newChallengeID 生成随机挑战ID
+func newChallengeID() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
@@ -0,0 +1,89 @@
+package challenge
+
+import (
+	"crypto/sha256"
+	"math/bits"
+	"strconv"
+	"time"
+)
+
+// TypePoW 工作量证明挑战
+const TypePoW = "pow"
+
+// 工作量证明难度范围（前导零比特数），默认 18 位约需浏览器计算数十万次哈希
+const (
+	DefaultPoWDifficulty = 18
+	minPoWDifficulty     = 8
+	maxPoWDifficulty     = 28
+)
+
+// maxPoWAnswerLength 答案（十进制计数器）最大长度
+const maxPoWAnswerLength = 20
+
+// PoWProvider 工作量证明：客户端需找到计数器 answer，使 sha256(nonce + ":" + answer) 的前导零比特数不少于 difficulty
+// 对正常用户几乎无感，但显著提高批量注册的成本
+type PoWProvider struct {
+	*Signer
+	difficulty int
+}
+
+// NewPoWProvider 创建工作量证明提供者，difficulty 超出范围时自动调整
+func NewPoWProvider(key []byte, difficulty int, ttl time.Duration) *PoWProvider {
+	if difficulty <= 0 {
+		difficulty = DefaultPoWDifficulty
+	}
+	if difficulty < minPoWDifficulty {
+		difficulty = minPoWDifficulty
+	}
+	if difficulty > maxPoWDifficulty {
+		difficulty = maxPoWDifficulty
+	}
+	return &PoWProvider{Signer: NewSigner(key, ttl), difficulty: difficulty}
+}
+
+// Type 挑战类型
+func (p *PoWProvider) Type() string {
+	return TypePoW
+}
+
+// Issue 签发工作量证明挑战（难度写入签名令牌，调整配置不影响已签发的挑战）
+func (p *PoWProvider) Issue(now time.Time) (*Challenge, error) {
+	id, err := newChallengeID()
+	if err != nil {
+		return nil, err
+	}
+	token, expiresAt := p.Sign(TypePoW, id, strconv.Itoa(p.difficulty), now)
+	return &Challenge{
+		Type:       TypePoW,
+		Token:      token,
+		ExpiresAt:  expiresAt,
+		Nonce:      id,
+		Difficulty: p.difficulty,
+		Algorithm:  "sha256",
+	}, nil
+}
+
+// CheckAnswer 校验计数器对应的摘要是否满足难度
+func (p *PoWProvider) CheckAnswer(claims *Claims, answer string) bool {
+	difficulty, err := strconv.Atoi(claims.Param)
+	if err != nil || difficulty < minPoWDifficulty || answer == "" || len(answer) > maxPoWAnswerLength {
+		return false
+	}
+	if _, err := strconv.ParseUint(answer, 10, 64); err != nil {
+		return false
+	}
+	sum := sha256.Sum256([]byte(claims.ID + ":" + answer))
+	return leadingZeroBits(sum[:]) >= difficulty
+}
+
+// leadingZeroBits 计算摘要的前导零比特数
+func leadingZeroBits(sum []byte) int {
+	n := 0
+	for _, b := range sum {
+		if b != 0 {
+			return n + bits.LeadingZeros8(b)
+		}
+		n += 8
+	}
+	return n
+}
@@ -4,6 +4,9 @@
 	"fmt"
 	"log"
 	"os"
+	"strings"
+
+	"lottery-system/utils"
 
 	"github.com/joho/godotenv"
 )
@@ -28,6 +31,56 @@ type Config struct {
 	// 默认管理员配置
 	DefaultAdminUsername string // 默认管理员用户名
 	DefaultAdminPassword string // 默认管理员密码
+
+	// 虚拟奖品兑换码加密密钥（为空时从 JWT_SECRET 派生）
+	PrizeCodeKey string
+
+	// 领奖核销二维码签名密钥（为空时从 JWT_SECRET 派生）
+	RedemptionKey string
+
+	// 签到二维码签名密钥（为空时从 JWT_SECRET 派生）
+	CheckInKey string
+
+	// 用户二维码签名配置（扫码添加用户）
+	UserQRKeys        string // 签名密钥环：<密钥ID>:<密钥>,...（为空时从 JWT_SECRET 派生）
+	UserQRActiveKey   string // 签发新二维码使用的密钥ID（为空时使用第一个）
+	UserQRTTLHours    int    // 二维码有效期（小时）
+	UserQRAllowLegacy bool   // 是否接受旧版未签名的明文二维码
+
+	// 短信验证码配置（扫码报名手机号验证）
+	SMSDriver       string // webhook, log, file（为空时不发送短信）
+	SMSFilePath     string // file 驱动的输出文件
+	SMSWebhookURL   string // webhook 驱动的短信网关地址
+	SMSWebhookToken string
+	OTPTTLMinutes   int // 验证码有效期（分钟）
+
+	// 参与者登录（手机验证码/登录链接）
+	PublicBaseURL         string // 前端公开访问地址（如 https://lottery.example.com），用于生成登录链接
+	ParticipantTokenHours int    // 参与者登录凭证有效期（小时）
+
+	// 报名防刷配置
+	ChallengePoWDifficulty int // 工作量证明难度（前导零比特数）
+	RegBurstIPLimit        int // 同一 IP 10 分钟内报名超过该人数时标记待审核（0 表示不检查）
+	RegBurstDeviceLimit    int // 同一设备 1 小时内报名超过该人数时标记待审核（0 表示不检查）
+
+	// 文件上传存储配置
+	StorageDriver string // local, s3, memory
+	UploadDir     string // 本地存储目录
+	UploadMaxMB   int    // 上传文件大小限制（MB）
+	S3Endpoint    string // S3 兼容存储地址（如 http://localhost:9000）
+	S3Region      string
+	S3Bucket      string
+	S3AccessKey   string
+	S3SecretKey   string
+
+	// 参与者目录同步（LDAP / HR 名单文件）
+	DirectoryDropDir string // HR 名单文件投放根目录，每个公司使用 <根目录>/<公司代码>/
+	DirectoryKey     string // LDAP 绑定密码加密密钥（为空时从 JWT_SECRET 派生）
+
+	// 参与者手机号加密存储
+	PhoneKeys      string // 主密钥环：<密钥ID>:<密钥>,...（为空时从 JWT_SECRET 派生）
+	PhoneActiveKey string // 加密使用的密钥ID（为空时使用第一个）
+	PhoneIndexKey  string // 盲索引密钥（为空时从 JWT_SECRET 派生，设置后不可随意更换）
 }
 
 var AppConfig *Config
@@ -75,12 +128,60 @@ func LoadConfig() {
 		// 默认管理员配置
 		DefaultAdminUsername: getEnv("DEFAULT_ADMIN_USERNAME", "makerroot"),
 		DefaultAdminPassword: getEnv("DEFAULT_ADMIN_PASSWORD", "123456"),
+		// 兑换码加密密钥
+		PrizeCodeKey: getEnv("PRIZE_CODE_KEY", ""),
+		// 领奖核销签名密钥
+		RedemptionKey: getEnv("REDEMPTION_KEY", ""),
+		// 签到二维码签名密钥
+		CheckInKey: getEnv("CHECKIN_KEY", ""),
+		// 用户二维码签名
+		UserQRKeys:        getEnv("USER_QR_KEYS", ""),
+		UserQRActiveKey:   getEnv("USER_QR_ACTIVE_KEY", ""),
+		UserQRTTLHours:    getEnvInt("USER_QR_TTL_HOURS", 72),
+		UserQRAllowLegacy: getEnvBool("USER_QR_ALLOW_LEGACY", false),
+		// 短信验证码
+		SMSDriver:       getEnv("SMS_DRIVER", ""),
+		SMSFilePath:     getEnv("SMS_FILE_PATH", "sms_outbox.log"),
+		SMSWebhookURL:   getEnv("SMS_WEBHOOK_URL", ""),
+		SMSWebhookToken: getEnv("SMS_WEBHOOK_TOKEN", ""),
+		OTPTTLMinutes:   getEnvInt("OTP_TTL_MINUTES", 5),
+		// 参与者登录
+		PublicBaseURL:         strings.TrimRight(getEnv("PUBLIC_BASE_URL", ""), "/"),
+		ParticipantTokenHours: getEnvInt("PARTICIPANT_TOKEN_HOURS", 24),
+		// 报名防刷
+		ChallengePoWDifficulty: getEnvInt("CHALLENGE_POW_DIFFICULTY", 18),
+		RegBurstIPLimit:        getEnvInt("REG_BURST_IP_LIMIT", 30),
+		RegBurstDeviceLimit:    getEnvInt("REG_BURST_DEVICE_LIMIT", 3),
+		// 文件上传存储
+		StorageDriver: getEnv("STORAGE_DRIVER", "local"),
+		UploadDir:     getEnv("UPLOAD_DIR", "uploads"),
+		UploadMaxMB:   getEnvInt("UPLOAD_MAX_MB", 5),
+		S3Endpoint:    getEnv("S3_ENDPOINT", ""),
+		S3Region:      getEnv("S3_REGION", "us-east-1"),
+		S3Bucket:      getEnv("S3_BUCKET", ""),
+		S3AccessKey:   getEnv("S3_ACCESS_KEY", ""),
+		S3SecretKey:   getEnv("S3_SECRET_KEY", ""),
+		// 目录同步
+		DirectoryDropDir: getEnv("DIRECTORY_DROP_DIR", "directory_drop"),
+		DirectoryKey:     getEnv("DIRECTORY_KEY", ""),
+		// 手机号加密
+		PhoneKeys:      getEnv("PHONE_KEYS", ""),
+		PhoneActiveKey: getEnv("PHONE_ACTIVE_KEY", ""),
+		PhoneIndexKey:  getEnv("PHONE_INDEX_KEY", ""),
 	}
 
 	log.Println("✅ Configuration loaded successfully")
 	log.Printf("📋 Server will listen on port: %s", AppConfig.ServerPort)
 }
 
+// PrizeCodeKey 获取兑换码加密密钥（未配置时从 JWT_SECRET 派生）
+func PrizeCodeKey() []byte {
+	if AppConfig.PrizeCodeKey != "" {
+		return utils.DeriveKey(AppConfig.PrizeCodeKey)
+	}
+	return utils.DeriveKey("prize-code:" + AppConfig.JWTSecret)
+}
+
 func getEnv(key, defaultValue string) string {
 	value := os.Getenv(key)
 	if value == "" {
@@ -7,6 +7,7 @@
 	"strings"
 	"time"
 
+	"lottery-system/fieldcrypt"
 	"lottery-system/migrations"
 	"lottery-system/models"
 	"lottery-system/utils"
@@ -28,6 +29,11 @@ func InitDB() {
 		}
 	}
 
+	// 手机号加密密钥环（读写用户数据和迁移前必须初始化）
+	if err := initFieldCrypt(); err != nil {
+		log.Fatal("Failed to initialize phone encryption keys:", err)
+	}
+
 	// 带重试的数据库连接
 	db, err := openDatabaseWithRetry()
 	if err != nil {
@@ -56,6 +62,26 @@ func InitDB() {
 	}
 }
 
+// initFieldCrypt 初始化手机号加密密钥环
+func initFieldCrypt() error {
+	indexKey := utils.DeriveKey("phone-index:" + AppConfig.JWTSecret)
+	if AppConfig.PhoneIndexKey != "" {
+		indexKey = utils.DeriveKey(AppConfig.PhoneIndexKey)
+	}
+	ring, err := fieldcrypt.ParseKeyring(
+		AppConfig.PhoneKeys,
+		AppConfig.PhoneActiveKey,
+		utils.DeriveKey("phone:"+AppConfig.JWTSecret),
+		indexKey,
+	)
+	if err != nil {
+		return err
+	}
+	fieldcrypt.SetDefault(ring)
+	log.Printf("✅ 手机号加密已启用（当前密钥: %s，密钥环: %s）", ring.ActiveKeyID(), strings.Join(ring.KeyIDs(), ","))
+	return nil
+}
+
 // createMySQLDatabaseIfNotExists 创建MySQL数据库（如果不存在）
 func createMySQLDatabaseIfNotExists() error {
 	// 从DSN中提取数据库名、主机、端口等信息
@@ -377,6 +403,19 @@ func runMigrations() error {
 	migrations.RegisterMigration(&migrations.Migration20260125ModifyUserUnique{})
 	migrations.RegisterMigration(&migrations.Migration20260125AddPrizeStock{})
 	migrations.RegisterMigration(&migrations.Migration20260131AllowDuplicateUsername{})
+	migrations.RegisterMigration(&migrations.Migration20261019AddDrawOrderGating{})
+	migrations.RegisterMigration(&migrations.Migration20261020AddPrizeFinance{})
+	migrations.RegisterMigration(&migrations.Migration20261021AddDrawFulfillment{})
+	migrations.RegisterMigration(&migrations.Migration20261022AddDrawRedemption{})
+	migrations.RegisterMigration(&migrations.Migration20261023AddCheckIn{})
+	migrations.RegisterMigration(&migrations.Migration20261024AddRegistrationWindow{})
+	migrations.RegisterMigration(&migrations.Migration20261025AddPhoneOTP{})
+	migrations.RegisterMigration(&migrations.Migration20261026AddRegistrationReview{})
+	migrations.RegisterMigration(&migrations.Migration20261027AddNameKeys{})
+	migrations.RegisterMigration(&migrations.Migration20261028AddDirectorySync{})
+	migrations.RegisterMigration(&migrations.Migration20261029AddPIIRetention{})
+	migrations.RegisterMigration(&migrations.Migration20261030EncryptUserPhones{})
+	migrations.RegisterMigration(&migrations.Migration20261101OpaqueUsernames{})
 
 	// 执行迁移
 	return migrations.RunMigrations(DB)
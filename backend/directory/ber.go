@@ -0,0 +1,180 @@
+package directory
+
+import (
+	"errors"
+	"io"
+)
+
+// 最小化的 BER 编解码，只实现 LDAP 消息所需的部分（单字节标签、定长编码）
+
+// maxMessageBytes 单条 LDAP 消息大小上限
+const maxMessageBytes = 16 << 20
+
+var errMalformedBER = errors.New("ldap: malformed BER data")
+
+// BER 通用标签
+const (
+	tagBoolean     = 0x01
+	tagInteger     = 0x02
+	tagOctetString = 0x04
+	tagEnumerated  = 0x0a
+	tagSequence    = 0x30
+	tagSet         = 0x31
+)
+
+// berElement 解码后的 BER 元素
+type berElement struct {
+	tag   byte
+	value []byte
+}
+
+// children 解析构造类型元素的子元素
+func (e berElement) children() ([]berElement, error) {
+	var elements []berElement
+	data := e.value
+	for len(data) > 0 {
+		el, rest, err := berParse(data)
+		if err != nil {
+			return nil, err
+		}
+		elements = append(elements, el)
+		data = rest
+	}
+	return elements, nil
+}
+
+// int 解析整数或枚举值
+func (e berElement) int() (int64, error) {
+	if len(e.value) == 0 || len(e.value) > 8 {
+		return 0, errMalformedBER
+	}
+	n := int64(int8(e.value[0]))
+	for _, b := range e.value[1:] {
+		n = n<<8 | int64(b)
+	}
+	return n, nil
+}
+
+// berParse 从 data 中解析一个元素，返回剩余数据
+func berParse(data []byte) (berElement, []byte, error) {
+	if len(data) < 2 || data[0]&0x1f == 0x1f {
+		return berElement{}, nil, errMalformedBER
+	}
+	tag := data[0]
+	length, n, err := berLength(data[1:])
+	if err != nil {
+		return berElement{}, nil, err
+	}
+	start := 1 + n
+	if length > len(data)-start {
+		return berElement{}, nil, errMalformedBER
+	}
+	return berElement{tag: tag, value: data[start : start+length]}, data[start+length:], nil
+}
+
+// berLength 解析长度字段，返回长度和长度字段占用的字节数
+func berLength(data []byte) (int, int, error) {
+	if len(data) == 0 {
+		return 0, 0, errMalformedBER
+	}
+	if data[0] < 0x80 {
+		return int(data[0]), 1, nil
+	}
+	size := int(data[0] & 0x7f)
+	if size == 0 || size > 4 || len(data) < 1+size {
+		return 0, 0, errMalformedBER // 不支持不定长编码
+	}
+	length := 0
+	for _, b := range data[1 : 1+size] {
+		length = length<<8 | int(b)
+	}
+	if length < 0 {
+		return 0, 0, errMalformedBER
+	}
+	return length, 1 + size, nil
+}
+
+// berRead 从连接中读取一个完整的元素
+func berRead(r io.Reader) (berElement, error) {
+	header := make([]byte, 2)
+	if _, err := io.ReadFull(r, header); err != nil {
+		return berElement{}, err
+	}
+	length := int(header[1])
+	if header[1] >= 0x80 {
+		size := int(header[1] & 0x7f)
+		if size == 0 || size > 4 {
+			return berElement{}, errMalformedBER
+		}
+		buf := make([]byte, size)
+		if _, err := io.ReadFull(r, buf); err != nil {
+			return berElement{}, err
+		}
+		length = 0
+		for _, b := range buf {
+			length = length<<8 | int(b)
+		}
+	}
+	if length < 0 || length > maxMessageBytes {
+		return berElement{}, errMalformedBER
+	}
+	value := make([]byte, length)
+	if _, err := io.ReadFull(r, value); err != nil {
+		return berElement{}, err
+	}
+	return berElement{tag: header[0], value: value}, nil
+}
+
+// berEncode 编码一个元素
+func berEncode(tag byte, value []byte) []byte {
+	out := []byte{tag}
+	switch n := len(value); {
+	case n < 0x80:
+		out = append(out, byte(n))
+	case n <= 0xff:
+		out = append(out, 0x81, byte(n))
+	case n <= 0xffff:
+		out = append(out, 0x82, byte(n>>8), byte(n))
+	case n <= 0xffffff:
+		out = append(out, 0x83, byte(n>>16), byte(n>>8), byte(n))
+	default:
+		out = append(out, 0x84, byte(n>>24), byte(n>>16), byte(n>>8), byte(n))
+	}
+	return append(out, value...)
+}
+
+// berConstructed 编码构造类型元素
+func berConstructed(tag byte, children ...[]byte) []byte {
+	var value []byte
+	for _, child := range children {
+		value = append(value, child...)
+	}
+	return berEncode(tag, value)
+}
+
+// berInt 编码整数（tag 可为 INTEGER 或 ENUMERATED）
+func berInt(tag byte, n int64) []byte {
+	size := 1
+	for v := n; v > 127 || v < -128; v >>= 8 {
+		size++
+	}
+	value := make([]byte, size)
+	for i := size - 1; i >= 0; i-- {
+		value[i] = byte(n)
+		n >>= 8
+	}
+	return berEncode(tag, value)
+}
+
+// berString 编码字符串
+func berString(tag byte, s string) []byte {
+	return berEncode(tag, []byte(s))
+}
+
+// berBool 编码布尔值
+func berBool(b bool) []byte {
+	if b {
+		return berEncode(tagBoolean, []byte{0xff})
+	}
+	return berEncode(tagBoolean, []byte{0x00})
+}
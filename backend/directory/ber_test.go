@@ -0,0 +1,141 @@
+package directory
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"testing"
+)
+
+func TestBerIntRoundTrip(t *testing.T) {
+	values := []int64{0, 1, -1, 127, 128, -128, -129, 255, 256, 65535, 1 << 31, -(1 << 31), 1<<62 + 5}
+	for _, n := range values {
+		el, rest, err := berParse(berInt(tagInteger, n))
+		if err != nil || len(rest) != 0 {
+			t.Fatalf("berParse(berInt(%d)) error: %v, rest %x", n, err, rest)
+		}
+		if el.tag != tagInteger {
+			t.Fatalf("tag = %#x, want INTEGER", el.tag)
+		}
+		got, err := el.int()
+		if err != nil || got != n {
+			t.Fatalf("berInt(%d) decoded as %d (%v)", n, got, err)
+		}
+	}
+}
+
+func TestBerIntMinimalEncoding(t *testing.T) {
+	cases := map[int64][]byte{
+		0:    {0x02, 0x01, 0x00},
+		127:  {0x02, 0x01, 0x7f},
+		128:  {0x02, 0x02, 0x00, 0x80},
+		-128: {0x02, 0x01, 0x80},
+		256:  {0x02, 0x02, 0x01, 0x00},
+	}
+	for n, want := range cases {
+		if got := berInt(tagInteger, n); !bytes.Equal(got, want) {
+			t.Errorf("berInt(%d) = %x, want %x", n, got, want)
+		}
+	}
+}
+
+func TestBerLengthForms(t *testing.T) {
+	for _, size := range []int{0, 1, 127, 128, 255, 256, 65535, 65536, 70000} {
+		value := bytes.Repeat([]byte{'x'}, size)
+		encoded := berEncode(tagOctetString, value)
+
+		el, rest, err := berParse(encoded)
+		if err != nil || len(rest) != 0 {
+			t.Fatalf("size %d: berParse error: %v", size, err)
+		}
+		if !bytes.Equal(el.value, value) {
+			t.Fatalf("size %d: value mismatch", size)
+		}
+
+		read, err := berRead(bytes.NewReader(encoded))
+		if err != nil {
+			t.Fatalf("size %d: berRead error: %v", size, err)
+		}
+		if read.tag != tagOctetString || !bytes.Equal(read.value, value) {
+			t.Fatalf("size %d: berRead mismatch", size)
+		}
+	}
+}
+
+func TestBerConstructedChildren(t *testing.T) {
+	encoded := berConstructed(tagSequence,
+		berInt(tagInteger, 7),
+		berString(tagOctetString, "cn=张三,dc=example"),
+		berBool(true),
+		berBool(false),
+		berConstructed(tagSet),
+	)
+	el, _, err := berParse(encoded)
+	if err != nil {
+		t.Fatalf("berParse error: %v", err)
+	}
+	children, err := el.children()
+	if err != nil {
+		t.Fatalf("children error: %v", err)
+	}
+	if len(children) != 5 {
+		t.Fatalf("got %d children, want 5", len(children))
+	}
+	if n, _ := children[0].int(); n != 7 {
+		t.Errorf("child 0 = %d, want 7", n)
+	}
+	if string(children[1].value) != "cn=张三,dc=example" {
+		t.Errorf("child 1 = %q", children[1].value)
+	}
+	if !bytes.Equal(children[2].value, []byte{0xff}) || !bytes.Equal(children[3].value, []byte{0x00}) {
+		t.Errorf("booleans = %x %x", children[2].value, children[3].value)
+	}
+	if children[4].tag != tagSet || len(children[4].value) != 0 {
+		t.Errorf("empty set = %#x %x", children[4].tag, children[4].value)
+	}
+}
+
+func TestBerParseMalformed(t *testing.T) {
+	cases := map[string][]byte{
+		"empty":              {},
+		"header only":        {0x04},
+		"multi-byte tag":     {0x1f, 0x01, 0x00},
+		"indefinite length":  {0x30, 0x80, 0x00, 0x00},
+		"length too long":    {0x04, 0x85, 0, 0, 0, 0, 1},
+		"truncated value":    {0x04, 0x05, 'a', 'b'},
+		"truncated length":   {0x04, 0x82, 0x01},
+		"bad child sequence": {0x30, 0x02, 0x04, 0x05},
+	}
+	for name, data := range cases {
+		el, _, err := berParse(data)
+		if err == nil && name == "bad child sequence" {
+			_, err = el.children()
+		}
+		if !errors.Is(err, errMalformedBER) {
+			t.Errorf("%s: err = %v, want errMalformedBER", name, err)
+		}
+	}
+
+	if _, err := (berElement{tag: tagInteger}).int(); !errors.Is(err, errMalformedBER) {
+		t.Errorf("empty integer: err = %v", err)
+	}
+	if _, err := (berElement{tag: tagInteger, value: make([]byte, 9)}).int(); !errors.Is(err, errMalformedBER) {
+		t.Errorf("9-byte integer: err = %v", err)
+	}
+}
+
+func TestBerReadErrors(t *testing.T) {
+	if _, err := berRead(bytes.NewReader(nil)); err != io.EOF {
+		t.Errorf("empty reader: err = %v, want EOF", err)
+	}
+	if _, err := berRead(bytes.NewReader([]byte{0x30, 0x80})); !errors.Is(err, errMalformedBER) {
+		t.Errorf("indefinite length: err = %v", err)
+	}
+	// 超过单条消息大小上限
+	if _, err := berRead(bytes.NewReader([]byte{0x30, 0x84, 0x7f, 0xff, 0xff, 0xff})); !errors.Is(err, errMalformedBER) {
+		t.Errorf("oversized message: err = %v", err)
+	}
+	if _, err := berRead(bytes.NewReader([]byte{0x04, 0x05, 'a'})); err != io.ErrUnexpectedEOF {
+		t.Errorf("truncated value: err = %v, want ErrUnexpectedEOF", err)
+	}
+}
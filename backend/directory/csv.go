@@ -0,0 +1,138 @@
+package directory
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"lottery-system/utils"
+)
+
+const (
+	maxSourceFileBytes = 50 << 20
+	// fileSettleTime 文件修改后需稳定的时间，避免读取正在上传的文件
+	fileSettleTime = 30 * time.Second
+)
+
+// FileSource 读取 HR 系统定期投放到目录中的 CSV/XLSX 文件（第一行为表头）
+type FileSource struct {
+	Dir     string  // 投放目录
+	File    string  // 文件名，为空时读取目录中最新的 .csv/.xlsx 文件
+	Mapping Mapping // 列名映射（不区分大小写）
+}
+
+// Name 返回数据源名称
+func (s *FileSource) Name() string {
+	return "csv"
+}
+
+// Fetch 读取文件中的全部人员
+func (s *FileSource) Fetch(ctx context.Context) (*Snapshot, error) {
+	if err := s.Mapping.Validate(); err != nil {
+		return nil, err
+	}
+	path, err := s.resolve()
+	if err != nil {
+		return nil, err
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	if info.Size() > maxSourceFileBytes {
+		return nil, fmt.Errorf("source file %s exceeds %dMB", filepath.Base(path), maxSourceFileBytes>>20)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	rows, err := utils.ReadSpreadsheet(path, data, maxPeople+1)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", filepath.Base(path), err)
+	}
+
+	columns := map[string]int{}
+	for i, header := range rows[0] {
+		key := strings.ToLower(strings.TrimSpace(header))
+		if _, exists := columns[key]; !exists {
+			columns[key] = i
+		}
+	}
+	for _, attr := range s.Mapping.attributes() {
+		if _, ok := columns[strings.ToLower(attr)]; !ok {
+			return nil, fmt.Errorf("%s: missing column %q", filepath.Base(path), attr)
+		}
+	}
+
+	people := make([]Person, 0, len(rows)-1)
+	for _, row := range rows[1:] {
+		person := s.Mapping.person(func(attr string) string {
+			i := columns[strings.ToLower(attr)]
+			if i < len(row) {
+				return row[i]
+			}
+			return ""
+		})
+		if person.ExternalID == "" {
+			continue
+		}
+		people = append(people, person)
+	}
+	if len(people) > maxPeople {
+		return nil, ErrTooManyPeople
+	}
+	return &Snapshot{People: people, Digest: digest(people)}, nil
+}
+
+// resolve 返回要读取的文件路径
+func (s *FileSource) resolve() (string, error) {
+	if s.File != "" {
+		if filepath.Base(s.File) != s.File {
+			return "", fmt.Errorf("invalid source file name %q", s.File)
+		}
+		path := filepath.Join(s.Dir, s.File)
+		info, err := os.Stat(path)
+		if os.IsNotExist(err) {
+			return "", ErrNoSourceFile
+		}
+		if err != nil {
+			return "", err
+		}
+		if time.Since(info.ModTime()) < fileSettleTime {
+			return "", fmt.Errorf("source file %s is still being written", s.File)
+		}
+		return path, nil
+	}
+
+	entries, err := os.ReadDir(s.Dir)
+	if os.IsNotExist(err) {
+		return "", ErrNoSourceFile
+	}
+	if err != nil {
+		return "", err
+	}
+	var latest string
+	var latestTime time.Time
+	for _, entry := range entries {
+		name := entry.Name()
+		ext := strings.ToLower(filepath.Ext(name))
+		if entry.IsDir() || strings.HasPrefix(name, ".") || (ext != ".csv" && ext != ".xlsx") {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil || time.Since(info.ModTime()) < fileSettleTime {
+			continue
+		}
+		if latest == "" || info.ModTime().After(latestTime) {
+			latest, latestTime = name, info.ModTime()
+		}
+	}
+	if latest == "" {
+		return "", ErrNoSourceFile
+	}
+	return filepath.Join(s.Dir, latest), nil
+}
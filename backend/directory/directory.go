@@ -0,0 +1,159 @@
+// Package directory 从外部员工目录（LDAP、HR 定期投放的 CSV/XLSX 文件）同步参与者名单
+// 数据源只负责读取人员列表，同步逻辑（新增、更新、停用）与数据源无关，测试时可使用 StaticSource
+package directory
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"sort"
+	"strings"
+)
+
+// maxPeople 单次同步的人员数量上限
+const maxPeople = 200000
+
+// 同步错误
+var (
+	ErrEmptySource          = errors.New("directory source returned no people")
+	ErrTooManyPeople        = errors.New("directory source returned too many people")
+	ErrTooManyDeactivations = errors.New("too many users would be deactivated")
+	ErrNoSourceFile         = errors.New("no source file found")
+)
+
+// Person 目录中的人员
+type Person struct {
+	ExternalID string            `json:"external_id"` // 目录中的唯一标识（工号、uid、objectGUID 等）
+	Name       string            `json:"name"`
+	Phone      string            `json:"phone"`
+	Fields     map[string]string `json:"fields,omitempty"` // 报名表单字段 key → 值
+}
+
+// Snapshot 数据源的一次读取结果
+type Snapshot struct {
+	People []Person
+	Digest string // 内容摘要，与上次同步相同时可跳过
+}
+
+// Source 参与者数据源
+type Source interface {
+	// Name 返回数据源名称（用于同步报告）
+	Name() string
+	// Fetch 读取全部人员
+	Fetch(ctx context.Context) (*Snapshot, error)
+}
+
+// Mapping 目录属性（LDAP 属性名或表格列名）到参与者信息的映射
+type Mapping struct {
+	ID     string            `json:"id"`               // 唯一标识，必填
+	Name   string            `json:"name"`             // 姓名，必填
+	Phone  string            `json:"phone"`            // 手机号，可选
+	Fields map[string]string `json:"fields,omitempty"` // 报名表单字段 key → 属性名
+}
+
+// Validate 检查映射是否完整
+func (m Mapping) Validate() error {
+	if strings.TrimSpace(m.ID) == "" {
+		return errors.New("mapping: id attribute is required")
+	}
+	if strings.TrimSpace(m.Name) == "" {
+		return errors.New("mapping: name attribute is required")
+	}
+	for key, attr := range m.Fields {
+		if strings.TrimSpace(key) == "" || strings.TrimSpace(attr) == "" {
+			return errors.New("mapping: field key and attribute must not be empty")
+		}
+	}
+	return nil
+}
+
+// attributes 返回需要读取的属性名（去重）
+func (m Mapping) attributes() []string {
+	seen := map[string]bool{}
+	var attrs []string
+	add := func(attr string) {
+		attr = strings.TrimSpace(attr)
+		if attr != "" && !seen[strings.ToLower(attr)] {
+			seen[strings.ToLower(attr)] = true
+			attrs = append(attrs, attr)
+		}
+	}
+	add(m.ID)
+	add(m.Name)
+	add(m.Phone)
+	keys := make([]string, 0, len(m.Fields))
+	for key := range m.Fields {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		add(m.Fields[key])
+	}
+	return attrs
+}
+
+// person 按映射从属性读取函数构造人员信息
+func (m Mapping) person(get func(attr string) string) Person {
+	p := Person{
+		ExternalID: strings.TrimSpace(get(m.ID)),
+		Name:       strings.TrimSpace(get(m.Name)),
+	}
+	if m.Phone != "" {
+		p.Phone = strings.TrimSpace(get(m.Phone))
+	}
+	if len(m.Fields) > 0 {
+		p.Fields = make(map[string]string, len(m.Fields))
+		for key, attr := range m.Fields {
+			p.Fields[key] = strings.TrimSpace(get(attr))
+		}
+	}
+	return p
+}
+
+// digest 计算人员列表的内容摘要（与顺序无关）
+func digest(people []Person) string {
+	lines := make([]string, len(people))
+	for i, p := range people {
+		keys := make([]string, 0, len(p.Fields))
+		for key := range p.Fields {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		var b strings.Builder
+		b.WriteString(p.ExternalID + "\x00" + p.Name + "\x00" + p.Phone)
+		for _, key := range keys {
+			b.WriteString("\x00" + key + "=" + p.Fields[key])
+		}
+		lines[i] = b.String()
+	}
+	sort.Strings(lines)
+
+	h := sha256.New()
+	for _, line := range lines {
+		h.Write([]byte(line))
+		h.Write([]byte{'\n'})
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// StaticSource 固定人员列表的数据源（用于测试或一次性同步）
+type StaticSource struct {
+	Label  string
+	People []Person
+}
+
+// Name 返回数据源名称
+func (s *StaticSource) Name() string {
+	if s.Label == "" {
+		return "static"
+	}
+	return s.Label
+}
+
+// Fetch 返回固定人员列表
+func (s *StaticSource) Fetch(ctx context.Context) (*Snapshot, error) {
+	people := make([]Person, len(s.People))
+	copy(people, s.People)
+	return &Snapshot{People: people, Digest: digest(people)}, nil
+}
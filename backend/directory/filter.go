@@ -0,0 +1,238 @@
+package directory
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// LDAP 搜索过滤器标签（RFC 4511 4.5.1）
+const (
+	filterAnd            = 0xa0
+	filterOr             = 0xa1
+	filterNot            = 0xa2
+	filterEquality       = 0xa3
+	filterSubstrings     = 0xa4
+	filterGreaterOrEqual = 0xa5
+	filterLessOrEqual    = 0xa6
+	filterPresent        = 0x87
+	filterApprox         = 0xa8
+
+	substringInitial = 0x80
+	substringAny     = 0x81
+	substringFinal   = 0x82
+)
+
+// maxFilterDepth 过滤器嵌套层数上限
+const maxFilterDepth = 32
+
+// ErrInvalidFilter 过滤器语法错误
+var ErrInvalidFilter = errors.New("ldap: invalid search filter")
+
+// ValidateFilter 检查 LDAP 搜索过滤器语法（RFC 4515），如 (&(objectClass=person)(mobile=*)(!(ou=外包)))
+// 不支持扩展匹配（:=）
+func ValidateFilter(filter string) error {
+	_, err := compileFilter(filter)
+	return err
+}
+
+// compileFilter 将过滤器字符串编码为 BER
+func compileFilter(filter string) ([]byte, error) {
+	filter = strings.TrimSpace(filter)
+	if filter == "" {
+		filter = "(objectClass=*)"
+	}
+	if !strings.HasPrefix(filter, "(") {
+		filter = "(" + filter + ")"
+	}
+	p := &filterParser{s: filter}
+	out, err := p.parse(0)
+	if err != nil {
+		return nil, err
+	}
+	if p.pos != len(p.s) {
+		return nil, fmt.Errorf("%w: unexpected %q at %d", ErrInvalidFilter, p.s[p.pos:], p.pos)
+	}
+	return out, nil
+}
+
+type filterParser struct {
+	s   string
+	pos int
+}
+
+func (p *filterParser) errorf(format string, args ...interface{}) error {
+	return fmt.Errorf("%w: %s at %d", ErrInvalidFilter, fmt.Sprintf(format, args...), p.pos)
+}
+
+// parse 解析一个带括号的过滤器
+func (p *filterParser) parse(depth int) ([]byte, error) {
+	if depth > maxFilterDepth {
+		return nil, p.errorf("too deeply nested")
+	}
+	if p.pos >= len(p.s) || p.s[p.pos] != '(' {
+		return nil, p.errorf("expected (")
+	}
+	p.pos++
+	if p.pos >= len(p.s) {
+		return nil, p.errorf("unexpected end")
+	}
+
+	var out []byte
+	var err error
+	switch p.s[p.pos] {
+	case '&', '|':
+		tag := byte(filterAnd)
+		if p.s[p.pos] == '|' {
+			tag = filterOr
+		}
+		p.pos++
+		var children [][]byte
+		for p.pos < len(p.s) && p.s[p.pos] == '(' {
+			child, err := p.parse(depth + 1)
+			if err != nil {
+				return nil, err
+			}
+			children = append(children, child)
+		}
+		if len(children) == 0 {
+			return nil, p.errorf("empty filter list")
+		}
+		out = berConstructed(tag, children...)
+	case '!':
+		p.pos++
+		child, err := p.parse(depth + 1)
+		if err != nil {
+			return nil, err
+		}
+		out = berConstructed(filterNot, child)
+	default:
+		out, err = p.item()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if p.pos >= len(p.s) || p.s[p.pos] != ')' {
+		return nil, p.errorf("expected )")
+	}
+	p.pos++
+	return out, nil
+}
+
+// item 解析简单条件：attr=value、attr=*、attr=a*b*c、attr>=value、attr<=value、attr~=value
+func (p *filterParser) item() ([]byte, error) {
+	end := strings.IndexByte(p.s[p.pos:], ')')
+	if end < 0 {
+		return nil, p.errorf("expected )")
+	}
+	expr := p.s[p.pos : p.pos+end]
+
+	eq := strings.IndexByte(expr, '=')
+	if eq <= 0 {
+		return nil, p.errorf("expected attribute=value")
+	}
+	attr, value := expr[:eq], expr[eq+1:]
+	tag := byte(filterEquality)
+	switch attr[len(attr)-1] {
+	case '>':
+		tag, attr = filterGreaterOrEqual, attr[:len(attr)-1]
+	case '<':
+		tag, attr = filterLessOrEqual, attr[:len(attr)-1]
+	case '~':
+		tag, attr = filterApprox, attr[:len(attr)-1]
+	case ':':
+		return nil, p.errorf("extensible match is not supported")
+	}
+	if !validAttributeName(attr) {
+		return nil, p.errorf("invalid attribute %q", attr)
+	}
+
+	var out []byte
+	switch {
+	case tag == filterEquality && value == "*":
+		out = berString(filterPresent, attr)
+	case tag == filterEquality && strings.Contains(value, "*"):
+		parts := strings.Split(value, "*")
+		var subs [][]byte
+		for i, part := range parts {
+			if part == "" {
+				continue
+			}
+			decoded, err := unescapeFilterValue(part)
+			if err != nil {
+				return nil, p.errorf("%v", err)
+			}
+			subTag := byte(substringAny)
+			if i == 0 {
+				subTag = substringInitial
+			} else if i == len(parts)-1 {
+				subTag = substringFinal
+			}
+			subs = append(subs, berString(subTag, decoded))
+		}
+		if len(subs) == 0 {
+			return nil, p.errorf("empty substring filter")
+		}
+		out = berConstructed(filterSubstrings, berString(tagOctetString, attr), berConstructed(tagSequence, subs...))
+	default:
+		decoded, err := unescapeFilterValue(value)
+		if err != nil {
+			return nil, p.errorf("%v", err)
+		}
+		out = berConstructed(tag, berString(tagOctetString, attr), berString(tagOctetString, decoded))
+	}
+
+	p.pos += end
+	return out, nil
+}
+
+// validAttributeName 属性名只能包含字母、数字、连字符（或为 OID），可带选项（如 cn;lang-zh）
+func validAttributeName(attr string) bool {
+	if attr == "" {
+		return false
+	}
+	for _, r := range attr {
+		if !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '-' || r == '.' || r == ';') {
+			return false
+		}
+	}
+	return true
+}
+
+// unescapeFilterValue 解码 \XX 转义（RFC 4515），值中的 ( ) 和 \ 必须转义
+func unescapeFilterValue(value string) (string, error) {
+	var b strings.Builder
+	for i := 0; i < len(value); i++ {
+		switch c := value[i]; c {
+		case '(', ')':
+			return "", errors.New("unescaped parenthesis")
+		case '\\':
+			if i+2 >= len(value) {
+				return "", errors.New("invalid escape")
+			}
+			hi, ok1 := hexValue(value[i+1])
+			lo, ok2 := hexValue(value[i+2])
+			if !ok1 || !ok2 {
+				return "", errors.New("invalid escape")
+			}
+			b.WriteByte(hi<<4 | lo)
+			i += 2
+		default:
+			b.WriteByte(c)
+		}
+	}
+	return b.String(), nil
+}
+
+func hexValue(c byte) (byte, bool) {
+	switch {
+	case c >= '0' && c <= '9':
+		return c - '0', true
+	case c >= 'a' && c <= 'f':
+		return c - 'a' + 10, true
+	case c >= 'A' && c <= 'F':
+		return c - 'A' + 10, true
+	}
+	return 0, false
+}
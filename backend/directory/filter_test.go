@@ -0,0 +1,150 @@
+package directory
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestCompileFilter(t *testing.T) {
+	eq := func(attr, value string) []byte {
+		return berConstructed(filterEquality, berString(tagOctetString, attr), berString(tagOctetString, value))
+	}
+
+	cases := []struct {
+		filter string
+		want   []byte
+	}{
+		{"", berString(filterPresent, "objectClass")},
+		{"  ", berString(filterPresent, "objectClass")},
+		{"uid=1001", eq("uid", "1001")},
+		{"(uid=1001)", eq("uid", "1001")},
+		{"(mobile=*)", berString(filterPresent, "mobile")},
+		{"(cn;lang-zh=张三)", eq("cn;lang-zh", "张三")},
+		{"(2.5.4.3=x)", eq("2.5.4.3", "x")},
+		{"(uidNumber>=100)", berConstructed(filterGreaterOrEqual, berString(tagOctetString, "uidNumber"), berString(tagOctetString, "100"))},
+		{"(uidNumber<=200)", berConstructed(filterLessOrEqual, berString(tagOctetString, "uidNumber"), berString(tagOctetString, "200"))},
+		{"(cn~=zhang)", berConstructed(filterApprox, berString(tagOctetString, "cn"), berString(tagOctetString, "zhang"))},
+		{"(&(objectClass=person)(mobile=*))", berConstructed(filterAnd, eq("objectClass", "person"), berString(filterPresent, "mobile"))},
+		{"(|(ou=a)(ou=b))", berConstructed(filterOr, eq("ou", "a"), eq("ou", "b"))},
+		{"(!(ou=外包))", berConstructed(filterNot, eq("ou", "外包"))},
+		{
+			"(&(objectClass=user)(!(userAccountControl=514))(|(department=IT)(department=HR)))",
+			berConstructed(filterAnd,
+				eq("objectClass", "user"),
+				berConstructed(filterNot, eq("userAccountControl", "514")),
+				berConstructed(filterOr, eq("department", "IT"), eq("department", "HR")),
+			),
+		},
+	}
+	for _, tc := range cases {
+		got, err := compileFilter(tc.filter)
+		if err != nil {
+			t.Errorf("compileFilter(%q) error: %v", tc.filter, err)
+			continue
+		}
+		if !bytes.Equal(got, tc.want) {
+			t.Errorf("compileFilter(%q) = %x, want %x", tc.filter, got, tc.want)
+		}
+	}
+}
+
+func TestCompileFilterSubstrings(t *testing.T) {
+	substr := func(attr string, subs ...[]byte) []byte {
+		return berConstructed(filterSubstrings, berString(tagOctetString, attr), berConstructed(tagSequence, subs...))
+	}
+
+	cases := []struct {
+		filter string
+		want   []byte
+	}{
+		{"(cn=张*)", substr("cn", berString(substringInitial, "张"))},
+		{"(mail=*@example.com)", substr("mail", berString(substringFinal, "@example.com"))},
+		{"(cn=*san*)", substr("cn", berString(substringAny, "san"))},
+		{"(cn=ab*cd*ef)", substr("cn", berString(substringInitial, "ab"), berString(substringAny, "cd"), berString(substringFinal, "ef"))},
+		{"(cn=a**b)", substr("cn", berString(substringInitial, "a"), berString(substringFinal, "b"))},
+	}
+	for _, tc := range cases {
+		got, err := compileFilter(tc.filter)
+		if err != nil {
+			t.Errorf("compileFilter(%q) error: %v", tc.filter, err)
+			continue
+		}
+		if !bytes.Equal(got, tc.want) {
+			t.Errorf("compileFilter(%q) = %x, want %x", tc.filter, got, tc.want)
+		}
+	}
+}
+
+func TestCompileFilterEscaping(t *testing.T) {
+	cases := map[string]string{
+		`(cn=a\28b\29)`:     "a(b)",
+		`(cn=\2a)`:          "*",
+		`(cn=back\5cslash)`: `back\slash`,
+		`(cn=\e5\bc\a0)`:    "张",
+		`(cn=\E5\BC\A0)`:    "张",
+		`(objectGUID=\00)`:  "\x00",
+	}
+	for filter, value := range cases {
+		got, err := compileFilter(filter)
+		if err != nil {
+			t.Errorf("compileFilter(%q) error: %v", filter, err)
+			continue
+		}
+		el, _, err := berParse(got)
+		if err != nil || el.tag != filterEquality {
+			t.Errorf("compileFilter(%q) is not an equality filter: %x", filter, got)
+			continue
+		}
+		parts, _ := el.children()
+		if len(parts) != 2 || string(parts[1].value) != value {
+			t.Errorf("compileFilter(%q) value = %q, want %q", filter, parts[1].value, value)
+		}
+	}
+
+	// 转义的 * 不是通配符
+	got, _ := compileFilter(`(cn=a\2a*)`)
+	el, _, _ := berParse(got)
+	if el.tag != filterSubstrings {
+		t.Fatalf("(cn=a\\2a*) tag = %#x, want substrings", el.tag)
+	}
+}
+
+func TestCompileFilterInvalid(t *testing.T) {
+	invalid := []string{
+		"(",
+		"()",
+		"(cn=a",
+		"(cn=a))",
+		"(cn=a)(sn=b)",
+		"(&)",
+		"(|)",
+		"(!)",
+		"(=value)",
+		"(cn)",
+		"(c n=a)",
+		"(cn=a(b)",
+		`(cn=a\2)`,
+		`(cn=a\zz)`,
+		`(cn=a\)`,
+		"(cn:dn:=a)",
+		"(cn:=a)",
+		"(cn=**)",
+		strings.Repeat("(!", maxFilterDepth+2) + "(cn=a)" + strings.Repeat(")", maxFilterDepth+2),
+	}
+	for _, filter := range invalid {
+		if _, err := compileFilter(filter); !errors.Is(err, ErrInvalidFilter) {
+			t.Errorf("compileFilter(%q) = %v, want ErrInvalidFilter", filter, err)
+		}
+		if err := ValidateFilter(filter); err == nil {
+			t.Errorf("ValidateFilter(%q) accepted invalid filter", filter)
+		}
+	}
+
+	// 嵌套层数未超过上限时允许
+	nested := strings.Repeat("(!", maxFilterDepth) + "(cn=a)" + strings.Repeat(")", maxFilterDepth)
+	if err := ValidateFilter(nested); err != nil {
+		t.Errorf("ValidateFilter(depth %d) error: %v", maxFilterDepth, err)
+	}
+}
@@ -0,0 +1,415 @@
+package directory
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// LDAP 协议消息标签（RFC 4511）
+const (
+	ldapBindRequest       = 0x60
+	ldapBindResponse      = 0x61
+	ldapUnbindRequest     = 0x42
+	ldapSearchRequest     = 0x63
+	ldapSearchEntry       = 0x64
+	ldapSearchDone        = 0x65
+	ldapSearchReference   = 0x73
+	ldapExtendedRequest   = 0x77
+	ldapExtendedResponse  = 0x78
+	ldapControls          = 0xa0
+	ldapAuthSimple        = 0x80
+	ldapExtendedName      = 0x80
+	ldapScopeWholeSubtree = 2
+
+	oidStartTLS     = "1.3.6.1.4.1.1466.20037"
+	oidPagedResults = "1.2.840.113556.1.4.319"
+)
+
+// ldapPageSize 分页读取的每页条数（Active Directory 默认单次最多返回 1000 条）
+const ldapPageSize = 500
+
+// LDAPError LDAP 服务器返回的错误
+type LDAPError struct {
+	Op      string
+	Code    int64
+	Message string
+}
+
+func (e *LDAPError) Error() string {
+	if e.Message == "" {
+		return fmt.Sprintf("ldap %s failed: result code %d", e.Op, e.Code)
+	}
+	return fmt.Sprintf("ldap %s failed: result code %d: %s", e.Op, e.Code, e.Message)
+}
+
+// LDAPConfig LDAP 连接和搜索配置
+type LDAPConfig struct {
+	URL           string        // ldap://host:389 或 ldaps://host:636
+	StartTLS      bool          // ldap:// 连接后升级为 TLS
+	SkipTLSVerify bool          // 不校验服务器证书（仅用于测试环境）
+	BindDN        string        // 为空时匿名查询
+	BindPassword  string        // 绑定密码
+	BaseDN        string        // 搜索起点，如 ou=people,dc=example,dc=com
+	Filter        string        // 搜索过滤器，为空时为 (objectClass=*)
+	Timeout       time.Duration // 连接和整个同步的超时时间，默认 60 秒
+}
+
+// ValidateLDAPURL 检查 LDAP 服务器地址
+func ValidateLDAPURL(raw string) error {
+	u, err := url.Parse(raw)
+	if err != nil || (u.Scheme != "ldap" && u.Scheme != "ldaps") || u.Host == "" {
+		return errors.New("ldap url must be ldap://host[:port] or ldaps://host[:port]")
+	}
+	if u.Path != "" && u.Path != "/" || u.RawQuery != "" {
+		return errors.New("ldap url must not contain a path or query")
+	}
+	return nil
+}
+
+// LDAPSource 从 LDAP 目录（OpenLDAP、Active Directory 等）读取人员
+type LDAPSource struct {
+	Config  LDAPConfig
+	Mapping Mapping
+}
+
+// Name 返回数据源名称
+func (s *LDAPSource) Name() string {
+	return "ldap"
+}
+
+// Fetch 绑定并分页搜索全部人员，缺少唯一标识的条目忽略
+func (s *LDAPSource) Fetch(ctx context.Context) (*Snapshot, error) {
+	if err := s.Mapping.Validate(); err != nil {
+		return nil, err
+	}
+	filter, err := compileFilter(s.Config.Filter)
+	if err != nil {
+		return nil, err
+	}
+
+	timeout := s.Config.Timeout
+	if timeout <= 0 {
+		timeout = 60 * time.Second
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	conn, err := dialLDAP(ctx, s.Config)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.close()
+
+	if s.Config.BindDN != "" {
+		if err := conn.bind(s.Config.BindDN, s.Config.BindPassword); err != nil {
+			return nil, err
+		}
+	}
+
+	var people []Person
+	err = conn.search(s.Config.BaseDN, filter, s.Mapping.attributes(), func(dn string, attrs map[string][]byte) error {
+		person := s.Mapping.person(func(attr string) string {
+			return attributeString(attrs[strings.ToLower(attr)])
+		})
+		if person.ExternalID == "" {
+			return nil
+		}
+		if len(people) >= maxPeople {
+			return ErrTooManyPeople
+		}
+		people = append(people, person)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &Snapshot{People: people, Digest: digest(people)}, nil
+}
+
+// attributeString 属性值转为字符串，二进制值（如 objectGUID）使用十六进制
+func attributeString(value []byte) string {
+	if utf8.Valid(value) {
+		return string(value)
+	}
+	return hex.EncodeToString(value)
+}
+
+// ldapConn 一个 LDAP 连接（同步请求，一次只处理一个操作）
+type ldapConn struct {
+	conn      net.Conn
+	messageID int64
+}
+
+// dialLDAP 建立连接，按配置使用 LDAPS 或 StartTLS
+func dialLDAP(ctx context.Context, cfg LDAPConfig) (*ldapConn, error) {
+	if err := ValidateLDAPURL(cfg.URL); err != nil {
+		return nil, err
+	}
+	u, _ := url.Parse(cfg.URL)
+	host := u.Hostname()
+	addr := u.Host
+	if u.Port() == "" {
+		if u.Scheme == "ldaps" {
+			addr = net.JoinHostPort(host, "636")
+		} else {
+			addr = net.JoinHostPort(host, "389")
+		}
+	}
+	tlsConfig := &tls.Config{ServerName: host, InsecureSkipVerify: cfg.SkipTLSVerify, MinVersion: tls.VersionTLS12}
+
+	var dialer net.Dialer
+	raw, err := dialer.DialContext(ctx, "tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		raw.SetDeadline(deadline)
+	}
+
+	c := &ldapConn{conn: raw}
+	if u.Scheme == "ldaps" {
+		tlsConn := tls.Client(raw, tlsConfig)
+		if err := tlsConn.HandshakeContext(ctx); err != nil {
+			raw.Close()
+			return nil, err
+		}
+		c.conn = tlsConn
+	} else if cfg.StartTLS {
+		if err := c.startTLS(ctx, tlsConfig); err != nil {
+			raw.Close()
+			return nil, err
+		}
+	}
+	return c, nil
+}
+
+// send 发送一条请求，返回消息 ID
+func (c *ldapConn) send(op []byte, controls ...[]byte) (int64, error) {
+	c.messageID++
+	parts := [][]byte{berInt(tagInteger, c.messageID), op}
+	if len(controls) > 0 {
+		parts = append(parts, berConstructed(ldapControls, controls...))
+	}
+	_, err := c.conn.Write(berConstructed(tagSequence, parts...))
+	return c.messageID, err
+}
+
+// receive 读取一条响应消息，返回操作元素和控制信息
+func (c *ldapConn) receive(id int64) (berElement, []berElement, error) {
+	for {
+		msg, err := berRead(c.conn)
+		if err != nil {
+			return berElement{}, nil, err
+		}
+		parts, err := msg.children()
+		if err != nil || msg.tag != tagSequence || len(parts) < 2 {
+			return berElement{}, nil, errMalformedBER
+		}
+		msgID, err := parts[0].int()
+		if err != nil {
+			return berElement{}, nil, err
+		}
+		if msgID == 0 {
+			// 服务器主动通知（如断开连接），按错误处理
+			return berElement{}, nil, errors.New("ldap: connection closed by server")
+		}
+		if msgID != id {
+			continue
+		}
+		var controls []berElement
+		if len(parts) > 2 && parts[2].tag == ldapControls {
+			controls, err = parts[2].children()
+			if err != nil {
+				return berElement{}, nil, err
+			}
+		}
+		return parts[1], controls, nil
+	}
+}
+
+// checkResult 解析 LDAPResult，非成功时返回 LDAPError
+func checkResult(op string, el berElement) error {
+	parts, err := el.children()
+	if err != nil || len(parts) < 3 {
+		return errMalformedBER
+	}
+	code, err := parts[0].int()
+	if err != nil {
+		return err
+	}
+	if code != 0 {
+		return &LDAPError{Op: op, Code: code, Message: string(parts[2].value)}
+	}
+	return nil
+}
+
+// startTLS 发送 StartTLS 扩展操作并升级连接
+func (c *ldapConn) startTLS(ctx context.Context, tlsConfig *tls.Config) error {
+	id, err := c.send(berConstructed(ldapExtendedRequest, berString(ldapExtendedName, oidStartTLS)))
+	if err != nil {
+		return err
+	}
+	resp, _, err := c.receive(id)
+	if err != nil {
+		return err
+	}
+	if resp.tag != ldapExtendedResponse {
+		return errMalformedBER
+	}
+	if err := checkResult("starttls", resp); err != nil {
+		return err
+	}
+	tlsConn := tls.Client(c.conn, tlsConfig)
+	if err := tlsConn.HandshakeContext(ctx); err != nil {
+		return err
+	}
+	c.conn = tlsConn
+	return nil
+}
+
+// bind 简单绑定
+func (c *ldapConn) bind(dn, password string) error {
+	if password == "" {
+		// 空密码会被服务器视为匿名绑定（RFC 4513 5.1.2），视为配置错误
+		return errors.New("ldap: bind password is required when bind dn is set")
+	}
+	id, err := c.send(berConstructed(ldapBindRequest,
+		berInt(tagInteger, 3),
+		berString(tagOctetString, dn),
+		berString(ldapAuthSimple, password),
+	))
+	if err != nil {
+		return err
+	}
+	resp, _, err := c.receive(id)
+	if err != nil {
+		return err
+	}
+	if resp.tag != ldapBindResponse {
+		return errMalformedBER
+	}
+	return checkResult("bind", resp)
+}
+
+// search 分页搜索子树，对每个条目调用 fn（属性名为小写，取第一个值）
+func (c *ldapConn) search(baseDN string, filter []byte, attrs []string, fn func(dn string, attrs map[string][]byte) error) error {
+	attrList := make([][]byte, len(attrs))
+	for i, attr := range attrs {
+		attrList[i] = berString(tagOctetString, attr)
+	}
+
+	var cookie []byte
+	for {
+		request := berConstructed(ldapSearchRequest,
+			berString(tagOctetString, baseDN),
+			berInt(tagEnumerated, ldapScopeWholeSubtree),
+			berInt(tagEnumerated, 0), // 不解引用别名
+			berInt(tagInteger, 0),    // 不限制条数（由服务器限制）
+			berInt(tagInteger, 0),    // 不限制时间（由连接超时控制）
+			berBool(false),
+			filter,
+			berConstructed(tagSequence, attrList...),
+		)
+		paging := berConstructed(tagSequence,
+			berString(tagOctetString, oidPagedResults),
+			berEncode(tagOctetString, berConstructed(tagSequence, berInt(tagInteger, ldapPageSize), berEncode(tagOctetString, cookie))),
+		)
+		id, err := c.send(request, paging)
+		if err != nil {
+			return err
+		}
+
+		cookie = nil
+		for done := false; !done; {
+			op, controls, err := c.receive(id)
+			if err != nil {
+				return err
+			}
+			switch op.tag {
+			case ldapSearchEntry:
+				dn, values, err := parseSearchEntry(op)
+				if err != nil {
+					return err
+				}
+				if err := fn(dn, values); err != nil {
+					return err
+				}
+			case ldapSearchReference:
+				// 不跟随引用
+			case ldapSearchDone:
+				if err := checkResult("search", op); err != nil {
+					return err
+				}
+				cookie = pagingCookie(controls)
+				done = true
+			default:
+				return errMalformedBER
+			}
+		}
+		if len(cookie) == 0 {
+			return nil
+		}
+	}
+}
+
+// parseSearchEntry 解析 SearchResultEntry
+func parseSearchEntry(el berElement) (string, map[string][]byte, error) {
+	parts, err := el.children()
+	if err != nil || len(parts) < 2 {
+		return "", nil, errMalformedBER
+	}
+	attrs, err := parts[1].children()
+	if err != nil {
+		return "", nil, err
+	}
+	values := make(map[string][]byte, len(attrs))
+	for _, attr := range attrs {
+		pair, err := attr.children()
+		if err != nil || len(pair) < 2 {
+			return "", nil, errMalformedBER
+		}
+		vals, err := pair[1].children()
+		if err != nil {
+			return "", nil, err
+		}
+		if len(vals) > 0 {
+			values[strings.ToLower(string(pair[0].value))] = vals[0].value
+		}
+	}
+	return string(parts[0].value), values, nil
+}
+
+// pagingCookie 从 SearchResultDone 的控制信息中读取分页 cookie，服务器不支持分页时返回空
+func pagingCookie(controls []berElement) []byte {
+	for _, control := range controls {
+		parts, err := control.children()
+		if err != nil || len(parts) < 2 || string(parts[0].value) != oidPagedResults {
+			continue
+		}
+		value := parts[len(parts)-1]
+		inner, _, err := berParse(value.value)
+		if err != nil {
+			return nil
+		}
+		fields, err := inner.children()
+		if err != nil || len(fields) < 2 {
+			return nil
+		}
+		return fields[1].value
+	}
+	return nil
+}
+
+// close 发送 Unbind 并关闭连接
+func (c *ldapConn) close() {
+	c.send(berEncode(ldapUnbindRequest, nil))
+	c.conn.Close()
+}
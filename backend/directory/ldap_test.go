@@ -0,0 +1,347 @@
+package directory
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeLDAPEntry 模拟目录中的条目
+type fakeLDAPEntry struct {
+	dn    string
+	attrs map[string]string
+}
+
+// fakeLDAPServer 进程内 LDAP 服务器，只实现 bind、分页 search 和 unbind
+type fakeLDAPServer struct {
+	t        *testing.T
+	listener net.Listener
+	entries  []fakeLDAPEntry
+	pageSize int    // 每页返回条数（与客户端请求的页大小无关）
+	paging   bool   // 是否支持分页控制
+	password string // 非空时校验绑定密码
+
+	mu       sync.Mutex
+	searches int      // 收到的搜索请求数（每页一次）
+	filters  [][]byte // 收到的过滤器
+	attrs    [][]string
+	binds    []string
+}
+
+func newFakeLDAPServer(t *testing.T, entries []fakeLDAPEntry, pageSize int) *fakeLDAPServer {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen error: %v", err)
+	}
+	s := &fakeLDAPServer{t: t, listener: listener, entries: entries, pageSize: pageSize, paging: true}
+	go s.serve()
+	t.Cleanup(func() { listener.Close() })
+	return s
+}
+
+func (s *fakeLDAPServer) url() string {
+	return "ldap://" + s.listener.Addr().String()
+}
+
+func (s *fakeLDAPServer) serve() {
+	for {
+		conn, err := s.listener.Accept()
+		if err != nil {
+			return
+		}
+		go s.handle(conn)
+	}
+}
+
+func (s *fakeLDAPServer) handle(conn net.Conn) {
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(5 * time.Second))
+
+	for {
+		msg, err := berRead(conn)
+		if err != nil {
+			return
+		}
+		parts, err := msg.children()
+		if err != nil || len(parts) < 2 {
+			s.t.Errorf("fake ldap: malformed message")
+			return
+		}
+		id, _ := parts[0].int()
+		var controls []berElement
+		if len(parts) > 2 {
+			controls, _ = parts[2].children()
+		}
+
+		switch op := parts[1]; op.tag {
+		case ldapBindRequest:
+			fields, _ := op.children()
+			dn, password := string(fields[1].value), string(fields[2].value)
+			s.mu.Lock()
+			s.binds = append(s.binds, dn)
+			s.mu.Unlock()
+			code := int64(0)
+			if s.password != "" && password != s.password {
+				code = 49 // invalidCredentials
+			}
+			s.reply(conn, id, ldapResult(ldapBindResponse, code, "bad credentials"))
+		case ldapSearchRequest:
+			s.search(conn, id, op, controls)
+		case ldapUnbindRequest:
+			return
+		default:
+			s.t.Errorf("fake ldap: unexpected operation %#x", op.tag)
+			return
+		}
+	}
+}
+
+func (s *fakeLDAPServer) search(conn net.Conn, id int64, op berElement, controls []berElement) {
+	fields, err := op.children()
+	if err != nil || len(fields) != 8 {
+		s.t.Errorf("fake ldap: malformed search request")
+		return
+	}
+	attrList, _ := fields[7].children()
+	attrs := make([]string, len(attrList))
+	for i, attr := range attrList {
+		attrs[i] = string(attr.value)
+	}
+	s.mu.Lock()
+	s.searches++
+	s.filters = append(s.filters, append([]byte(nil), berEncode(fields[6].tag, fields[6].value)...))
+	s.attrs = append(s.attrs, attrs)
+	s.mu.Unlock()
+
+	// 客户端请求中的分页 cookie 与响应格式相同，cookie 为下一页的起始位置
+	start := 0
+	if cookie := pagingCookie(controls); len(cookie) > 0 {
+		start, _ = strconv.Atoi(string(cookie))
+	}
+	end := len(s.entries)
+	if s.paging && start+s.pageSize < end {
+		end = start + s.pageSize
+	}
+
+	for _, entry := range s.entries[start:end] {
+		var list [][]byte
+		for _, name := range attrs {
+			if value, ok := entry.attrs[name]; ok {
+				list = append(list, berConstructed(tagSequence,
+					berString(tagOctetString, name),
+					berConstructed(tagSet, berString(tagOctetString, value)),
+				))
+			}
+		}
+		s.reply(conn, id, berConstructed(ldapSearchEntry,
+			berString(tagOctetString, entry.dn),
+			berConstructed(tagSequence, list...),
+		))
+	}
+	// 引用条目应被忽略
+	s.reply(conn, id, berConstructed(ldapSearchReference, berString(tagOctetString, "ldap://other/dc=example")))
+
+	done := ldapResult(ldapSearchDone, 0, "")
+	if !s.paging {
+		s.reply(conn, id, done)
+		return
+	}
+	var cookie []byte
+	if end < len(s.entries) {
+		cookie = []byte(strconv.Itoa(end))
+	}
+	s.reply(conn, id, done, berConstructed(tagSequence,
+		berString(tagOctetString, oidPagedResults),
+		berEncode(tagOctetString, berConstructed(tagSequence, berInt(tagInteger, 0), berEncode(tagOctetString, cookie))),
+	))
+}
+
+func (s *fakeLDAPServer) reply(conn net.Conn, id int64, op []byte, controls ...[]byte) {
+	parts := [][]byte{berInt(tagInteger, id), op}
+	if len(controls) > 0 {
+		parts = append(parts, berConstructed(ldapControls, controls...))
+	}
+	conn.Write(berConstructed(tagSequence, parts...))
+}
+
+func ldapResult(tag byte, code int64, message string) []byte {
+	return berConstructed(tag,
+		berInt(tagEnumerated, code),
+		berString(tagOctetString, ""),
+		berString(tagOctetString, message),
+	)
+}
+
+func fakeEntries(n int) []fakeLDAPEntry {
+	entries := make([]fakeLDAPEntry, n)
+	for i := range entries {
+		uid := fmt.Sprintf("u%03d", i+1)
+		entries[i] = fakeLDAPEntry{
+			dn: "uid=" + uid + ",ou=people,dc=example,dc=com",
+			attrs: map[string]string{
+				"uid":        uid,
+				"cn":         fmt.Sprintf("员工%d", i+1),
+				"mobile":     fmt.Sprintf("138%08d", i+1),
+				"department": "研发部",
+			},
+		}
+	}
+	return entries
+}
+
+var testMapping = Mapping{ID: "uid", Name: "cn", Phone: "mobile", Fields: map[string]string{"dept": "department"}}
+
+func TestLDAPSourcePaging(t *testing.T) {
+	server := newFakeLDAPServer(t, fakeEntries(7), 3)
+	server.password = "secret"
+
+	source := &LDAPSource{
+		Config: LDAPConfig{
+			URL:          server.url(),
+			BindDN:       "cn=sync,dc=example,dc=com",
+			BindPassword: "secret",
+			BaseDN:       "ou=people,dc=example,dc=com",
+			Filter:       "(&(objectClass=person)(mobile=*))",
+			Timeout:      5 * time.Second,
+		},
+		Mapping: testMapping,
+	}
+	snapshot, err := source.Fetch(context.Background())
+	if err != nil {
+		t.Fatalf("Fetch error: %v", err)
+	}
+
+	if len(snapshot.People) != 7 {
+		t.Fatalf("got %d people, want 7", len(snapshot.People))
+	}
+	for i, p := range snapshot.People {
+		wantID := fmt.Sprintf("u%03d", i+1)
+		if p.ExternalID != wantID || p.Name != fmt.Sprintf("员工%d", i+1) || p.Phone != fmt.Sprintf("138%08d", i+1) {
+			t.Errorf("person %d = %+v", i, p)
+		}
+		if p.Fields["dept"] != "研发部" {
+			t.Errorf("person %d fields = %v", i, p.Fields)
+		}
+	}
+	if snapshot.Digest != digest(snapshot.People) {
+		t.Error("snapshot digest mismatch")
+	}
+
+	server.mu.Lock()
+	defer server.mu.Unlock()
+	if server.searches != 3 {
+		t.Errorf("got %d search requests, want 3 pages", server.searches)
+	}
+	wantFilter, _ := compileFilter(source.Config.Filter)
+	for i, filter := range server.filters {
+		if string(filter) != string(wantFilter) {
+			t.Errorf("page %d filter = %x, want %x", i, filter, wantFilter)
+		}
+	}
+	if len(server.attrs) == 0 || fmt.Sprint(server.attrs[0]) != fmt.Sprint(testMapping.attributes()) {
+		t.Errorf("requested attributes = %v, want %v", server.attrs, testMapping.attributes())
+	}
+	if len(server.binds) != 1 || server.binds[0] != source.Config.BindDN {
+		t.Errorf("binds = %v", server.binds)
+	}
+}
+
+func TestLDAPSourceWithoutPagingSupport(t *testing.T) {
+	server := newFakeLDAPServer(t, fakeEntries(4), 0)
+	server.paging = false
+
+	source := &LDAPSource{Config: LDAPConfig{URL: server.url(), Timeout: 5 * time.Second}, Mapping: testMapping}
+	snapshot, err := source.Fetch(context.Background())
+	if err != nil {
+		t.Fatalf("Fetch error: %v", err)
+	}
+	if len(snapshot.People) != 4 {
+		t.Fatalf("got %d people, want 4", len(snapshot.People))
+	}
+
+	server.mu.Lock()
+	defer server.mu.Unlock()
+	if server.searches != 1 {
+		t.Errorf("got %d search requests, want 1", server.searches)
+	}
+	if len(server.binds) != 0 {
+		t.Errorf("anonymous fetch sent bind: %v", server.binds)
+	}
+}
+
+func TestLDAPSourceSkipsEntriesWithoutID(t *testing.T) {
+	entries := fakeEntries(3)
+	delete(entries[1].attrs, "uid")
+	server := newFakeLDAPServer(t, entries, 10)
+
+	source := &LDAPSource{Config: LDAPConfig{URL: server.url(), Timeout: 5 * time.Second}, Mapping: testMapping}
+	snapshot, err := source.Fetch(context.Background())
+	if err != nil {
+		t.Fatalf("Fetch error: %v", err)
+	}
+	if len(snapshot.People) != 2 || snapshot.People[0].ExternalID != "u001" || snapshot.People[1].ExternalID != "u003" {
+		t.Fatalf("people = %+v", snapshot.People)
+	}
+}
+
+func TestLDAPSourceBindFailure(t *testing.T) {
+	server := newFakeLDAPServer(t, fakeEntries(1), 10)
+	server.password = "secret"
+
+	source := &LDAPSource{
+		Config:  LDAPConfig{URL: server.url(), BindDN: "cn=sync", BindPassword: "wrong", Timeout: 5 * time.Second},
+		Mapping: testMapping,
+	}
+	_, err := source.Fetch(context.Background())
+	var ldapErr *LDAPError
+	if !errors.As(err, &ldapErr) || ldapErr.Op != "bind" || ldapErr.Code != 49 {
+		t.Fatalf("Fetch error = %v, want bind LDAPError 49", err)
+	}
+
+	source.Config.BindPassword = ""
+	if _, err := source.Fetch(context.Background()); err == nil {
+		t.Fatal("expected error for bind dn without password")
+	}
+}
+
+func TestLDAPSourceConfigErrors(t *testing.T) {
+	cases := []LDAPSource{
+		{Config: LDAPConfig{URL: "ldap://127.0.0.1:1"}, Mapping: Mapping{Name: "cn"}},
+		{Config: LDAPConfig{URL: "ldap://127.0.0.1:1", Filter: "(cn=a"}, Mapping: testMapping},
+		{Config: LDAPConfig{URL: "http://127.0.0.1"}, Mapping: testMapping},
+	}
+	for i, source := range cases {
+		if _, err := source.Fetch(context.Background()); err == nil {
+			t.Errorf("case %d: expected error", i)
+		}
+	}
+}
+
+func TestValidateLDAPURL(t *testing.T) {
+	valid := []string{"ldap://ldap.example.com", "ldaps://ldap.example.com:636", "ldap://10.0.0.1:389/"}
+	for _, raw := range valid {
+		if err := ValidateLDAPURL(raw); err != nil {
+			t.Errorf("ValidateLDAPURL(%q) error: %v", raw, err)
+		}
+	}
+	invalid := []string{"", "ldap://", "http://ldap.example.com", "ldap://host/dc=example", "ldap://host?x=1"}
+	for _, raw := range invalid {
+		if err := ValidateLDAPURL(raw); err == nil {
+			t.Errorf("ValidateLDAPURL(%q) accepted invalid url", raw)
+		}
+	}
+}
+
+func TestAttributeString(t *testing.T) {
+	if got := attributeString([]byte("张三")); got != "张三" {
+		t.Errorf("attributeString(utf8) = %q", got)
+	}
+	if got := attributeString([]byte{0xff, 0x00, 0x10}); got != "ff0010" {
+		t.Errorf("attributeString(binary) = %q", got)
+	}
+}
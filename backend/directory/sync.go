@@ -0,0 +1,313 @@
+package directory
+
+import (
+	"context"
+	"fmt"
+	"time"
+	"unicode/utf8"
+
+	"lottery-system/models"
+	"lottery-system/namematch"
+	"lottery-system/utils"
+
+	"gorm.io/gorm"
+)
+
+const (
+	maxReportIssues = 100
+	maxExternalID   = 128
+	// 停用人数超过已同步人数的一半时需确认（防止过滤器或文件错误导致大批用户被停用）
+	deactivateGuardMin   = 10
+	deactivateGuardRatio = 0.5
+)
+
+// SyncOptions 同步选项
+type SyncOptions struct {
+	DryRun            bool // 只生成报告，不修改数据
+	DeactivateMissing bool // 停用目录中已不存在的用户
+	Force             bool // 跳过停用人数检查
+}
+
+// Issue 未能同步的人员
+type Issue struct {
+	ExternalID string `json:"external_id"`
+	Name       string `json:"name,omitempty"`
+	Message    string `json:"message"`
+}
+
+// Report 同步报告
+type Report struct {
+	Source      string    `json:"source"`
+	DryRun      bool      `json:"dry_run"`
+	Total       int       `json:"total"`       // 数据源人数
+	Created     int       `json:"created"`     // 新增
+	Updated     int       `json:"updated"`     // 姓名、手机号或字段有变化
+	Reactivated int       `json:"reactivated"` // 重新出现在目录中的已停用用户
+	Deactivated int       `json:"deactivated"` // 已从目录中移除
+	Unchanged   int       `json:"unchanged"`
+	Invalid     int       `json:"invalid"` // 数据不合法未同步（已有用户不会被停用）
+	Issues      []Issue   `json:"issues,omitempty"`
+	StartedAt   time.Time `json:"started_at"`
+	FinishedAt  time.Time `json:"finished_at"`
+}
+
+// Summary 返回同步报告摘要（用于操作日志）
+func (r *Report) Summary() string {
+	prefix := "目录同步"
+	if r.DryRun {
+		prefix = "目录同步预览"
+	}
+	return fmt.Sprintf("%s（%s）: 共 %d 人，新增 %d，更新 %d，恢复 %d，停用 %d，未变化 %d，无效 %d",
+		prefix, r.Source, r.Total, r.Created, r.Updated, r.Reactivated, r.Deactivated, r.Unchanged, r.Invalid)
+}
+
+func (r *Report) addIssue(p Person, message string) {
+	r.Invalid++
+	if len(r.Issues) < maxReportIssues {
+		r.Issues = append(r.Issues, Issue{ExternalID: p.ExternalID, Name: p.Name, Message: message})
+	}
+}
+
+// userChange 对已有用户的修改
+type userChange struct {
+	userID  int
+	updates map[string]interface{}
+	fields  map[string]string // 非 nil 时覆盖保存
+}
+
+// Sync 将数据源人员同步到公司：按唯一标识匹配（首次同步时按手机号关联手动添加的用户），
+// 新增不存在的用户，更新姓名、手机号和映射的表单字段，可选停用目录中已不存在的用户
+// 只停用由目录同步管理的用户（有唯一标识），手动添加的用户不受影响
+func Sync(ctx context.Context, db *gorm.DB, companyID int, snapshot *Snapshot, source string, opts SyncOptions) (*Report, error) {
+	report := &Report{Source: source, DryRun: opts.DryRun, Total: len(snapshot.People), StartedAt: time.Now()}
+	if len(snapshot.People) == 0 {
+		return report, ErrEmptySource
+	}
+
+	// 目录数据为准：映射的字段即使在表单中为必填，目录中为空时也允许
+	var definitions []models.RegistrationField
+	if err := db.Where("company_id = ? AND is_active = ?", companyID, true).Find(&definitions).Error; err != nil {
+		return nil, err
+	}
+	active := map[string]bool{}
+	for i := range definitions {
+		definitions[i].Required = false
+		active[definitions[i].Key] = true
+	}
+
+	// 使用用户模型读取，手机号自动解密
+	var users []models.User
+	if err := db.Select("id", "name", "phone", "external_id", "deactivated_at").
+		Where("company_id = ?", companyID).Find(&users).Error; err != nil {
+		return nil, err
+	}
+	byExternalID := map[string]*models.User{}
+	byPhone := map[string][]*models.User{}
+	for i := range users {
+		u := &users[i]
+		if u.ExternalID != "" {
+			byExternalID[u.ExternalID] = u
+		} else if phone := namematch.NormalizePhone(u.Phone); phone != "" {
+			byPhone[phone] = append(byPhone[phone], u)
+		}
+	}
+
+	var values []models.UserFieldValue
+	if err := db.Select("user_id", "field_key", "value").Where("company_id = ?", companyID).Find(&values).Error; err != nil {
+		return nil, err
+	}
+	fieldValues := map[int]map[string]string{}
+	for _, v := range values {
+		if fieldValues[v.UserID] == nil {
+			fieldValues[v.UserID] = map[string]string{}
+		}
+		fieldValues[v.UserID][v.FieldKey] = v.Value
+	}
+
+	now := time.Now()
+	seen := map[string]bool{}
+	linked := map[int]bool{}
+	var created []models.User
+	var createdFields []map[string]string
+	var changes []userChange
+
+	for _, p := range snapshot.People {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		if seen[p.ExternalID] {
+			report.addIssue(p, "目录中的唯一标识重复")
+			continue
+		}
+		seen[p.ExternalID] = true
+
+		if len(p.ExternalID) > maxExternalID {
+			report.addIssue(p, fmt.Sprintf("唯一标识不能超过%d个字符", maxExternalID))
+			continue
+		}
+		if p.Name == "" || utf8.RuneCountInString(p.Name) > 100 {
+			report.addIssue(p, "姓名为空或超过100个字符")
+			continue
+		}
+		phone := namematch.NormalizePhone(p.Phone)
+		if phone != "" {
+			if err := utils.ValidatePhone(phone); err != nil {
+				report.addIssue(p, err.Error())
+				continue
+			}
+		}
+		answers, err := utils.ValidateFormAnswers(definitions, p.Fields)
+		if err != nil {
+			report.addIssue(p, err.Error())
+			continue
+		}
+
+		u := byExternalID[p.ExternalID]
+		if u == nil && phone != "" && len(byPhone[phone]) == 1 && !linked[byPhone[phone][0].ID] {
+			u = byPhone[phone][0]
+		}
+		if u == nil {
+			created = append(created, models.User{
+				CompanyID:  companyID,
+				Password:   "",
+				Role:       models.RoleUser,
+				Name:       p.Name,
+				Phone:      phone,
+				ExternalID: p.ExternalID,
+			})
+			createdFields = append(createdFields, answers)
+			report.Created++
+			continue
+		}
+		linked[u.ID] = true
+
+		change := userChange{userID: u.ID, updates: map[string]interface{}{}}
+		if u.ExternalID != p.ExternalID {
+			change.updates["external_id"] = p.ExternalID
+		}
+		if u.Name != p.Name {
+			nameKey, namePinyin := namematch.NameKeys(p.Name)
+			change.updates["name"] = p.Name
+			change.updates["name_key"] = nameKey
+			change.updates["name_pinyin"] = namePinyin
+		}
+		// 目录中没有手机号时保留已有的手机号
+		if phone != "" && u.Phone != phone {
+			if err := models.SetPhoneUpdate(change.updates, phone); err != nil {
+				return nil, err
+			}
+		}
+		if merged, changed := mergeFields(fieldValues[u.ID], p.Fields, answers, active); changed {
+			change.fields = merged
+		}
+
+		if u.DeactivatedAt != nil {
+			change.updates["deactivated_at"] = nil
+			report.Reactivated++
+		} else if len(change.updates) > 0 || change.fields != nil {
+			report.Updated++
+		} else {
+			report.Unchanged++
+			continue
+		}
+		changes = append(changes, change)
+	}
+
+	var deactivate []int
+	managed := 0
+	if opts.DeactivateMissing {
+		for _, u := range users {
+			if u.ExternalID == "" || u.DeactivatedAt != nil {
+				continue
+			}
+			managed++
+			if !seen[u.ExternalID] {
+				deactivate = append(deactivate, u.ID)
+			}
+		}
+	}
+	report.Deactivated = len(deactivate)
+	if !opts.Force && managed >= deactivateGuardMin && float64(len(deactivate)) > float64(managed)*deactivateGuardRatio {
+		report.FinishedAt = time.Now()
+		return report, fmt.Errorf("%w: %d of %d", ErrTooManyDeactivations, len(deactivate), managed)
+	}
+
+	if opts.DryRun {
+		report.FinishedAt = time.Now()
+		return report, nil
+	}
+
+	err := db.Transaction(func(tx *gorm.DB) error {
+		if len(created) > 0 {
+			if err := tx.CreateInBatches(&created, 200).Error; err != nil {
+				return err
+			}
+			var newValues []models.UserFieldValue
+			for i, user := range created {
+				for key, value := range createdFields[i] {
+					newValues = append(newValues, models.UserFieldValue{CompanyID: companyID, UserID: user.ID, FieldKey: key, Value: value})
+				}
+			}
+			if len(newValues) > 0 {
+				if err := tx.CreateInBatches(&newValues, 500).Error; err != nil {
+					return err
+				}
+			}
+		}
+
+		for _, change := range changes {
+			if len(change.updates) > 0 {
+				if err := tx.Model(&models.User{}).Where("id = ?", change.userID).Updates(change.updates).Error; err != nil {
+					return err
+				}
+			}
+			if change.fields != nil {
+				if err := utils.SaveUserFieldValues(tx, companyID, change.userID, change.fields); err != nil {
+					return err
+				}
+			}
+		}
+
+		for start := 0; start < len(deactivate); start += 500 {
+			end := start + 500
+			if end > len(deactivate) {
+				end = len(deactivate)
+			}
+			if err := tx.Model(&models.User{}).Where("id IN ?", deactivate[start:end]).
+				Update("deactivated_at", now).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	report.FinishedAt = time.Now()
+	return report, nil
+}
+
+// mergeFields 用目录中映射的字段覆盖已有字段值（目录中为空时删除），未映射或表单中未启用的字段保持不变
+func mergeFields(existing, mapped, answers map[string]string, active map[string]bool) (map[string]string, bool) {
+	merged := make(map[string]string, len(existing)+len(answers))
+	for key, value := range existing {
+		merged[key] = value
+	}
+	changed := false
+	for key := range mapped {
+		if !active[key] {
+			continue
+		}
+		old, exists := merged[key]
+		if value := answers[key]; value == "" {
+			if exists {
+				delete(merged, key)
+				changed = true
+			}
+		} else if !exists || old != value {
+			merged[key] = value
+			changed = true
+		}
+	}
+	return merged, changed
+}
@@ -0,0 +1,333 @@
+package directory
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"lottery-system/fieldcrypt"
+	"lottery-system/models"
+
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+const testCompanyID = 1
+
+func newSyncTestDB(t *testing.T) *gorm.DB {
+	t.Helper()
+	ring, err := fieldcrypt.ParseKeyring("", "", []byte("0123456789abcdef0123456789abcdef"), []byte("index-key"))
+	if err != nil {
+		t.Fatalf("ParseKeyring error: %v", err)
+	}
+	fieldcrypt.SetDefault(ring)
+
+	db, err := gorm.Open(sqlite.Open("file::memory:"), &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+	if err != nil {
+		t.Fatalf("open sqlite error: %v", err)
+	}
+	sqlDB, _ := db.DB()
+	sqlDB.SetMaxOpenConns(1) // 内存数据库每个连接独立
+	t.Cleanup(func() { sqlDB.Close() })
+
+	if err := db.AutoMigrate(&models.User{}, &models.RegistrationField{}, &models.UserFieldValue{}); err != nil {
+		t.Fatalf("migrate error: %v", err)
+	}
+	if err := db.Create(&models.RegistrationField{CompanyID: testCompanyID, Key: "dept", Label: "部门", Type: "text", MaxLength: 100, IsActive: true}).Error; err != nil {
+		t.Fatalf("create field error: %v", err)
+	}
+	return db
+}
+
+func syncPeople(t *testing.T, db *gorm.DB, people []Person, opts SyncOptions) (*Report, error) {
+	t.Helper()
+	source := &StaticSource{Label: "hr", People: people}
+	snapshot, err := source.Fetch(context.Background())
+	if err != nil {
+		t.Fatalf("Fetch error: %v", err)
+	}
+	return Sync(context.Background(), db, testCompanyID, snapshot, source.Name(), opts)
+}
+
+func loadUsers(t *testing.T, db *gorm.DB) map[string]models.User {
+	t.Helper()
+	var users []models.User
+	if err := db.Where("company_id = ?", testCompanyID).Find(&users).Error; err != nil {
+		t.Fatalf("load users error: %v", err)
+	}
+	byID := make(map[string]models.User, len(users))
+	for _, u := range users {
+		key := u.ExternalID
+		if key == "" {
+			key = "manual:" + u.Name
+		}
+		byID[key] = u
+	}
+	return byID
+}
+
+func loadFields(t *testing.T, db *gorm.DB, userID int) map[string]string {
+	t.Helper()
+	var values []models.UserFieldValue
+	db.Where("user_id = ?", userID).Find(&values)
+	fields := make(map[string]string, len(values))
+	for _, v := range values {
+		fields[v.FieldKey] = v.Value
+	}
+	return fields
+}
+
+func person(id, name, phone, dept string) Person {
+	p := Person{ExternalID: id, Name: name, Phone: phone}
+	if dept != "" {
+		p.Fields = map[string]string{"dept": dept}
+	}
+	return p
+}
+
+func TestSyncAddUpdateDeactivate(t *testing.T) {
+	db := newSyncTestDB(t)
+
+	// 首次同步：全部新增
+	report, err := syncPeople(t, db, []Person{
+		person("e1", "张三", "13800000001", "研发部"),
+		person("e2", "李四", "13800000002", "市场部"),
+		person("e3", "王五", "", ""),
+	}, SyncOptions{DeactivateMissing: true})
+	if err != nil {
+		t.Fatalf("first sync error: %v", err)
+	}
+	if report.Created != 3 || report.Updated != 0 || report.Deactivated != 0 {
+		t.Fatalf("first sync report = %s", report.Summary())
+	}
+
+	users := loadUsers(t, db)
+	if len(users) != 3 {
+		t.Fatalf("got %d users, want 3", len(users))
+	}
+	if u := users["e1"]; u.Name != "张三" || u.Phone != "13800000001" || u.PhoneHash != models.PhoneIndex("13800000001") {
+		t.Fatalf("user e1 = %+v", u)
+	}
+	if got := loadFields(t, db, users["e1"].ID)["dept"]; got != "研发部" {
+		t.Fatalf("user e1 dept = %q", got)
+	}
+	// 用户名不使用手机号
+	for id, u := range users {
+		if u.Username != models.OpaqueUsername(u.ID) {
+			t.Errorf("user %s username = %q, want %q", id, u.Username, models.OpaqueUsername(u.ID))
+		}
+	}
+	// 手机号加密存储
+	var raw string
+	db.Table("users").Select("phone").Where("id = ?", users["e1"].ID).Scan(&raw)
+	if !fieldcrypt.IsEncrypted(raw) {
+		t.Fatalf("stored phone is not encrypted: %q", raw)
+	}
+
+	// 相同数据再次同步：无变化
+	report, err = syncPeople(t, db, []Person{
+		person("e1", "张三", "13800000001", "研发部"),
+		person("e2", "李四", "13800000002", "市场部"),
+		person("e3", "王五", "", ""),
+	}, SyncOptions{DeactivateMissing: true})
+	if err != nil {
+		t.Fatalf("unchanged sync error: %v", err)
+	}
+	if report.Unchanged != 3 || report.Created+report.Updated+report.Deactivated != 0 {
+		t.Fatalf("unchanged sync report = %s", report.Summary())
+	}
+
+	// 改名、换手机号、换部门，e3 从目录中移除，新增 e4
+	report, err = syncPeople(t, db, []Person{
+		person("e1", "张三丰", "13800000001", "研发部"),
+		person("e2", "李四", "13900000002", "销售部"),
+		person("e4", "赵六", "13800000004", ""),
+	}, SyncOptions{DeactivateMissing: true})
+	if err != nil {
+		t.Fatalf("update sync error: %v", err)
+	}
+	if report.Created != 1 || report.Updated != 2 || report.Deactivated != 1 {
+		t.Fatalf("update sync report = %s", report.Summary())
+	}
+
+	users = loadUsers(t, db)
+	if users["e1"].Name != "张三丰" {
+		t.Errorf("user e1 name = %q", users["e1"].Name)
+	}
+	if u := users["e2"]; u.Phone != "13900000002" || u.PhoneHash != models.PhoneIndex("13900000002") {
+		t.Errorf("user e2 phone = %q hash %q", u.Phone, u.PhoneHash)
+	}
+	if got := loadFields(t, db, users["e2"].ID)["dept"]; got != "销售部" {
+		t.Errorf("user e2 dept = %q", got)
+	}
+	if users["e3"].DeactivatedAt == nil {
+		t.Error("user e3 was not deactivated")
+	}
+	if users["e4"].DeactivatedAt != nil || users["e4"].ID == 0 {
+		t.Errorf("user e4 = %+v", users["e4"])
+	}
+
+	// e3 重新出现在目录中：恢复
+	report, err = syncPeople(t, db, []Person{
+		person("e1", "张三丰", "13800000001", "研发部"),
+		person("e2", "李四", "13900000002", "销售部"),
+		person("e3", "王五", "", ""),
+		person("e4", "赵六", "13800000004", ""),
+	}, SyncOptions{DeactivateMissing: true})
+	if err != nil {
+		t.Fatalf("reactivate sync error: %v", err)
+	}
+	if report.Reactivated != 1 || report.Unchanged != 3 {
+		t.Fatalf("reactivate sync report = %s", report.Summary())
+	}
+	if loadUsers(t, db)["e3"].DeactivatedAt != nil {
+		t.Error("user e3 was not reactivated")
+	}
+}
+
+func TestSyncLinksManualUserByPhone(t *testing.T) {
+	db := newSyncTestDB(t)
+
+	manual := models.User{CompanyID: testCompanyID, Username: "manual", Role: models.RoleUser, Name: "张三", Phone: "13800000001"}
+	other := models.User{CompanyID: testCompanyID, Username: "other", Role: models.RoleUser, Name: "手动添加", Phone: "13700000000"}
+	if err := db.Create(&manual).Error; err != nil {
+		t.Fatalf("create manual user error: %v", err)
+	}
+	if err := db.Create(&other).Error; err != nil {
+		t.Fatalf("create other user error: %v", err)
+	}
+
+	report, err := syncPeople(t, db, []Person{person("e1", "张三", "138-0000-0001", "")}, SyncOptions{DeactivateMissing: true})
+	if err != nil {
+		t.Fatalf("sync error: %v", err)
+	}
+	if report.Created != 0 || report.Updated != 1 || report.Deactivated != 0 {
+		t.Fatalf("report = %s", report.Summary())
+	}
+
+	var linked models.User
+	db.First(&linked, manual.ID)
+	if linked.ExternalID != "e1" {
+		t.Fatalf("manual user external_id = %q, want e1", linked.ExternalID)
+	}
+	// 手动添加且未关联目录的用户不会被停用
+	var untouched models.User
+	db.First(&untouched, other.ID)
+	if untouched.DeactivatedAt != nil || untouched.ExternalID != "" {
+		t.Fatalf("manual user was modified: %+v", untouched)
+	}
+}
+
+func TestSyncDryRun(t *testing.T) {
+	db := newSyncTestDB(t)
+
+	report, err := syncPeople(t, db, []Person{
+		person("e1", "张三", "13800000001", ""),
+		person("e2", "李四", "", ""),
+	}, SyncOptions{DryRun: true})
+	if err != nil {
+		t.Fatalf("dry run error: %v", err)
+	}
+	if !report.DryRun || report.Created != 2 {
+		t.Fatalf("report = %s", report.Summary())
+	}
+
+	var count int64
+	db.Model(&models.User{}).Count(&count)
+	if count != 0 {
+		t.Fatalf("dry run created %d users", count)
+	}
+}
+
+func TestSyncInvalidPeople(t *testing.T) {
+	db := newSyncTestDB(t)
+
+	longID := make([]byte, maxExternalID+1)
+	for i := range longID {
+		longID[i] = 'x'
+	}
+	report, err := syncPeople(t, db, []Person{
+		person("e1", "张三", "13800000001", ""),
+		person("e1", "重复", "13800000009", ""),
+		person("e2", "", "13800000002", ""),
+		person("e3", "王五", "12345", ""),
+		person(string(longID), "赵六", "", ""),
+	}, SyncOptions{})
+	if err != nil {
+		t.Fatalf("sync error: %v", err)
+	}
+	if report.Created != 1 || report.Invalid != 4 || len(report.Issues) != 4 {
+		t.Fatalf("report = %s, issues %+v", report.Summary(), report.Issues)
+	}
+}
+
+func TestSyncEmptySource(t *testing.T) {
+	db := newSyncTestDB(t)
+	if _, err := syncPeople(t, db, nil, SyncOptions{}); !errors.Is(err, ErrEmptySource) {
+		t.Fatalf("err = %v, want ErrEmptySource", err)
+	}
+}
+
+func TestSyncDeactivationGuard(t *testing.T) {
+	db := newSyncTestDB(t)
+
+	var people []Person
+	for i := 1; i <= 12; i++ {
+		people = append(people, person(fmt.Sprintf("e%d", i), fmt.Sprintf("员工%d", i), "", ""))
+	}
+	if _, err := syncPeople(t, db, people, SyncOptions{}); err != nil {
+		t.Fatalf("initial sync error: %v", err)
+	}
+
+	// 目录只剩 2 人，超过半数用户将被停用，需要确认
+	report, err := syncPeople(t, db, people[:2], SyncOptions{DeactivateMissing: true})
+	if !errors.Is(err, ErrTooManyDeactivations) {
+		t.Fatalf("err = %v, want ErrTooManyDeactivations", err)
+	}
+	if report == nil || report.Deactivated != 10 {
+		t.Fatalf("report = %+v", report)
+	}
+	var deactivated int64
+	db.Model(&models.User{}).Where("deactivated_at IS NOT NULL").Count(&deactivated)
+	if deactivated != 0 {
+		t.Fatalf("guard failed: %d users deactivated", deactivated)
+	}
+
+	if _, err := syncPeople(t, db, people[:2], SyncOptions{DeactivateMissing: true, Force: true}); err != nil {
+		t.Fatalf("forced sync error: %v", err)
+	}
+	db.Model(&models.User{}).Where("deactivated_at IS NOT NULL").Count(&deactivated)
+	if deactivated != 10 {
+		t.Fatalf("forced sync deactivated %d users, want 10", deactivated)
+	}
+}
+
+func TestDigestIgnoresOrder(t *testing.T) {
+	a := []Person{person("e1", "张三", "1", "x"), person("e2", "李四", "2", "")}
+	b := []Person{a[1], a[0]}
+	if digest(a) != digest(b) {
+		t.Fatal("digest depends on order")
+	}
+	c := []Person{person("e1", "张三", "1", "y"), a[1]}
+	if digest(a) == digest(c) {
+		t.Fatal("digest ignores field changes")
+	}
+}
+
+func TestMappingValidate(t *testing.T) {
+	if err := testMapping.Validate(); err != nil {
+		t.Fatalf("Validate error: %v", err)
+	}
+	invalid := []Mapping{
+		{Name: "cn"},
+		{ID: "uid"},
+		{ID: "uid", Name: "cn", Fields: map[string]string{"dept": " "}},
+	}
+	for i, m := range invalid {
+		if err := m.Validate(); err == nil {
+			t.Errorf("case %d: expected error", i)
+		}
+	}
+}
@@ -0,0 +1,234 @@
+// Package fieldcrypt 字段级加密（手机号等个人信息加密存储）
+//
+// 采用信封加密：每个值使用随机生成的数据密钥（AES-256-GCM）加密，数据密钥再由主密钥加密后与密文一起保存，
+// 密文中记录主密钥ID，轮换主密钥时旧密钥保留在密钥环中用于解密，新写入的数据使用当前密钥。
+// 加密结果带随机数，相同的值每次加密结果不同，等值查找使用单独的盲索引（HMAC-SHA256）。
+package fieldcrypt
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+)
+
+// Prefix 加密值前缀（带版本号）：FE1.<主密钥ID>.<加密的数据密钥>.<密文>
+const Prefix = "FE1."
+
+const dataKeySize = 32
+
+// 解密错误
+var (
+	ErrMalformed  = errors.New("malformed encrypted value")
+	ErrUnknownKey = errors.New("encrypted with unknown key")
+)
+
+// Keyring 字段加密密钥环
+type Keyring struct {
+	keys   map[string][]byte // 主密钥ID → 主密钥
+	active string
+	index  []byte // 盲索引密钥（不随主密钥轮换；更换时需同时轮换主密钥并重新加密，以重建索引）
+}
+
+// ParseKeyring 解析主密钥配置：<密钥ID>:<密钥>,<密钥ID>:<密钥>
+// active 为加密使用的密钥ID，为空时使用第一个；spec 为空时使用 fallback 作为唯一主密钥（ID 为 k0）
+// indexKey 为盲索引密钥
+func ParseKeyring(spec, active string, fallback, indexKey []byte) (*Keyring, error) {
+	if len(indexKey) == 0 {
+		return nil, errors.New("blind index key is required")
+	}
+	ring := &Keyring{keys: make(map[string][]byte), index: indexKey}
+
+	spec = strings.TrimSpace(spec)
+	if spec == "" {
+		ring.keys["k0"] = fallback
+		ring.active = "k0"
+		return ring, nil
+	}
+
+	for _, entry := range strings.Split(spec, ",") {
+		parts := strings.SplitN(strings.TrimSpace(entry), ":", 2)
+		if len(parts) != 2 || parts[0] == "" || len(parts[1]) < 16 {
+			return nil, fmt.Errorf("invalid field encryption key entry %q (expected <id>:<secret of at least 16 chars>)", entry)
+		}
+		if len(parts[0]) > 16 || strings.ContainsAny(parts[0], ".%_") {
+			return nil, fmt.Errorf("field encryption key id %q must be at most 16 chars without '.', '%%' or '_'", parts[0])
+		}
+		if _, exists := ring.keys[parts[0]]; exists {
+			return nil, fmt.Errorf("duplicate field encryption key id %q", parts[0])
+		}
+		ring.keys[parts[0]] = deriveKey(parts[1])
+		if ring.active == "" {
+			ring.active = parts[0]
+		}
+	}
+
+	if active != "" {
+		if _, ok := ring.keys[active]; !ok {
+			return nil, fmt.Errorf("active field encryption key %q not found", active)
+		}
+		ring.active = active
+	}
+	return ring, nil
+}
+
+// ActiveKeyID 返回当前加密使用的密钥ID
+func (r *Keyring) ActiveKeyID() string {
+	return r.active
+}
+
+// KeyIDs 返回密钥环中所有密钥ID（用于日志和状态展示）
+func (r *Keyring) KeyIDs() []string {
+	ids := make([]string, 0, len(r.keys))
+	for id := range r.keys {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+// Encrypt 使用当前主密钥加密，空字符串原样返回（便于按是否为空查询）
+func (r *Keyring) Encrypt(plaintext string) (string, error) {
+	if plaintext == "" {
+		return "", nil
+	}
+
+	dataKey := make([]byte, dataKeySize)
+	if _, err := io.ReadFull(rand.Reader, dataKey); err != nil {
+		return "", err
+	}
+	sealed, err := seal(dataKey, []byte(plaintext), nil)
+	if err != nil {
+		return "", err
+	}
+	// 主密钥ID作为附加认证数据，防止替换密钥ID
+	wrapped, err := seal(r.keys[r.active], dataKey, []byte(r.active))
+	if err != nil {
+		return "", err
+	}
+
+	return Prefix + r.active + "." +
+		base64.RawURLEncoding.EncodeToString(wrapped) + "." +
+		base64.RawURLEncoding.EncodeToString(sealed), nil
+}
+
+// Decrypt 解密 Encrypt 生成的值，不带前缀的值视为尚未加密的明文原样返回
+func (r *Keyring) Decrypt(value string) (string, error) {
+	if !IsEncrypted(value) {
+		return value, nil
+	}
+
+	parts := strings.Split(strings.TrimPrefix(value, Prefix), ".")
+	if len(parts) != 3 {
+		return "", ErrMalformed
+	}
+	key, ok := r.keys[parts[0]]
+	if !ok {
+		return "", fmt.Errorf("%w: %s", ErrUnknownKey, parts[0])
+	}
+	wrapped, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return "", ErrMalformed
+	}
+	sealed, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return "", ErrMalformed
+	}
+
+	dataKey, err := open(key, wrapped, []byte(parts[0]))
+	if err != nil {
+		return "", err
+	}
+	plaintext, err := open(dataKey, sealed, nil)
+	if err != nil {
+		return "", err
+	}
+	return string(plaintext), nil
+}
+
+// BlindIndex 计算等值查找使用的盲索引，空字符串返回空
+func (r *Keyring) BlindIndex(value string) string {
+	if value == "" {
+		return ""
+	}
+	mac := hmac.New(sha256.New, r.index)
+	mac.Write([]byte(value))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// IsEncrypted 判断值是否为加密格式
+func IsEncrypted(value string) bool {
+	return strings.HasPrefix(value, Prefix)
+}
+
+// KeyID 返回加密值使用的主密钥ID，未加密时返回空
+func KeyID(value string) string {
+	if !IsEncrypted(value) {
+		return ""
+	}
+	rest := strings.TrimPrefix(value, Prefix)
+	if i := strings.IndexByte(rest, '.'); i > 0 {
+		return rest[:i]
+	}
+	return ""
+}
+
+// seal 使用 AES-256-GCM 加密，返回 nonce + 密文
+func seal(key, plaintext, additional []byte) ([]byte, error) {
+	gcm, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return nil, err
+	}
+	return gcm.Seal(nonce, nonce, plaintext, additional), nil
+}
+
+// open 解密 seal 生成的数据
+func open(key, data, additional []byte) ([]byte, error) {
+	gcm, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+	if len(data) < gcm.NonceSize() {
+		return nil, ErrMalformed
+	}
+	nonce, sealed := data[:gcm.NonceSize()], data[gcm.NonceSize():]
+	return gcm.Open(nil, nonce, sealed, additional)
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// deriveKey 从任意长度的密钥字符串派生 32 字节 AES-256 密钥
+func deriveKey(secret string) []byte {
+	sum := sha256.Sum256([]byte(secret))
+	return sum[:]
+}
+
+var defaultKeyring *Keyring
+
+// SetDefault 设置全局默认密钥环
+func SetDefault(r *Keyring) {
+	defaultKeyring = r
+}
+
+// Default 获取全局默认密钥环
+func Default() *Keyring {
+	return defaultKeyring
+}
@@ -0,0 +1,143 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"lottery-system/config"
+	"lottery-system/models"
+	"lottery-system/storage"
+	"lottery-system/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	maxBadgesPerExport = 3000
+	maxBadgeLogoBytes  = 5 << 20
+)
+
+// GenerateBadgesPDF 生成公司参与者胸牌 PDF（姓名、公司 Logo、个人签到二维码），支持 A4 网格和标签打印机排版（权限隔离）
+// 查询参数：layout=a4|label、checked_in、has_drawn、user_ids=1,2,3、field.<key>=<value>、
+// subtitle_field（作为副标题的报名表单字段）、label_width_mm / label_height_mm
+func GenerateBadgesPDF(c *gin.Context) {
+	companyID, ok := getScopedCompanyID(c, c.Query("company_id"))
+	if !ok {
+		return
+	}
+
+	var company models.Company
+	if err := config.DB.First(&company, companyID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Company not found"})
+		return
+	}
+
+	opts := utils.BadgeSheetOptions{
+		Layout: c.DefaultQuery("layout", utils.BadgeLayoutA4),
+		Title:  company.Name,
+	}
+	if opts.Layout != utils.BadgeLayoutA4 && opts.Layout != utils.BadgeLayoutLabel {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的排版方式，可选 a4 或 label"})
+		return
+	}
+	if opts.Layout == utils.BadgeLayoutLabel {
+		opts.LabelWidthMM, _ = strconv.ParseFloat(c.Query("label_width_mm"), 64)
+		opts.LabelHeightMM, _ = strconv.ParseFloat(c.Query("label_height_mm"), 64)
+	}
+
+	// 候补用户未获得参与资格，不生成胸牌
+	query := config.DB.Model(&models.User{}).Where("company_id = ? AND waitlisted = ?", company.ID, false)
+	switch c.Query("checked_in") {
+	case "true":
+		query = query.Where("checked_in_at IS NOT NULL")
+	case "false":
+		query = query.Where("checked_in_at IS NULL")
+	}
+	if hasDrawn := c.Query("has_drawn"); hasDrawn != "" {
+		query = query.Where("has_drawn = ?", hasDrawn)
+	}
+	if raw := strings.TrimSpace(c.Query("user_ids")); raw != "" {
+		var ids []int
+		for _, part := range strings.Split(raw, ",") {
+			id, err := strconv.Atoi(strings.TrimSpace(part))
+			if err != nil || id <= 0 {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "user_ids 格式错误"})
+				return
+			}
+			ids = append(ids, id)
+		}
+		query = query.Where("id IN ?", ids)
+	}
+	if filters := utils.FieldFiltersFromQuery(c.Request.URL.Query()); len(filters) > 0 {
+		if err := utils.ValidateFieldFilters(filters); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		query = utils.ApplyFieldFilters(config.DB, query, company.ID, filters)
+	}
+
+	var total int64
+	query.Count(&total)
+	if total == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "没有符合条件的参与者", "error_code": "NO_PARTICIPANTS"})
+		return
+	}
+	if total > maxBadgesPerExport {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":      fmt.Sprintf("单次最多生成 %d 个胸牌，请缩小筛选范围", maxBadgesPerExport),
+			"error_code": "TOO_MANY_BADGES",
+		})
+		return
+	}
+
+	var users []models.User
+	query.Order("id ASC").Find(&users)
+
+	subtitleField := strings.TrimSpace(c.Query("subtitle_field"))
+	if subtitleField != "" {
+		utils.AttachUserFields(config.DB, users)
+	}
+
+	key := checkInKey()
+	badges := make([]utils.Badge, 0, len(users))
+	for _, user := range users {
+		badges = append(badges, utils.Badge{
+			Name:      user.Name,
+			Subtitle:  user.Fields[subtitleField],
+			QRContent: utils.SignCheckInToken(company.ID, user.ID, key),
+		})
+	}
+
+	// 仅使用已上传到本系统存储的 Logo，读取失败时生成不带 Logo 的胸牌
+	if company.Logo != "" {
+		logo, err := utils.LoadStoredImage(c.Request.Context(), storage.Default(), company.Logo, uploadURLPrefix, maxBadgeLogoBytes)
+		if err != nil {
+			utils.WithFields(map[string]interface{}{"error": err, "company_id": company.ID}).Warn("读取公司Logo失败，胸牌将不包含Logo")
+		} else {
+			opts.Logo = logo
+		}
+	}
+
+	pdf, err := utils.RenderBadgeSheet(badges, opts)
+	if err != nil {
+		if errors.Is(err, utils.ErrInvalidBadgeLayout) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "无效的标签尺寸（宽50-210mm，高40-297mm）"})
+			return
+		}
+		utils.WithFields(map[string]interface{}{"error": err, "company_id": company.ID}).Error("生成胸牌PDF失败")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "生成胸牌失败"})
+		return
+	}
+
+	resourceID := uint(company.ID)
+	LogOperation(c, "generate_badges", "company", &resourceID,
+		fmt.Sprintf("生成胸牌PDF: %s，%d 人（%s）", company.Name, len(badges), opts.Layout))
+
+	filename := fmt.Sprintf("badges_%s_%s.pdf", company.Code, time.Now().Format("20060102"))
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+	c.Data(http.StatusOK, "application/pdf", pdf)
+}
@@ -0,0 +1,105 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"lottery-system/challenge"
+	"lottery-system/models"
+	"lottery-system/otp"
+	"lottery-system/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ChallengeAnswer 人机验证答案（与 GET /api/challenge 返回的 token 一起提交）
+type ChallengeAnswer struct {
+	Token  string `json:"token"`
+	Answer string `json:"answer"` // 图形验证码内容，或工作量证明的计数器
+}
+
+// GetRegistrationChallenge 获取报名人机验证挑战（公开）
+// 公司未开启人机验证时返回 type=none；每个挑战只能提交一次，提交失败需重新获取
+func GetRegistrationChallenge(c *gin.Context) {
+	companyCode := c.Query("company_code")
+	if companyCode == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "company_code parameter is required"})
+		return
+	}
+
+	company, err := getCompanyByCode(companyCode)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid company code"})
+		return
+	}
+
+	if company.ChallengeMode == "" {
+		c.JSON(http.StatusOK, gin.H{"type": "none"})
+		return
+	}
+
+	provider := challenge.Get(company.ChallengeMode)
+	if provider == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "人机验证服务不可用"})
+		return
+	}
+	issued, err := provider.Issue(time.Now())
+	if err != nil {
+		utils.WithFields(map[string]interface{}{"error": err, "type": company.ChallengeMode}).Error("生成人机验证挑战失败")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "生成人机验证失败"})
+		return
+	}
+
+	c.Header("Cache-Control", "no-store")
+	c.JSON(http.StatusOK, issued)
+}
+
+// checkRegistrationChallenge 公司开启人机验证时，校验报名请求中的挑战答案
+func checkRegistrationChallenge(c *gin.Context, company *models.Company, answer *ChallengeAnswer) bool {
+	if company.ChallengeMode == "" {
+		return true
+	}
+	if answer == nil || answer.Token == "" {
+		c.JSON(http.StatusForbidden, gin.H{
+			"error":          "请先完成人机验证",
+			"error_code":     "CHALLENGE_REQUIRED",
+			"challenge_type": company.ChallengeMode,
+		})
+		return false
+	}
+
+	guard := otp.Default()
+	if guard == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "人机验证服务不可用"})
+		return false
+	}
+
+	err := challenge.Verify(c.Request.Context(), guard, company.ChallengeMode, answer.Token, answer.Answer, time.Now())
+	switch {
+	case err == nil:
+		return true
+	case errors.Is(err, challenge.ErrAlreadyUsed), errors.Is(err, challenge.ErrInvalidToken):
+		utils.NewSecurityLogger().LogSuspiciousActivity("registration_challenge",
+			fmt.Sprintf("人机验证凭证无效或重复使用: company=%d, %v", company.ID, err), c.ClientIP())
+		respondChallengeFailed(c, company, "人机验证失败，请重试")
+	case errors.Is(err, challenge.ErrExpired):
+		respondChallengeFailed(c, company, "人机验证已过期，请重试")
+	case errors.Is(err, challenge.ErrWrongAnswer):
+		respondChallengeFailed(c, company, "人机验证失败，请重试")
+	default:
+		utils.WithFields(map[string]interface{}{"error": err, "type": company.ChallengeMode}).Error("校验人机验证失败")
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "人机验证服务不可用"})
+	}
+	return false
+}
+
+// respondChallengeFailed 返回人机验证失败响应（客户端需重新获取挑战）
+func respondChallengeFailed(c *gin.Context, company *models.Company, message string) {
+	c.JSON(http.StatusForbidden, gin.H{
+		"error":          message,
+		"error_code":     "CHALLENGE_FAILED",
+		"challenge_type": company.ChallengeMode,
+	})
+}
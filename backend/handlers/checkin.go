@@ -0,0 +1,499 @@
+package handlers
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"lottery-system/config"
+	"lottery-system/models"
+	"lottery-system/realtime"
+	"lottery-system/utils"
+
+	"github.com/gin-gonic/gin"
+	"github.com/skip2/go-qrcode"
+	"gorm.io/gorm"
+)
+
+// CheckInTokenRequest 扫码签到请求
+type CheckInTokenRequest struct {
+	Token string `json:"token" binding:"required"` // 二维码内容
+}
+
+// UpdateCheckInSettingsRequest 更新签到配置请求
+type UpdateCheckInSettingsRequest struct {
+	CheckInRequired *bool   `json:"check_in_required"`
+	CheckInCutoffAt *string `json:"check_in_cutoff_at"` // RFC3339 时间，传空字符串取消截止时间
+}
+
+// checkInError 签到失败原因
+type checkInError struct {
+	Status  int
+	Code    string
+	Message string
+}
+
+func (e *checkInError) Error() string {
+	return e.Message
+}
+
+var (
+	errCheckInClosed  = &checkInError{http.StatusForbidden, "CHECK_IN_CLOSED", "签到已截止"}
+	errAlreadyChecked = &checkInError{http.StatusConflict, "ALREADY_CHECKED_IN", "已签到，无需重复签到"}
+)
+
+// GetMyCheckIn 获取我的签到状态和个人签到码（用户端）
+// 用户token使用自身身份；管理员token需通过 phone 和 company_code 参数指定用户
+func GetMyCheckIn(c *gin.Context) {
+	user, ok := findCurrentUser(c)
+	if !ok {
+		return
+	}
+
+	var company models.Company
+	if err := config.DB.First(&company, user.CompanyID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Company not found"})
+		return
+	}
+
+	response := gin.H{
+		"checked_in":         user.CheckedInAt != nil,
+		"checked_in_at":      user.CheckedInAt,
+		"check_in_required":  company.CheckInRequired,
+		"check_in_cutoff_at": company.CheckInCutoffAt,
+	}
+
+	if user.CheckedInAt == nil {
+		token := utils.SignCheckInToken(user.CompanyID, user.ID, checkInKey())
+		png, err := qrcode.Encode(token, qrcode.Medium, 256)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "生成签到码失败"})
+			return
+		}
+		response["token"] = token
+		response["qr"] = fmt.Sprintf("data:image/png;base64,%s", base64.StdEncoding.EncodeToString(png))
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// CheckInByVenueQR 参与者扫描现场轮换二维码签到（用户端）
+func CheckInByVenueQR(c *gin.Context) {
+	var req CheckInTokenRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "请求参数格式错误"})
+		return
+	}
+
+	user, ok := findCurrentUser(c)
+	if !ok {
+		return
+	}
+
+	companyID, err := utils.VerifyVenueCheckInToken(req.Token, time.Now(), checkInKey())
+	if err != nil || companyID != user.CompanyID {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "签到二维码无效或已过期，请重新扫描", "error_code": "INVALID_TOKEN"})
+		return
+	}
+
+	if err := checkInUser(user, models.CheckInMethodVenueQR, nil); err != nil {
+		respondCheckInError(c, user, err)
+		return
+	}
+
+	utils.WithFields(map[string]interface{}{
+		"user_id":    user.ID,
+		"company_id": user.CompanyID,
+		"ip":         c.ClientIP(),
+	}).Info("参与者扫码签到")
+
+	c.JSON(http.StatusOK, user)
+}
+
+// ScanCheckIn 工作人员或自助签到机扫描个人签到码（权限检查）
+func ScanCheckIn(c *gin.Context) {
+	var req CheckInTokenRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "请求参数格式错误"})
+		return
+	}
+
+	companyID, userID, err := utils.VerifyCheckInToken(req.Token, checkInKey())
+	if err != nil {
+		utils.NewSecurityLogger().LogSuspiciousActivity("checkin_invalid_token", "签到码签名无效", c.ClientIP())
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的签到码", "error_code": "INVALID_TOKEN"})
+		return
+	}
+
+	var user models.User
+	if err := config.DB.Where("id = ? AND company_id = ?", userID, companyID).First(&user).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return
+	}
+
+	if !canAccessCompany(c, user.CompanyID) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Permission denied"})
+		return
+	}
+
+	operatorID := currentAdminID(c)
+	if err := checkInUser(&user, models.CheckInMethodPersonalQR, &operatorID); err != nil {
+		respondCheckInError(c, &user, err)
+		return
+	}
+
+	resourceID := uint(user.ID)
+	LogOperation(c, "check_in", "user", &resourceID, fmt.Sprintf("扫码签到: %s (%s)", user.Name, maskPhone(user.Phone)))
+
+	c.JSON(http.StatusOK, user)
+}
+
+// ManualCheckIn 管理员手动为用户签到（权限检查），用于无法扫码的情况
+func ManualCheckIn(c *gin.Context) {
+	user, ok := loadUserForCheckIn(c)
+	if !ok {
+		return
+	}
+
+	operatorID := currentAdminID(c)
+	if err := checkInUser(user, models.CheckInMethodManual, &operatorID); err != nil {
+		respondCheckInError(c, user, err)
+		return
+	}
+
+	resourceID := uint(user.ID)
+	LogOperation(c, "check_in", "user", &resourceID, fmt.Sprintf("手动签到: %s (%s)", user.Name, maskPhone(user.Phone)))
+
+	c.JSON(http.StatusOK, user)
+}
+
+// CancelCheckIn 撤销用户签到（权限检查），已抽奖的用户不能撤销
+func CancelCheckIn(c *gin.Context) {
+	user, ok := loadUserForCheckIn(c)
+	if !ok {
+		return
+	}
+
+	if user.CheckedInAt == nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "该用户尚未签到"})
+		return
+	}
+	if user.HasDrawn {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "该用户已参与抽奖，无法撤销签到"})
+		return
+	}
+
+	if err := config.DB.Model(user).Updates(map[string]interface{}{
+		"checked_in_at":   nil,
+		"check_in_method": "",
+		"checked_in_by":   nil,
+	}).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "撤销签到失败"})
+		return
+	}
+
+	resourceID := uint(user.ID)
+	LogOperation(c, "cancel_check_in", "user", &resourceID, fmt.Sprintf("撤销签到: %s (%s)", user.Name, maskPhone(user.Phone)))
+
+	broadcastCheckIn(user.CompanyID, nil)
+	c.JSON(http.StatusOK, gin.H{"message": "Check-in cancelled"})
+}
+
+// GetVenueCheckInQR 获取当前周期的现场签到二维码（权限隔离），大屏/签到台每30秒刷新
+func GetVenueCheckInQR(c *gin.Context) {
+	companyID, ok := getScopedCompanyID(c, c.Query("company_id"))
+	if !ok {
+		return
+	}
+	respondVenueCheckInQR(c, companyID)
+}
+
+// GetDisplayCheckInQR 大屏获取现场签到二维码（使用大屏凭证认证，只有已配对的大屏可以获取）
+// 未配对的大屏由管理员通过管理后台获取签到二维码
+func GetDisplayCheckInQR(c *gin.Context) {
+	session, ok := loadDisplayForScreen(c)
+	if !ok {
+		return
+	}
+	if session.Status != models.DisplayStatusPaired {
+		c.JSON(http.StatusForbidden, gin.H{"error": "大屏尚未配对，无法获取签到二维码", "error_code": "DISPLAY_NOT_PAIRED"})
+		return
+	}
+	respondVenueCheckInQR(c, session.CompanyID)
+}
+
+// UpdateCheckInSettings 更新公司签到配置（权限检查）
+func UpdateCheckInSettings(c *gin.Context) {
+	var company models.Company
+	if err := config.DB.First(&company, c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Company not found"})
+		return
+	}
+
+	if !canAccessCompany(c, company.ID) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Permission denied"})
+		return
+	}
+
+	var req UpdateCheckInSettingsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "请求参数格式错误"})
+		return
+	}
+
+	updates := map[string]interface{}{}
+	if req.CheckInRequired != nil {
+		updates["check_in_required"] = *req.CheckInRequired
+	}
+	if req.CheckInCutoffAt != nil {
+		cutoff, err := parseOptionalTime(*req.CheckInCutoffAt)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "签到截止时间格式错误"})
+			return
+		}
+		updates["check_in_cutoff_at"] = cutoff
+	}
+
+	if len(updates) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "没有需要更新的配置"})
+		return
+	}
+
+	if err := config.DB.Model(&company).Updates(updates).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "更新签到配置失败"})
+		return
+	}
+
+	resourceID := uint(company.ID)
+	LogOperation(c, "update_check_in", "company", &resourceID, fmt.Sprintf("更新签到配置: %s", company.Name))
+
+	config.DB.First(&company, company.ID)
+	c.JSON(http.StatusOK, company)
+}
+
+// GetCheckInStats 获取签到统计（权限隔离）
+func GetCheckInStats(c *gin.Context) {
+	companyID, ok := getScopedCompanyID(c, c.Query("company_id"))
+	if !ok {
+		return
+	}
+
+	var company models.Company
+	if err := config.DB.First(&company, companyID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Company not found"})
+		return
+	}
+
+	var total, checkedIn, eligible int64
+	config.DB.Model(&models.User{}).Where("company_id = ?", company.ID).Count(&total)
+	config.DB.Model(&models.User{}).Where("company_id = ? AND checked_in_at IS NOT NULL", company.ID).Count(&checkedIn)
+	eligibleUsersQuery(&company, nil).Model(&models.User{}).Count(&eligible)
+
+	var methods []struct {
+		Method string `json:"method"`
+		Count  int64  `json:"count"`
+	}
+	config.DB.Model(&models.User{}).
+		Where("company_id = ? AND checked_in_at IS NOT NULL", company.ID).
+		Select("check_in_method as method, COUNT(*) as count").
+		Group("check_in_method").
+		Scan(&methods)
+
+	var recent []models.User
+	config.DB.Where("company_id = ? AND checked_in_at IS NOT NULL", company.ID).
+		Order("checked_in_at DESC").
+		Limit(20).
+		Find(&recent)
+
+	rate := 0.0
+	if total > 0 {
+		rate = float64(checkedIn) / float64(total)
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"company_id":         company.ID,
+		"check_in_required":  company.CheckInRequired,
+		"check_in_cutoff_at": company.CheckInCutoffAt,
+		"check_in_closed":    checkInClosed(&company, time.Now()),
+		"total_participants": total,
+		"checked_in":         checkedIn,
+		"not_checked_in":     total - checkedIn,
+		"check_in_rate":      rate,
+		"eligible_to_draw":   eligible,
+		"by_method":          methods,
+		"recent":             recent,
+	})
+}
+
+// eligibleUsersQuery 返回公司可参与抽奖的用户查询：未抽奖、不在候补名单、报名未被审核拒绝、未被目录同步停用，开启签到时还需已签到
+// filters 为报名表单字段筛选条件（如只抽某个部门），调用前需先校验
+func eligibleUsersQuery(company *models.Company, filters map[string]string) *gorm.DB {
+	query := config.DB.Where("company_id = ? AND has_drawn = ? AND waitlisted = ? AND review_status <> ? AND deactivated_at IS NULL",
+		company.ID, false, false, models.ReviewStatusRejected)
+	if company.CheckInRequired {
+		query = query.Where("checked_in_at IS NOT NULL")
+	}
+	return utils.ApplyFieldFilters(config.DB, query, company.ID, filters)
+}
+
+// checkInUser 为用户签到（条件更新，防止重复签到），成功后广播签到人数
+func checkInUser(user *models.User, method string, operatorID *int) error {
+	var company models.Company
+	if err := config.DB.First(&company, user.CompanyID).Error; err != nil {
+		return err
+	}
+
+	now := time.Now()
+	if user.CheckedInAt != nil {
+		return errAlreadyChecked
+	}
+	if checkInClosed(&company, now) {
+		return errCheckInClosed
+	}
+
+	updates := map[string]interface{}{
+		"checked_in_at":   now,
+		"check_in_method": method,
+		"checked_in_by":   operatorID,
+	}
+	result := config.DB.Model(&models.User{}).
+		Where("id = ? AND checked_in_at IS NULL", user.ID).
+		Updates(updates)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		config.DB.First(user, user.ID)
+		return errAlreadyChecked
+	}
+
+	user.CheckedInAt = &now
+	user.CheckInMethod = method
+	user.CheckedInBy = operatorID
+
+	broadcastCheckIn(user.CompanyID, user)
+	return nil
+}
+
+// checkInClosed 是否已过签到截止时间
+func checkInClosed(company *models.Company, now time.Time) bool {
+	return company.CheckInCutoffAt != nil && now.After(*company.CheckInCutoffAt)
+}
+
+// respondCheckInError 返回签到失败的响应
+func respondCheckInError(c *gin.Context, user *models.User, err error) {
+	var ciErr *checkInError
+	if errors.As(err, &ciErr) {
+		c.JSON(ciErr.Status, gin.H{
+			"error":         ciErr.Message,
+			"error_code":    ciErr.Code,
+			"checked_in_at": user.CheckedInAt,
+		})
+		return
+	}
+	c.JSON(http.StatusInternalServerError, gin.H{"error": "签到失败，请稍后重试"})
+}
+
+// respondVenueCheckInQR 返回当前周期的现场签到码
+func respondVenueCheckInQR(c *gin.Context, companyID int) {
+	var company models.Company
+	if err := config.DB.First(&company, companyID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Company not found"})
+		return
+	}
+
+	now := time.Now()
+	if checkInClosed(&company, now) {
+		c.JSON(http.StatusForbidden, gin.H{"error": errCheckInClosed.Message, "error_code": errCheckInClosed.Code})
+		return
+	}
+
+	token, expiresAt := utils.SignVenueCheckInToken(company.ID, now, checkInKey())
+	png, err := qrcode.Encode(token, qrcode.Medium, 512)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "生成签到码失败"})
+		return
+	}
+
+	c.Header("Cache-Control", "no-store")
+	c.JSON(http.StatusOK, gin.H{
+		"token":         token,
+		"qr":            fmt.Sprintf("data:image/png;base64,%s", base64.StdEncoding.EncodeToString(png)),
+		"expires_at":    expiresAt,
+		"refresh_after": int(expiresAt.Sub(now).Seconds()) + 1,
+	})
+}
+
+// findCurrentUser 查找当前操作的参与者（签到、我的奖品、个人资料）
+// 用户token使用自身身份；管理员token需通过 phone 和 company_code 参数指定用户
+func findCurrentUser(c *gin.Context) (*models.User, bool) {
+	var user models.User
+
+	isAdmin, _ := c.Get("is_admin")
+	if isAdmin == true {
+		company, err := getCompanyByCode(c.Query("company_code"))
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid company code"})
+			return nil, false
+		}
+		if !canAccessCompany(c, company.ID) {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Permission denied"})
+			return nil, false
+		}
+		if err := config.DB.Where("phone_hash = ? AND company_id = ?", models.PhoneIndex(c.Query("phone")), company.ID).First(&user).Error; err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+			return nil, false
+		}
+		return &user, true
+	}
+
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "未登录"})
+		return nil, false
+	}
+	if err := config.DB.First(&user, userID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return nil, false
+	}
+	return &user, true
+}
+
+// loadUserForCheckIn 根据路由参数加载用户并检查管理员权限
+func loadUserForCheckIn(c *gin.Context) (*models.User, bool) {
+	var user models.User
+	if err := config.DB.First(&user, c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return nil, false
+	}
+
+	if !canAccessCompany(c, user.CompanyID) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Permission denied"})
+		return nil, false
+	}
+
+	return &user, true
+}
+
+// broadcastCheckIn 广播签到人数变化
+func broadcastCheckIn(companyID int, user *models.User) {
+	var checkedIn int64
+	config.DB.Model(&models.User{}).Where("company_id = ? AND checked_in_at IS NOT NULL", companyID).Count(&checkedIn)
+
+	data := gin.H{"checked_in": checkedIn}
+	if user != nil {
+		data["user_id"] = user.ID
+		data["name"] = user.Name
+	}
+	publishLiveEvent(companyID, realtime.EventParticipantCheckedIn, data)
+}
+
+// checkInKey 获取签到凭证签名密钥
+func checkInKey() []byte {
+	if config.AppConfig.CheckInKey != "" {
+		return utils.DeriveKey(config.AppConfig.CheckInKey)
+	}
+	return utils.DeriveKey("checkin:" + config.AppConfig.JWTSecret)
+}
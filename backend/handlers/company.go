@@ -24,7 +24,7 @@ func GetCompanyByCode(c *gin.Context) {
 		return
 	}
 
-	c.JSON(http.StatusOK, company)
+	c.JSON(http.StatusOK, publicCompanyInfo{Company: &company})
 }
 
 // CreateCompany 创建公司（超级管理员）
@@ -42,6 +42,14 @@ func CreateCompany(c *gin.Context) {
 		return
 	}
 
+	if company.DrawOrder == "" {
+		company.DrawOrder = models.DrawOrderNone
+	}
+	if !models.DrawOrderIsValid(company.DrawOrder) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的抽奖顺序配置"})
+		return
+	}
+
 	if err := config.DB.Create(&company).Error; err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create company"})
 		return
@@ -54,6 +62,30 @@ func CreateCompany(c *gin.Context) {
 	c.JSON(http.StatusCreated, company)
 }
 
+// UpdateCompanyRequest 更新公司信息请求（只包含基本信息和文案，活动结束等配置使用各自的接口）
+type UpdateCompanyRequest struct {
+	Code       string `json:"code"`
+	Name       string `json:"name"`
+	Logo       string `json:"logo"`
+	ThemeColor string `json:"theme_color"`
+	BgColor    string `json:"bg_color"`
+
+	Title          string `json:"title"`
+	Subtitle       string `json:"subtitle"`
+	WelcomeText    string `json:"welcome_text"`
+	RulesText      string `json:"rules_text"`
+	DrawButtonText string `json:"draw_button_text"`
+	SuccessText    string `json:"success_text"`
+
+	ContactName  string `json:"contact_name"`
+	ContactPhone string `json:"contact_phone"`
+	ContactEmail string `json:"contact_email"`
+
+	IsActive bool `json:"is_active"`
+
+	DrawOrder string `json:"draw_order"` // 抽奖顺序: none, desc, asc
+}
+
 // UpdateCompany 更新公司信息
 func UpdateCompany(c *gin.Context) {
 	id := c.Param("id")
@@ -75,13 +107,37 @@ func UpdateCompany(c *gin.Context) {
 		}
 	}
 
-	var req models.Company
+	var req UpdateCompanyRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
-	if err := config.DB.Model(&company).Updates(req).Error; err != nil {
+	if req.DrawOrder != "" && !models.DrawOrderIsValid(req.DrawOrder) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的抽奖顺序配置"})
+		return
+	}
+
+	// 只更新请求中非空的字段
+	updates := models.Company{
+		Code:           req.Code,
+		Name:           req.Name,
+		Logo:           req.Logo,
+		ThemeColor:     req.ThemeColor,
+		BgColor:        req.BgColor,
+		Title:          req.Title,
+		Subtitle:       req.Subtitle,
+		WelcomeText:    req.WelcomeText,
+		RulesText:      req.RulesText,
+		DrawButtonText: req.DrawButtonText,
+		SuccessText:    req.SuccessText,
+		ContactName:    req.ContactName,
+		ContactPhone:   req.ContactPhone,
+		ContactEmail:   req.ContactEmail,
+		IsActive:       req.IsActive,
+		DrawOrder:      req.DrawOrder,
+	}
+	if err := config.DB.Model(&company).Updates(updates).Error; err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update company"})
 		return
 	}
@@ -230,3 +286,53 @@ func DeleteCompany(c *gin.Context) {
 
 	c.JSON(http.StatusOK, gin.H{"message": "Company deleted successfully"})
 }
+
+// getScopedCompanyID 解析管理员可操作的公司ID
+// 普通管理员强制使用自己的公司；超级管理员使用请求参数中的 company_id（必须提供）
+func getScopedCompanyID(c *gin.Context, companyIDParam string) (int, bool) {
+	isSuperAdmin, exists := c.Get("is_super_admin")
+	if !exists || !isSuperAdmin.(bool) {
+		companyID, exists := c.Get("company_id")
+		if !exists || companyID == nil || companyID.(*int) == nil {
+			c.JSON(http.StatusForbidden, gin.H{"error": "No company assigned"})
+			return 0, false
+		}
+		return *companyID.(*int), true
+	}
+
+	if companyIDParam == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "company_id is required"})
+		return 0, false
+	}
+
+	var companyID int
+	if _, err := fmt.Sscanf(companyIDParam, "%d", &companyID); err != nil || companyID <= 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid company_id"})
+		return 0, false
+	}
+	return companyID, true
+}
+
+// canAccessCompany 检查当前管理员是否可以操作指定公司
+func canAccessCompany(c *gin.Context, targetCompanyID int) bool {
+	isSuperAdmin, exists := c.Get("is_super_admin")
+	if exists && isSuperAdmin.(bool) {
+		return true
+	}
+	companyID, exists := c.Get("company_id")
+	return exists && companyID != nil && companyID.(*int) != nil && *companyID.(*int) == targetCompanyID
+}
+
+// loadCompanyForAdmin 根据路由参数 id 加载公司并检查当前管理员是否可以操作
+func loadCompanyForAdmin(c *gin.Context) (*models.Company, bool) {
+	var company models.Company
+	if err := config.DB.First(&company, c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Company not found"})
+		return nil, false
+	}
+	if !canAccessCompany(c, company.ID) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Permission denied"})
+		return nil, false
+	}
+	return &company, true
+}
@@ -0,0 +1,143 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strconv"
+	"time"
+
+	"lottery-system/archive"
+	"lottery-system/config"
+	"lottery-system/models"
+	"lottery-system/storage"
+	"lottery-system/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// maxArchiveBytes 导入归档文件大小上限
+const maxArchiveBytes = 200 << 20
+
+// archiveOptions 归档文件处理选项（Logo 和奖品图片使用本系统存储）
+func archiveOptions() archive.Options {
+	return archive.Options{Store: storage.Default(), AssetPrefix: uploadURLPrefix}
+}
+
+// ExportCompanyArchive 导出公司备份归档（zip，可导入到本实例或其他实例）
+func ExportCompanyArchive(c *gin.Context) {
+	var company models.Company
+	if err := config.DB.First(&company, c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Company not found"})
+		return
+	}
+	if !canAccessCompany(c, company.ID) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Permission denied"})
+		return
+	}
+
+	// 先写入临时文件，生成失败时仍可返回错误信息
+	tmp, err := os.CreateTemp("", "company-archive-*.zip")
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "创建临时文件失败"})
+		return
+	}
+	defer os.Remove(tmp.Name())
+	defer tmp.Close()
+
+	manifest, err := archive.Export(c.Request.Context(), config.DB, company.ID, tmp, archiveOptions())
+	if err != nil {
+		utils.WithFields(map[string]interface{}{"error": err, "company_id": company.ID}).Error("导出公司归档失败")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "导出公司归档失败"})
+		return
+	}
+	size, err := tmp.Seek(0, io.SeekCurrent)
+	if err == nil {
+		_, err = tmp.Seek(0, io.SeekStart)
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "读取归档文件失败"})
+		return
+	}
+
+	resourceID := uint(company.ID)
+	LogOperation(c, "export_archive", "company", &resourceID,
+		fmt.Sprintf("导出公司归档: %s (代码: %s)，参与者 %d 人，中奖记录 %d 条",
+			company.Name, company.Code, manifest.Counts["users"], manifest.Counts["draw_records"]))
+
+	filename := fmt.Sprintf("company_%s_%s.zip", company.Code, time.Now().Format("20060102"))
+	c.Header("Content-Type", "application/zip")
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+	c.Header("Cache-Control", "private, no-store")
+	c.Header("Content-Length", strconv.FormatInt(size, 10))
+	c.Status(http.StatusOK)
+	io.Copy(c.Writer, tmp)
+}
+
+// ImportCompanyArchive 从归档导入为新公司（超级管理员）
+// 表单参数：file 归档文件、code 新公司代码（可选，默认使用归档中的代码）、on_conflict 代码冲突处理方式（fail/rename）
+func ImportCompanyArchive(c *gin.Context) {
+	isSuperAdmin, exists := c.Get("is_super_admin")
+	if !exists || !isSuperAdmin.(bool) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "只有超级管理员可以导入公司"})
+		return
+	}
+
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxArchiveBytes+64*1024)
+	fileHeader, err := c.FormFile("file")
+	if err != nil || fileHeader.Size > maxArchiveBytes {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("请选择不超过 %dMB 的公司归档文件", maxArchiveBytes>>20)})
+		return
+	}
+
+	code := c.PostForm("code")
+	if code != "" {
+		if err := utils.ValidateCompanyCode(code); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+	onConflict := c.DefaultPostForm("on_conflict", archive.ConflictFail)
+	if onConflict != archive.ConflictFail && onConflict != archive.ConflictRename {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "on_conflict 只能是 fail 或 rename"})
+		return
+	}
+
+	file, err := fileHeader.Open()
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "读取归档文件失败"})
+		return
+	}
+	defer file.Close()
+
+	result, err := archive.Import(c.Request.Context(), config.DB, file, fileHeader.Size, archive.ImportOptions{
+		Options:    archiveOptions(),
+		Code:       code,
+		OnConflict: onConflict,
+	})
+	if err != nil {
+		switch {
+		case errors.Is(err, archive.ErrCodeConflict):
+			c.JSON(http.StatusConflict, gin.H{"error": "公司代码已存在，请指定新的公司代码或选择自动重命名", "error_code": "COMPANY_CODE_EXISTS"})
+		case errors.Is(err, archive.ErrUnsupportedVersion):
+			c.JSON(http.StatusBadRequest, gin.H{"error": "归档格式版本过新，请先升级系统", "error_code": "ARCHIVE_VERSION_UNSUPPORTED"})
+		case errors.Is(err, archive.ErrInvalidArchive):
+			c.JSON(http.StatusBadRequest, gin.H{"error": "无效的公司归档文件: " + err.Error(), "error_code": "ARCHIVE_INVALID"})
+		default:
+			utils.WithFields(map[string]interface{}{"error": err}).Error("导入公司归档失败")
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "导入公司归档失败: " + err.Error()})
+		}
+		return
+	}
+
+	resourceID := uint(result.CompanyID)
+	LogOperation(c, "import_archive", "company", &resourceID,
+		fmt.Sprintf("导入公司归档: %s，参与者 %d 人，中奖记录 %d 条", result.Code, result.Counts["users"], result.Counts["draw_records"]))
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message": "导入成功",
+		"result":  result,
+	})
+}
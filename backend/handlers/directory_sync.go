@@ -0,0 +1,496 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"lottery-system/config"
+	"lottery-system/directory"
+	"lottery-system/models"
+	"lottery-system/utils"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	defaultDirectorySyncInterval = 60 // 分钟
+	minDirectorySyncInterval     = 15 // 分钟
+	maxDirectorySyncInterval     = 7 * 24 * 60
+	directorySyncTimeout         = 5 * time.Minute
+)
+
+// DirectorySyncRequest 目录同步配置请求
+type DirectorySyncRequest struct {
+	SourceType        string `json:"source_type" binding:"required"` // ldap, csv
+	Enabled           bool   `json:"enabled"`
+	IntervalMinutes   int    `json:"interval_minutes"`
+	DeactivateMissing bool   `json:"deactivate_missing"`
+
+	ServerURL     string  `json:"server_url"`
+	StartTLS      bool    `json:"start_tls"`
+	SkipTLSVerify bool    `json:"skip_tls_verify"`
+	BindDN        string  `json:"bind_dn"`
+	BindPassword  *string `json:"bind_password"` // 不传时保留原密码，传空字符串时清除
+	BaseDN        string  `json:"base_dn"`
+	Filter        string  `json:"filter"`
+
+	FileName string `json:"file_name"`
+
+	IDAttribute    string            `json:"id_attribute" binding:"required"`
+	NameAttribute  string            `json:"name_attribute" binding:"required"`
+	PhoneAttribute string            `json:"phone_attribute"`
+	FieldMapping   map[string]string `json:"field_mapping"` // 报名表单字段 key → 属性名
+}
+
+// directoryKey 获取 LDAP 绑定密码加密密钥
+func directoryKey() []byte {
+	if config.AppConfig.DirectoryKey != "" {
+		return utils.DeriveKey(config.AppConfig.DirectoryKey)
+	}
+	return utils.DeriveKey("directory:" + config.AppConfig.JWTSecret)
+}
+
+// directorySyncResponse 目录同步配置响应（不返回绑定密码）
+func directorySyncResponse(cfg *models.DirectorySync) gin.H {
+	fieldMapping := map[string]string{}
+	if cfg.FieldMapping != "" {
+		json.Unmarshal([]byte(cfg.FieldMapping), &fieldMapping)
+	}
+	var report *directory.Report
+	if cfg.LastReport != "" {
+		report = &directory.Report{}
+		if err := json.Unmarshal([]byte(cfg.LastReport), report); err != nil {
+			report = nil
+		}
+	}
+	return gin.H{
+		"config":            cfg,
+		"has_bind_password": cfg.BindPassword != "",
+		"field_mapping":     fieldMapping,
+		"last_report":       report,
+	}
+}
+
+// GetDirectorySync 获取公司目录同步配置和最近一次同步报告
+func GetDirectorySync(c *gin.Context) {
+	company, ok := loadCompanyForAdmin(c)
+	if !ok {
+		return
+	}
+
+	var cfg models.DirectorySync
+	if err := config.DB.Where("company_id = ?", company.ID).First(&cfg).Error; err != nil {
+		c.JSON(http.StatusOK, gin.H{"config": nil})
+		return
+	}
+	c.JSON(http.StatusOK, directorySyncResponse(&cfg))
+}
+
+// UpdateDirectorySync 创建或更新公司目录同步配置（超级管理员，配置中包含外部系统地址和凭据）
+func UpdateDirectorySync(c *gin.Context) {
+	isSuperAdmin, exists := c.Get("is_super_admin")
+	if !exists || !isSuperAdmin.(bool) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "只有超级管理员可以配置目录同步"})
+		return
+	}
+	company, ok := loadCompanyForAdmin(c)
+	if !ok {
+		return
+	}
+
+	var req DirectorySyncRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var cfg models.DirectorySync
+	isNew := config.DB.Where("company_id = ?", company.ID).First(&cfg).Error != nil
+	if isNew {
+		cfg = models.DirectorySync{CompanyID: company.ID}
+	}
+
+	if req.IntervalMinutes == 0 {
+		req.IntervalMinutes = defaultDirectorySyncInterval
+	}
+	if req.IntervalMinutes < minDirectorySyncInterval || req.IntervalMinutes > maxDirectorySyncInterval {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("同步间隔应在 %d 分钟到 7 天之间", minDirectorySyncInterval)})
+		return
+	}
+
+	mapping := directory.Mapping{
+		ID:     strings.TrimSpace(req.IDAttribute),
+		Name:   strings.TrimSpace(req.NameAttribute),
+		Phone:  strings.TrimSpace(req.PhoneAttribute),
+		Fields: req.FieldMapping,
+	}
+	if err := mapping.Validate(); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "属性映射不完整: " + err.Error()})
+		return
+	}
+	if len(mapping.Fields) > 0 {
+		var keys []string
+		config.DB.Model(&models.RegistrationField{}).Where("company_id = ?", company.ID).Pluck("key", &keys)
+		defined := map[string]bool{}
+		for _, key := range keys {
+			defined[key] = true
+		}
+		for key := range mapping.Fields {
+			if !defined[key] {
+				c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("报名表单中没有字段 %s", key)})
+				return
+			}
+		}
+	}
+
+	bindPassword := cfg.BindPassword
+	if req.BindPassword != nil {
+		bindPassword = ""
+		if *req.BindPassword != "" {
+			encrypted, err := utils.EncryptString(*req.BindPassword, directoryKey())
+			if err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "保存绑定密码失败"})
+				return
+			}
+			bindPassword = encrypted
+		}
+	}
+
+	switch req.SourceType {
+	case models.DirectorySourceLDAP:
+		if err := directory.ValidateLDAPURL(req.ServerURL); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "LDAP 服务器地址应为 ldap://主机[:端口] 或 ldaps://主机[:端口]"})
+			return
+		}
+		if strings.TrimSpace(req.BaseDN) == "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "请填写搜索起点（Base DN）"})
+			return
+		}
+		if err := directory.ValidateFilter(req.Filter); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "LDAP 过滤器格式错误: " + err.Error()})
+			return
+		}
+		if req.BindDN != "" && bindPassword == "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "填写绑定 DN 时需提供绑定密码"})
+			return
+		}
+		if req.StartTLS && strings.HasPrefix(req.ServerURL, "ldaps://") {
+			req.StartTLS = false
+		}
+	case models.DirectorySourceFile:
+		if req.FileName != "" {
+			ext := strings.ToLower(filepath.Ext(req.FileName))
+			if filepath.Base(req.FileName) != req.FileName || strings.HasPrefix(req.FileName, ".") || (ext != ".csv" && ext != ".xlsx") {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "文件名应为 .csv 或 .xlsx 文件，不能包含目录"})
+				return
+			}
+		}
+		req.ServerURL, req.BindDN, req.BaseDN, req.Filter = "", "", "", ""
+		req.StartTLS, req.SkipTLSVerify = false, false
+		bindPassword = ""
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "数据源只能是 ldap 或 csv"})
+		return
+	}
+
+	fieldMapping := ""
+	if len(mapping.Fields) > 0 {
+		data, _ := json.Marshal(mapping.Fields)
+		fieldMapping = string(data)
+	}
+
+	cfg.SourceType = req.SourceType
+	cfg.Enabled = req.Enabled
+	cfg.IntervalMinutes = req.IntervalMinutes
+	cfg.DeactivateMissing = req.DeactivateMissing
+	cfg.ServerURL = strings.TrimSpace(req.ServerURL)
+	cfg.StartTLS = req.StartTLS
+	cfg.SkipTLSVerify = req.SkipTLSVerify
+	cfg.BindDN = strings.TrimSpace(req.BindDN)
+	cfg.BindPassword = bindPassword
+	cfg.BaseDN = strings.TrimSpace(req.BaseDN)
+	cfg.Filter = strings.TrimSpace(req.Filter)
+	cfg.FileName = req.FileName
+	cfg.IDAttribute = mapping.ID
+	cfg.NameAttribute = mapping.Name
+	cfg.PhoneAttribute = mapping.Phone
+	cfg.FieldMapping = fieldMapping
+	// 配置变化后下次定时同步不跳过
+	cfg.LastDigest = ""
+	cfg.NextRunAt = nil
+	if cfg.Enabled {
+		now := time.Now()
+		cfg.NextRunAt = &now
+	}
+
+	if err := config.DB.Save(&cfg).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "保存目录同步配置失败"})
+		return
+	}
+
+	resourceID := uint(company.ID)
+	LogOperation(c, "update_directory_sync", "company", &resourceID,
+		fmt.Sprintf("更新目录同步配置: %s（数据源 %s，定时同步 %v）", company.Name, cfg.SourceType, cfg.Enabled))
+
+	c.JSON(http.StatusOK, directorySyncResponse(&cfg))
+}
+
+// DeleteDirectorySync 删除公司目录同步配置（已同步的用户保留）
+func DeleteDirectorySync(c *gin.Context) {
+	isSuperAdmin, exists := c.Get("is_super_admin")
+	if !exists || !isSuperAdmin.(bool) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "只有超级管理员可以配置目录同步"})
+		return
+	}
+	company, ok := loadCompanyForAdmin(c)
+	if !ok {
+		return
+	}
+
+	result := config.DB.Where("company_id = ?", company.ID).Delete(&models.DirectorySync{})
+	if result.Error != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "删除目录同步配置失败"})
+		return
+	}
+	if result.RowsAffected == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "未配置目录同步"})
+		return
+	}
+
+	resourceID := uint(company.ID)
+	LogOperation(c, "delete_directory_sync", "company", &resourceID, fmt.Sprintf("删除目录同步配置: %s", company.Name))
+	c.JSON(http.StatusOK, gin.H{"message": "目录同步配置已删除"})
+}
+
+// RunDirectorySync 立即执行目录同步
+// 查询参数：dry_run=true 只返回同步报告不修改数据；force=true 停用人数超过一半时仍继续
+func RunDirectorySync(c *gin.Context) {
+	company, ok := loadCompanyForAdmin(c)
+	if !ok {
+		return
+	}
+
+	var cfg models.DirectorySync
+	if err := config.DB.Where("company_id = ?", company.ID).First(&cfg).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "未配置目录同步", "error_code": "DIRECTORY_SYNC_NOT_CONFIGURED"})
+		return
+	}
+
+	opts := directory.SyncOptions{
+		DryRun:            c.Query("dry_run") == "true",
+		DeactivateMissing: cfg.DeactivateMissing,
+		Force:             c.Query("force") == "true",
+	}
+	report, digest, err := executeDirectorySync(c.Request.Context(), &cfg, company, opts, false)
+	if !opts.DryRun {
+		recordDirectorySyncResult(&cfg, report, digest, err)
+	}
+	if err != nil {
+		respondDirectorySyncError(c, report, err)
+		return
+	}
+
+	if !opts.DryRun {
+		resourceID := uint(company.ID)
+		LogOperation(c, "directory_sync", "company", &resourceID, report.Summary())
+		if report.Created > 0 {
+			broadcastParticipantRegistered(company.ID, nil, report.Created)
+		}
+	}
+	c.JSON(http.StatusOK, gin.H{"report": report})
+}
+
+// respondDirectorySyncError 返回目录同步错误
+func respondDirectorySyncError(c *gin.Context, report *directory.Report, err error) {
+	switch {
+	case errors.Is(err, directory.ErrTooManyDeactivations):
+		c.JSON(http.StatusConflict, gin.H{
+			"error":      fmt.Sprintf("将停用 %d 个用户，超过已同步用户的一半，请检查数据源后使用 force=true 确认", report.Deactivated),
+			"error_code": "DIRECTORY_SYNC_TOO_MANY_DEACTIVATIONS",
+			"report":     report,
+		})
+	case errors.Is(err, directory.ErrEmptySource):
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": "数据源中没有人员，未执行同步", "error_code": "DIRECTORY_SOURCE_EMPTY"})
+	case errors.Is(err, directory.ErrNoSourceFile):
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": "投放目录中没有可读取的名单文件", "error_code": "DIRECTORY_SOURCE_FILE_MISSING"})
+	case errors.As(err, new(*directorySourceError)):
+		// 连接、认证、文件格式等错误
+		c.JSON(http.StatusBadGateway, gin.H{"error": "读取目录数据源失败: " + err.Error(), "error_code": "DIRECTORY_SOURCE_ERROR"})
+	default:
+		utils.WithFields(map[string]interface{}{"error": err}).Error("目录同步失败")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "目录同步失败"})
+	}
+}
+
+// directorySource 根据配置创建数据源
+func directorySource(cfg *models.DirectorySync, company *models.Company) (directory.Source, error) {
+	mapping := directory.Mapping{ID: cfg.IDAttribute, Name: cfg.NameAttribute, Phone: cfg.PhoneAttribute}
+	if cfg.FieldMapping != "" {
+		if err := json.Unmarshal([]byte(cfg.FieldMapping), &mapping.Fields); err != nil {
+			return nil, err
+		}
+	}
+
+	switch cfg.SourceType {
+	case models.DirectorySourceLDAP:
+		password := ""
+		if cfg.BindPassword != "" {
+			var err error
+			if password, err = utils.DecryptString(cfg.BindPassword, directoryKey()); err != nil {
+				return nil, errors.New("无法解密绑定密码，请重新设置")
+			}
+		}
+		return &directory.LDAPSource{
+			Config: directory.LDAPConfig{
+				URL:           cfg.ServerURL,
+				StartTLS:      cfg.StartTLS,
+				SkipTLSVerify: cfg.SkipTLSVerify,
+				BindDN:        cfg.BindDN,
+				BindPassword:  password,
+				BaseDN:        cfg.BaseDN,
+				Filter:        cfg.Filter,
+			},
+			Mapping: mapping,
+		}, nil
+	case models.DirectorySourceFile:
+		return &directory.FileSource{
+			Dir:     filepath.Join(config.AppConfig.DirectoryDropDir, company.Code),
+			File:    cfg.FileName,
+			Mapping: mapping,
+		}, nil
+	}
+	return nil, fmt.Errorf("unknown directory source %q", cfg.SourceType)
+}
+
+// directorySourceError 读取目录数据源失败
+type directorySourceError struct {
+	err error
+}
+
+func (e *directorySourceError) Error() string { return e.err.Error() }
+func (e *directorySourceError) Unwrap() error { return e.err }
+
+// executeDirectorySync 读取数据源并同步（锁定公司，与导入等操作互斥），返回同步报告和数据源内容摘要
+// skipUnchanged 为 true 时数据源内容与上次成功同步相同则跳过，返回的报告为 nil
+func executeDirectorySync(ctx context.Context, cfg *models.DirectorySync, company *models.Company, opts directory.SyncOptions, skipUnchanged bool) (*directory.Report, string, error) {
+	ctx, cancel := context.WithTimeout(ctx, directorySyncTimeout)
+	defer cancel()
+
+	source, err := directorySource(cfg, company)
+	if err != nil {
+		return nil, "", &directorySourceError{err}
+	}
+	snapshot, err := source.Fetch(ctx)
+	if err != nil {
+		return nil, "", &directorySourceError{err}
+	}
+	if skipUnchanged && snapshot.Digest == cfg.LastDigest {
+		return nil, snapshot.Digest, nil
+	}
+
+	var report *directory.Report
+	err = config.DB.Transaction(func(tx *gorm.DB) error {
+		var locked models.Company
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&locked, company.ID).Error; err != nil {
+			return err
+		}
+		var syncErr error
+		report, syncErr = directory.Sync(ctx, tx, company.ID, snapshot, source.Name(), opts)
+		return syncErr
+	})
+	return report, snapshot.Digest, err
+}
+
+// recordDirectorySyncResult 保存同步结果（digest 为空时不更新上次同步的内容摘要）
+func recordDirectorySyncResult(cfg *models.DirectorySync, report *directory.Report, digest string, err error) {
+	now := time.Now()
+	updates := map[string]interface{}{"last_run_at": now}
+	switch {
+	case err != nil:
+		message := err.Error()
+		if len(message) > 500 {
+			message = message[:500]
+		}
+		updates["last_status"] = models.DirectorySyncFailed
+		updates["last_error"] = message
+	case report == nil:
+		updates["last_status"] = models.DirectorySyncSkipped
+		updates["last_error"] = ""
+	default:
+		updates["last_status"] = models.DirectorySyncSuccess
+		updates["last_error"] = ""
+		if digest != "" {
+			updates["last_digest"] = digest
+		}
+	}
+	if report != nil {
+		if data, err := json.Marshal(report); err == nil {
+			updates["last_report"] = string(data)
+		}
+	}
+	config.DB.Model(&models.DirectorySync{}).Where("id = ?", cfg.ID).Updates(updates)
+}
+
+// RunDirectorySyncWorker 定时执行已启用的目录同步（在独立 goroutine 中运行）
+// 多实例部署时通过条件更新下次执行时间认领任务，同一配置只会被一个实例执行
+func RunDirectorySyncWorker(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		var due []models.DirectorySync
+		if err := config.DB.Where("enabled = ? AND next_run_at <= ?", true, time.Now()).Find(&due).Error; err != nil {
+			utils.WithFields(map[string]interface{}{"error": err}).Error("查询待执行的目录同步失败")
+			continue
+		}
+		for i := range due {
+			runScheduledDirectorySync(&due[i])
+		}
+	}
+}
+
+// runScheduledDirectorySync 认领并执行一次定时同步
+func runScheduledDirectorySync(cfg *models.DirectorySync) {
+	now := time.Now()
+	claim := config.DB.Model(&models.DirectorySync{}).
+		Where("id = ? AND enabled = ? AND next_run_at <= ?", cfg.ID, true, now).
+		Update("next_run_at", now.Add(time.Duration(cfg.IntervalMinutes)*time.Minute))
+	if claim.Error != nil || claim.RowsAffected == 0 {
+		return
+	}
+
+	var company models.Company
+	if err := config.DB.First(&company, cfg.CompanyID).Error; err != nil {
+		return
+	}
+
+	opts := directory.SyncOptions{DeactivateMissing: cfg.DeactivateMissing}
+	report, digest, err := executeDirectorySync(context.Background(), cfg, &company, opts, true)
+	recordDirectorySyncResult(cfg, report, digest, err)
+
+	resourceID := uint(company.ID)
+	if err != nil {
+		utils.WithFields(map[string]interface{}{"error": err, "company_id": company.ID}).Error("定时目录同步失败")
+		details := "定时目录同步失败: " + err.Error()
+		if report != nil {
+			details = report.Summary() + "，未执行: " + err.Error()
+		}
+		LogSystemOperation(company.ID, "directory_sync", "company", &resourceID, details)
+		return
+	}
+	if report == nil {
+		return // 数据源内容未变化
+	}
+	LogSystemOperation(company.ID, "directory_sync", "company", &resourceID, "定时"+report.Summary())
+	if report.Created > 0 {
+		broadcastParticipantRegistered(company.ID, nil, report.Created)
+	}
+}
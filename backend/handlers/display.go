@@ -0,0 +1,389 @@
+package handlers
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"fmt"
+	"math/big"
+	"net/http"
+	"time"
+
+	"lottery-system/config"
+	"lottery-system/models"
+	"lottery-system/realtime"
+	"lottery-system/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// 大屏会话限制
+const (
+	displayPairingTTL      = 10 * time.Minute // 配对码有效期
+	maxWaitingDisplays     = 20               // 每个公司同时等待配对的大屏数量上限
+	displayPairingCodeSize = 6
+)
+
+// RegisterDisplayRequest 大屏注册请求
+type RegisterDisplayRequest struct {
+	Name string `json:"name"`
+}
+
+// PairDisplayRequest 遥控端配对请求
+type PairDisplayRequest struct {
+	PairingCode string `json:"pairing_code" binding:"required"`
+}
+
+// DisplayCommandRequest 遥控指令请求
+type DisplayCommandRequest struct {
+	Command       string `json:"command" binding:"required"` // start_spin, stop, reveal, next_level
+	LevelID       int    `json:"level_id"`                   // next_level/start_spin 时指定奖项
+	DrawRecordIDs []int  `json:"draw_record_ids"`            // reveal 时指定要公布的中奖记录
+}
+
+// RegisterDisplay 大屏注册展示会话（公开接口），返回配对码和大屏访问凭证
+func RegisterDisplay(c *gin.Context) {
+	company, err := getCompanyByCode(c.Query("company_code"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid company code"})
+		return
+	}
+
+	var req RegisterDisplayRequest
+	// 请求体可选
+	_ = c.ShouldBindJSON(&req)
+	if len([]rune(req.Name)) > 100 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "大屏名称不能超过100个字符"})
+		return
+	}
+
+	now := time.Now()
+	var waiting int64
+	config.DB.Model(&models.DisplaySession{}).
+		Where("company_id = ? AND status = ? AND pairing_expires_at > ?", company.ID, models.DisplayStatusWaiting, now).
+		Count(&waiting)
+	if waiting >= maxWaitingDisplays {
+		c.JSON(http.StatusTooManyRequests, gin.H{"error": "等待配对的大屏过多，请稍后再试"})
+		return
+	}
+
+	code, err := newDisplayPairingCode(now)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "生成配对码失败"})
+		return
+	}
+	token, err := utils.RandomHex(32)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "生成访问凭证失败"})
+		return
+	}
+
+	session := models.DisplaySession{
+		CompanyID:        company.ID,
+		Name:             req.Name,
+		PairingCode:      code,
+		TokenHash:        utils.HashString(token),
+		Status:           models.DisplayStatusWaiting,
+		PairingExpiresAt: now.Add(displayPairingTTL),
+	}
+	if err := config.DB.Create(&session).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "创建大屏会话失败"})
+		return
+	}
+
+	utils.WithFields(map[string]interface{}{
+		"display_id": session.ID,
+		"company_id": company.ID,
+		"ip":         c.ClientIP(),
+	}).Info("大屏注册展示会话")
+
+	// 配对前大屏只能接收配对和关闭事件，不能订阅公司抽奖事件
+	c.JSON(http.StatusCreated, gin.H{
+		"display_id":         session.ID,
+		"status":             session.Status,
+		"pairing_code":       code,
+		"display_token":      token,
+		"pairing_expires_at": session.PairingExpiresAt,
+	})
+}
+
+// DisplayStream 大屏事件流（SSE）：推送公司抽奖事件以及发给该大屏的遥控指令
+// 使用注册时返回的 display_token 认证；未配对的大屏只推送本会话的配对和关闭事件，
+// 配对后结束事件流，大屏重新连接后才能订阅公司抽奖事件
+func DisplayStream(c *gin.Context) {
+	session, ok := loadDisplayForScreen(c)
+	if !ok {
+		return
+	}
+
+	now := time.Now()
+	config.DB.Model(session).Update("last_seen_at", now)
+
+	hello := gin.H{
+		"company_id": session.CompanyID,
+		"display_id": session.ID,
+		"status":     session.Status,
+	}
+	isSessionEvent := func(event realtime.Event) bool {
+		return event.DisplayID == session.ID &&
+			(event.Type == realtime.EventDisplayPaired || event.Type == realtime.EventDisplayClosed)
+	}
+
+	if session.Status != models.DisplayStatusPaired {
+		streamLiveEvents(c, session.CompanyID, hello, isSessionEvent, isSessionEvent)
+		return
+	}
+
+	streamLiveEvents(c, session.CompanyID, hello, func(event realtime.Event) bool {
+		return event.DisplayID == 0 || event.DisplayID == session.ID
+	}, func(event realtime.Event) bool {
+		return event.DisplayID == session.ID && event.Type == realtime.EventDisplayClosed
+	})
+}
+
+// GetDisplayStatus 大屏查询会话状态（用于断线重连后判断是否需要重新配对）
+func GetDisplayStatus(c *gin.Context) {
+	session, ok := loadDisplayForScreen(c)
+	if !ok {
+		return
+	}
+	c.JSON(http.StatusOK, session)
+}
+
+// PairDisplay 管理员通过配对码将自己绑定为大屏的遥控端（只能配对本公司的大屏）
+func PairDisplay(c *gin.Context) {
+	var req PairDisplayRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "请输入配对码"})
+		return
+	}
+
+	var session models.DisplaySession
+	err := config.DB.Where("pairing_code = ? AND status = ? AND pairing_expires_at > ?",
+		req.PairingCode, models.DisplayStatusWaiting, time.Now()).
+		First(&session).Error
+	// 其他公司的大屏与无效配对码返回相同错误，避免探测配对码
+	if err != nil || !canAccessCompany(c, session.CompanyID) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "配对码无效或已过期"})
+		return
+	}
+
+	adminID := currentAdminID(c)
+	now := time.Now()
+	result := config.DB.Model(&models.DisplaySession{}).
+		Where("id = ? AND status = ?", session.ID, models.DisplayStatusWaiting).
+		Updates(map[string]interface{}{
+			"status":              models.DisplayStatusPaired,
+			"controller_admin_id": adminID,
+			"paired_at":           now,
+		})
+	if result.Error != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "配对失败，请稍后重试"})
+		return
+	}
+	if result.RowsAffected == 0 {
+		c.JSON(http.StatusConflict, gin.H{"error": "该大屏已被其他遥控端配对"})
+		return
+	}
+
+	username, _ := c.Get("username")
+	publishDisplayEvent(&session, realtime.EventDisplayPaired, gin.H{
+		"controller_admin_id": adminID,
+		"controller":          username,
+	})
+
+	resourceID := uint(session.ID)
+	LogOperation(c, "pair", "display", &resourceID, fmt.Sprintf("配对大屏: %s (ID %d)", session.Name, session.ID))
+
+	config.DB.First(&session, session.ID)
+	c.JSON(http.StatusOK, session)
+}
+
+// GetDisplays 获取公司的大屏会话列表（权限隔离）
+func GetDisplays(c *gin.Context) {
+	companyID, ok := getScopedCompanyID(c, c.Query("company_id"))
+	if !ok {
+		return
+	}
+
+	var sessions []models.DisplaySession
+	config.DB.Where("company_id = ? AND status <> ?", companyID, models.DisplayStatusClosed).
+		Order("id DESC").
+		Find(&sessions)
+
+	c.JSON(http.StatusOK, sessions)
+}
+
+// SendDisplayCommand 向已配对的大屏发送遥控指令（只有配对的遥控端或超级管理员可以发送）
+func SendDisplayCommand(c *gin.Context) {
+	session, ok := loadDisplayForController(c)
+	if !ok {
+		return
+	}
+
+	var req DisplayCommandRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "请求参数格式错误"})
+		return
+	}
+	if !models.DisplayCommandIsValid(req.Command) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的遥控指令"})
+		return
+	}
+
+	data := gin.H{"command": req.Command}
+
+	if req.LevelID > 0 {
+		var level models.PrizeLevel
+		if err := config.DB.Where("id = ? AND company_id = ?", req.LevelID, session.CompanyID).First(&level).Error; err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "奖项不存在"})
+			return
+		}
+		data["level_id"] = level.ID
+		data["level_name"] = level.Name
+	} else if req.Command == models.DisplayCommandNextLevel {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "切换奖项需要指定 level_id"})
+		return
+	}
+
+	if len(req.DrawRecordIDs) > 0 {
+		var count int64
+		config.DB.Model(&models.DrawRecord{}).
+			Where("id IN ? AND company_id = ?", req.DrawRecordIDs, session.CompanyID).
+			Count(&count)
+		if int(count) != len(req.DrawRecordIDs) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "中奖记录不存在"})
+			return
+		}
+		data["draw_record_ids"] = req.DrawRecordIDs
+	}
+
+	publishDisplayEvent(session, realtime.EventDisplayCommand, data)
+
+	c.JSON(http.StatusAccepted, gin.H{"display_id": session.ID, "command": req.Command})
+}
+
+// CloseDisplay 关闭大屏会话（配对的遥控端或本公司管理员）
+func CloseDisplay(c *gin.Context) {
+	var session models.DisplaySession
+	if err := config.DB.First(&session, c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "大屏会话不存在"})
+		return
+	}
+	if !canAccessCompany(c, session.CompanyID) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Permission denied"})
+		return
+	}
+
+	if err := config.DB.Model(&session).Update("status", models.DisplayStatusClosed).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "操作失败，请稍后重试"})
+		return
+	}
+
+	publishDisplayEvent(&session, realtime.EventDisplayClosed, gin.H{})
+
+	resourceID := uint(session.ID)
+	LogOperation(c, "close", "display", &resourceID, fmt.Sprintf("关闭大屏会话: %s (ID %d)", session.Name, session.ID))
+
+	c.JSON(http.StatusOK, gin.H{"message": "Display session closed"})
+}
+
+// loadDisplayForScreen 根据路由参数和 token 参数加载大屏会话（大屏端认证）
+func loadDisplayForScreen(c *gin.Context) (*models.DisplaySession, bool) {
+	var session models.DisplaySession
+	if err := config.DB.First(&session, c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "大屏会话不存在"})
+		return nil, false
+	}
+
+	token := c.Query("token")
+	if token == "" || !hmac.Equal([]byte(utils.HashString(token)), []byte(session.TokenHash)) {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "无效的大屏凭证"})
+		return nil, false
+	}
+
+	if session.Status == models.DisplayStatusClosed {
+		c.JSON(http.StatusGone, gin.H{"error": "大屏会话已关闭，请重新注册"})
+		return nil, false
+	}
+	// 配对码过期仍未配对的会话不再可用
+	if session.Status == models.DisplayStatusWaiting && !session.PairingExpiresAt.After(time.Now()) {
+		c.JSON(http.StatusGone, gin.H{"error": "配对码已过期，请重新注册", "error_code": "PAIRING_EXPIRED"})
+		return nil, false
+	}
+
+	return &session, true
+}
+
+// loadDisplayForController 加载大屏会话并检查当前管理员是否为其遥控端
+func loadDisplayForController(c *gin.Context) (*models.DisplaySession, bool) {
+	var session models.DisplaySession
+	if err := config.DB.First(&session, c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "大屏会话不存在"})
+		return nil, false
+	}
+
+	if !canAccessCompany(c, session.CompanyID) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Permission denied"})
+		return nil, false
+	}
+
+	if session.Status != models.DisplayStatusPaired {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "大屏尚未配对"})
+		return nil, false
+	}
+
+	isSuperAdmin, _ := c.Get("is_super_admin")
+	if isSuperAdmin != true && (session.ControllerAdminID == nil || *session.ControllerAdminID != currentAdminID(c)) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "您不是该大屏的遥控端"})
+		return nil, false
+	}
+
+	return &session, true
+}
+
+// newDisplayPairingCode 生成未被占用的数字配对码
+func newDisplayPairingCode(now time.Time) (string, error) {
+	max := big.NewInt(1)
+	for i := 0; i < displayPairingCodeSize; i++ {
+		max.Mul(max, big.NewInt(10))
+	}
+
+	for attempt := 0; attempt < 10; attempt++ {
+		n, err := rand.Int(rand.Reader, max)
+		if err != nil {
+			return "", err
+		}
+		code := fmt.Sprintf("%0*d", displayPairingCodeSize, n.Int64())
+
+		var exists int64
+		config.DB.Model(&models.DisplaySession{}).
+			Where("pairing_code = ? AND status = ? AND pairing_expires_at > ?", code, models.DisplayStatusWaiting, now).
+			Count(&exists)
+		if exists == 0 {
+			return code, nil
+		}
+	}
+	return "", fmt.Errorf("pairing code collision")
+}
+
+// publishDisplayEvent 发布只投递给指定大屏的事件
+func publishDisplayEvent(session *models.DisplaySession, eventType string, data gin.H) {
+	data["display_id"] = session.ID
+	if err := realtime.PublishToDisplay(session.CompanyID, session.ID, eventType, data); err != nil {
+		utils.WithFields(map[string]interface{}{
+			"error":      err,
+			"display_id": session.ID,
+			"event":      eventType,
+		}).Warn("大屏事件发布失败")
+	}
+}
+
+// currentAdminID 获取当前管理员ID
+func currentAdminID(c *gin.Context) int {
+	if v, exists := c.Get("user_id"); exists {
+		if id, ok := v.(int); ok {
+			return id
+		}
+	}
+	return 0
+}
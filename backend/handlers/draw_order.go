@@ -0,0 +1,168 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+
+	"lottery-system/config"
+	"lottery-system/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// drawGateError 抽奖顺序/奖项状态检查失败
+type drawGateError struct {
+	Code    string
+	Message string
+}
+
+func (e *drawGateError) Error() string {
+	return e.Message
+}
+
+// OpenPrizeLevel 开放奖项抽奖（权限检查）
+func OpenPrizeLevel(c *gin.Context) {
+	setPrizeLevelDrawState(c, models.LevelDrawStateOpen)
+}
+
+// ClosePrizeLevel 关闭奖项抽奖（权限检查），关闭后按顺序抽奖时视为已完成
+func ClosePrizeLevel(c *gin.Context) {
+	setPrizeLevelDrawState(c, models.LevelDrawStateClosed)
+}
+
+// setPrizeLevelDrawState 更新奖项的抽奖状态
+func setPrizeLevelDrawState(c *gin.Context, state string) {
+	id := c.Param("id")
+
+	var level models.PrizeLevel
+	if err := config.DB.First(&level, id).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "奖项不存在"})
+		return
+	}
+
+	if !canAccessCompany(c, level.CompanyID) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Permission denied"})
+		return
+	}
+
+	if err := config.DB.Model(&level).Update("draw_state", state).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "操作失败，请稍后重试"})
+		return
+	}
+
+	action := "open"
+	details := fmt.Sprintf("开放奖项抽奖: %s", level.Name)
+	if state == models.LevelDrawStateClosed {
+		action = "close"
+		details = fmt.Sprintf("关闭奖项抽奖: %s", level.Name)
+	}
+	resourceID := uint(level.ID)
+	LogOperation(c, action, "prize_level", &resourceID, details)
+
+	c.JSON(http.StatusOK, level)
+}
+
+// levelRemainingStock 计算奖项等级下所有奖品的剩余库存
+func levelRemainingStock(levelID int) int {
+	var stock struct {
+		TotalStock int
+		UsedStock  int
+	}
+	config.DB.Model(&models.Prize{}).
+		Where("level_id = ?", levelID).
+		Select("COALESCE(SUM(total_stock), 0) as total_stock, COALESCE(SUM(used_stock), 0) as used_stock").
+		Scan(&stock)
+	return stock.TotalStock - stock.UsedStock
+}
+
+// levelDrawsBefore 判断按公司的抽奖顺序，奖项 a 是否应在奖项 b 之前抽取
+func levelDrawsBefore(order string, a, b models.PrizeLevel) bool {
+	switch order {
+	case models.DrawOrderDesc:
+		return a.SortOrder > b.SortOrder
+	case models.DrawOrderAsc:
+		return a.SortOrder < b.SortOrder
+	default:
+		return false
+	}
+}
+
+// pendingDrawLevels 获取公司中仍可抽奖（启用、开放且有库存）的奖项，按抽奖顺序排列
+func pendingDrawLevels(company *models.Company) ([]models.PrizeLevel, error) {
+	var levels []models.PrizeLevel
+	if err := config.DB.Where("company_id = ? AND is_active = ? AND draw_state = ?",
+		company.ID, true, models.LevelDrawStateOpen).
+		Order("sort_order ASC, id ASC").
+		Find(&levels).Error; err != nil {
+		return nil, err
+	}
+
+	pending := make([]models.PrizeLevel, 0, len(levels))
+	for _, level := range levels {
+		if levelRemainingStock(level.ID) > 0 {
+			pending = append(pending, level)
+		}
+	}
+
+	sort.SliceStable(pending, func(i, j int) bool {
+		return levelDrawsBefore(company.DrawOrder, pending[i], pending[j])
+	})
+	return pending, nil
+}
+
+// currentDrawLevel 获取按抽奖顺序当前应抽取的奖项
+func currentDrawLevel(company *models.Company) (*models.PrizeLevel, error) {
+	pending, err := pendingDrawLevels(company)
+	if err != nil {
+		return nil, err
+	}
+	if len(pending) == 0 {
+		return nil, &drawGateError{Code: "NO_PRIZES_AVAILABLE", Message: "没有可用的奖品"}
+	}
+	return &pending[0], nil
+}
+
+// checkLevelDrawable 检查奖项是否开放以及是否满足公司的抽奖顺序
+func checkLevelDrawable(company *models.Company, level *models.PrizeLevel) error {
+	if level.DrawState == models.LevelDrawStateClosed {
+		return &drawGateError{
+			Code:    "LEVEL_CLOSED",
+			Message: fmt.Sprintf("奖项「%s」已关闭抽奖", level.Name),
+		}
+	}
+
+	if !models.DrawOrderEnforced(company.DrawOrder) {
+		return nil
+	}
+
+	pending, err := pendingDrawLevels(company)
+	if err != nil {
+		return err
+	}
+	for _, other := range pending {
+		if other.ID != level.ID && levelDrawsBefore(company.DrawOrder, other, *level) {
+			return &drawGateError{
+				Code:    "DRAW_ORDER_VIOLATION",
+				Message: fmt.Sprintf("请先抽完或关闭「%s」，再抽取「%s」", other.Name, level.Name),
+			}
+		}
+	}
+	return nil
+}
+
+// levelBlocker 返回阻塞该奖项抽奖的奖项名称（用于前端展示），为空表示可抽
+func levelBlocker(company *models.Company, pending []models.PrizeLevel, level models.PrizeLevel) string {
+	if level.DrawState == models.LevelDrawStateClosed {
+		return level.Name
+	}
+	if !models.DrawOrderEnforced(company.DrawOrder) {
+		return ""
+	}
+	for _, other := range pending {
+		if other.ID != level.ID && levelDrawsBefore(company.DrawOrder, other, level) {
+			return other.Name
+		}
+	}
+	return ""
+}
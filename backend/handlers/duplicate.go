@@ -0,0 +1,343 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"lottery-system/config"
+	"lottery-system/models"
+	"lottery-system/namematch"
+	"lottery-system/utils"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// maxDuplicatePairs 疑似重复列表最多返回的对数
+const maxDuplicatePairs = 500
+
+// likelyDuplicateScore 报名时视为同一人的得分（手机号相同且姓名相同/同音/相近）
+const likelyDuplicateScore = 85
+
+// DuplicatePair 疑似重复的参与者对
+type DuplicatePair struct {
+	UserA     models.User `json:"user_a"`
+	UserB     models.User `json:"user_b"`
+	Score     int         `json:"score"`   // 0-100
+	Reasons   []string    `json:"reasons"` // same_phone, same_name, same_pinyin, similar_name
+	Dismissed bool        `json:"dismissed,omitempty"`
+}
+
+// DuplicatePairRequest 标记不是重复请求
+type DuplicatePairRequest struct {
+	UserAID int `json:"user_a_id" binding:"required"`
+	UserBID int `json:"user_b_id" binding:"required"`
+}
+
+// MergeUsersRequest 合并重复参与者请求
+type MergeUsersRequest struct {
+	KeepUserID  int `json:"keep_user_id" binding:"required"`  // 保留的用户
+	MergeUserID int `json:"merge_user_id" binding:"required"` // 合并后删除的用户
+}
+
+// errMergeConflict 合并的两个用户不满足条件
+var errMergeConflict = errors.New("users cannot be merged")
+
+// GetDuplicateCandidates 获取疑似重复的参与者对（权限检查）
+// 按规范化手机号、繁简/全半角统一后的姓名、姓名拼音和编辑距离比对，已标记不是重复的默认不返回
+func GetDuplicateCandidates(c *gin.Context) {
+	companyID, ok := getScopedCompanyID(c, c.Query("company_id"))
+	if !ok {
+		return
+	}
+
+	minScore := namematch.DefaultMinScore
+	if s := c.Query("min_score"); s != "" {
+		v, err := strconv.Atoi(s)
+		if err != nil || v < 1 || v > 100 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "min_score 必须是 1-100 的整数"})
+			return
+		}
+		minScore = v
+	}
+	includeDismissed := c.Query("include_dismissed") == "true"
+
+	var users []models.User
+	if err := config.DB.Select("id", "name", "phone").Where("company_id = ?", companyID).Find(&users).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取参与者失败"})
+		return
+	}
+	candidates := make([]namematch.Candidate, len(users))
+	for i, u := range users {
+		candidates[i] = namematch.Candidate{ID: u.ID, Keys: namematch.KeysOf(u.Name, u.Phone)}
+	}
+	pairs := namematch.FindPairs(candidates, minScore)
+
+	var dismissals []models.DuplicateDismissal
+	config.DB.Where("company_id = ?", companyID).Find(&dismissals)
+	dismissed := make(map[[2]int]bool, len(dismissals))
+	for _, d := range dismissals {
+		dismissed[[2]int{d.UserAID, d.UserBID}] = true
+	}
+
+	var result []DuplicatePair
+	ids := map[int]bool{}
+	total := 0
+	for _, p := range pairs {
+		isDismissed := dismissed[[2]int{p.AID, p.BID}]
+		if isDismissed && !includeDismissed {
+			continue
+		}
+		total++
+		if len(result) >= maxDuplicatePairs {
+			continue
+		}
+		result = append(result, DuplicatePair{
+			UserA:     models.User{ID: p.AID},
+			UserB:     models.User{ID: p.BID},
+			Score:     p.Score,
+			Reasons:   p.Reasons,
+			Dismissed: isDismissed,
+		})
+		ids[p.AID], ids[p.BID] = true, true
+	}
+
+	// 加载完整的用户信息（含报名表单字段）
+	userIDs := make([]int, 0, len(ids))
+	for id := range ids {
+		userIDs = append(userIDs, id)
+	}
+	var details []models.User
+	if len(userIDs) > 0 {
+		config.DB.Where("id IN ?", userIDs).Find(&details)
+		utils.AttachUserFields(config.DB, details)
+	}
+	byID := make(map[int]models.User, len(details))
+	for _, u := range details {
+		byID[u.ID] = u
+	}
+	for i := range result {
+		result[i].UserA = byID[result[i].UserA.ID]
+		result[i].UserB = byID[result[i].UserB.ID]
+	}
+	if result == nil {
+		result = []DuplicatePair{}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"pairs":     result,
+		"total":     total,
+		"truncated": total > len(result),
+		"min_score": minScore,
+	})
+}
+
+// DismissDuplicate 标记两个参与者不是重复（权限检查），之后不再出现在疑似重复列表中
+func DismissDuplicate(c *gin.Context) {
+	var req DuplicatePairRequest
+	if err := c.ShouldBindJSON(&req); err != nil || req.UserAID == req.UserBID {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "请求参数格式错误"})
+		return
+	}
+
+	a, b, ok := loadUserPair(c, req.UserAID, req.UserBID)
+	if !ok {
+		return
+	}
+	if a.ID > b.ID {
+		a, b = b, a
+	}
+
+	dismissal := models.DuplicateDismissal{
+		CompanyID:   a.CompanyID,
+		UserAID:     a.ID,
+		UserBID:     b.ID,
+		DismissedBy: currentAdminID(c),
+	}
+	if err := config.DB.Clauses(clause.OnConflict{DoNothing: true}).Create(&dismissal).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "操作失败"})
+		return
+	}
+
+	resourceID := uint(a.ID)
+	LogOperation(c, "dismiss_duplicate", "user", &resourceID,
+		fmt.Sprintf("标记不是重复: %s(#%d) / %s(#%d)", a.Name, a.ID, b.Name, b.ID))
+
+	c.JSON(http.StatusOK, gin.H{"message": "已标记为不是重复"})
+}
+
+// MergeUsers 合并重复的参与者（权限检查）
+// 被合并用户的抽奖记录、回流记录转到保留用户名下，报名表单字段只补充保留用户缺少的，然后删除被合并用户
+func MergeUsers(c *gin.Context) {
+	var req MergeUsersRequest
+	if err := c.ShouldBindJSON(&req); err != nil || req.KeepUserID == req.MergeUserID {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "请求参数格式错误"})
+		return
+	}
+
+	keep, merge, ok := loadUserPair(c, req.KeepUserID, req.MergeUserID)
+	if !ok {
+		return
+	}
+
+	var keepDraws, movedDraws int64
+	freedSlot := false
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		// 锁定两个用户，防止合并时并发抽奖
+		var locked []models.User
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("id IN ?", []int{keep.ID, merge.ID}).Order("id ASC").Find(&locked).Error; err != nil {
+			return err
+		}
+		if len(locked) != 2 {
+			return errMergeConflict
+		}
+		for _, u := range locked {
+			if u.ID == keep.ID {
+				*keep = u
+			} else {
+				*merge = u
+			}
+		}
+
+		if err := tx.Model(&models.DrawRecord{}).Where("user_id = ?", keep.ID).Count(&keepDraws).Error; err != nil {
+			return err
+		}
+		result := tx.Model(&models.DrawRecord{}).Where("user_id = ?", merge.ID).Update("user_id", keep.ID)
+		if result.Error != nil {
+			return result.Error
+		}
+		movedDraws = result.RowsAffected
+		if err := tx.Model(&models.PrizeRollover{}).Where("user_id = ?", merge.ID).Update("user_id", keep.ID).Error; err != nil {
+			return err
+		}
+
+		if err := mergeUserFieldValues(tx, keep.ID, merge.ID); err != nil {
+			return err
+		}
+
+		updates := map[string]interface{}{
+			"has_drawn":  keep.HasDrawn || merge.HasDrawn,
+			"waitlisted": keep.Waitlisted && merge.Waitlisted,
+		}
+		if keep.Phone == "" && merge.Phone != "" {
+			if err := models.SetPhoneUpdate(updates, merge.Phone); err != nil {
+				return err
+			}
+		}
+		// 签到取较早的一次
+		if merge.CheckedInAt != nil && (keep.CheckedInAt == nil || merge.CheckedInAt.Before(*keep.CheckedInAt)) {
+			updates["checked_in_at"] = merge.CheckedInAt
+			updates["check_in_method"] = merge.CheckInMethod
+			updates["checked_in_by"] = merge.CheckedInBy
+		}
+		if err := tx.Model(keep).Updates(updates).Error; err != nil {
+			return err
+		}
+
+		if err := tx.Where("user_a_id = ? OR user_b_id = ?", merge.ID, merge.ID).Delete(&models.DuplicateDismissal{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Delete(merge).Error; err != nil {
+			return err
+		}
+
+		// 两人都是正式参与者时合并后空出一个名额
+		freedSlot = !keep.Waitlisted && !merge.Waitlisted
+		return nil
+	})
+	if errors.Is(err, errMergeConflict) {
+		c.JSON(http.StatusConflict, gin.H{"error": "用户不存在或已被合并"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "合并用户失败"})
+		return
+	}
+
+	resourceID := uint(keep.ID)
+	details := fmt.Sprintf("合并重复用户: %s(#%d) 合并到 %s(#%d)", merge.Name, merge.ID, keep.Name, keep.ID)
+	if movedDraws > 0 {
+		details += fmt.Sprintf("，转移抽奖记录%d条", movedDraws)
+	}
+	LogOperation(c, "merge_user", "user", &resourceID, details)
+
+	if freedSlot {
+		promoteWaitlistAfterRemoval(keep.CompanyID)
+	}
+
+	config.DB.First(keep, keep.ID)
+	users := []models.User{*keep}
+	utils.AttachUserFields(config.DB, users)
+
+	response := gin.H{
+		"message":            "合并成功",
+		"user":               users[0],
+		"moved_draw_records": movedDraws,
+	}
+	if keepDraws > 0 && movedDraws > 0 {
+		// 两人都中过奖，合并后同一人有多条中奖记录，需要管理员处理
+		response["warning"] = "两个用户都有抽奖记录，合并后同一人有多条中奖记录，请确认是否需要作废"
+		response["warning_code"] = "MULTIPLE_WINS"
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+// loadUserPair 加载同一公司的两个用户并检查权限
+func loadUserPair(c *gin.Context, firstID, secondID int) (*models.User, *models.User, bool) {
+	var first, second models.User
+	if err := config.DB.First(&first, firstID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return nil, nil, false
+	}
+	if err := config.DB.First(&second, secondID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return nil, nil, false
+	}
+	if first.CompanyID != second.CompanyID {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "两个用户不属于同一公司"})
+		return nil, nil, false
+	}
+	if !canAccessCompany(c, first.CompanyID) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Permission denied"})
+		return nil, nil, false
+	}
+	return &first, &second, true
+}
+
+// mergeUserFieldValues 合并报名表单字段：保留用户已填写的字段不变，缺少的从被合并用户补充
+func mergeUserFieldValues(tx *gorm.DB, keepID, mergeID int) error {
+	var keepKeys []string
+	if err := tx.Model(&models.UserFieldValue{}).Where("user_id = ?", keepID).Pluck("field_key", &keepKeys).Error; err != nil {
+		return err
+	}
+
+	move := tx.Model(&models.UserFieldValue{}).Where("user_id = ?", mergeID)
+	if len(keepKeys) > 0 {
+		move = move.Where("field_key NOT IN ?", keepKeys)
+	}
+	if err := move.Update("user_id", keepID).Error; err != nil {
+		return err
+	}
+	return tx.Where("user_id = ?", mergeID).Delete(&models.UserFieldValue{}).Error
+}
+
+// findLikelyDuplicate 查找与报名信息疑似为同一人的已有参与者（手机号相同且姓名相同/同音/相近）
+func findLikelyDuplicate(companyID int, name, phone string) *models.User {
+	if phone == "" {
+		return nil
+	}
+	keys := namematch.KeysOf(name, phone)
+
+	var users []models.User
+	config.DB.Where("company_id = ? AND phone_hash = ?", companyID, models.PhoneIndex(keys.Phone)).Limit(50).Find(&users)
+	for i := range users {
+		if score, _ := namematch.Match(keys, namematch.KeysOf(users[i].Name, users[i].Phone)); score >= likelyDuplicateScore {
+			return &users[i]
+		}
+	}
+	return nil
+}
@@ -0,0 +1,552 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"lottery-system/config"
+	"lottery-system/models"
+	"lottery-system/storage"
+	"lottery-system/utils"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// 导出限制
+const (
+	exportSyncMaxRows   = 2000             // 不超过该行数时直接下载，否则转为后台任务
+	exportMaxRows       = 200000           // 单次导出行数上限
+	exportPDFMaxRows    = 5000             // PDF 在内存中排版，行数上限较低
+	exportBatchSize     = 500              // 分批查询的行数
+	exportJobTTL        = 24 * time.Hour   // 导出文件保留时间
+	exportJobTimeout    = 30 * time.Minute // 超时未完成的任务视为失败（如生成过程中实例重启）
+	exportStoragePrefix = "exports/"
+)
+
+// drawStatusLabels 抽奖记录状态的中文名称（导出用）
+var drawStatusLabels = map[string]string{
+	models.DrawStatusWon:       "待领取",
+	models.DrawStatusClaimed:   "已领取",
+	models.DrawStatusShipped:   "已发货",
+	models.DrawStatusPickedUp:  "已自提",
+	models.DrawStatusCompleted: "已完成",
+	models.DrawStatusForfeited: "已放弃",
+	models.DrawStatusVoided:    "已作废",
+}
+
+// exportWake 通知后台导出任务有新任务（无需等待下一次轮询）
+var exportWake = make(chan struct{}, 1)
+
+// exportSpec 导出参数（直接下载和后台任务共用）
+type exportSpec struct {
+	Kind      string
+	Format    string
+	CompanyID int // 0 表示所有公司
+	Params    url.Values
+}
+
+// ExportDrawRecords 导出中奖记录（权限隔离），筛选条件同 GET /admin/draw-records（status、search）
+// format=csv|xlsx|pdf，PDF 为可打印的中奖名单（手机号脱敏）；数据量较大或 async=true 时创建后台任务
+func ExportDrawRecords(c *gin.Context) {
+	startExport(c, models.ExportKindDrawRecords)
+}
+
+// ExportUsers 导出参与者（权限隔离），筛选条件同 GET /admin/users（has_drawn、waitlisted、review_status、field.<key>）
+// format=csv|xlsx|pdf，导出单个公司时包含报名表单字段；数据量较大或 async=true 时创建后台任务
+func ExportUsers(c *gin.Context) {
+	startExport(c, models.ExportKindUsers)
+}
+
+// startExport 校验导出参数，数据量较小时直接下载，否则创建后台任务
+func startExport(c *gin.Context, kind string) {
+	format := c.DefaultQuery("format", utils.ExportFormatCSV)
+	if format != utils.ExportFormatCSV && format != utils.ExportFormatXLSX && format != utils.ExportFormatPDF {
+		c.JSON(http.StatusBadRequest, gin.H{"error": utils.ErrInvalidExportFormat.Error()})
+		return
+	}
+
+	// 超级管理员未指定公司时导出所有公司
+	companyID := 0
+	if isSuperAdmin, exists := c.Get("is_super_admin"); !exists || !isSuperAdmin.(bool) || c.Query("company_id") != "" {
+		var ok bool
+		if companyID, ok = getScopedCompanyID(c, c.Query("company_id")); !ok {
+			return
+		}
+	}
+
+	params := c.Request.URL.Query()
+	for _, key := range []string{"format", "async", "company_id", "page", "page_size"} {
+		params.Del(key)
+	}
+	spec := exportSpec{Kind: kind, Format: format, CompanyID: companyID, Params: params}
+
+	query, err := spec.query(config.DB)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "导出失败"})
+		return
+	}
+	limit := exportMaxRows
+	if format == utils.ExportFormatPDF {
+		limit = exportPDFMaxRows
+	}
+	if total > int64(limit) {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":      fmt.Sprintf("单次最多导出 %d 条（%s），请缩小筛选范围", limit, format),
+			"error_code": "EXPORT_TOO_LARGE",
+		})
+		return
+	}
+
+	var resourceID *uint
+	if companyID > 0 {
+		rid := uint(companyID)
+		resourceID = &rid
+	}
+
+	if total <= exportSyncMaxRows && c.Query("async") != "true" {
+		c.Header("Content-Type", utils.ExportContentType(format))
+		c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", spec.filename(time.Now())))
+		c.Status(http.StatusOK)
+		rows, err := spec.write(config.DB, c.Writer)
+		if err != nil {
+			// 响应头已发送，只能记录日志
+			utils.WithFields(map[string]interface{}{"error": err, "kind": kind, "company_id": companyID}).Error("导出失败")
+			return
+		}
+		LogOperation(c, "export", "company", resourceID, fmt.Sprintf("导出%s: %d 条（%s）", spec.title(), rows, format))
+		return
+	}
+
+	job := models.ExportJob{
+		CompanyID: companyID,
+		AdminID:   currentAdminID(c),
+		Kind:      kind,
+		Format:    format,
+		Params:    params.Encode(),
+		Status:    models.ExportStatusPending,
+		RowCount:  int(total),
+	}
+	if err := config.DB.Create(&job).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "创建导出任务失败"})
+		return
+	}
+	select {
+	case exportWake <- struct{}{}:
+	default:
+	}
+
+	LogOperation(c, "export", "company", resourceID, fmt.Sprintf("创建导出任务 #%d: %s 约 %d 条（%s）", job.ID, spec.title(), total, format))
+	c.JSON(http.StatusAccepted, gin.H{
+		"message": "数据量较大，已转为后台导出，完成后可在导出任务中下载",
+		"job":     job,
+	})
+}
+
+// GetExportJobs 获取导出任务列表（权限隔离，最近 50 个）
+func GetExportJobs(c *gin.Context) {
+	query := config.DB.Model(&models.ExportJob{})
+	if isSuperAdmin, exists := c.Get("is_super_admin"); !exists || !isSuperAdmin.(bool) {
+		companyID, ok := getScopedCompanyID(c, "")
+		if !ok {
+			return
+		}
+		query = query.Where("company_id = ?", companyID)
+	} else if companyIDParam := c.Query("company_id"); companyIDParam != "" {
+		query = query.Where("company_id = ?", companyIDParam)
+	}
+
+	var jobs []models.ExportJob
+	if err := query.Order("id DESC").Limit(50).Find(&jobs).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取导出任务失败"})
+		return
+	}
+	c.JSON(http.StatusOK, jobs)
+}
+
+// GetExportJob 获取导出任务状态（权限隔离）
+func GetExportJob(c *gin.Context) {
+	job, ok := loadExportJobForAdmin(c)
+	if !ok {
+		return
+	}
+	c.JSON(http.StatusOK, job)
+}
+
+// DownloadExportJob 下载已完成的导出文件（权限隔离）
+func DownloadExportJob(c *gin.Context) {
+	job, ok := loadExportJobForAdmin(c)
+	if !ok {
+		return
+	}
+	if job.Status != models.ExportStatusDone || job.FileKey == "" {
+		c.JSON(http.StatusConflict, gin.H{"error": "导出任务尚未完成", "error_code": "EXPORT_NOT_READY", "status": job.Status})
+		return
+	}
+	if job.ExpiresAt != nil && time.Now().After(*job.ExpiresAt) {
+		c.JSON(http.StatusGone, gin.H{"error": "导出文件已过期，请重新导出"})
+		return
+	}
+
+	store := storage.Default()
+	if store == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "文件存储未配置"})
+		return
+	}
+	reader, info, err := store.Get(c.Request.Context(), job.FileKey)
+	if err != nil {
+		if errors.Is(err, storage.ErrNotFound) {
+			c.JSON(http.StatusGone, gin.H{"error": "导出文件已过期，请重新导出"})
+			return
+		}
+		utils.WithFields(map[string]interface{}{"error": err, "job_id": job.ID}).Error("读取导出文件失败")
+		c.JSON(http.StatusBadGateway, gin.H{"error": "读取导出文件失败"})
+		return
+	}
+	defer reader.Close()
+
+	spec := exportSpec{Kind: job.Kind, Format: job.Format, CompanyID: job.CompanyID}
+	c.Header("Content-Type", utils.ExportContentType(job.Format))
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", spec.filename(job.CreatedAt)))
+	c.Header("Cache-Control", "private, no-store")
+	if info.Size > 0 {
+		c.Header("Content-Length", strconv.FormatInt(info.Size, 10))
+	}
+	c.Status(http.StatusOK)
+	io.Copy(c.Writer, reader)
+}
+
+// loadExportJobForAdmin 根据路由参数加载导出任务并检查管理员权限（所有公司的导出只有超级管理员可访问）
+func loadExportJobForAdmin(c *gin.Context) (*models.ExportJob, bool) {
+	var job models.ExportJob
+	if err := config.DB.First(&job, c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "导出任务不存在"})
+		return nil, false
+	}
+	isSuperAdmin, _ := c.Get("is_super_admin")
+	if super, _ := isSuperAdmin.(bool); !super && (job.CompanyID == 0 || !canAccessCompany(c, job.CompanyID)) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Permission denied"})
+		return nil, false
+	}
+	return &job, true
+}
+
+// RunExportWorker 后台导出任务（在独立 goroutine 中运行）
+// 多实例部署时通过条件更新认领任务，同一任务只会被一个实例执行；同时清理超时任务和过期文件
+func RunExportWorker(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		for runNextExportJob() {
+		}
+		cleanupExportJobs()
+
+		select {
+		case <-ticker.C:
+		case <-exportWake:
+		}
+	}
+}
+
+// runNextExportJob 认领并执行一个排队中的导出任务，没有任务时返回 false
+func runNextExportJob() bool {
+	var job models.ExportJob
+	if err := config.DB.Where("status = ?", models.ExportStatusPending).Order("id ASC").First(&job).Error; err != nil {
+		return false
+	}
+
+	now := time.Now()
+	claim := config.DB.Model(&models.ExportJob{}).
+		Where("id = ? AND status = ?", job.ID, models.ExportStatusPending).
+		Updates(map[string]interface{}{"status": models.ExportStatusRunning, "started_at": now})
+	if claim.Error != nil {
+		utils.WithFields(map[string]interface{}{"error": claim.Error, "job_id": job.ID}).Error("认领导出任务失败")
+		return false
+	}
+	if claim.RowsAffected == 0 {
+		return true // 已被其他实例认领
+	}
+
+	updates := map[string]interface{}{"finished_at": time.Now()}
+	rows, key, size, err := executeExportJob(&job)
+	if err != nil {
+		utils.WithFields(map[string]interface{}{"error": err, "job_id": job.ID}).Error("导出任务失败")
+		updates["status"] = models.ExportStatusFailed
+		updates["error"] = "生成导出文件失败"
+	} else {
+		expiresAt := time.Now().Add(exportJobTTL)
+		updates["status"] = models.ExportStatusDone
+		updates["row_count"] = rows
+		updates["file_key"] = key
+		updates["file_size"] = size
+		updates["expires_at"] = expiresAt
+	}
+	config.DB.Model(&models.ExportJob{}).Where("id = ?", job.ID).Updates(updates)
+	return true
+}
+
+// executeExportJob 生成导出文件并保存到文件存储（对象键包含随机数，且不在公开上传目录下）
+func executeExportJob(job *models.ExportJob) (int, string, int64, error) {
+	store := storage.Default()
+	if store == nil {
+		return 0, "", 0, errors.New("file storage not configured")
+	}
+	params, err := url.ParseQuery(job.Params)
+	if err != nil {
+		return 0, "", 0, err
+	}
+	spec := exportSpec{Kind: job.Kind, Format: job.Format, CompanyID: job.CompanyID, Params: params}
+
+	var buf bytes.Buffer
+	rows, err := spec.write(config.DB, &buf)
+	if err != nil {
+		return 0, "", 0, err
+	}
+
+	nonce := make([]byte, 16)
+	if _, err := rand.Read(nonce); err != nil {
+		return 0, "", 0, err
+	}
+	key := fmt.Sprintf("%s%d_%s.%s", exportStoragePrefix, job.ID, hex.EncodeToString(nonce), job.Format)
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+	if err := store.Put(ctx, key, buf.Bytes(), utils.ExportContentType(job.Format)); err != nil {
+		return 0, "", 0, err
+	}
+	return rows, key, int64(buf.Len()), nil
+}
+
+// cleanupExportJobs 将超时未完成的任务标记为失败，删除过期的导出文件和任务记录
+func cleanupExportJobs() {
+	now := time.Now()
+	config.DB.Model(&models.ExportJob{}).
+		Where("status = ? AND started_at < ?", models.ExportStatusRunning, now.Add(-exportJobTimeout)).
+		Updates(map[string]interface{}{"status": models.ExportStatusFailed, "error": "导出超时", "finished_at": now})
+
+	var expired []models.ExportJob
+	config.DB.Where("(expires_at IS NOT NULL AND expires_at < ?) OR (status = ? AND created_at < ?)",
+		now, models.ExportStatusFailed, now.Add(-exportJobTTL)).
+		Limit(100).Find(&expired)
+	for _, job := range expired {
+		if job.FileKey != "" && storage.Default() != nil {
+			if err := storage.Default().Delete(context.Background(), job.FileKey); err != nil {
+				utils.WithFields(map[string]interface{}{"error": err, "job_id": job.ID}).Warn("删除过期导出文件失败")
+				continue
+			}
+		}
+		config.DB.Delete(&models.ExportJob{}, job.ID)
+	}
+}
+
+// title 导出内容名称（文件标题和操作日志）
+func (s exportSpec) title() string {
+	if s.Kind == models.ExportKindDrawRecords {
+		return "中奖名单"
+	}
+	return "参与者名单"
+}
+
+// filename 下载文件名
+func (s exportSpec) filename(at time.Time) string {
+	code := "all"
+	if s.CompanyID > 0 {
+		var company models.Company
+		if config.DB.Select("code").First(&company, s.CompanyID).Error == nil {
+			code = company.Code
+		}
+	}
+	return fmt.Sprintf("%s_%s_%s.%s", s.Kind, code, at.Format("20060102_1504"), s.Format)
+}
+
+// query 构造导出查询（公司范围 + 与列表接口相同的筛选条件）
+func (s exportSpec) query(db *gorm.DB) (*gorm.DB, error) {
+	switch s.Kind {
+	case models.ExportKindDrawRecords:
+		query := applyDrawRecordFilters(db.Model(&models.DrawRecord{}), s.Params)
+		if s.CompanyID > 0 {
+			query = query.Where("draw_records.company_id = ?", s.CompanyID)
+		}
+		return query, nil
+	case models.ExportKindUsers:
+		query := applyUserFilters(db.Model(&models.User{}), s.Params)
+		if s.CompanyID > 0 {
+			query = query.Where("company_id = ?", s.CompanyID)
+		}
+		if filters := utils.FieldFiltersFromQuery(s.Params); len(filters) > 0 {
+			if s.CompanyID == 0 {
+				return nil, errors.New("按报名字段筛选时需指定 company_id")
+			}
+			if err := utils.ValidateFieldFilters(filters); err != nil {
+				return nil, err
+			}
+			query = utils.ApplyFieldFilters(db, query, s.CompanyID, filters)
+		}
+		return query, nil
+	default:
+		return nil, fmt.Errorf("unknown export kind: %s", s.Kind)
+	}
+}
+
+// write 分批查询并写出导出文件，返回写出的行数
+func (s exportSpec) write(db *gorm.DB, w io.Writer) (int, error) {
+	query, err := s.query(db)
+	if err != nil {
+		return 0, err
+	}
+
+	title := s.title()
+	if s.CompanyID > 0 {
+		var company models.Company
+		if err := db.First(&company, s.CompanyID).Error; err == nil {
+			title = company.Name + " " + title
+		}
+	}
+
+	rows := 0
+	switch s.Kind {
+	case models.ExportKindDrawRecords:
+		rows, err = s.writeDrawRecords(query, w, title)
+	default:
+		rows, err = s.writeUsers(db, query, w, title)
+	}
+	return rows, err
+}
+
+// writeDrawRecords 写出中奖记录；PDF 为精简的中奖名单（手机号脱敏），CSV/XLSX 包含发放信息
+func (s exportSpec) writeDrawRecords(query *gorm.DB, w io.Writer, title string) (int, error) {
+	pdf := s.Format == utils.ExportFormatPDF
+	var headers []string
+	if s.CompanyID == 0 {
+		headers = append(headers, "公司")
+	}
+	if pdf {
+		headers = append(headers, "序号", "奖项", "奖品", "姓名", "手机号", "中奖时间", "状态")
+	} else {
+		headers = append(headers, "记录ID", "中奖时间", "奖项", "奖品", "规格", "姓名", "手机号", "状态",
+			"领取时间", "收件人", "收件电话", "收货地址", "快递公司", "快递单号", "核销时间")
+	}
+
+	tw, err := utils.NewTableWriter(s.Format, w, title, headers)
+	if err != nil {
+		return 0, err
+	}
+
+	rows := 0
+	var records []models.DrawRecord
+	result := query.Preload("User").Preload("Level").Preload("Prize").Preload("Variant").Preload("Company").
+		FindInBatches(&records, exportBatchSize, func(tx *gorm.DB, batch int) error {
+			for _, record := range records {
+				rows++
+				var row []string
+				if s.CompanyID == 0 {
+					row = append(row, record.Company.Name)
+				}
+				status := drawStatusLabels[record.Status]
+				if status == "" {
+					status = record.Status
+				}
+				if pdf {
+					row = append(row, strconv.Itoa(rows), record.Level.Name, record.Prize.Name, record.User.Name,
+						maskPhone(record.User.Phone), record.CreatedAt.Format("2006-01-02 15:04"), status)
+				} else {
+					variant := ""
+					if record.Variant != nil {
+						variant = record.Variant.Name
+					}
+					row = append(row, strconv.Itoa(record.ID), record.CreatedAt.Format("2006-01-02 15:04:05"),
+						record.Level.Name, record.Prize.Name, variant, record.User.Name, record.User.Phone, status,
+						formatExportTime(record.ClaimedAt), record.RecipientName, record.RecipientPhone, record.ShippingAddress,
+						record.Courier, record.TrackingNumber, formatExportTime(record.RedeemedAt))
+				}
+				if err := tw.WriteRow(row); err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+	if result.Error != nil {
+		return rows, result.Error
+	}
+	return rows, tw.Close()
+}
+
+// writeUsers 写出参与者；导出单个公司时追加报名表单字段列
+func (s exportSpec) writeUsers(db *gorm.DB, query *gorm.DB, w io.Writer, title string) (int, error) {
+	var fields []models.RegistrationField
+	if s.CompanyID > 0 {
+		db.Where("company_id = ? AND is_active = ?", s.CompanyID, true).Order("sort_order ASC, id ASC").Find(&fields)
+	}
+
+	var headers []string
+	if s.CompanyID == 0 {
+		headers = append(headers, "公司ID")
+	}
+	headers = append(headers, "ID", "姓名", "手机号", "报名时间", "签到时间", "已中奖", "候补", "审核状态")
+	for _, field := range fields {
+		headers = append(headers, field.Label)
+	}
+
+	tw, err := utils.NewTableWriter(s.Format, w, title, headers)
+	if err != nil {
+		return 0, err
+	}
+
+	rows := 0
+	var users []models.User
+	result := query.FindInBatches(&users, exportBatchSize, func(tx *gorm.DB, batch int) error {
+		if len(fields) > 0 {
+			utils.AttachUserFields(db, users)
+		}
+		for _, user := range users {
+			rows++
+			var row []string
+			if s.CompanyID == 0 {
+				row = append(row, strconv.Itoa(user.CompanyID))
+			}
+			row = append(row, strconv.Itoa(user.ID), user.Name, user.Phone, user.CreatedAt.Format("2006-01-02 15:04:05"),
+				formatExportTime(user.CheckedInAt), exportYesNo(user.HasDrawn), exportYesNo(user.Waitlisted), user.ReviewStatus)
+			for _, field := range fields {
+				row = append(row, user.Fields[field.Key])
+			}
+			if err := tw.WriteRow(row); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if result.Error != nil {
+		return rows, result.Error
+	}
+	return rows, tw.Close()
+}
+
+// formatExportTime 格式化可选时间
+func formatExportTime(t *time.Time) string {
+	if t == nil {
+		return ""
+	}
+	return t.Format("2006-01-02 15:04:05")
+}
+
+// exportYesNo 布尔值转中文
+func exportYesNo(v bool) string {
+	if v {
+		return "是"
+	}
+	return "否"
+}
@@ -0,0 +1,320 @@
+package handlers
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"lottery-system/config"
+	"lottery-system/models"
+	"lottery-system/utils"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// UpdateCompanyFinanceRequest 更新公司财务配置请求
+type UpdateCompanyFinanceRequest struct {
+	PrizeBudget    *float64             `json:"prize_budget"`    // 奖品预算上限（0 表示不限制）
+	BudgetCurrency string               `json:"budget_currency"` // 预算币种
+	TaxBrackets    *[]models.TaxBracket `json:"tax_brackets"`    // 个税档位，传空数组恢复默认税率
+}
+
+// FinanceReportRow 财务报表行（每条中奖记录）
+type FinanceReportRow struct {
+	DrawRecordID int       `json:"draw_record_id"`
+	UserName     string    `json:"user_name"`
+	Phone        string    `json:"phone"`
+	LevelName    string    `json:"level_name"`
+	PrizeName    string    `json:"prize_name"`
+	Status       string    `json:"status"`
+	Value        float64   `json:"value"`
+	Currency     string    `json:"currency"`
+	Withholding  float64   `json:"withholding"` // 代扣个人所得税
+	NetValue     float64   `json:"net_value"`   // 税后价值
+	WonAt        time.Time `json:"won_at"`
+}
+
+// FinanceCurrencyTotal 按币种汇总
+type FinanceCurrencyTotal struct {
+	Currency    string  `json:"currency"`
+	Count       int     `json:"count"`
+	Value       float64 `json:"value"`
+	Withholding float64 `json:"withholding"`
+}
+
+// UpdateCompanyFinance 更新公司奖品预算和个税档位（权限检查）
+func UpdateCompanyFinance(c *gin.Context) {
+	id := c.Param("id")
+
+	var company models.Company
+	if err := config.DB.First(&company, id).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Company not found"})
+		return
+	}
+
+	if !canAccessCompany(c, company.ID) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Permission denied"})
+		return
+	}
+
+	var req UpdateCompanyFinanceRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "请求参数格式错误"})
+		return
+	}
+
+	updates := map[string]interface{}{}
+
+	currency := company.BudgetCurrency
+	if req.BudgetCurrency != "" {
+		currency = normalizeCurrency(req.BudgetCurrency)
+		if !currencyIsValid(currency) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "无效的币种代码"})
+			return
+		}
+		updates["budget_currency"] = currency
+	}
+
+	if req.PrizeBudget != nil {
+		if *req.PrizeBudget < 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "预算不能为负数"})
+			return
+		}
+		budget := utils.RoundMoney(*req.PrizeBudget)
+		if budget > 0 {
+			committed := committedPrizeValue(config.DB, company.ID, currency, 0)
+			if committed > budget {
+				c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("预算 (%.2f) 不能低于已配置奖品总价值 (%.2f)", budget, committed)})
+				return
+			}
+		}
+		updates["prize_budget"] = budget
+	}
+
+	if req.TaxBrackets != nil {
+		raw := ""
+		if len(*req.TaxBrackets) > 0 {
+			data, _ := json.Marshal(*req.TaxBrackets)
+			if _, err := models.ParseTaxBrackets(string(data)); err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+				return
+			}
+			raw = string(data)
+		}
+		updates["tax_brackets"] = raw
+	}
+
+	if len(updates) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "没有需要更新的内容"})
+		return
+	}
+
+	if err := config.DB.Model(&company).Updates(updates).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update company"})
+		return
+	}
+
+	resourceID := uint(company.ID)
+	LogOperation(c, "update_finance", "company", &resourceID,
+		fmt.Sprintf("更新财务配置: %s (预算: %.2f %s)", company.Name, company.PrizeBudget, company.BudgetCurrency))
+
+	c.JSON(http.StatusOK, company)
+}
+
+// GetFinanceReport 获取公司财务报表：每位中奖者的奖品价值及代扣个税（权限隔离）
+// format=csv 时导出 CSV 文件
+func GetFinanceReport(c *gin.Context) {
+	companyID, ok := getScopedCompanyID(c, c.Query("company_id"))
+	if !ok {
+		return
+	}
+
+	var company models.Company
+	if err := config.DB.First(&company, companyID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Company not found"})
+		return
+	}
+
+	brackets, err := models.ParseTaxBrackets(company.TaxBrackets)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "公司税率档位配置错误: " + err.Error()})
+		return
+	}
+
+	var records []models.DrawRecord
+	config.DB.Where("company_id = ? AND status NOT IN ?", company.ID, models.DrawStatusesReleased()).
+		Preload("User").Preload("Level").Preload("Prize").
+		Order("created_at ASC").
+		Find(&records)
+
+	rows := make([]FinanceReportRow, 0, len(records))
+	totals := make(map[string]*FinanceCurrencyTotal)
+	var currencies []string
+	for _, record := range records {
+		currency := record.Prize.Currency
+		if currency == "" {
+			currency = models.DefaultCurrency
+		}
+		value := utils.RoundMoney(record.Prize.Value)
+		withholding := utils.CalculateWithholding(value, brackets)
+
+		rows = append(rows, FinanceReportRow{
+			DrawRecordID: record.ID,
+			UserName:     record.User.Name,
+			Phone:        record.User.Phone,
+			LevelName:    record.Level.Name,
+			PrizeName:    record.Prize.Name,
+			Status:       record.Status,
+			Value:        value,
+			Currency:     currency,
+			Withholding:  withholding,
+			NetValue:     utils.RoundMoney(value - withholding),
+			WonAt:        record.CreatedAt,
+		})
+
+		total, exists := totals[currency]
+		if !exists {
+			total = &FinanceCurrencyTotal{Currency: currency}
+			totals[currency] = total
+			currencies = append(currencies, currency)
+		}
+		total.Count++
+		total.Value = utils.RoundMoney(total.Value + value)
+		total.Withholding = utils.RoundMoney(total.Withholding + withholding)
+	}
+
+	if c.Query("format") == "csv" {
+		writeFinanceReportCSV(c, &company, rows)
+		return
+	}
+
+	summary := make([]FinanceCurrencyTotal, 0, len(currencies))
+	for _, currency := range currencies {
+		summary = append(summary, *totals[currency])
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"company_id":      company.ID,
+		"prize_budget":    company.PrizeBudget,
+		"budget_currency": company.BudgetCurrency,
+		"committed_value": committedPrizeValue(config.DB, company.ID, company.BudgetCurrency, 0),
+		"tax_brackets":    brackets,
+		"summary":         summary,
+		"rows":            rows,
+	})
+}
+
+// writeFinanceReportCSV 输出财务报表 CSV（带 UTF-8 BOM，便于 Excel 打开中文）
+func writeFinanceReportCSV(c *gin.Context, company *models.Company, rows []FinanceReportRow) {
+	filename := fmt.Sprintf("finance_%s_%s.csv", company.Code, time.Now().Format("20060102"))
+	c.Header("Content-Type", "text/csv; charset=utf-8")
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+	c.Status(http.StatusOK)
+
+	c.Writer.Write([]byte("\xEF\xBB\xBF"))
+	w := csv.NewWriter(c.Writer)
+	w.Write([]string{"记录ID", "姓名", "手机号", "奖项", "奖品", "状态", "奖品价值", "币种", "代扣个税", "税后价值", "中奖时间"})
+	for _, row := range rows {
+		w.Write([]string{
+			strconv.Itoa(row.DrawRecordID),
+			row.UserName,
+			row.Phone,
+			row.LevelName,
+			row.PrizeName,
+			row.Status,
+			strconv.FormatFloat(row.Value, 'f', 2, 64),
+			row.Currency,
+			strconv.FormatFloat(row.Withholding, 'f', 2, 64),
+			strconv.FormatFloat(row.NetValue, 'f', 2, 64),
+			row.WonAt.Format("2006-01-02 15:04:05"),
+		})
+	}
+	w.Flush()
+
+	resourceID := uint(company.ID)
+	LogOperation(c, "export_finance", "company", &resourceID, fmt.Sprintf("导出财务报表: %s (%d条)", company.Name, len(rows)))
+}
+
+// prizeBudgetError 奖品总价值超出预算（可直接返回给管理员的提示）
+type prizeBudgetError struct {
+	message string
+}
+
+func (e *prizeBudgetError) Error() string {
+	return e.message
+}
+
+// prizeStockErrorResponse 奖品库存变更失败的响应，超出预算时附带 BUDGET_EXCEEDED 错误码
+func prizeStockErrorResponse(message string, err error) gin.H {
+	resp := gin.H{"error": message + ": " + err.Error()}
+	var budgetErr *prizeBudgetError
+	if errors.As(err, &budgetErr) {
+		resp["error_code"] = "BUDGET_EXCEEDED"
+	}
+	return resp
+}
+
+// checkPrizeBudget 检查新增/修改奖品后公司奖品总价值是否超出预算
+// excludePrizeID 为正在修改的奖品ID（新建时为 0），db 可以是事务
+func checkPrizeBudget(db *gorm.DB, companyID int, prize *models.Prize, excludePrizeID int) error {
+	var company models.Company
+	if err := db.First(&company, companyID).Error; err != nil {
+		return err
+	}
+	if company.PrizeBudget <= 0 {
+		return nil
+	}
+
+	if prize.Currency != company.BudgetCurrency {
+		return &prizeBudgetError{fmt.Sprintf("奖品币种 (%s) 与公司预算币种 (%s) 不一致", prize.Currency, company.BudgetCurrency)}
+	}
+
+	committed := committedPrizeValue(db, companyID, company.BudgetCurrency, excludePrizeID)
+	total := utils.RoundMoney(committed + prize.Value*float64(prize.TotalStock))
+	if total > company.PrizeBudget {
+		return &prizeBudgetError{fmt.Sprintf("奖品总价值 (%.2f) 超出预算 (%.2f)，剩余可用 %.2f",
+			total, company.PrizeBudget, utils.RoundMoney(company.PrizeBudget-committed))}
+	}
+	return nil
+}
+
+// committedPrizeValue 计算公司已配置奖品的总价值（单价 × 总库存）
+func committedPrizeValue(db *gorm.DB, companyID int, currency string, excludePrizeID int) float64 {
+	var committed struct {
+		Total float64
+	}
+	db.Raw(`
+		SELECT COALESCE(SUM(p.value * p.total_stock), 0) as total FROM prizes p
+		INNER JOIN prize_levels l ON p.level_id = l.id
+		WHERE l.company_id = ? AND p.currency = ? AND p.id <> ?
+	`, companyID, currency, excludePrizeID).Scan(&committed)
+	return utils.RoundMoney(committed.Total)
+}
+
+// normalizeCurrency 规范化币种代码（大写，空值为默认币种）
+func normalizeCurrency(currency string) string {
+	currency = strings.ToUpper(strings.TrimSpace(currency))
+	if currency == "" {
+		return models.DefaultCurrency
+	}
+	return currency
+}
+
+// currencyIsValid 检查币种代码是否为三位字母（ISO 4217）
+func currencyIsValid(currency string) bool {
+	if len(currency) != 3 {
+		return false
+	}
+	for _, r := range currency {
+		if r < 'A' || r > 'Z' {
+			return false
+		}
+	}
+	return true
+}
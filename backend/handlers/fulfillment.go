@@ -0,0 +1,373 @@
+package handlers
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"lottery-system/config"
+	"lottery-system/models"
+	"lottery-system/utils"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// ShippingInfoRequest 中奖者提交收货信息请求
+type ShippingInfoRequest struct {
+	RecipientName   string `json:"recipient_name" binding:"required"`
+	RecipientPhone  string `json:"recipient_phone" binding:"required"`
+	ShippingAddress string `json:"shipping_address" binding:"required"`
+}
+
+// FulfillmentItem 单条记录的发放信息（批量更新时可为每条记录指定快递单号）
+type FulfillmentItem struct {
+	ID             int    `json:"id"`
+	Courier        string `json:"courier"`
+	TrackingNumber string `json:"tracking_number"`
+}
+
+// UpdateDrawStatusRequest 更新中奖记录状态请求
+type UpdateDrawStatusRequest struct {
+	Status         string `json:"status" binding:"required"`
+	Courier        string `json:"courier"`
+	TrackingNumber string `json:"tracking_number"`
+	Note           string `json:"note"`
+}
+
+// BulkUpdateDrawStatusRequest 批量更新中奖记录状态请求
+type BulkUpdateDrawStatusRequest struct {
+	Status  string            `json:"status" binding:"required"`
+	IDs     []int             `json:"ids"`
+	Items   []FulfillmentItem `json:"items"`   // 发货时逐条指定快递单号
+	Courier string            `json:"courier"` // 统一的快递公司（items 中未指定时使用）
+	Note    string            `json:"note"`
+}
+
+// BulkStatusResult 批量更新单条结果
+type BulkStatusResult struct {
+	ID      int    `json:"id"`
+	Success bool   `json:"success"`
+	Error   string `json:"error,omitempty"`
+}
+
+// FulfillmentRecord 管理端返回的中奖记录，附带收件人、地址、单号和备注（模型中这些字段不序列化，避免公开接口泄露）
+type FulfillmentRecord struct {
+	*models.DrawRecord
+	RecipientName   string `json:"recipient_name,omitempty"`
+	RecipientPhone  string `json:"recipient_phone,omitempty"`
+	ShippingAddress string `json:"shipping_address,omitempty"`
+	TrackingNumber  string `json:"tracking_number,omitempty"`
+	FulfillmentNote string `json:"fulfillment_note,omitempty"`
+}
+
+// newFulfillmentRecord 转换为管理端返回的中奖记录
+func newFulfillmentRecord(record *models.DrawRecord) FulfillmentRecord {
+	return FulfillmentRecord{
+		DrawRecord:      record,
+		RecipientName:   record.RecipientName,
+		RecipientPhone:  record.RecipientPhone,
+		ShippingAddress: record.ShippingAddress,
+		TrackingNumber:  record.TrackingNumber,
+		FulfillmentNote: record.FulfillmentNote,
+	}
+}
+
+// newFulfillmentRecords 批量转换为管理端返回的中奖记录
+func newFulfillmentRecords(records []models.DrawRecord) []FulfillmentRecord {
+	result := make([]FulfillmentRecord, len(records))
+	for i := range records {
+		result[i] = newFulfillmentRecord(&records[i])
+	}
+	return result
+}
+
+// errStatusChanged 记录状态已被其他操作修改
+var errStatusChanged = errors.New("记录状态已变化，请刷新后重试")
+
+// SubmitShippingInfo 中奖者提交收货信息（用户端），提交后记录变为已领取
+func SubmitShippingInfo(c *gin.Context) {
+	record, ok := findMyDrawRecord(c)
+	if !ok {
+		return
+	}
+
+	var req ShippingInfoRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "请填写收件人、电话和收货地址"})
+		return
+	}
+
+	req.RecipientName = strings.TrimSpace(req.RecipientName)
+	req.ShippingAddress = strings.TrimSpace(req.ShippingAddress)
+	if err := utils.ValidateName(req.RecipientName); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err := utils.ValidatePhone(req.RecipientPhone); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if len([]rune(req.ShippingAddress)) < 5 || len([]rune(req.ShippingAddress)) > 200 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "收货地址长度应为5-200个字符"})
+		return
+	}
+
+	if record.RolledOver {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "该记录的奖品已回流，无法领取", "error_code": "ROLLED_OVER"})
+		return
+	}
+
+	// 只有未发货前可以提交/修改收货信息
+	if record.Status != models.DrawStatusWon && record.Status != models.DrawStatusClaimed {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "奖品已发放，无法修改收货信息", "error_code": "ALREADY_FULFILLED"})
+		return
+	}
+
+	updates := map[string]interface{}{
+		"recipient_name":   req.RecipientName,
+		"recipient_phone":  req.RecipientPhone,
+		"shipping_address": req.ShippingAddress,
+	}
+	if record.Status == models.DrawStatusWon {
+		updates["status"] = models.DrawStatusClaimed
+		updates["claimed_at"] = time.Now()
+	}
+
+	result := config.DB.Model(&models.DrawRecord{}).
+		Where("id = ? AND status = ? AND rolled_over = ?", record.ID, record.Status, false).
+		Updates(updates)
+	if result.Error != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "提交失败，请稍后重试"})
+		return
+	}
+	if result.RowsAffected == 0 {
+		c.JSON(http.StatusConflict, gin.H{"error": errStatusChanged.Error()})
+		return
+	}
+
+	utils.WithFields(map[string]interface{}{
+		"draw_record_id": record.ID,
+		"user_id":        record.UserID,
+	}).Info("中奖者提交收货信息")
+
+	config.DB.Preload("Level").Preload("Prize").Preload("Variant").First(record, record.ID)
+	c.JSON(http.StatusOK, record)
+}
+
+// UpdateDrawRecordStatus 更新单条中奖记录的发放状态（权限检查）
+func UpdateDrawRecordStatus(c *gin.Context) {
+	record, ok := loadDrawRecordForAdmin(c)
+	if !ok {
+		return
+	}
+
+	var req UpdateDrawStatusRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "请求参数格式错误"})
+		return
+	}
+
+	item := FulfillmentItem{ID: record.ID, Courier: req.Courier, TrackingNumber: req.TrackingNumber}
+	if err := transitionDrawRecord(config.DB, record, req.Status, item, req.Note); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	resourceID := uint(record.ID)
+	LogOperation(c, "update_status", "draw_record", &resourceID,
+		fmt.Sprintf("更新中奖记录状态: 记录ID %d → %s", record.ID, req.Status))
+
+	config.DB.First(record, record.ID)
+	c.JSON(http.StatusOK, newFulfillmentRecord(record))
+}
+
+// BulkUpdateDrawRecordStatus 批量更新中奖记录的发放状态（权限检查），逐条返回结果
+func BulkUpdateDrawRecordStatus(c *gin.Context) {
+	var req BulkUpdateDrawStatusRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "请求参数格式错误"})
+		return
+	}
+
+	if !models.DrawStatusIsValid(req.Status) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的状态"})
+		return
+	}
+
+	// 合并 ids 和 items，items 中的快递信息优先
+	items := make([]FulfillmentItem, 0, len(req.IDs)+len(req.Items))
+	seen := make(map[int]bool)
+	for _, item := range req.Items {
+		if item.ID > 0 && !seen[item.ID] {
+			seen[item.ID] = true
+			items = append(items, item)
+		}
+	}
+	for _, id := range req.IDs {
+		if id > 0 && !seen[id] {
+			seen[id] = true
+			items = append(items, FulfillmentItem{ID: id})
+		}
+	}
+
+	if len(items) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "请选择要更新的记录"})
+		return
+	}
+	if len(items) > 500 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "单次最多更新500条记录"})
+		return
+	}
+
+	results := make([]BulkStatusResult, 0, len(items))
+	successCount := 0
+	for _, item := range items {
+		result := BulkStatusResult{ID: item.ID}
+
+		var record models.DrawRecord
+		if err := config.DB.First(&record, item.ID).Error; err != nil {
+			result.Error = "抽奖记录不存在"
+		} else if !canAccessCompany(c, record.CompanyID) {
+			result.Error = "Permission denied"
+		} else {
+			if item.Courier == "" {
+				item.Courier = req.Courier
+			}
+			if err := transitionDrawRecord(config.DB, &record, req.Status, item, req.Note); err != nil {
+				result.Error = err.Error()
+			} else {
+				result.Success = true
+				successCount++
+			}
+		}
+
+		results = append(results, result)
+	}
+
+	LogOperation(c, "bulk_update_status", "draw_record", nil,
+		fmt.Sprintf("批量更新中奖记录状态 → %s: 成功%d条，失败%d条", req.Status, successCount, len(items)-successCount))
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": successCount,
+		"failed":  len(items) - successCount,
+		"results": results,
+	})
+}
+
+// ExportShippingCSV 导出待发货记录的快递面单 CSV（权限隔离）
+// 默认导出已领取且已填写收货地址的记录，可通过 status 参数指定其他状态
+func ExportShippingCSV(c *gin.Context) {
+	companyID, ok := getScopedCompanyID(c, c.Query("company_id"))
+	if !ok {
+		return
+	}
+
+	var company models.Company
+	if err := config.DB.First(&company, companyID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Company not found"})
+		return
+	}
+
+	status := c.DefaultQuery("status", models.DrawStatusClaimed)
+	if !models.DrawStatusIsValid(status) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的状态"})
+		return
+	}
+
+	var records []models.DrawRecord
+	config.DB.Where("company_id = ? AND status = ? AND shipping_address <> ''", company.ID, status).
+		Preload("User").Preload("Level").Preload("Prize").Preload("Variant").
+		Order("id ASC").
+		Find(&records)
+
+	filename := fmt.Sprintf("shipping_%s_%s.csv", company.Code, time.Now().Format("20060102"))
+	c.Header("Content-Type", "text/csv; charset=utf-8")
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+	c.Status(http.StatusOK)
+
+	c.Writer.Write([]byte("\xEF\xBB\xBF"))
+	w := csv.NewWriter(c.Writer)
+	w.Write([]string{"订单号", "收件人", "收件电话", "收货地址", "物品名称", "规格", "数量", "快递公司", "快递单号", "备注"})
+	for _, record := range records {
+		variantName := ""
+		if record.Variant != nil {
+			variantName = record.Variant.Name
+		}
+		w.Write([]string{
+			strconv.Itoa(record.ID),
+			record.RecipientName,
+			record.RecipientPhone,
+			record.ShippingAddress,
+			record.Prize.Name,
+			variantName,
+			"1",
+			record.Courier,
+			record.TrackingNumber,
+			fmt.Sprintf("%s %s", record.Level.Name, record.User.Name),
+		})
+	}
+	w.Flush()
+
+	resourceID := uint(company.ID)
+	LogOperation(c, "export_shipping", "company", &resourceID, fmt.Sprintf("导出快递面单: %s (%d条)", company.Name, len(records)))
+}
+
+// transitionDrawRecord 按状态机流转中奖记录状态（条件更新，防止并发修改）
+func transitionDrawRecord(db *gorm.DB, record *models.DrawRecord, status string, item FulfillmentItem, note string) error {
+	if !models.DrawStatusIsValid(status) {
+		return errors.New("无效的状态")
+	}
+	if !models.DrawStatusCanTransition(record.Status, status) {
+		return fmt.Errorf("当前状态(%s)不能变更为%s", record.Status, status)
+	}
+	if record.RolledOver {
+		return errors.New("该记录的奖品已回流")
+	}
+
+	now := time.Now()
+	updates := map[string]interface{}{"status": status}
+	switch status {
+	case models.DrawStatusClaimed:
+		updates["claimed_at"] = now
+	case models.DrawStatusShipped:
+		if record.ShippingAddress == "" {
+			return errors.New("中奖者尚未提交收货地址")
+		}
+		if strings.TrimSpace(item.TrackingNumber) == "" {
+			return errors.New("发货需要填写快递单号")
+		}
+		updates["courier"] = strings.TrimSpace(item.Courier)
+		updates["tracking_number"] = strings.TrimSpace(item.TrackingNumber)
+		updates["shipped_at"] = now
+	case models.DrawStatusPickedUp:
+		updates["shipped_at"] = now
+	case models.DrawStatusCompleted:
+		updates["completed_at"] = now
+	}
+	if note != "" {
+		updates["fulfillment_note"] = note
+	}
+
+	return db.Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&models.DrawRecord{}).
+			Where("id = ? AND status = ?", record.ID, record.Status).
+			Updates(updates)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return errStatusChanged
+		}
+
+		// 作废或放弃时归还规格库存
+		if status == models.DrawStatusForfeited || status == models.DrawStatusVoided {
+			return utils.ReleaseVariant(tx, record)
+		}
+		return nil
+	})
+}
@@ -0,0 +1,221 @@
+package handlers
+
+import (
+	"io"
+	"net/http"
+	"time"
+
+	"lottery-system/config"
+	"lottery-system/models"
+	"lottery-system/realtime"
+	"lottery-system/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// liveHeartbeatInterval 心跳间隔，防止代理因空闲断开连接
+const liveHeartbeatInterval = 25 * time.Second
+
+// LiveWinner 实时广播的中奖者信息（手机号脱敏）
+type LiveWinner struct {
+	DrawRecordID int    `json:"draw_record_id"`
+	UserID       int    `json:"user_id"`
+	Name         string `json:"name"`
+	Phone        string `json:"phone"`
+	LevelID      int    `json:"level_id"`
+	LevelName    string `json:"level_name"`
+	PrizeID      int    `json:"prize_id"`
+	PrizeName    string `json:"prize_name"`
+	PrizeImage   string `json:"prize_image"`
+}
+
+// LiveLevelStock 实时广播的奖项库存
+type LiveLevelStock struct {
+	LevelID    int    `json:"level_id"`
+	LevelName  string `json:"level_name"`
+	TotalStock int    `json:"total_stock"`
+	UsedStock  int    `json:"used_stock"`
+	Remaining  int    `json:"remaining"`
+}
+
+// LiveEvents 大屏实时事件流（SSE），推送开始抽奖、中奖公布、库存变化、新参与者事件
+// 浏览器 EventSource 无法设置请求头，可通过 access_token 参数传递 token
+func LiveEvents(c *gin.Context) {
+	company, err := getCompanyByCode(c.Query("company_code"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid company code"})
+		return
+	}
+
+	if !canViewCompanyLive(c, company.ID) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Permission denied"})
+		return
+	}
+
+	// 大屏定向事件（遥控指令）不对普通订阅者推送
+	streamLiveEvents(c, company.ID, gin.H{"company_id": company.ID}, func(event realtime.Event) bool {
+		return event.DisplayID == 0
+	}, nil)
+}
+
+// streamLiveEvents 以 SSE 推送公司事件，filter 返回 false 的事件不推送，stopAfter 返回 true 时推送该事件后结束事件流
+func streamLiveEvents(c *gin.Context, companyID int, hello gin.H, filter, stopAfter func(realtime.Event) bool) {
+	sub := realtime.Default().Subscribe(companyID)
+	defer sub.Close()
+
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+	c.Header("X-Accel-Buffering", "no") // 禁用 Nginx 缓冲
+
+	hello["time"] = time.Now()
+	c.SSEvent("connected", hello)
+	c.Writer.Flush()
+
+	heartbeat := time.NewTicker(liveHeartbeatInterval)
+	defer heartbeat.Stop()
+
+	c.Stream(func(w io.Writer) bool {
+		select {
+		case <-c.Request.Context().Done():
+			return false
+		case event, ok := <-sub.C:
+			if !ok {
+				return false
+			}
+			if !filter(event) {
+				return true
+			}
+			c.SSEvent(event.Type, event)
+			return stopAfter == nil || !stopAfter(event)
+		case <-heartbeat.C:
+			c.SSEvent("ping", gin.H{"time": time.Now()})
+			return true
+		}
+	})
+}
+
+// canViewCompanyLive 检查当前 token 能否查看公司的实时事件
+// 用户只能查看所属公司；管理员按公司权限检查
+func canViewCompanyLive(c *gin.Context, companyID int) bool {
+	isAdmin, _ := c.Get("is_admin")
+	if isAdmin == true {
+		return canAccessCompany(c, companyID)
+	}
+	userCompanyID, exists := c.Get("company_id")
+	if !exists {
+		return false
+	}
+	id, ok := userCompanyID.(int)
+	return ok && id == companyID
+}
+
+// broadcastDrawStarted 广播开始抽奖事件
+func broadcastDrawStarted(companyID, levelID, count int) {
+	data := gin.H{"level_id": levelID, "count": count}
+	if levelID > 0 {
+		var level models.PrizeLevel
+		if err := config.DB.First(&level, levelID).Error; err == nil {
+			data["level_name"] = level.Name
+		}
+	}
+	publishLiveEvent(companyID, realtime.EventDrawStarted, data)
+}
+
+// broadcastDrawResult 广播中奖结果及相关奖项的库存变化
+func broadcastDrawResult(companyID int, records []models.DrawRecord) {
+	if len(records) == 0 {
+		return
+	}
+
+	winners := make([]LiveWinner, 0, len(records))
+	levelIDs := make([]int, 0)
+	seenLevels := make(map[int]bool)
+	for _, record := range records {
+		var full models.DrawRecord
+		if err := config.DB.Preload("User").Preload("Level").Preload("Prize").First(&full, record.ID).Error; err != nil {
+			continue
+		}
+		winners = append(winners, LiveWinner{
+			DrawRecordID: full.ID,
+			UserID:       full.UserID,
+			Name:         full.User.Name,
+			Phone:        maskPhone(full.User.Phone),
+			LevelID:      full.LevelID,
+			LevelName:    full.Level.Name,
+			PrizeID:      full.PrizeID,
+			PrizeName:    full.Prize.Name,
+			PrizeImage:   full.Prize.Image,
+		})
+		if !seenLevels[full.LevelID] {
+			seenLevels[full.LevelID] = true
+			levelIDs = append(levelIDs, full.LevelID)
+		}
+	}
+
+	publishLiveEvent(companyID, realtime.EventWinnersRevealed, gin.H{"winners": winners})
+	broadcastStockChanged(companyID, levelIDs)
+}
+
+// broadcastStockChanged 广播奖项库存变化，levelIDs 为空时广播公司全部奖项
+func broadcastStockChanged(companyID int, levelIDs []int) {
+	var levels []models.PrizeLevel
+	query := config.DB.Where("company_id = ?", companyID)
+	if len(levelIDs) > 0 {
+		query = query.Where("id IN ?", levelIDs)
+	}
+	query.Order("sort_order ASC").Find(&levels)
+
+	stocks := make([]LiveLevelStock, 0, len(levels))
+	for _, level := range levels {
+		var stock struct {
+			TotalStock int
+			UsedStock  int
+		}
+		config.DB.Model(&models.Prize{}).
+			Where("level_id = ?", level.ID).
+			Select("COALESCE(SUM(total_stock), 0) as total_stock, COALESCE(SUM(used_stock), 0) as used_stock").
+			Scan(&stock)
+		stocks = append(stocks, LiveLevelStock{
+			LevelID:    level.ID,
+			LevelName:  level.Name,
+			TotalStock: stock.TotalStock,
+			UsedStock:  stock.UsedStock,
+			Remaining:  stock.TotalStock - stock.UsedStock,
+		})
+	}
+
+	publishLiveEvent(companyID, realtime.EventStockChanged, gin.H{"levels": stocks})
+}
+
+// broadcastParticipantRegistered 广播新参与者加入事件（added 为本次新增人数）
+func broadcastParticipantRegistered(companyID int, user *models.User, added int) {
+	var total int64
+	config.DB.Model(&models.User{}).Where("company_id = ? AND waitlisted = ?", companyID, false).Count(&total)
+
+	data := gin.H{"added": added, "total_participants": total}
+	if user != nil {
+		data["user_id"] = user.ID
+		data["name"] = user.Name
+	}
+	publishLiveEvent(companyID, realtime.EventParticipantRegistered, data)
+}
+
+// publishLiveEvent 发布实时事件，失败只记录日志，不影响业务请求
+func publishLiveEvent(companyID int, eventType string, data interface{}) {
+	if err := realtime.Publish(companyID, eventType, data); err != nil {
+		utils.WithFields(map[string]interface{}{
+			"error":      err,
+			"company_id": companyID,
+			"event":      eventType,
+		}).Warn("实时事件发布失败")
+	}
+}
+
+// maskPhone 手机号脱敏（保留前3位和后4位）
+func maskPhone(phone string) string {
+	runes := []rune(phone)
+	if len(runes) < 7 {
+		return phone
+	}
+	return string(runes[:3]) + "****" + string(runes[len(runes)-4:])
+}
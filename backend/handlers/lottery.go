@@ -16,12 +16,21 @@ type RegisterRequest struct {
 	Password string `json:"password"` // 可选，保留以兼容旧接口
 	Name     string `json:"name"`     // 必填：姓名
 	Phone    string `json:"phone"`    // 可选：手机号
+
+	Fields map[string]string `json:"fields"` // 公司自定义的报名表单字段
+
+	PhoneToken string `json:"phone_token"` // 手机号验证凭证（公司开启报名手机验证时必填）
+
+	Challenge *ChallengeAnswer `json:"challenge"` // 人机验证答案（公司开启人机验证时必填）
+	DeviceID  string           `json:"device_id"` // 前端生成的设备标识（用于批量报名检测）
 }
 
 type DrawRequest struct {
 	LevelID   int    `json:"level_id"`   // 指定抽取的奖项等级ID，0表示不指定
 	Count     int    `json:"count"`      // 抽取人数
 	UserPhone string `json:"user_phone"` // 指定中奖用户的手机号（用于前端选择中奖者）
+
+	FieldFilters map[string]string `json:"field_filters"` // 按报名表单字段筛选抽奖范围（如 {"department": "研发部"}）
 }
 
 // getCompanyByCode 根据代码获取公司（必须提供参数）
@@ -206,24 +215,10 @@ func RegisterOrLogin(c *gin.Context) {
 }
 
 // GetUserInfo 获取用户信息
+// 用户token返回本人信息；管理员token需通过 phone 和 company_code 参数指定用户
 func GetUserInfo(c *gin.Context) {
-	phone := c.Query("phone")
-	companyCode := c.Query("company_code")
-
-	if companyCode == "" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "company_code parameter is required"})
-		return
-	}
-
-	company, err := getCompanyByCode(companyCode)
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid company code"})
-		return
-	}
-
-	var user models.User
-	if err := config.DB.Where("phone = ? AND company_id = ?", phone, company.ID).First(&user).Error; err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+	user, ok := findCurrentUser(c)
+	if !ok {
 		return
 	}
 
@@ -249,11 +244,20 @@ func GetActivePrizeLevels(c *gin.Context) {
 		Order("sort_order ASC").
 		Find(&levels)
 
+	// 当前仍可抽奖的奖项（用于计算抽奖顺序限制）
+	pending, err := pendingDrawLevels(company)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch prize levels"})
+		return
+	}
+
 	// 为每个奖项等级计算奖品的库存信息
 	type PrizeLevelWithStock struct {
 		models.PrizeLevel
-		TotalStock int `json:"total_stock"`
-		UsedStock  int `json:"used_stock"`
+		TotalStock int    `json:"total_stock"`
+		UsedStock  int    `json:"used_stock"`
+		Drawable   bool   `json:"drawable"`             // 当前是否可以抽取
+		BlockedBy  string `json:"blocked_by,omitempty"` // 阻塞抽奖的奖项名称
 	}
 
 	result := make([]PrizeLevelWithStock, len(levels))
@@ -268,10 +272,13 @@ type PrizeLevelWithStock struct {
 			Select("COALESCE(SUM(total_stock), 0) as total_stock, COALESCE(SUM(used_stock), 0) as used_stock").
 			Scan(&stockData)
 
+		blockedBy := levelBlocker(company, pending, level)
 		result[i] = PrizeLevelWithStock{
 			PrizeLevel: level,
 			TotalStock: stockData.TotalStock,
 			UsedStock:  stockData.UsedStock,
+			Drawable:   blockedBy == "" && stockData.UsedStock < stockData.TotalStock,
+			BlockedBy:  blockedBy,
 		}
 	}
 
@@ -312,10 +319,25 @@ func Draw(c *gin.Context) {
 		return
 	}
 
+	if err := utils.ValidateFieldFilters(req.FieldFilters); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
 	// 如果没有指定level_id，使用用户传递的count
 	levelID := req.LevelID
 	drawCount := req.Count
 
+	// 启用抽奖顺序时，未指定奖项则只从当前轮次的奖项中抽取
+	if levelID == 0 && models.DrawOrderEnforced(company.DrawOrder) {
+		current, err := currentDrawLevel(company)
+		if err != nil {
+			respondDrawGateError(c, err)
+			return
+		}
+		levelID = current.ID
+	}
+
 	if levelID == 0 {
 		// 未指定奖项，从所有未抽奖用户中抽取
 		if drawCount <= 0 {
@@ -324,7 +346,7 @@ func Draw(c *gin.Context) {
 
 		// 查找该公司所有未抽奖的用户
 		var users []models.User
-		if err := config.DB.Where("company_id = ? AND has_drawn = ?", company.ID, false).
+		if err := eligibleUsersQuery(company, req.FieldFilters).
 			Order("id ASC").
 			Find(&users).Error; err != nil {
 			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch users"})
@@ -333,7 +355,7 @@ func Draw(c *gin.Context) {
 
 		// 检查是否还有未抽奖的用户
 		if len(users) == 0 {
-			c.JSON(http.StatusBadRequest, gin.H{"error": "没有可抽奖的用户"})
+			respondNoEligibleUsers(c, company)
 			return
 		}
 
@@ -342,6 +364,8 @@ func Draw(c *gin.Context) {
 			drawCount = len(users)
 		}
 
+		broadcastDrawStarted(company.ID, 0, drawCount)
+
 		// 随机选择用户
 		selectedIndices := utils.RandomIndices(len(users), drawCount)
 		var records []models.DrawRecord
@@ -368,6 +392,7 @@ func Draw(c *gin.Context) {
 			config.DB.Preload("User").Preload("Level").Preload("Prize").First(&records[i], records[i].ID)
 		}
 
+		broadcastDrawResult(company.ID, records)
 		c.JSON(http.StatusOK, records)
 		return
 	}
@@ -381,6 +406,12 @@ func Draw(c *gin.Context) {
 		return
 	}
 
+	// 检查奖项开放状态和抽奖顺序
+	if err := checkLevelDrawable(company, &level); err != nil {
+		respondDrawGateError(c, err)
+		return
+	}
+
 	// 计算该奖项等级下所有奖品的实际库存（从 Prize 表聚合）
 	type StockInfo struct {
 		TotalStock int `json:"total_stock"`
@@ -409,7 +440,7 @@ type StockInfo struct {
 
 	// 查找该公司所有未抽奖的用户
 	var users []models.User
-	if err := config.DB.Where("company_id = ? AND has_drawn = ?", company.ID, false).
+	if err := eligibleUsersQuery(company, req.FieldFilters).
 		Order("id ASC").
 		Find(&users).Error; err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch users"})
@@ -418,7 +449,7 @@ type StockInfo struct {
 
 	// 检查用户数量
 	if len(users) == 0 {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "没有可抽奖的用户"})
+		respondNoEligibleUsers(c, company)
 		return
 	}
 
@@ -426,12 +457,14 @@ type StockInfo struct {
 	var records []models.DrawRecord
 	ip := c.ClientIP()
 
+	broadcastDrawStarted(company.ID, levelID, drawCount)
+
 	if req.UserPhone != "" {
 		// 查找指定的用户
 		var specifiedUser models.User
-		if err := config.DB.Where("phone = ? AND company_id = ? AND has_drawn = ?", req.UserPhone, company.ID, false).
+		if err := eligibleUsersQuery(company, req.FieldFilters).Where("phone_hash = ?", models.PhoneIndex(req.UserPhone)).
 			First(&specifiedUser).Error; err != nil {
-			c.JSON(http.StatusNotFound, gin.H{"error": "指定的用户不存在或已抽过奖"})
+			c.JSON(http.StatusNotFound, gin.H{"error": "指定的用户不存在、已抽过奖或未签到"})
 			return
 		}
 
@@ -449,7 +482,7 @@ type StockInfo struct {
 
 			// 获取其他未抽奖的用户（排除已指定的用户）
 			var otherUsers []models.User
-			if err := config.DB.Where("company_id = ? AND has_drawn = ? AND id != ?", company.ID, false, specifiedUser.ID).
+			if err := eligibleUsersQuery(company, req.FieldFilters).Where("id != ?", specifiedUser.ID).
 				Order("id ASC").
 				Find(&otherUsers).Error; err != nil {
 				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch users"})
@@ -459,6 +492,7 @@ type StockInfo struct {
 			// 检查是否有足够的其他用户
 			if len(otherUsers) == 0 {
 				// 没有其他用户，只返回指定的1个
+				broadcastDrawResult(company.ID, records)
 				c.JSON(http.StatusOK, records)
 				return
 			}
@@ -513,28 +547,39 @@ type StockInfo struct {
 		config.DB.Preload("User").Preload("Level").Preload("Prize").First(&records[i], records[i].ID)
 	}
 
+	broadcastDrawResult(company.ID, records)
 	c.JSON(http.StatusOK, records)
 }
 
-// GetMyPrize 获取我的奖品
-func GetMyPrize(c *gin.Context) {
-	phone := c.Query("phone")
-	companyCode := c.Query("company_code")
-
-	if companyCode == "" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "company_code parameter is required"})
+// respondDrawGateError 返回抽奖顺序/奖项状态检查失败的响应
+func respondDrawGateError(c *gin.Context, err error) {
+	if gateErr, ok := err.(*drawGateError); ok {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":      gateErr.Message,
+			"error_code": gateErr.Code,
+		})
 		return
 	}
+	c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check draw order"})
+}
 
-	company, err := getCompanyByCode(companyCode)
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid company code"})
+// respondNoEligibleUsers 返回没有可抽奖用户的响应（开启签到时提示签到情况）
+func respondNoEligibleUsers(c *gin.Context, company *models.Company) {
+	if company.CheckInRequired {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":      "没有已签到且未抽奖的用户",
+			"error_code": "NO_CHECKED_IN_USERS",
+		})
 		return
 	}
+	c.JSON(http.StatusBadRequest, gin.H{"error": "没有可抽奖的用户"})
+}
 
-	var user models.User
-	if err := config.DB.Where("phone = ? AND company_id = ?", phone, company.ID).First(&user).Error; err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+// GetMyPrize 获取我的奖品
+// 用户token查询本人；管理员token需通过 phone 和 company_code 参数指定用户
+func GetMyPrize(c *gin.Context) {
+	user, ok := findCurrentUser(c)
+	if !ok {
 		return
 	}
 
@@ -543,12 +588,23 @@ func GetMyPrize(c *gin.Context) {
 		return
 	}
 
+	// 已作废或放弃的记录不再返回
 	var record models.DrawRecord
-	config.DB.Where("user_id = ? AND company_id = ?", user.ID, company.ID).
+	config.DB.Where("user_id = ? AND company_id = ? AND status NOT IN ?", user.ID, user.CompanyID, models.DrawStatusesReleased()).
 		Preload("Level").
 		Preload("Prize").
+		Preload("Variant").
+		Order("id DESC").
 		First(&record)
 
+	// 虚拟奖品：返回分配的兑换码
+	if record.Prize.CodePool && prizeCodeVisible(record.Status) {
+		record.PrizeCode = prizeCodeForRecord(record.ID)
+	}
+
+	// 实物奖品：返回现场领奖核销二维码
+	attachRedemptionQR(&record)
+
 	c.JSON(http.StatusOK, record)
 }
 
@@ -617,7 +673,7 @@ func GetDrawRecordsPublic(c *gin.Context) {
 	c.JSON(http.StatusOK, records)
 }
 
-// GetAvailableUsersPublic 获取未抽奖的用户列表（公开API）
+// GetAvailableUsersPublic 获取可参与抽奖的用户列表（公开API，开启签到时只返回已签到用户）
 func GetAvailableUsersPublic(c *gin.Context) {
 	companyCode := c.Query("company_code")
 	if companyCode == "" {
@@ -631,8 +687,14 @@ func GetAvailableUsersPublic(c *gin.Context) {
 		return
 	}
 
+	filters := utils.FieldFiltersFromQuery(c.Request.URL.Query())
+	if err := utils.ValidateFieldFilters(filters); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
 	var users []models.User
-	config.DB.Where("company_id = ? AND has_drawn = ?", company.ID, false).
+	eligibleUsersQuery(company, filters).
 		Order("id ASC").
 		Find(&users)
 
@@ -75,6 +75,26 @@ func LogOperation(c *gin.Context, action, resource string, resourceID *uint, det
 	}
 }
 
+// systemAdminName 后台任务操作日志的操作人名称（管理员ID为0）
+const systemAdminName = "system"
+
+// LogSystemOperation 记录后台任务（定时同步、定时清理等）的操作日志
+func LogSystemOperation(companyID int, action, resource string, resourceID *uint, details string) {
+	cid := uint(companyID)
+	log := models.OperationLog{
+		AdminName:  systemAdminName,
+		CompanyID:  &cid,
+		Action:     action,
+		Resource:   resource,
+		ResourceID: resourceID,
+		Details:    details,
+	}
+	if err := config.DB.Create(&log).Error; err != nil {
+		fmt.Printf("[LogOperation Error] Failed to create log: %v, Action: %s, Resource: %s, AdminID: 0\n",
+			err, action, resource)
+	}
+}
+
 // GetOperationLogs 获取操作日志（分页）
 func GetOperationLogs(c *gin.Context) {
 	// 分页参数
@@ -0,0 +1,340 @@
+package handlers
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"lottery-system/config"
+	"lottery-system/models"
+	"lottery-system/namematch"
+	"lottery-system/utils"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// loginLinkTTL 一次性登录链接有效期
+const loginLinkTTL = 15 * time.Minute
+
+// ParticipantLoginRequest 参与者验证码登录请求
+type ParticipantLoginRequest struct {
+	Phone string `json:"phone" binding:"required"`
+	Code  string `json:"code" binding:"required"`
+}
+
+// ParticipantLinkLoginRequest 参与者登录链接请求
+type ParticipantLinkLoginRequest struct {
+	Token string `json:"token" binding:"required"`
+}
+
+// UpdateMyProfileRequest 参与者修改个人资料请求
+type UpdateMyProfileRequest struct {
+	Name   *string           `json:"name"`   // 抽奖后不能修改
+	Fields map[string]string `json:"fields"` // 报名表单字段（传入时整体替换）
+}
+
+// SendParticipantLoginOTP 向已报名的手机号发送登录验证码
+// 手机号未报名时同样返回成功（不发送短信），避免暴露报名名单
+func SendParticipantLoginOTP(c *gin.Context) {
+	company, phone, ok := bindParticipantPhone(c)
+	if !ok {
+		return
+	}
+
+	sender, ok := smsSender(c)
+	if !ok {
+		return
+	}
+
+	store, ok := otpStore(c)
+	if !ok || !checkOTPSendLimits(c, store, phone) {
+		return
+	}
+
+	ttl := otpTTL()
+	if user, found := findParticipantByPhone(company.ID, phone); found {
+		code, err := utils.GenerateOTPCode()
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "生成验证码失败"})
+			return
+		}
+		message := fmt.Sprintf("【%s】您的登录验证码为 %s，%d 分钟内有效。如非本人操作请忽略。",
+			company.Name, code, int(ttl/time.Minute))
+		if !deliverOTP(c, sender, store, participantLoginOTPKey(company.ID, user.Phone), code, ttl, user.Phone, message) {
+			return
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":      "如该手机号已报名，将收到登录验证码",
+		"expires_in":   int(ttl / time.Second),
+		"resend_after": int(otpResendInterval / time.Second),
+	})
+}
+
+// ParticipantLoginByOTP 参与者使用手机号和验证码登录，签发参与者范围的token
+func ParticipantLoginByOTP(c *gin.Context) {
+	companyCode := c.Query("company_code")
+	company, err := getCompanyByCode(companyCode)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid company code"})
+		return
+	}
+
+	var req ParticipantLoginRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "请求参数格式错误"})
+		return
+	}
+	req.Phone = strings.TrimSpace(req.Phone)
+	if err := utils.ValidatePhone(req.Phone); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	store, ok := otpStore(c)
+	if !ok || !checkOTPVerifyLimit(c, store) {
+		return
+	}
+	if !checkOTPCode(c, store, participantLoginOTPKey(company.ID, req.Phone), strings.TrimSpace(req.Code), req.Phone) {
+		return
+	}
+
+	user, found := findParticipantByPhone(company.ID, req.Phone)
+	if !found {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return
+	}
+	respondParticipantLogin(c, user, "otp")
+}
+
+// SendParticipantLoginLink 向已报名的手机号发送一次性登录链接（需配置 PUBLIC_BASE_URL）
+// 链接地址只使用配置的前端地址，不信任请求的 Host 头，防止链接被篡改为外部站点
+func SendParticipantLoginLink(c *gin.Context) {
+	baseURL := config.AppConfig.PublicBaseURL
+	if baseURL == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "未开启登录链接，请使用验证码登录", "error_code": "LOGIN_LINK_DISABLED"})
+		return
+	}
+
+	company, phone, ok := bindParticipantPhone(c)
+	if !ok {
+		return
+	}
+
+	sender, ok := smsSender(c)
+	if !ok {
+		return
+	}
+
+	store, ok := otpStore(c)
+	if !ok || !checkOTPSendLimits(c, store, phone) {
+		return
+	}
+
+	if user, found := findParticipantByPhone(company.ID, phone); found {
+		secret := make([]byte, 24)
+		if _, err := rand.Read(secret); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "生成登录链接失败"})
+			return
+		}
+		code := base64.RawURLEncoding.EncodeToString(secret)
+		token := fmt.Sprintf("%d.%s", user.ID, code)
+
+		link := fmt.Sprintf("%s/#/participant-login?company_code=%s&token=%s",
+			baseURL, url.QueryEscape(company.Code), url.QueryEscape(token))
+		message := fmt.Sprintf("【%s】点击链接登录查看中奖结果：%s （%d 分钟内有效，仅可使用一次）",
+			company.Name, link, int(loginLinkTTL/time.Minute))
+		if !deliverOTP(c, sender, store, participantLoginLinkKey(user.ID), code, loginLinkTTL, user.Phone, message) {
+			return
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":      "如该手机号已报名，将收到登录链接",
+		"expires_in":   int(loginLinkTTL / time.Second),
+		"resend_after": int(otpResendInterval / time.Second),
+	})
+}
+
+// ParticipantLoginByLink 使用一次性登录链接中的 token 登录
+func ParticipantLoginByLink(c *gin.Context) {
+	var req ParticipantLinkLoginRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "请求参数格式错误"})
+		return
+	}
+
+	parts := strings.SplitN(strings.TrimSpace(req.Token), ".", 2)
+	userID, err := strconv.Atoi(parts[0])
+	if len(parts) != 2 || err != nil || userID <= 0 || parts[1] == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "登录链接无效", "error_code": "OTP_INVALID"})
+		return
+	}
+
+	store, ok := otpStore(c)
+	if !ok || !checkOTPVerifyLimit(c, store) {
+		return
+	}
+
+	var user models.User
+	if err := config.DB.First(&user, userID).Error; err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "登录链接无效", "error_code": "OTP_INVALID"})
+		return
+	}
+	if !checkOTPCode(c, store, participantLoginLinkKey(user.ID), parts[1], user.Phone) {
+		return
+	}
+	respondParticipantLogin(c, &user, "link")
+}
+
+// UpdateMyProfile 参与者修改本人的姓名和报名表单字段（用户端）
+func UpdateMyProfile(c *gin.Context) {
+	if isAdmin, _ := c.Get("is_admin"); isAdmin == true {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "管理员请在后台修改用户信息"})
+		return
+	}
+	user, ok := findCurrentUser(c)
+	if !ok {
+		return
+	}
+
+	var req UpdateMyProfileRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "请求参数格式错误"})
+		return
+	}
+
+	updates := map[string]interface{}{}
+	if req.Name != nil {
+		name := strings.TrimSpace(*req.Name)
+		if err := utils.ValidateName(name); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "姓名格式错误: " + err.Error()})
+			return
+		}
+		if name != user.Name {
+			if user.HasDrawn {
+				c.JSON(http.StatusConflict, gin.H{"error": "已参与抽奖，不能修改姓名", "error_code": "NAME_LOCKED"})
+				return
+			}
+			updates["name"] = name
+			updates["name_key"], updates["name_pinyin"] = namematch.NameKeys(name)
+		}
+	}
+
+	var answers map[string]string
+	if req.Fields != nil {
+		var err error
+		answers, err = utils.ValidateFormAnswers(activeRegistrationFields(user.CompanyID), req.Fields)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error(), "error_code": "INVALID_FORM_FIELD"})
+			return
+		}
+	}
+
+	if len(updates) == 0 && req.Fields == nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "没有需要更新的内容"})
+		return
+	}
+
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		if len(updates) > 0 {
+			if err := tx.Model(user).Updates(updates).Error; err != nil {
+				return err
+			}
+		}
+		if req.Fields != nil {
+			return utils.SaveUserFieldValues(tx, user.CompanyID, user.ID, answers)
+		}
+		return nil
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "更新个人资料失败"})
+		return
+	}
+
+	utils.WithFields(map[string]interface{}{
+		"user_id":    user.ID,
+		"company_id": user.CompanyID,
+		"ip":         c.ClientIP(),
+	}).Info("参与者修改个人资料")
+
+	config.DB.First(user, user.ID)
+	users := []models.User{*user}
+	utils.AttachUserFields(config.DB, users)
+	c.JSON(http.StatusOK, gin.H{"user": users[0]})
+}
+
+// bindParticipantPhone 读取公司和请求中的手机号
+func bindParticipantPhone(c *gin.Context) (*models.Company, string, bool) {
+	company, err := getCompanyByCode(c.Query("company_code"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid company code"})
+		return nil, "", false
+	}
+
+	var req SendOTPRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "请求参数格式错误"})
+		return nil, "", false
+	}
+	phone := strings.TrimSpace(req.Phone)
+	if err := utils.ValidatePhone(phone); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return nil, "", false
+	}
+	return company, phone, true
+}
+
+// findParticipantByPhone 按手机号查找公司的参与者（同一手机号多条记录时取最早报名的）
+func findParticipantByPhone(companyID int, phone string) (*models.User, bool) {
+	var user models.User
+	if err := config.DB.Where("company_id = ? AND phone_hash = ?", companyID, models.PhoneIndex(phone)).Order("id ASC").First(&user).Error; err != nil {
+		return nil, false
+	}
+	return &user, true
+}
+
+// respondParticipantLogin 签发参与者范围的token并返回登录结果
+func respondParticipantLogin(c *gin.Context, user *models.User, method string) {
+	hours := config.AppConfig.ParticipantTokenHours
+	if hours <= 0 {
+		hours = 24
+	}
+	token, expiresAt, err := utils.GenerateParticipantToken(user.ID, user.Username, config.AppConfig.JWTSecret, time.Duration(hours)*time.Hour)
+	if err != nil {
+		utils.WithFields(map[string]interface{}{"error": err}).Error("生成参与者token失败")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "生成token失败"})
+		return
+	}
+
+	utils.WithFields(map[string]interface{}{
+		"user_id":    user.ID,
+		"company_id": user.CompanyID,
+		"method":     method,
+		"ip":         c.ClientIP(),
+	}).Info("参与者登录成功")
+
+	c.JSON(http.StatusOK, gin.H{
+		"token":      token,
+		"expires_at": expiresAt,
+		"user":       user,
+		"user_type":  utils.TokenScopeParticipant,
+	})
+}
+
+// participantLoginOTPKey 参与者登录验证码存储键
+func participantLoginOTPKey(companyID int, phone string) string {
+	return fmt.Sprintf("login:%d:%s", companyID, phone)
+}
+
+// participantLoginLinkKey 一次性登录链接存储键
+func participantLoginLinkKey(userID int) string {
+	return fmt.Sprintf("login-link:%d", userID)
+}
@@ -0,0 +1,323 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"strings"
+	"time"
+
+	"lottery-system/config"
+	"lottery-system/models"
+	"lottery-system/otp"
+	"lottery-system/sms"
+	"lottery-system/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// 短信验证码限制（报名验证和参与者登录共用）
+const (
+	otpResendInterval    = time.Minute      // 同一手机号两次发送的最短间隔
+	otpPhoneHourlyLimit  = 5                // 同一手机号每小时最多发送次数
+	otpIPHourlyLimit     = 20               // 同一 IP 每小时最多发送次数
+	otpVerifyIPLimit     = 30               // 同一 IP 每小时最多校验次数
+	otpMaxAttempts       = 5                // 每个验证码最多尝试次数
+	phoneVerificationTTL = 30 * time.Minute // 验证通过后提交报名的有效期
+)
+
+// SendOTPRequest 发送报名验证码请求
+type SendOTPRequest struct {
+	Phone     string           `json:"phone" binding:"required"`
+	Challenge *ChallengeAnswer `json:"challenge"` // 人机验证答案（报名验证码，公司开启人机验证时必填）
+}
+
+// VerifyOTPRequest 校验报名验证码请求
+type VerifyOTPRequest struct {
+	Phone string `json:"phone" binding:"required"`
+	Code  string `json:"code" binding:"required"`
+}
+
+// SendRegistrationOTP 向报名手机号发送短信验证码（公司开启报名手机验证时可用）
+// 同一手机号 60 秒内只能发送一次、每小时最多 5 次，同一 IP 每小时最多 20 次
+func SendRegistrationOTP(c *gin.Context) {
+	company, ok := loadOTPCompany(c)
+	if !ok {
+		return
+	}
+
+	var req SendOTPRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "请求参数格式错误"})
+		return
+	}
+	req.Phone = strings.TrimSpace(req.Phone)
+	if err := utils.ValidatePhone(req.Phone); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// 报名不可用时不发送短信
+	if info := registrationInfo(config.DB, company, time.Now()); info.Status != models.RegistrationStatusOpen &&
+		info.Status != models.RegistrationStatusWaitlist {
+		respondRegistrationBlocked(c, company, info.Status)
+		return
+	}
+
+	sender, ok := smsSender(c)
+	if !ok {
+		return
+	}
+
+	// 开启人机验证时发送短信前也需验证，防止批量消耗短信
+	if !checkRegistrationChallenge(c, company, req.Challenge) {
+		return
+	}
+
+	store, ok := otpStore(c)
+	if !ok || !checkOTPSendLimits(c, store, req.Phone) {
+		return
+	}
+
+	code, err := utils.GenerateOTPCode()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "生成验证码失败"})
+		return
+	}
+
+	ttl := otpTTL()
+	message := fmt.Sprintf("【%s】您的报名验证码为 %s，%d 分钟内有效。如非本人操作请忽略。",
+		company.Name, code, int(ttl/time.Minute))
+	if !deliverOTP(c, sender, store, registrationOTPKey(company.ID, req.Phone), code, ttl, req.Phone, message) {
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":      "验证码已发送",
+		"expires_in":   int(ttl / time.Second),
+		"resend_after": int(otpResendInterval / time.Second),
+	})
+}
+
+// VerifyRegistrationOTP 校验报名验证码，成功后返回手机号验证凭证（报名时作为 phone_token 提交）
+func VerifyRegistrationOTP(c *gin.Context) {
+	company, ok := loadOTPCompany(c)
+	if !ok {
+		return
+	}
+
+	var req VerifyOTPRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "请求参数格式错误"})
+		return
+	}
+	req.Phone = strings.TrimSpace(req.Phone)
+	req.Code = strings.TrimSpace(req.Code)
+	if err := utils.ValidatePhone(req.Phone); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	store, ok := otpStore(c)
+	if !ok || !checkOTPVerifyLimit(c, store) {
+		return
+	}
+	if !checkOTPCode(c, store, registrationOTPKey(company.ID, req.Phone), req.Code, req.Phone) {
+		return
+	}
+
+	expiresAt := time.Now().Add(phoneVerificationTTL)
+	c.JSON(http.StatusOK, gin.H{
+		"message":     "手机号验证成功",
+		"phone_token": utils.SignPhoneVerification(company.ID, req.Phone, expiresAt, phoneVerificationKey()),
+		"expires_at":  expiresAt,
+	})
+}
+
+// checkPhoneVerified 公司开启报名手机验证时，校验报名请求中的手机号验证凭证
+func checkPhoneVerified(c *gin.Context, company *models.Company, phone, token string) bool {
+	if !company.PhoneOTPRequired {
+		return true
+	}
+	if phone == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "请填写手机号", "error_code": "PHONE_REQUIRED"})
+		return false
+	}
+	if err := utils.VerifyPhoneVerification(token, company.ID, phone, time.Now(), phoneVerificationKey()); err != nil {
+		c.JSON(http.StatusForbidden, gin.H{"error": "请先验证手机号", "error_code": "PHONE_NOT_VERIFIED"})
+		return false
+	}
+	return true
+}
+
+// loadOTPCompany 读取 company_code 对应的公司，并检查是否开启了报名手机验证
+func loadOTPCompany(c *gin.Context) (*models.Company, bool) {
+	companyCode := c.Query("company_code")
+	if companyCode == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "company_code parameter is required"})
+		return nil, false
+	}
+
+	company, err := getCompanyByCode(companyCode)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid company code"})
+		return nil, false
+	}
+	if !company.PhoneOTPRequired {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "该活动未开启手机号验证", "error_code": "OTP_NOT_ENABLED"})
+		return nil, false
+	}
+	return company, true
+}
+
+// smsSender 获取短信发送器，未配置短信驱动时返回服务不可用
+func smsSender(c *gin.Context) (sms.Sender, bool) {
+	sender := sms.Default()
+	if sender == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "短信服务未配置，暂不支持短信验证码", "error_code": "SMS_NOT_CONFIGURED"})
+		return nil, false
+	}
+	return sender, true
+}
+
+// otpStore 获取验证码存储，未初始化时返回服务不可用
+func otpStore(c *gin.Context) (otp.Store, bool) {
+	store := otp.Default()
+	if store == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "验证码服务不可用"})
+		return nil, false
+	}
+	return store, true
+}
+
+// checkOTPSendLimits 检查短信发送频率：同一 IP 每小时次数、同一手机号发送间隔和每小时次数
+func checkOTPSendLimits(c *gin.Context, store otp.Store, phone string) bool {
+	limits := []struct {
+		key    string
+		window time.Duration
+		limit  int
+	}{
+		{"send:ip:" + c.ClientIP(), time.Hour, otpIPHourlyLimit},
+		{"send:resend:" + phone, otpResendInterval, 1},
+		{"send:phone:" + phone, time.Hour, otpPhoneHourlyLimit},
+	}
+	for _, l := range limits {
+		count, ttl, err := store.Incr(c.Request.Context(), l.key, l.window)
+		if err != nil {
+			utils.WithFields(map[string]interface{}{"error": err}).Error("验证码频率计数失败")
+			c.JSON(http.StatusServiceUnavailable, gin.H{"error": "验证码服务不可用"})
+			return false
+		}
+		if count > l.limit {
+			if l.window == time.Hour {
+				utils.NewSecurityLogger().LogRateLimitExceeded("phone_otp", l.key)
+			}
+			respondOTPThrottled(c, ttl)
+			return false
+		}
+	}
+	return true
+}
+
+// checkOTPVerifyLimit 检查同一 IP 每小时校验验证码的次数（防止跨手机号暴力尝试）
+func checkOTPVerifyLimit(c *gin.Context, store otp.Store) bool {
+	count, ttl, err := store.Incr(c.Request.Context(), "verify:ip:"+c.ClientIP(), time.Hour)
+	if err != nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "验证码服务不可用"})
+		return false
+	}
+	if count > otpVerifyIPLimit {
+		utils.NewSecurityLogger().LogRateLimitExceeded("phone_otp_verify", c.ClientIP())
+		respondOTPThrottled(c, ttl)
+		return false
+	}
+	return true
+}
+
+// deliverOTP 保存验证码摘要并发送短信，发送失败时删除验证码
+func deliverOTP(c *gin.Context, sender sms.Sender, store otp.Store, otpKey, code string, ttl time.Duration, phone, message string) bool {
+	ctx := c.Request.Context()
+	if err := store.Save(ctx, otpKey, utils.HashOTPCode(otpKey, code, otpHashKey()), ttl); err != nil {
+		utils.WithFields(map[string]interface{}{"error": err}).Error("保存验证码失败")
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "验证码服务不可用"})
+		return false
+	}
+
+	if err := sender.Send(ctx, phone, message); err != nil {
+		store.Delete(ctx, otpKey)
+		utils.WithFields(map[string]interface{}{
+			"error":   err,
+			"purpose": strings.SplitN(otpKey, ":", 2)[0],
+			"phone":   maskPhone(phone),
+		}).Error("发送短信验证码失败")
+		c.JSON(http.StatusBadGateway, gin.H{"error": "短信发送失败，请稍后重试", "error_code": "SMS_SEND_FAILED"})
+		return false
+	}
+
+	utils.WithFields(map[string]interface{}{
+		"purpose": strings.SplitN(otpKey, ":", 2)[0],
+		"phone":   maskPhone(phone),
+		"ip":      c.ClientIP(),
+	}).Info("已发送短信验证码")
+	return true
+}
+
+// checkOTPCode 校验验证码（一次性），失败时返回对应的错误响应
+func checkOTPCode(c *gin.Context, store otp.Store, otpKey, code, phone string) bool {
+	err := store.Check(c.Request.Context(), otpKey, utils.HashOTPCode(otpKey, code, otpHashKey()), otpMaxAttempts)
+	switch {
+	case err == nil:
+		return true
+	case errors.Is(err, otp.ErrMismatch):
+		c.JSON(http.StatusBadRequest, gin.H{"error": "验证码错误", "error_code": "OTP_INVALID"})
+	case errors.Is(err, otp.ErrTooManyAttempts):
+		utils.NewSecurityLogger().LogSuspiciousActivity("phone_otp_attempts",
+			fmt.Sprintf("验证码错误次数过多: %s", maskPhone(phone)), c.ClientIP())
+		c.JSON(http.StatusBadRequest, gin.H{"error": "验证码错误次数过多，请重新获取", "error_code": "OTP_TOO_MANY_ATTEMPTS"})
+	case errors.Is(err, otp.ErrNotFound):
+		c.JSON(http.StatusBadRequest, gin.H{"error": "验证码已过期，请重新获取", "error_code": "OTP_EXPIRED"})
+	default:
+		utils.WithFields(map[string]interface{}{"error": err}).Error("校验验证码失败")
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "验证码服务不可用"})
+	}
+	return false
+}
+
+// respondOTPThrottled 返回验证码频率限制响应
+func respondOTPThrottled(c *gin.Context, retryAfter time.Duration) {
+	seconds := int(math.Ceil(retryAfter.Seconds()))
+	if seconds < 1 {
+		seconds = 1
+	}
+	c.Header("Retry-After", fmt.Sprint(seconds))
+	c.JSON(http.StatusTooManyRequests, gin.H{
+		"error":       fmt.Sprintf("操作过于频繁，请 %d 秒后重试", seconds),
+		"error_code":  "OTP_THROTTLED",
+		"retry_after": seconds,
+	})
+}
+
+// registrationOTPKey 报名验证码存储键
+func registrationOTPKey(companyID int, phone string) string {
+	return fmt.Sprintf("register:%d:%s", companyID, phone)
+}
+
+// otpTTL 验证码有效期
+func otpTTL() time.Duration {
+	minutes := config.AppConfig.OTPTTLMinutes
+	if minutes <= 0 {
+		minutes = 5
+	}
+	return time.Duration(minutes) * time.Minute
+}
+
+// otpHashKey 验证码摘要密钥
+func otpHashKey() []byte {
+	return utils.DeriveKey("otp:" + config.AppConfig.JWTSecret)
+}
+
+// phoneVerificationKey 手机号验证凭证签名密钥
+func phoneVerificationKey() []byte {
+	return utils.DeriveKey("phone-verified:" + config.AppConfig.JWTSecret)
+}
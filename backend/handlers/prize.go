@@ -3,12 +3,14 @@
 import (
 	"fmt"
 	"net/http"
+	"net/url"
 	"strconv"
 
 	"lottery-system/config"
 	"lottery-system/models"
 
 	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
 )
 
 // CreatePrizeLevel 创建奖项等级（权限检查）
@@ -38,6 +40,21 @@ func CreatePrizeLevel(c *gin.Context) {
 		return
 	}
 
+	// 校验回流目标
+	if err := validateRolloverTarget(&level); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// 新建奖项默认开放抽奖
+	if level.DrawState == "" {
+		level.DrawState = models.LevelDrawStateOpen
+	}
+	if level.DrawState != models.LevelDrawStateOpen && level.DrawState != models.LevelDrawStateClosed {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的奖项抽奖状态"})
+		return
+	}
+
 	// 库存由奖品管理，奖项等级的库存字段设置为0
 	level.TotalStock = 0
 	level.UsedStock = 0
@@ -109,14 +126,23 @@ func UpdatePrizeLevel(c *gin.Context) {
 		return
 	}
 
+	// 校验回流目标
+	req.ID = level.ID
+	req.CompanyID = level.CompanyID
+	if err := validateRolloverTarget(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
 	// 库存由奖品管理，不允许通过此接口修改
-	// 只允许更新名称、描述、概率、排序、状态
+	// 只允许更新名称、描述、概率、排序、状态、回流策略
 	updateData := map[string]interface{}{
-		"name":        req.Name,
-		"description": req.Description,
-		"probability": req.Probability,
-		"sort_order":  req.SortOrder,
-		"is_active":   req.IsActive,
+		"name":              req.Name,
+		"description":       req.Description,
+		"probability":       req.Probability,
+		"sort_order":        req.SortOrder,
+		"is_active":         req.IsActive,
+		"rollover_level_id": req.RolloverLevelID,
 	}
 
 	if err := config.DB.Model(&level).Updates(updateData).Error; err != nil {
@@ -172,6 +198,10 @@ func CreatePrize(c *gin.Context) {
 		return
 	}
 
+	// 兑换码池和多规格只能通过导入兑换码、配置规格开启
+	prize.CodePool = false
+	prize.HasVariants = false
+
 	// 验证奖品名称不为空
 	if prize.Name == "" {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "奖品名称不能为空"})
@@ -202,6 +232,21 @@ func CreatePrize(c *gin.Context) {
 		}
 	}
 
+	// 奖品价值与预算检查
+	if prize.Value < 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "奖品价值不能为负数"})
+		return
+	}
+	prize.Currency = normalizeCurrency(prize.Currency)
+	if !currencyIsValid(prize.Currency) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的币种代码"})
+		return
+	}
+	if err := checkPrizeBudget(config.DB, level.CompanyID, &prize, 0); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error(), "error_code": "BUDGET_EXCEEDED"})
+		return
+	}
+
 	if err := config.DB.Create(&prize).Error; err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create prize"})
 		return
@@ -276,6 +321,16 @@ func UpdatePrize(c *gin.Context) {
 		return
 	}
 
+	// 兑换码池和多规格只能通过导入兑换码、配置规格开启，此接口不修改
+	req.CodePool = false
+	req.HasVariants = false
+
+	// 兑换码池/多规格奖品的库存由兑换码数量或规格库存决定，不允许手动修改
+	if prize.CodePool || prize.HasVariants {
+		req.TotalStock = prize.TotalStock
+		req.UsedStock = prize.UsedStock
+	}
+
 	// 验证库存：总库存必须 >= 已发放
 	if req.TotalStock < req.UsedStock {
 		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("总库存 (%d) 不能小于已发放 (%d)", req.TotalStock, req.UsedStock)})
@@ -299,6 +354,40 @@ func UpdatePrize(c *gin.Context) {
 		}
 	}
 
+	// 奖品价值与预算检查（按修改后的奖品计算）
+	if req.Value < 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "奖品价值不能为负数"})
+		return
+	}
+	if req.Currency != "" {
+		req.Currency = normalizeCurrency(req.Currency)
+		if !currencyIsValid(req.Currency) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "无效的币种代码"})
+			return
+		}
+	}
+	budgetCheck := prize
+	if req.Value > 0 {
+		budgetCheck.Value = req.Value
+	}
+	if req.Currency != "" {
+		budgetCheck.Currency = req.Currency
+	}
+	if req.TotalStock > 0 {
+		budgetCheck.TotalStock = req.TotalStock
+	}
+	targetLevelID := prize.LevelID
+	if req.LevelID != 0 {
+		targetLevelID = req.LevelID
+	}
+	var targetLevel models.PrizeLevel
+	if err := config.DB.First(&targetLevel, targetLevelID).Error; err == nil {
+		if err := checkPrizeBudget(config.DB, targetLevel.CompanyID, &budgetCheck, prize.ID); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error(), "error_code": "BUDGET_EXCEEDED"})
+			return
+		}
+	}
+
 	if err := config.DB.Model(&prize).Updates(req).Error; err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update prize"})
 		return
@@ -397,12 +486,11 @@ func GetAllPrizes(c *gin.Context) {
 func GetDrawRecords(c *gin.Context) {
 	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
 	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "100")) // 默认100条
-	search := c.Query("search")
 	companyIDParam := c.Query("company_id")
 
 	offset := (page - 1) * pageSize
 
-	query := config.DB.Model(&models.DrawRecord{})
+	query := applyDrawRecordFilters(config.DB.Model(&models.DrawRecord{}), c.Request.URL.Query())
 
 	// 检查是否是超级管理员
 	isSuperAdmin, exists := c.Get("is_super_admin")
@@ -413,19 +501,14 @@ func GetDrawRecords(c *gin.Context) {
 			c.JSON(http.StatusForbidden, gin.H{"error": "No company assigned"})
 			return
 		}
-		query = query.Where("company_id = ?", companyID)
+		query = query.Where("draw_records.company_id = ?", companyID)
 	} else {
 		// 超级管理员，可以按公司过滤
 		if companyIDParam != "" {
-			query = query.Where("company_id = ?", companyIDParam)
+			query = query.Where("draw_records.company_id = ?", companyIDParam)
 		}
 	}
 
-	if search != "" {
-		query = query.Joins("JOIN users ON draw_records.user_id = users.id").
-			Where("users.phone LIKE ? OR users.name LIKE ?", "%"+search+"%", "%"+search+"%")
-	}
-
 	var total int64
 	query.Count(&total)
 
@@ -437,13 +520,26 @@ func GetDrawRecords(c *gin.Context) {
 		Find(&records)
 
 	c.JSON(http.StatusOK, gin.H{
-		"data":      records,
+		"data":      newFulfillmentRecords(records),
 		"total":     total,
 		"page":      page,
 		"page_size": pageSize,
 	})
 }
 
+// applyDrawRecordFilters 按查询参数筛选抽奖记录（列表和导出共用）：status、search（姓名模糊匹配或完整手机号）
+// 手机号加密存储，只能按完整手机号查找
+func applyDrawRecordFilters(query *gorm.DB, params url.Values) *gorm.DB {
+	if status := params.Get("status"); status != "" {
+		query = query.Where("draw_records.status = ?", status)
+	}
+	if search := params.Get("search"); search != "" {
+		query = query.Joins("JOIN users ON draw_records.user_id = users.id").
+			Where("users.phone_hash = ? OR users.name LIKE ?", models.PhoneIndex(search), "%"+search+"%")
+	}
+	return query
+}
+
 // GetStats 获取统计数据（权限隔离）
 func GetStats(c *gin.Context) {
 	var totalUsers int64
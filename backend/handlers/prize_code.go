@@ -0,0 +1,283 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"lottery-system/config"
+	"lottery-system/models"
+	"lottery-system/utils"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// ImportPrizeCodesRequest 批量导入兑换码请求
+type ImportPrizeCodesRequest struct {
+	Codes          []string `json:"codes"`           // 兑换码列表
+	Text           string   `json:"text"`            // 或者：每行一个兑换码的文本
+	AlertThreshold *int     `json:"alert_threshold"` // 可选：低余量告警阈值
+}
+
+// PrizeCodePoolStats 兑换码池统计
+type PrizeCodePoolStats struct {
+	PrizeID        int    `json:"prize_id"`
+	PrizeName      string `json:"prize_name"`
+	LevelID        int    `json:"level_id"`
+	Total          int64  `json:"total"`
+	Assigned       int64  `json:"assigned"`
+	Unassigned     int64  `json:"unassigned"`
+	AlertThreshold int    `json:"alert_threshold"`
+	Low            bool   `json:"low"` // 是否低于告警阈值
+}
+
+// ImportPrizeCodes 批量导入奖品兑换码（权限检查），导入后奖品库存等于兑换码数量
+func ImportPrizeCodes(c *gin.Context) {
+	prize, ok := loadPrizeForAdmin(c)
+	if !ok {
+		return
+	}
+
+	if prize.HasVariants {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "多规格奖品不支持兑换码池"})
+		return
+	}
+
+	var req ImportPrizeCodesRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "请求参数格式错误"})
+		return
+	}
+
+	rawCodes := append([]string{}, req.Codes...)
+	if req.Text != "" {
+		rawCodes = append(rawCodes, strings.Split(req.Text, "\n")...)
+	}
+
+	key := prizeCodeKey()
+	seen := make(map[string]bool)
+	var codes []models.PrizeCode
+	duplicates := 0
+	for _, raw := range rawCodes {
+		code := strings.TrimSpace(raw)
+		if code == "" {
+			continue
+		}
+		if len(code) > 200 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "兑换码长度不能超过200个字符"})
+			return
+		}
+
+		hash := utils.PrizeCodeHash(code, key)
+		if seen[hash] {
+			duplicates++
+			continue
+		}
+		seen[hash] = true
+
+		ciphertext, err := utils.EncryptString(code, key)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "兑换码加密失败"})
+			return
+		}
+
+		codes = append(codes, models.PrizeCode{
+			PrizeID:    prize.ID,
+			CodeHash:   hash,
+			Ciphertext: ciphertext,
+			Masked:     utils.MaskPrizeCode(code),
+		})
+	}
+
+	if len(codes) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "没有可导入的兑换码"})
+		return
+	}
+
+	var level models.PrizeLevel
+	if err := config.DB.First(&level, prize.LevelID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Prize level not found"})
+		return
+	}
+
+	imported := 0
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		for i := range codes {
+			var exists int64
+			tx.Model(&models.PrizeCode{}).
+				Where("prize_id = ? AND code_hash = ?", prize.ID, codes[i].CodeHash).
+				Count(&exists)
+			if exists > 0 {
+				duplicates++
+				continue
+			}
+			if err := tx.Create(&codes[i]).Error; err != nil {
+				return err
+			}
+			imported++
+		}
+
+		// 库存 = 已发放 + 未分配的兑换码
+		unassigned := utils.CountUnassignedPrizeCodes(tx, prize.ID)
+		budgetCheck := *prize
+		budgetCheck.TotalStock = prize.UsedStock + int(unassigned)
+		if err := checkPrizeBudget(tx, level.CompanyID, &budgetCheck, prize.ID); err != nil {
+			return err
+		}
+
+		updates := map[string]interface{}{
+			"code_pool":   true,
+			"total_stock": budgetCheck.TotalStock,
+		}
+		if req.AlertThreshold != nil && *req.AlertThreshold >= 0 {
+			updates["code_alert_threshold"] = *req.AlertThreshold
+		}
+		return tx.Model(prize).Updates(updates).Error
+	})
+	var budgetErr *prizeBudgetError
+	if errors.As(err, &budgetErr) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": budgetErr.Error(), "error_code": "BUDGET_EXCEEDED"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "导入兑换码失败"})
+		return
+	}
+
+	resourceID := uint(prize.ID)
+	LogOperation(c, "import_codes", "prize", &resourceID,
+		fmt.Sprintf("导入兑换码: %s，成功%d个，重复%d个", prize.Name, imported, duplicates))
+
+	c.JSON(http.StatusOK, gin.H{
+		"imported":   imported,
+		"duplicates": duplicates,
+		"stats":      prizeCodePoolStats(prize),
+	})
+}
+
+// GetPrizeCodes 获取奖品兑换码列表（脱敏）和统计（权限检查）
+func GetPrizeCodes(c *gin.Context) {
+	prize, ok := loadPrizeForAdmin(c)
+	if !ok {
+		return
+	}
+
+	query := config.DB.Where("prize_id = ?", prize.ID)
+	switch c.Query("status") {
+	case "assigned":
+		query = query.Where("draw_record_id IS NOT NULL")
+	case "unassigned":
+		query = query.Where("draw_record_id IS NULL")
+	}
+
+	var codes []models.PrizeCode
+	query.Order("id ASC").Find(&codes)
+
+	c.JSON(http.StatusOK, gin.H{
+		"stats": prizeCodePoolStats(prize),
+		"codes": codes,
+	})
+}
+
+// GetPrizeCodeAlerts 获取兑换码余量不足的奖品（权限隔离）
+func GetPrizeCodeAlerts(c *gin.Context) {
+	companyID, ok := getScopedCompanyID(c, c.Query("company_id"))
+	if !ok {
+		return
+	}
+
+	var prizes []models.Prize
+	config.DB.Raw(`
+		SELECT p.* FROM prizes p
+		INNER JOIN prize_levels l ON p.level_id = l.id
+		WHERE l.company_id = ? AND p.code_pool = ?
+		ORDER BY l.sort_order ASC, p.id ASC
+	`, companyID, true).Find(&prizes)
+
+	alerts := make([]PrizeCodePoolStats, 0)
+	for i := range prizes {
+		stats := prizeCodePoolStats(&prizes[i])
+		if stats.Low {
+			alerts = append(alerts, stats)
+		}
+	}
+
+	c.JSON(http.StatusOK, alerts)
+}
+
+// loadPrizeForAdmin 根据路由参数加载奖品并检查管理员权限
+func loadPrizeForAdmin(c *gin.Context) (*models.Prize, bool) {
+	id := c.Param("id")
+
+	var prize models.Prize
+	if err := config.DB.First(&prize, id).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Prize not found"})
+		return nil, false
+	}
+
+	var level models.PrizeLevel
+	if err := config.DB.First(&level, prize.LevelID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Prize level not found"})
+		return nil, false
+	}
+
+	if !canAccessCompany(c, level.CompanyID) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Permission denied"})
+		return nil, false
+	}
+
+	return &prize, true
+}
+
+// prizeCodePoolStats 统计奖品的兑换码池
+func prizeCodePoolStats(prize *models.Prize) PrizeCodePoolStats {
+	var total int64
+	config.DB.Model(&models.PrizeCode{}).Where("prize_id = ?", prize.ID).Count(&total)
+	unassigned := utils.CountUnassignedPrizeCodes(config.DB, prize.ID)
+
+	return PrizeCodePoolStats{
+		PrizeID:        prize.ID,
+		PrizeName:      prize.Name,
+		LevelID:        prize.LevelID,
+		Total:          total,
+		Assigned:       total - unassigned,
+		Unassigned:     unassigned,
+		AlertThreshold: prize.CodeAlertThreshold,
+		Low:            unassigned <= int64(prize.CodeAlertThreshold),
+	}
+}
+
+// prizeCodeForRecord 解密抽奖记录分配到的兑换码，未分配时返回空字符串
+func prizeCodeForRecord(recordID int) string {
+	var code models.PrizeCode
+	if err := config.DB.Where("draw_record_id = ?", recordID).First(&code).Error; err != nil {
+		return ""
+	}
+
+	plaintext, err := utils.DecryptString(code.Ciphertext, prizeCodeKey())
+	if err != nil {
+		utils.WithFields(map[string]interface{}{
+			"error":          err,
+			"prize_code_id":  code.ID,
+			"draw_record_id": recordID,
+		}).Error("兑换码解密失败")
+		return ""
+	}
+	return plaintext
+}
+
+// prizeCodeVisible 中奖记录处于该状态时是否向中奖者返回兑换码
+func prizeCodeVisible(status string) bool {
+	switch status {
+	case models.DrawStatusWon, models.DrawStatusClaimed, models.DrawStatusCompleted:
+		return true
+	}
+	return false
+}
+
+// prizeCodeKey 获取兑换码加密密钥
+func prizeCodeKey() []byte {
+	return config.PrizeCodeKey()
+}
@@ -0,0 +1,365 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"lottery-system/config"
+	"lottery-system/models"
+	"lottery-system/utils"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// PrizeVariantRequest 创建/更新奖品规格请求
+type PrizeVariantRequest struct {
+	Name       string `json:"name"`
+	TotalStock int    `json:"total_stock"`
+	SortOrder  int    `json:"sort_order"`
+}
+
+// ChooseVariantRequest 中奖者选择规格请求
+type ChooseVariantRequest struct {
+	VariantID int `json:"variant_id" binding:"required"`
+}
+
+// CreatePrizeVariant 为奖品添加规格（权限检查），奖品库存同步为各规格库存之和
+func CreatePrizeVariant(c *gin.Context) {
+	prize, ok := loadPrizeForAdmin(c)
+	if !ok {
+		return
+	}
+
+	if prize.CodePool {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "兑换码奖品不支持设置规格"})
+		return
+	}
+
+	var req PrizeVariantRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "请求参数格式错误"})
+		return
+	}
+	if req.Name == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "规格名称不能为空"})
+		return
+	}
+	if req.TotalStock < 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "库存不能为负数"})
+		return
+	}
+
+	variant := models.PrizeVariant{
+		PrizeID:    prize.ID,
+		Name:       req.Name,
+		TotalStock: req.TotalStock,
+		SortOrder:  req.SortOrder,
+	}
+
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&variant).Error; err != nil {
+			return err
+		}
+		return syncPrizeVariantStock(tx, prize.ID)
+	})
+	if err != nil {
+		c.JSON(http.StatusBadRequest, prizeStockErrorResponse("添加规格失败", err))
+		return
+	}
+
+	resourceID := uint(prize.ID)
+	LogOperation(c, "create", "prize_variant", &resourceID, fmt.Sprintf("添加奖品规格: %s - %s (库存: %d)", prize.Name, variant.Name, variant.TotalStock))
+
+	c.JSON(http.StatusCreated, variant)
+}
+
+// GetPrizeVariants 获取奖品的规格列表（权限检查）
+func GetPrizeVariants(c *gin.Context) {
+	prize, ok := loadPrizeForAdmin(c)
+	if !ok {
+		return
+	}
+
+	var variants []models.PrizeVariant
+	config.DB.Where("prize_id = ?", prize.ID).Order("sort_order ASC, id ASC").Find(&variants)
+
+	c.JSON(http.StatusOK, variants)
+}
+
+// UpdatePrizeVariant 更新奖品规格（权限检查）
+func UpdatePrizeVariant(c *gin.Context) {
+	variant, prize, ok := loadVariantForAdmin(c)
+	if !ok {
+		return
+	}
+
+	var req PrizeVariantRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "请求参数格式错误"})
+		return
+	}
+	if req.Name == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "规格名称不能为空"})
+		return
+	}
+	if req.TotalStock < variant.UsedStock {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("总库存 (%d) 不能小于已分配 (%d)", req.TotalStock, variant.UsedStock)})
+		return
+	}
+
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(variant).Updates(map[string]interface{}{
+			"name":        req.Name,
+			"total_stock": req.TotalStock,
+			"sort_order":  req.SortOrder,
+		}).Error; err != nil {
+			return err
+		}
+		return syncPrizeVariantStock(tx, prize.ID)
+	})
+	if err != nil {
+		c.JSON(http.StatusBadRequest, prizeStockErrorResponse("更新规格失败", err))
+		return
+	}
+
+	resourceID := uint(prize.ID)
+	LogOperation(c, "update", "prize_variant", &resourceID, fmt.Sprintf("更新奖品规格: %s - %s", prize.Name, variant.Name))
+
+	c.JSON(http.StatusOK, variant)
+}
+
+// DeletePrizeVariant 删除奖品规格（权限检查），已被选择的规格不能删除
+func DeletePrizeVariant(c *gin.Context) {
+	variant, prize, ok := loadVariantForAdmin(c)
+	if !ok {
+		return
+	}
+
+	if variant.UsedStock > 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "该规格已被中奖者选择，无法删除"})
+		return
+	}
+
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Delete(variant).Error; err != nil {
+			return err
+		}
+		return syncPrizeVariantStock(tx, prize.ID)
+	})
+	if err != nil {
+		c.JSON(http.StatusBadRequest, prizeStockErrorResponse("删除规格失败", err))
+		return
+	}
+
+	resourceID := uint(prize.ID)
+	LogOperation(c, "delete", "prize_variant", &resourceID, fmt.Sprintf("删除奖品规格: %s - %s", prize.Name, variant.Name))
+
+	c.JSON(http.StatusOK, gin.H{"message": "Prize variant deleted successfully"})
+}
+
+// GetMyPrizeVariants 获取我的奖品可选规格（用户端）
+func GetMyPrizeVariants(c *gin.Context) {
+	record, ok := findMyDrawRecord(c)
+	if !ok {
+		return
+	}
+
+	var variants []models.PrizeVariant
+	config.DB.Where("prize_id = ?", record.PrizeID).Order("sort_order ASC, id ASC").Find(&variants)
+
+	type variantOption struct {
+		models.PrizeVariant
+		Available bool `json:"available"`
+	}
+	options := make([]variantOption, len(variants))
+	for i, variant := range variants {
+		options[i] = variantOption{
+			PrizeVariant: variant,
+			Available:    variant.UsedStock < variant.TotalStock,
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"draw_record_id": record.ID,
+		"variant_id":     record.VariantID,
+		"deadline":       record.VariantDeadline,
+		"variants":       options,
+	})
+}
+
+// ChooseMyPrizeVariant 中奖者在截止时间内选择奖品规格（用户端）
+func ChooseMyPrizeVariant(c *gin.Context) {
+	record, ok := findMyDrawRecord(c)
+	if !ok {
+		return
+	}
+
+	var req ChooseVariantRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "请求参数格式错误"})
+		return
+	}
+
+	if record.VariantID != nil {
+		c.JSON(http.StatusConflict, gin.H{"error": "您已选择过规格", "error_code": "VARIANT_ALREADY_CHOSEN"})
+		return
+	}
+	if record.VariantDeadline == nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "该奖品无需选择规格"})
+		return
+	}
+	if time.Now().After(*record.VariantDeadline) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "已超过规格选择截止时间", "error_code": "VARIANT_DEADLINE_PASSED"})
+		return
+	}
+
+	var variant models.PrizeVariant
+	if err := config.DB.Where("id = ? AND prize_id = ?", req.VariantID, record.PrizeID).First(&variant).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "规格不存在"})
+		return
+	}
+
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		return utils.AssignVariant(tx, record.ID, variant.ID, false)
+	})
+	switch err {
+	case nil:
+	case utils.ErrVariantOutOfStock:
+		c.JSON(http.StatusConflict, gin.H{"error": "该规格已无库存，请选择其他规格", "error_code": "VARIANT_OUT_OF_STOCK"})
+		return
+	case utils.ErrVariantAlreadyChosen:
+		c.JSON(http.StatusConflict, gin.H{"error": "您已选择过规格", "error_code": "VARIANT_ALREADY_CHOSEN"})
+		return
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "选择规格失败"})
+		return
+	}
+
+	utils.WithFields(map[string]interface{}{
+		"draw_record_id": record.ID,
+		"user_id":        record.UserID,
+		"variant_id":     variant.ID,
+	}).Info("中奖者选择奖品规格")
+
+	config.DB.Preload("Level").Preload("Prize").Preload("Variant").First(record, record.ID)
+	c.JSON(http.StatusOK, record)
+}
+
+// findMyDrawRecord 查找当前用户的中奖记录
+// 用户token使用自身身份；管理员token需通过 phone 和 company_code 参数指定中奖者
+func findMyDrawRecord(c *gin.Context) (*models.DrawRecord, bool) {
+	query := config.DB.Model(&models.DrawRecord{})
+
+	isAdmin, _ := c.Get("is_admin")
+	if isAdmin == true {
+		company, err := getCompanyByCode(c.Query("company_code"))
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid company code"})
+			return nil, false
+		}
+		if !canAccessCompany(c, company.ID) {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Permission denied"})
+			return nil, false
+		}
+
+		var user models.User
+		if err := config.DB.Where("phone_hash = ? AND company_id = ?", models.PhoneIndex(c.Query("phone")), company.ID).First(&user).Error; err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+			return nil, false
+		}
+		query = query.Where("user_id = ? AND company_id = ?", user.ID, company.ID)
+	} else {
+		userID, exists := c.Get("user_id")
+		if !exists {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "未登录"})
+			return nil, false
+		}
+		query = query.Where("user_id = ?", userID)
+	}
+
+	var record models.DrawRecord
+	if err := query.Where("status NOT IN ?", models.DrawStatusesReleased()).
+		Preload("Prize").
+		Order("id DESC").
+		First(&record).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "未找到中奖记录"})
+		return nil, false
+	}
+
+	return &record, true
+}
+
+// loadVariantForAdmin 根据路由参数加载规格及其奖品并检查管理员权限
+func loadVariantForAdmin(c *gin.Context) (*models.PrizeVariant, *models.Prize, bool) {
+	id := c.Param("id")
+
+	var variant models.PrizeVariant
+	if err := config.DB.First(&variant, id).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "规格不存在"})
+		return nil, nil, false
+	}
+
+	var prize models.Prize
+	if err := config.DB.First(&prize, variant.PrizeID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Prize not found"})
+		return nil, nil, false
+	}
+
+	var level models.PrizeLevel
+	if err := config.DB.First(&level, prize.LevelID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Prize level not found"})
+		return nil, nil, false
+	}
+
+	if !canAccessCompany(c, level.CompanyID) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Permission denied"})
+		return nil, nil, false
+	}
+
+	return &variant, &prize, true
+}
+
+// syncPrizeVariantStock 将奖品库存同步为各规格库存之和
+func syncPrizeVariantStock(tx *gorm.DB, prizeID int) error {
+	var prize models.Prize
+	if err := tx.First(&prize, prizeID).Error; err != nil {
+		return err
+	}
+
+	var stock struct {
+		Count      int64
+		TotalStock int
+	}
+	if err := tx.Model(&models.PrizeVariant{}).
+		Where("prize_id = ?", prizeID).
+		Select("COUNT(*) as count, COALESCE(SUM(total_stock), 0) as total_stock").
+		Scan(&stock).Error; err != nil {
+		return err
+	}
+
+	if stock.Count == 0 {
+		// 删除全部规格后恢复为普通奖品，保留当前库存
+		return tx.Model(&prize).Update("has_variants", false).Error
+	}
+
+	if stock.TotalStock < prize.UsedStock {
+		return fmt.Errorf("规格库存合计 (%d) 不能小于奖品已发放 (%d)", stock.TotalStock, prize.UsedStock)
+	}
+
+	var level models.PrizeLevel
+	if err := tx.First(&level, prize.LevelID).Error; err != nil {
+		return err
+	}
+	budgetCheck := prize
+	budgetCheck.TotalStock = stock.TotalStock
+	if err := checkPrizeBudget(tx, level.CompanyID, &budgetCheck, prize.ID); err != nil {
+		return err
+	}
+
+	return tx.Model(&prize).Updates(map[string]interface{}{
+		"has_variants": true,
+		"total_stock":  stock.TotalStock,
+	}).Error
+}
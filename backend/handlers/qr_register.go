@@ -3,16 +3,17 @@
 import (
 	"encoding/base64"
 	"fmt"
-	"math/rand"
 	"net/http"
 	"time"
 
 	"lottery-system/config"
 	"lottery-system/models"
+	"lottery-system/namematch"
 	"lottery-system/utils"
 
 	"github.com/gin-gonic/gin"
 	"github.com/skip2/go-qrcode"
+	"gorm.io/gorm"
 )
 
 // GetRegisterQRCode 生成用户注册二维码
@@ -111,16 +112,41 @@ func UserSelfRegister(c *gin.Context) {
 		return
 	}
 
-	// 检查用户是否已存在（根据姓名和手机号）
+	// 开启人机验证时，必须提交有效的挑战答案
+	if !checkRegistrationChallenge(c, company, req.Challenge) {
+		return
+	}
+
+	// 开启报名手机验证时，手机号必须已通过短信验证码验证
+	if !checkPhoneVerified(c, company, req.Phone, req.PhoneToken) {
+		return
+	}
+
+	// 校验公司自定义的报名表单字段
+	answers, err := utils.ValidateFormAnswers(activeRegistrationFields(company.ID), req.Fields)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error(), "error_code": "INVALID_FORM_FIELD"})
+		return
+	}
+
+	// 检查用户是否已存在（根据姓名和手机号，姓名按繁简体、全半角、空格统一后比较）
 	var existingUser models.User
-	query := config.DB.Where("company_id = ? AND name = ?", company.ID, req.Name)
+	nameKey, _ := namematch.NameKeys(req.Name)
+	query := config.DB.Where("company_id = ? AND (name = ? OR name_key = ?)", company.ID, req.Name, nameKey)
 	if req.Phone != "" {
-		query = query.Where("phone = ?", req.Phone)
+		query = query.Where("phone_hash = ?", models.PhoneIndex(req.Phone))
 	}
 
 	if err := query.First(&existingUser).Error; err == nil {
 		// 用户已存在
-		if existingUser.HasDrawn {
+		if existingUser.Waitlisted {
+			c.JSON(http.StatusConflict, gin.H{
+				"error":      "您已在候补名单中，有空位时将自动加入抽奖池",
+				"error_code": "ALREADY_WAITLISTED",
+				"user":       existingUser,
+			})
+			return
+		} else if existingUser.HasDrawn {
 			c.JSON(http.StatusConflict, gin.H{
 				"error": "该用户已经抽过奖",
 				"user":  existingUser,
@@ -135,41 +161,101 @@ func UserSelfRegister(c *gin.Context) {
 		}
 	}
 
-	// 生成用户名：优先使用手机号，否则使用 "u_" + 时间戳 + 随机数
-	username := ""
-	if req.Phone != "" {
-		username = req.Phone
-	} else {
-		username = fmt.Sprintf("u_%d_%d", time.Now().Unix(), rand.Intn(10000))
+	// 手机号相同且姓名同音或相近（如错别字）时视为重复报名
+	if findLikelyDuplicate(company.ID, req.Name, req.Phone) != nil {
+		c.JSON(http.StatusConflict, gin.H{"error": "该手机号已报名，请勿重复报名", "error_code": "DUPLICATE_REGISTRATION"})
+		return
 	}
 
-	// 创建新用户（扫码注册，无法登录）
+	// 创建新用户（扫码注册，无法登录；用户名创建后自动生成 u_<ID>）
 	user := models.User{
-		Username:  username, // 自动生成用户名（用于唯一标识）
-		Password:  "",       // 空密码，无法登录
+		Fields:    answers,
+		Password:  "", // 空密码，无法登录
 		Name:      req.Name,
 		Phone:     req.Phone,
 		CompanyID: company.ID,
 		HasDrawn:  false,
 		Role:      models.RoleUser,
 	}
+	source := newRegistrationSource(c, req.DeviceID)
+	user.RegisteredIP = source.IP
+	user.DeviceHash = source.DeviceHash
+
+	// 检查报名时间、关闭开关和人数上限，满员且开启候补时进入候补名单
+	blockedBy := ""
+	err = config.DB.Transaction(func(tx *gorm.DB) error {
+		waitlisted, blocked, err := admitSelfRegistration(tx, company.ID)
+		if err != nil || blocked != "" {
+			blockedBy = blocked
+			return err
+		}
+		user.Waitlisted = waitlisted
 
-	if err := config.DB.Create(&user).Error; err != nil {
+		// 同一 IP/设备短时间内批量报名时标记待审核（仍可参与抽奖，由管理员决定是否拒绝）
+		reason, err := flagBurstRegistration(tx, company.ID, source, time.Now())
+		if err != nil {
+			return err
+		}
+		if reason != "" {
+			user.ReviewStatus = models.ReviewStatusFlagged
+			user.ReviewReason = reason
+		}
+		if err := tx.Create(&user).Error; err != nil {
+			return err
+		}
+		return utils.SaveUserFieldValues(tx, company.ID, user.ID, answers)
+	})
+	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "创建用户失败"})
 		return
 	}
+	if blockedBy != "" {
+		respondRegistrationBlocked(c, company, blockedBy)
+		return
+	}
+	if user.ReviewStatus == models.ReviewStatusFlagged {
+		utils.NewSecurityLogger().LogSuspiciousActivity("burst_registration",
+			fmt.Sprintf("疑似批量报名: company=%d, user=%d, %s", company.ID, user.ID, user.ReviewReason), source.IP)
+	}
 
 	// 记录操作日志
 	userID := uint(user.ID)
+	if user.Waitlisted {
+		var position int64
+		config.DB.Model(&models.User{}).
+			Where("company_id = ? AND waitlisted = ? AND id <= ?", company.ID, true, user.ID).
+			Count(&position)
+
+		LogOperation(c, "self_register", "user", &userID, fmt.Sprintf("用户扫码报名候补: %s", user.Name))
+
+		c.JSON(http.StatusAccepted, gin.H{
+			"message":           "名额已满，您已进入候补名单，有空位时将自动加入抽奖池",
+			"error_code":        "WAITLISTED",
+			"user":              user,
+			"waitlist_position": position,
+		})
+		return
+	}
+
 	details := fmt.Sprintf("用户扫码参与: %s", user.Name)
 	LogOperation(c, "self_register", "user", &userID, details)
 
+	broadcastParticipantRegistered(company.ID, &user, 1)
+
 	c.JSON(http.StatusCreated, gin.H{
 		"message": "参与成功！您已加入抽奖池",
 		"user":    user,
 	})
 }
 
+// publicCompanyInfo 公开接口返回的公司信息，隐藏预算和税率等财务配置
+type publicCompanyInfo struct {
+	*models.Company
+	PrizeBudget    *float64 `json:"prize_budget,omitempty"`
+	BudgetCurrency *string  `json:"budget_currency,omitempty"`
+	TaxBrackets    *string  `json:"tax_brackets,omitempty"`
+}
+
 // GetCompanyInfo 获取公司信息（用于注册页面）
 func GetCompanyInfo(c *gin.Context) {
 	companyCode := c.Query("company_code")
@@ -189,13 +275,21 @@ func GetCompanyInfo(c *gin.Context) {
 	config.DB.Model(&models.User{}).Where("company_id = ?", company.ID).Count(&totalUsers)
 
 	var undrawnUsers int64
-	config.DB.Model(&models.User{}).Where("company_id = ? AND has_drawn = ?", company.ID, false).Count(&undrawnUsers)
+	config.DB.Model(&models.User{}).Where("company_id = ? AND has_drawn = ? AND waitlisted = ?", company.ID, false, false).Count(&undrawnUsers)
+
+	registration := registrationInfo(config.DB, company, time.Now())
 
 	c.JSON(http.StatusOK, gin.H{
-		"company": company,
+		"company":      publicCompanyInfo{Company: company},
+		"registration": registration,
+		"form_fields":  activeRegistrationFields(company.ID),
 		"stats": gin.H{
-			"total_users":   totalUsers,
-			"undrawn_users": undrawnUsers,
+			"total_users":      totalUsers,
+			"undrawn_users":    undrawnUsers,
+			"registered_users": registration.Registered,
+			"waitlisted_users": registration.Waitlisted,
+			"capacity":         registration.Capacity,
+			"remaining_slots":  registration.Remaining,
 		},
 	})
 }
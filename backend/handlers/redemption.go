@@ -0,0 +1,183 @@
+package handlers
+
+import (
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"time"
+
+	"lottery-system/config"
+	"lottery-system/models"
+	"lottery-system/utils"
+
+	"github.com/gin-gonic/gin"
+	"github.com/skip2/go-qrcode"
+	"gorm.io/gorm"
+)
+
+// RedeemPrizeRequest 扫码核销请求
+type RedeemPrizeRequest struct {
+	Token string `json:"token" binding:"required"` // 二维码内容
+}
+
+// RedeemPrize 领奖台扫码核销（权限检查）：校验签名、检查未核销后按状态机标记为已自提并记录操作人
+func RedeemPrize(c *gin.Context) {
+	var req RedeemPrizeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "请求参数格式错误"})
+		return
+	}
+
+	recordID, nonce, err := utils.VerifyRedemptionToken(req.Token, redemptionKey())
+	if err != nil {
+		utils.NewSecurityLogger().LogSuspiciousActivity("redemption_invalid_token", "领奖核销二维码签名无效", c.ClientIP())
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的领奖二维码", "error_code": "INVALID_TOKEN"})
+		return
+	}
+
+	var record models.DrawRecord
+	if err := config.DB.Preload("User").Preload("Level").Preload("Prize").Preload("Variant").
+		First(&record, recordID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "中奖记录不存在"})
+		return
+	}
+
+	if !canAccessCompany(c, record.CompanyID) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Permission denied"})
+		return
+	}
+
+	// 随机数不一致说明二维码已被重新生成，旧码作废
+	if record.RedemptionNonce == "" || record.RedemptionNonce != nonce {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "领奖二维码已失效", "error_code": "TOKEN_REVOKED"})
+		return
+	}
+
+	if record.RedeemedAt != nil {
+		c.JSON(http.StatusConflict, gin.H{
+			"error":       "该奖品已核销",
+			"error_code":  "ALREADY_REDEEMED",
+			"redeemed_at": record.RedeemedAt,
+			"redeemed_by": record.RedeemedBy,
+		})
+		return
+	}
+
+	if record.RolledOver {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "该记录的奖品已回流，无法核销", "error_code": "ROLLED_OVER"})
+		return
+	}
+
+	// 按状态机流转：未领取的记录先标记为已领取，再变为已自提
+	canRedeem := models.DrawStatusCanTransition(record.Status, models.DrawStatusPickedUp) ||
+		(record.Status == models.DrawStatusWon && models.DrawStatusCanTransition(record.Status, models.DrawStatusClaimed))
+	if !canRedeem {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("当前状态(%s)不能核销", record.Status), "error_code": "INVALID_STATUS"})
+		return
+	}
+
+	var operatorID int
+	if v, exists := c.Get("user_id"); exists {
+		operatorID, _ = v.(int)
+	}
+
+	now := time.Now()
+	err = config.DB.Transaction(func(tx *gorm.DB) error {
+		// 条件更新，防止同一二维码被并发重复核销
+		status := record.Status
+		if status == models.DrawStatusWon {
+			result := tx.Model(&models.DrawRecord{}).
+				Where("id = ? AND redeemed_at IS NULL AND status = ? AND rolled_over = ?", record.ID, status, false).
+				Updates(map[string]interface{}{
+					"status":     models.DrawStatusClaimed,
+					"claimed_at": now,
+				})
+			if result.Error != nil {
+				return result.Error
+			}
+			if result.RowsAffected == 0 {
+				return errStatusChanged
+			}
+			status = models.DrawStatusClaimed
+		}
+
+		result := tx.Model(&models.DrawRecord{}).
+			Where("id = ? AND redeemed_at IS NULL AND status = ? AND rolled_over = ?", record.ID, status, false).
+			Updates(map[string]interface{}{
+				"status":      models.DrawStatusPickedUp,
+				"shipped_at":  now,
+				"redeemed_at": now,
+				"redeemed_by": operatorID,
+			})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return errStatusChanged
+		}
+		return nil
+	})
+	if err == errStatusChanged {
+		c.JSON(http.StatusConflict, gin.H{"error": "该奖品已核销或状态已变化，请刷新后重试", "error_code": "ALREADY_REDEEMED"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "核销失败，请稍后重试"})
+		return
+	}
+
+	resourceID := uint(record.ID)
+	LogOperation(c, "redeem", "draw_record", &resourceID,
+		fmt.Sprintf("扫码核销领奖: %s - %s %s", record.User.Name, record.Level.Name, record.Prize.Name))
+
+	config.DB.Preload("User").Preload("Level").Preload("Prize").Preload("Variant").First(&record, record.ID)
+	c.JSON(http.StatusOK, record)
+}
+
+// attachRedemptionQR 为可现场领取的中奖记录生成核销凭证和二维码
+// 首次调用时生成随机数并保存；已核销、已发放或虚拟奖品不生成
+func attachRedemptionQR(record *models.DrawRecord) {
+	if record.ID == 0 || record.Prize.CodePool || record.RedeemedAt != nil {
+		return
+	}
+	if record.Status != models.DrawStatusWon && record.Status != models.DrawStatusClaimed {
+		return
+	}
+
+	if record.RedemptionNonce == "" {
+		nonce, err := utils.NewRedemptionNonce()
+		if err != nil {
+			return
+		}
+		// 条件更新，并发请求时以先写入的随机数为准
+		config.DB.Model(&models.DrawRecord{}).
+			Where("id = ? AND (redemption_nonce IS NULL OR redemption_nonce = '')", record.ID).
+			Update("redemption_nonce", nonce)
+		var stored models.DrawRecord
+		if err := config.DB.Select("id", "redemption_nonce").First(&stored, record.ID).Error; err != nil || stored.RedemptionNonce == "" {
+			return
+		}
+		record.RedemptionNonce = stored.RedemptionNonce
+	}
+
+	token := utils.SignRedemptionToken(record.ID, record.RedemptionNonce, redemptionKey())
+	png, err := qrcode.Encode(token, qrcode.Medium, 256)
+	if err != nil {
+		utils.WithFields(map[string]interface{}{
+			"error":          err,
+			"draw_record_id": record.ID,
+		}).Error("生成领奖二维码失败")
+		return
+	}
+
+	record.RedemptionToken = token
+	record.RedemptionQR = fmt.Sprintf("data:image/png;base64,%s", base64.StdEncoding.EncodeToString(png))
+}
+
+// redemptionKey 获取核销凭证签名密钥
+func redemptionKey() []byte {
+	if config.AppConfig.RedemptionKey != "" {
+		return utils.DeriveKey(config.AppConfig.RedemptionKey)
+	}
+	return utils.DeriveKey("redemption:" + config.AppConfig.JWTSecret)
+}
@@ -0,0 +1,328 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"lottery-system/challenge"
+	"lottery-system/config"
+	"lottery-system/models"
+	"lottery-system/utils"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// UpdateRegistrationSettingsRequest 更新报名配置请求
+type UpdateRegistrationSettingsRequest struct {
+	RegistrationOpensAt  *string `json:"registration_opens_at"`  // RFC3339 时间，传空字符串取消
+	RegistrationClosesAt *string `json:"registration_closes_at"` // RFC3339 时间，传空字符串取消
+	RegistrationClosed   *bool   `json:"registration_closed"`    // 立即关闭/重新开放报名
+	MaxParticipants      *int    `json:"max_participants"`       // 0 表示不限制
+	WaitlistEnabled      *bool   `json:"waitlist_enabled"`
+	PhoneOTPRequired     *bool   `json:"phone_otp_required"` // 报名时必须通过短信验证码验证手机号
+	ChallengeMode        *string `json:"challenge_mode"`     // 报名人机验证方式：空字符串（关闭）、captcha、pow
+}
+
+// RegistrationInfo 报名状态（注册页面展示）
+type RegistrationInfo struct {
+	Status          string     `json:"status"` // open, not_open, ended, closed, full, waitlist
+	OpensAt         *time.Time `json:"opens_at,omitempty"`
+	ClosesAt        *time.Time `json:"closes_at,omitempty"`
+	Capacity        int        `json:"capacity"`            // 0 表示不限制
+	Registered      int64      `json:"registered"`          // 正式参与人数
+	Remaining       *int64     `json:"remaining,omitempty"` // 剩余名额（不限制时不返回）
+	Waitlisted      int64      `json:"waitlisted"`          // 候补人数
+	WaitlistEnabled bool       `json:"waitlist_enabled"`
+
+	PhoneOTPRequired bool   `json:"phone_otp_required"`       // 报名需先验证手机号
+	ChallengeMode    string `json:"challenge_mode,omitempty"` // 报名需先完成的人机验证方式
+}
+
+// registrationErrors 报名状态对应的错误响应
+var registrationErrors = map[string]struct {
+	Status  int
+	Code    string
+	Message string
+}{
+	models.RegistrationStatusNotOpen: {http.StatusForbidden, "REGISTRATION_NOT_OPEN", "报名尚未开始"},
+	models.RegistrationStatusEnded:   {http.StatusForbidden, "REGISTRATION_ENDED", "报名已截止"},
+	models.RegistrationStatusClosed:  {http.StatusForbidden, "REGISTRATION_CLOSED", "报名已关闭"},
+	models.RegistrationStatusFull:    {http.StatusConflict, "REGISTRATION_FULL", "名额已满"},
+}
+
+// UpdateRegistrationSettings 更新公司报名时间、人数上限、候补名单和关闭开关（权限检查）
+// 提高上限或取消限制时自动将候补名单按报名顺序转正
+func UpdateRegistrationSettings(c *gin.Context) {
+	var company models.Company
+	if err := config.DB.First(&company, c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Company not found"})
+		return
+	}
+
+	if !canAccessCompany(c, company.ID) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Permission denied"})
+		return
+	}
+
+	var req UpdateRegistrationSettingsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "请求参数格式错误"})
+		return
+	}
+
+	updates := map[string]interface{}{}
+	opensAt, closesAt := company.RegistrationOpensAt, company.RegistrationClosesAt
+
+	if req.RegistrationOpensAt != nil {
+		t, err := parseOptionalTime(*req.RegistrationOpensAt)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "报名开始时间格式错误"})
+			return
+		}
+		opensAt = t
+		updates["registration_opens_at"] = t
+	}
+	if req.RegistrationClosesAt != nil {
+		t, err := parseOptionalTime(*req.RegistrationClosesAt)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "报名截止时间格式错误"})
+			return
+		}
+		closesAt = t
+		updates["registration_closes_at"] = t
+	}
+	if opensAt != nil && closesAt != nil && !closesAt.After(*opensAt) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "报名截止时间必须晚于开始时间"})
+		return
+	}
+
+	if req.MaxParticipants != nil {
+		if *req.MaxParticipants < 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "人数上限不能为负数"})
+			return
+		}
+		updates["max_participants"] = *req.MaxParticipants
+	}
+	if req.WaitlistEnabled != nil {
+		updates["waitlist_enabled"] = *req.WaitlistEnabled
+	}
+	if req.PhoneOTPRequired != nil {
+		updates["phone_otp_required"] = *req.PhoneOTPRequired
+	}
+	if req.ChallengeMode != nil {
+		mode := strings.TrimSpace(*req.ChallengeMode)
+		if mode != "" && challenge.Get(mode) == nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error":     "不支持的人机验证方式",
+				"supported": challenge.Types(),
+			})
+			return
+		}
+		updates["challenge_mode"] = mode
+	}
+	if req.RegistrationClosed != nil {
+		updates["registration_closed"] = *req.RegistrationClosed
+	}
+
+	if len(updates) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "没有需要更新的配置"})
+		return
+	}
+
+	var promoted []models.User
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&company).Updates(updates).Error; err != nil {
+			return err
+		}
+		if err := tx.First(&company, company.ID).Error; err != nil {
+			return err
+		}
+		var err error
+		promoted, err = promoteWaitlist(tx, &company)
+		return err
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "更新报名配置失败"})
+		return
+	}
+
+	resourceID := uint(company.ID)
+	details := fmt.Sprintf("更新报名配置: %s", company.Name)
+	if req.RegistrationClosed != nil {
+		if *req.RegistrationClosed {
+			details = fmt.Sprintf("关闭报名: %s", company.Name)
+		} else {
+			details = fmt.Sprintf("重新开放报名: %s", company.Name)
+		}
+	}
+	if len(promoted) > 0 {
+		details += fmt.Sprintf("，候补转正%d人", len(promoted))
+	}
+	LogOperation(c, "update_registration", "company", &resourceID, details)
+
+	if len(promoted) > 0 {
+		broadcastParticipantRegistered(company.ID, nil, len(promoted))
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"company":      company,
+		"registration": registrationInfo(config.DB, &company, time.Now()),
+		"promoted":     promoted,
+	})
+}
+
+// registrationInfo 计算公司当前报名状态
+func registrationInfo(db *gorm.DB, company *models.Company, now time.Time) RegistrationInfo {
+	info := RegistrationInfo{
+		OpensAt:         company.RegistrationOpensAt,
+		ClosesAt:        company.RegistrationClosesAt,
+		Capacity:        company.MaxParticipants,
+		WaitlistEnabled: company.WaitlistEnabled,
+
+		PhoneOTPRequired: company.PhoneOTPRequired,
+		ChallengeMode:    company.ChallengeMode,
+	}
+
+	db.Model(&models.User{}).Where("company_id = ? AND waitlisted = ?", company.ID, false).Count(&info.Registered)
+	db.Model(&models.User{}).Where("company_id = ? AND waitlisted = ?", company.ID, true).Count(&info.Waitlisted)
+
+	if company.MaxParticipants > 0 {
+		remaining := int64(company.MaxParticipants) - info.Registered
+		if remaining < 0 {
+			remaining = 0
+		}
+		info.Remaining = &remaining
+	}
+
+	switch {
+	case company.RegistrationClosed:
+		info.Status = models.RegistrationStatusClosed
+	case company.RegistrationOpensAt != nil && now.Before(*company.RegistrationOpensAt):
+		info.Status = models.RegistrationStatusNotOpen
+	case company.RegistrationClosesAt != nil && !now.Before(*company.RegistrationClosesAt):
+		info.Status = models.RegistrationStatusEnded
+	case info.Remaining != nil && *info.Remaining == 0 && company.WaitlistEnabled:
+		info.Status = models.RegistrationStatusWaitlist
+	case info.Remaining != nil && *info.Remaining == 0:
+		info.Status = models.RegistrationStatusFull
+	default:
+		info.Status = models.RegistrationStatusOpen
+	}
+
+	return info
+}
+
+// admitSelfRegistration 在事务中锁定公司记录并检查报名状态，返回新用户是否进入候补名单
+// 报名不可用时返回对应状态（not_open/ended/closed/full）
+func admitSelfRegistration(tx *gorm.DB, companyID int) (waitlisted bool, blockedBy string, err error) {
+	var company models.Company
+	// 锁定公司记录，串行化同一公司的并发报名，避免超出人数上限
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&company, companyID).Error; err != nil {
+		return false, "", err
+	}
+
+	info := registrationInfo(tx, &company, time.Now())
+	switch info.Status {
+	case models.RegistrationStatusOpen:
+		return false, "", nil
+	case models.RegistrationStatusWaitlist:
+		return true, "", nil
+	default:
+		return false, info.Status, nil
+	}
+}
+
+// respondRegistrationBlocked 返回报名不可用的响应
+func respondRegistrationBlocked(c *gin.Context, company *models.Company, status string) {
+	regErr, ok := registrationErrors[status]
+	if !ok {
+		c.JSON(http.StatusForbidden, gin.H{"error": "暂不能报名"})
+		return
+	}
+	c.JSON(regErr.Status, gin.H{
+		"error":        regErr.Message,
+		"error_code":   regErr.Code,
+		"registration": registrationInfo(config.DB, company, time.Now()),
+	})
+}
+
+// promoteWaitlist 按报名顺序将候补用户转正，直到达到人数上限（不限制时全部转正）
+func promoteWaitlist(tx *gorm.DB, company *models.Company) ([]models.User, error) {
+	query := tx.Where("company_id = ? AND waitlisted = ?", company.ID, true).Order("id ASC")
+
+	if company.MaxParticipants > 0 {
+		var registered int64
+		if err := tx.Model(&models.User{}).
+			Where("company_id = ? AND waitlisted = ?", company.ID, false).
+			Count(&registered).Error; err != nil {
+			return nil, err
+		}
+		slots := company.MaxParticipants - int(registered)
+		if slots <= 0 {
+			return nil, nil
+		}
+		query = query.Limit(slots)
+	}
+
+	var users []models.User
+	if err := query.Find(&users).Error; err != nil || len(users) == 0 {
+		return nil, err
+	}
+
+	ids := make([]int, len(users))
+	for i := range users {
+		ids[i] = users[i].ID
+		users[i].Waitlisted = false
+	}
+	if err := tx.Model(&models.User{}).Where("id IN ?", ids).Update("waitlisted", false).Error; err != nil {
+		return nil, err
+	}
+
+	utils.WithFields(map[string]interface{}{
+		"company_id": company.ID,
+		"promoted":   len(users),
+	}).Info("候补名单转正")
+
+	return users, nil
+}
+
+// promoteWaitlistAfterRemoval 正式参与者被删除后为候补用户补位
+func promoteWaitlistAfterRemoval(companyID int) {
+	var promoted []models.User
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		var company models.Company
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&company, companyID).Error; err != nil {
+			return err
+		}
+		var err error
+		promoted, err = promoteWaitlist(tx, &company)
+		return err
+	})
+	if err != nil {
+		utils.WithFields(map[string]interface{}{
+			"error":      err,
+			"company_id": companyID,
+		}).Error("候补名单转正失败")
+		return
+	}
+	if len(promoted) > 0 {
+		broadcastParticipantRegistered(companyID, nil, len(promoted))
+	}
+}
+
+// parseOptionalTime 解析 RFC3339 时间，空字符串返回 nil（表示取消设置）
+func parseOptionalTime(raw string) (*time.Time, error) {
+	raw = strings.TrimSpace(raw)
+	if raw == "" {
+		return nil, nil
+	}
+	t, err := time.Parse(time.RFC3339, raw)
+	if err != nil {
+		return nil, err
+	}
+	return &t, nil
+}
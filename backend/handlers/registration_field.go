@@ -0,0 +1,193 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+
+	"lottery-system/config"
+	"lottery-system/models"
+	"lottery-system/utils"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// RegistrationFieldRequest 创建/更新报名表单字段请求
+type RegistrationFieldRequest struct {
+	CompanyID int      `json:"company_id"` // 仅超级管理员创建时需要指定
+	Key       string   `json:"key"`        // 创建后不可修改
+	Label     string   `json:"label"`
+	Type      string   `json:"type"`
+	Required  bool     `json:"required"`
+	Pattern   string   `json:"pattern"`
+	Options   []string `json:"options"`
+	MaxLength int      `json:"max_length"`
+	SortOrder int      `json:"sort_order"`
+	IsActive  *bool    `json:"is_active"`
+}
+
+// CreateRegistrationField 添加报名表单字段（权限检查）
+func CreateRegistrationField(c *gin.Context) {
+	var req RegistrationFieldRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "请求参数格式错误"})
+		return
+	}
+
+	companyID, ok := getScopedCompanyID(c, fmt.Sprint(req.CompanyID))
+	if !ok {
+		return
+	}
+
+	field := models.RegistrationField{
+		CompanyID: companyID,
+		Key:       req.Key,
+		Label:     req.Label,
+		Type:      req.Type,
+		Required:  req.Required,
+		Pattern:   req.Pattern,
+		Options:   req.Options,
+		MaxLength: req.MaxLength,
+		SortOrder: req.SortOrder,
+		IsActive:  req.IsActive == nil || *req.IsActive,
+	}
+	if field.Type == "" {
+		field.Type = models.FieldTypeText
+	}
+	if err := utils.ValidateFieldDefinition(&field); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var exists int64
+	config.DB.Model(&models.RegistrationField{}).Where("company_id = ? AND field_key = ?", companyID, field.Key).Count(&exists)
+	if exists > 0 {
+		c.JSON(http.StatusConflict, gin.H{"error": "字段标识已存在"})
+		return
+	}
+
+	if err := config.DB.Create(&field).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "添加字段失败"})
+		return
+	}
+
+	resourceID := uint(field.ID)
+	LogOperation(c, "create", "registration_field", &resourceID, fmt.Sprintf("添加报名字段: %s (%s)", field.Label, field.Key))
+
+	c.JSON(http.StatusCreated, field)
+}
+
+// GetRegistrationFields 获取公司的报名表单字段（权限隔离）
+func GetRegistrationFields(c *gin.Context) {
+	companyID, ok := getScopedCompanyID(c, c.Query("company_id"))
+	if !ok {
+		return
+	}
+
+	var fields []models.RegistrationField
+	config.DB.Where("company_id = ?", companyID).Order("sort_order ASC, id ASC").Find(&fields)
+
+	c.JSON(http.StatusOK, fields)
+}
+
+// UpdateRegistrationField 更新报名表单字段（权限检查），字段标识不可修改
+func UpdateRegistrationField(c *gin.Context) {
+	field, ok := loadRegistrationFieldForAdmin(c)
+	if !ok {
+		return
+	}
+
+	var req RegistrationFieldRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "请求参数格式错误"})
+		return
+	}
+
+	updated := *field
+	updated.Label = req.Label
+	updated.Required = req.Required
+	updated.Pattern = req.Pattern
+	updated.Options = req.Options
+	updated.MaxLength = req.MaxLength
+	updated.SortOrder = req.SortOrder
+	if req.Type != "" {
+		updated.Type = req.Type
+	}
+	if req.IsActive != nil {
+		updated.IsActive = *req.IsActive
+	}
+	if err := utils.ValidateFieldDefinition(&updated); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := config.DB.Model(field).Updates(map[string]interface{}{
+		"label":      updated.Label,
+		"type":       updated.Type,
+		"required":   updated.Required,
+		"pattern":    updated.Pattern,
+		"options":    updated.Options,
+		"max_length": updated.MaxLength,
+		"sort_order": updated.SortOrder,
+		"is_active":  updated.IsActive,
+	}).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "更新字段失败"})
+		return
+	}
+
+	resourceID := uint(field.ID)
+	LogOperation(c, "update", "registration_field", &resourceID, fmt.Sprintf("更新报名字段: %s (%s)", updated.Label, field.Key))
+
+	config.DB.First(field, field.ID)
+	c.JSON(http.StatusOK, field)
+}
+
+// DeleteRegistrationField 删除报名表单字段及参与者已填写的值（权限检查）
+func DeleteRegistrationField(c *gin.Context) {
+	field, ok := loadRegistrationFieldForAdmin(c)
+	if !ok {
+		return
+	}
+
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("company_id = ? AND field_key = ?", field.CompanyID, field.Key).
+			Delete(&models.UserFieldValue{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(field).Error
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "删除字段失败"})
+		return
+	}
+
+	resourceID := uint(field.ID)
+	LogOperation(c, "delete", "registration_field", &resourceID, fmt.Sprintf("删除报名字段: %s (%s)", field.Label, field.Key))
+
+	c.JSON(http.StatusOK, gin.H{"message": "Registration field deleted successfully"})
+}
+
+// activeRegistrationFields 获取公司启用的报名表单字段（注册页面展示及校验）
+func activeRegistrationFields(companyID int) []models.RegistrationField {
+	var fields []models.RegistrationField
+	config.DB.Where("company_id = ? AND is_active = ?", companyID, true).
+		Order("sort_order ASC, id ASC").
+		Find(&fields)
+	return fields
+}
+
+// loadRegistrationFieldForAdmin 根据路由参数加载报名字段并检查管理员权限
+func loadRegistrationFieldForAdmin(c *gin.Context) (*models.RegistrationField, bool) {
+	var field models.RegistrationField
+	if err := config.DB.First(&field, c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "字段不存在"})
+		return nil, false
+	}
+
+	if !canAccessCompany(c, field.CompanyID) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Permission denied"})
+		return nil, false
+	}
+
+	return &field, true
+}
@@ -0,0 +1,183 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"lottery-system/config"
+	"lottery-system/models"
+	"lottery-system/utils"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// 批量报名检测时间窗口（人数阈值见 REG_BURST_IP_LIMIT / REG_BURST_DEVICE_LIMIT）
+const (
+	burstIPWindow     = 10 * time.Minute
+	burstDeviceWindow = time.Hour
+	maxDeviceIDLength = 128
+)
+
+// ReviewRegistrationsRequest 审核报名请求
+type ReviewRegistrationsRequest struct {
+	UserIDs []int  `json:"user_ids" binding:"required"`
+	Action  string `json:"action" binding:"required"` // approve, reject
+}
+
+// registrationSource 报名来源（客户端IP和设备标识摘要）
+type registrationSource struct {
+	IP         string
+	DeviceHash string
+}
+
+// newRegistrationSource 读取报名来源，设备标识由前端生成并持久保存（请求体 device_id 或 X-Device-ID 头）
+// 未提供设备标识时只按 IP 检测，不使用 User-Agent 代替，避免同型号手机被误判为同一设备
+func newRegistrationSource(c *gin.Context, deviceID string) registrationSource {
+	if deviceID == "" {
+		deviceID = c.GetHeader("X-Device-ID")
+	}
+	deviceID = strings.TrimSpace(deviceID)
+	if len(deviceID) > maxDeviceIDLength {
+		deviceID = deviceID[:maxDeviceIDLength]
+	}
+
+	source := registrationSource{IP: c.ClientIP()}
+	if deviceID != "" {
+		source.DeviceHash = utils.HashString("device:" + deviceID)
+	}
+	return source
+}
+
+// flagBurstRegistration 检查同一 IP/设备在时间窗口内的报名人数，超过阈值时返回标记原因
+// 同时把窗口内同一来源尚未审核的报名一并标记，便于管理员整批审核；需在锁定公司记录的事务中调用
+func flagBurstRegistration(tx *gorm.DB, companyID int, source registrationSource, now time.Time) (string, error) {
+	checks := []struct {
+		column string
+		value  string
+		window time.Duration
+		limit  int
+		reason string
+	}{
+		{"registered_ip", source.IP, burstIPWindow, config.AppConfig.RegBurstIPLimit, "同一IP %d分钟内报名%d人"},
+		{"device_hash", source.DeviceHash, burstDeviceWindow, config.AppConfig.RegBurstDeviceLimit, "同一设备%d分钟内报名%d人"},
+	}
+
+	for _, check := range checks {
+		if check.value == "" || check.limit <= 0 {
+			continue
+		}
+		since := now.Add(-check.window)
+		var count int64
+		if err := tx.Model(&models.User{}).
+			Where("company_id = ? AND "+check.column+" = ? AND created_at >= ?", companyID, check.value, since).
+			Count(&count).Error; err != nil {
+			return "", err
+		}
+		if count < int64(check.limit) {
+			continue
+		}
+
+		reason := fmt.Sprintf(check.reason, int(check.window/time.Minute), count+1)
+		if err := tx.Model(&models.User{}).
+			Where("company_id = ? AND "+check.column+" = ? AND created_at >= ? AND review_status = ?", companyID, check.value, since, "").
+			Updates(map[string]interface{}{
+				"review_status": models.ReviewStatusFlagged,
+				"review_reason": reason,
+			}).Error; err != nil {
+			return "", err
+		}
+		return reason, nil
+	}
+	return "", nil
+}
+
+// GetRegistrationReviews 获取报名审核列表（权限检查），默认返回待审核（flagged）的报名
+// status=all 时返回所有标记过的报名（含已通过/已拒绝）
+func GetRegistrationReviews(c *gin.Context) {
+	companyID, ok := getScopedCompanyID(c, c.Query("company_id"))
+	if !ok {
+		return
+	}
+
+	query := config.DB.Model(&models.User{}).Where("company_id = ?", companyID)
+	switch status := c.DefaultQuery("status", models.ReviewStatusFlagged); status {
+	case "all":
+		query = query.Where("review_status <> ?", "")
+	case models.ReviewStatusFlagged, models.ReviewStatusApproved, models.ReviewStatusRejected:
+		query = query.Where("review_status = ?", status)
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的审核状态"})
+		return
+	}
+
+	var users []models.User
+	if err := query.Order("created_at ASC, id ASC").Find(&users).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取报名审核列表失败"})
+		return
+	}
+	utils.AttachUserFields(config.DB, users)
+
+	var pending int64
+	config.DB.Model(&models.User{}).
+		Where("company_id = ? AND review_status = ?", companyID, models.ReviewStatusFlagged).
+		Count(&pending)
+
+	c.JSON(http.StatusOK, gin.H{
+		"users":   users,
+		"total":   len(users),
+		"pending": pending,
+	})
+}
+
+// ReviewRegistrations 批量审核被标记的报名（权限检查）
+// 拒绝后用户不再参与抽奖（已抽奖的用户不受影响），通过后可正常抽奖
+func ReviewRegistrations(c *gin.Context) {
+	companyID, ok := getScopedCompanyID(c, c.Query("company_id"))
+	if !ok {
+		return
+	}
+
+	var req ReviewRegistrationsRequest
+	if err := c.ShouldBindJSON(&req); err != nil || len(req.UserIDs) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "请求参数格式错误"})
+		return
+	}
+
+	var status string
+	switch req.Action {
+	case "approve":
+		status = models.ReviewStatusApproved
+	case "reject":
+		status = models.ReviewStatusRejected
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "action 只能是 approve 或 reject"})
+		return
+	}
+
+	query := config.DB.Model(&models.User{}).
+		Where("company_id = ? AND id IN ? AND review_status <> ?", companyID, req.UserIDs, "")
+	if status == models.ReviewStatusRejected {
+		query = query.Where("has_drawn = ?", false)
+	}
+	result := query.Update("review_status", status)
+	if result.Error != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "审核报名失败"})
+		return
+	}
+
+	resourceID := uint(companyID)
+	action := "通过"
+	if status == models.ReviewStatusRejected {
+		action = "拒绝"
+	}
+	LogOperation(c, "review_registration", "company", &resourceID,
+		fmt.Sprintf("审核报名: %s %d 人（提交 %d 人）", action, result.RowsAffected, len(req.UserIDs)))
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": fmt.Sprintf("已%s %d 条报名", action, result.RowsAffected),
+		"updated": result.RowsAffected,
+	})
+}
@@ -0,0 +1,402 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"lottery-system/config"
+	"lottery-system/models"
+	"lottery-system/storage"
+	"lottery-system/utils"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// maxRetentionDays 个人信息保留期限上限
+const maxRetentionDays = 3650
+
+// piiRedacted 操作日志中手机号等个人信息的替换文本
+const piiRedacted = "[已匿名]"
+
+// errEventNotClosed 活动未结束，不能清理个人信息
+var errEventNotClosed = errors.New("event not closed")
+
+// UpdateRetentionRequest 更新个人信息保留期限请求
+type UpdateRetentionRequest struct {
+	RetentionDays *int `json:"retention_days" binding:"required"` // 活动结束后保留的天数，0 表示不自动清理
+}
+
+// RetentionReport 个人信息清理报告（只包含条数，不包含个人信息）
+type RetentionReport struct {
+	CompanyID     int        `json:"company_id"`
+	DryRun        bool       `json:"dry_run"`
+	DueAt         *time.Time `json:"due_at,omitempty"` // 按保留期限计划清理的时间
+	Users         int64      `json:"users"`            // 匿名化的参与者
+	FieldValues   int64      `json:"field_values"`     // 删除的报名表单字段值
+	DrawRecords   int64      `json:"draw_records"`     // 清除收件信息和 IP 的抽奖记录
+	OperationLogs int64      `json:"operation_logs"`   // 替换个人信息的操作日志
+	ExportJobs    int64      `json:"export_jobs"`      // 删除的导出文件
+}
+
+// Summary 返回清理报告摘要（用于操作日志）
+func (r *RetentionReport) Summary() string {
+	prefix := "匿名化参与者个人信息"
+	if r.DryRun {
+		prefix = "个人信息清理预览"
+	}
+	return fmt.Sprintf("%s: 参与者 %d 人，表单字段值 %d 条，抽奖记录 %d 条，操作日志 %d 条，导出文件 %d 个",
+		prefix, r.Users, r.FieldValues, r.DrawRecords, r.OperationLogs, r.ExportJobs)
+}
+
+// retentionDueAt 返回公司按保留期限应清理个人信息的时间，未设置期限或活动未结束时返回 nil
+func retentionDueAt(company *models.Company) *time.Time {
+	if company.RetentionDays <= 0 || company.EventClosedAt == nil {
+		return nil
+	}
+	due := company.EventClosedAt.AddDate(0, 0, company.RetentionDays)
+	return &due
+}
+
+// pseudonym 参与者匿名后的名称（按用户 ID 生成，清理后中奖记录仍可按匿名 ID 区分）
+func pseudonym(userID int) string {
+	return fmt.Sprintf("匿名用户#%d", userID)
+}
+
+// GetRetention 获取公司个人信息保留设置和清理预览
+func GetRetention(c *gin.Context) {
+	company, ok := loadCompanyForAdmin(c)
+	if !ok {
+		return
+	}
+
+	report, err := purgeCompanyPII(config.DB, company, true)
+	if err != nil && !errors.Is(err, errEventNotClosed) {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "生成清理预览失败"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"retention_days":  company.RetentionDays,
+		"event_closed_at": company.EventClosedAt,
+		"purge_due_at":    retentionDueAt(company),
+		"pii_purged_at":   company.PIIPurgedAt,
+		"preview":         report,
+	})
+}
+
+// UpdateRetention 设置个人信息保留期限（活动结束后 N 天自动匿名化）
+func UpdateRetention(c *gin.Context) {
+	company, ok := loadCompanyForAdmin(c)
+	if !ok {
+		return
+	}
+
+	var req UpdateRetentionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "请求参数格式错误"})
+		return
+	}
+	if *req.RetentionDays < 0 || *req.RetentionDays > maxRetentionDays {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("保留天数应在 0 到 %d 之间", maxRetentionDays)})
+		return
+	}
+
+	if err := config.DB.Model(company).Update("retention_days", *req.RetentionDays).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "更新保留期限失败"})
+		return
+	}
+	company.RetentionDays = *req.RetentionDays
+
+	resourceID := uint(company.ID)
+	LogOperation(c, "update_retention", "company", &resourceID,
+		fmt.Sprintf("设置个人信息保留期限: %s，活动结束后 %d 天", company.Name, company.RetentionDays))
+
+	c.JSON(http.StatusOK, gin.H{
+		"retention_days": company.RetentionDays,
+		"purge_due_at":   retentionDueAt(company),
+		"pii_purged_at":  company.PIIPurgedAt,
+	})
+}
+
+// PurgeCompanyPII 立即匿名化公司参与者个人信息（活动结束后可用，不可恢复）
+// 查询参数 dry_run=true 时只返回清理报告
+func PurgeCompanyPII(c *gin.Context) {
+	company, ok := loadCompanyForAdmin(c)
+	if !ok {
+		return
+	}
+
+	dryRun := c.Query("dry_run") == "true"
+	report, err := purgeCompanyPII(config.DB, company, dryRun)
+	if err != nil {
+		if errors.Is(err, errEventNotClosed) {
+			c.JSON(http.StatusConflict, gin.H{"error": "活动结束后才能清理个人信息", "error_code": "EVENT_NOT_CLOSED"})
+			return
+		}
+		utils.WithFields(map[string]interface{}{"error": err, "company_id": company.ID}).Error("清理个人信息失败")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "清理个人信息失败"})
+		return
+	}
+
+	if !dryRun {
+		resourceID := uint(company.ID)
+		LogOperation(c, "purge_pii", "company", &resourceID, report.Summary())
+	}
+	c.JSON(http.StatusOK, gin.H{"report": report})
+}
+
+// RunRetentionWorker 定时匿名化超过保留期限的公司个人信息（在独立 goroutine 中运行）
+// 多实例部署时通过条件更新 pii_purged_at 认领，同一公司只会被一个实例处理
+func RunRetentionWorker(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		var companies []models.Company
+		if err := config.DB.Where("retention_days > ? AND event_closed_at IS NOT NULL AND pii_purged_at IS NULL", 0).
+			Find(&companies).Error; err != nil {
+			utils.WithFields(map[string]interface{}{"error": err}).Error("查询待清理个人信息的公司失败")
+			continue
+		}
+		now := time.Now()
+		for i := range companies {
+			if due := retentionDueAt(&companies[i]); due != nil && !now.Before(*due) {
+				runScheduledPurge(&companies[i])
+			}
+		}
+	}
+}
+
+// runScheduledPurge 认领并执行一个公司的定时清理，失败时释放认领以便下次重试
+func runScheduledPurge(company *models.Company) {
+	claim := config.DB.Model(&models.Company{}).
+		Where("id = ? AND pii_purged_at IS NULL", company.ID).
+		Update("pii_purged_at", time.Now())
+	if claim.Error != nil || claim.RowsAffected == 0 {
+		return
+	}
+
+	resourceID := uint(company.ID)
+	report, err := purgeCompanyPII(config.DB, company, false)
+	if err != nil {
+		config.DB.Model(&models.Company{}).Where("id = ?", company.ID).Update("pii_purged_at", nil)
+		utils.WithFields(map[string]interface{}{"error": err, "company_id": company.ID}).Error("定时清理个人信息失败")
+		LogSystemOperation(company.ID, "purge_pii", "company", &resourceID, "定时清理个人信息失败: "+err.Error())
+		return
+	}
+	LogSystemOperation(company.ID, "purge_pii", "company", &resourceID,
+		fmt.Sprintf("保留期限（%d 天）已到，%s", company.RetentionDays, report.Summary()))
+}
+
+// purgeCompanyPII 匿名化公司参与者个人信息：
+// 参与者姓名替换为匿名 ID，清除手机号、工号、IP、设备标识和报名表单字段；清除抽奖记录中的收件信息和 IP；
+// 替换操作日志详情中的参与者姓名和手机号；删除可能包含个人信息的导出文件；停止目录同步（避免重新同步个人信息）
+// 抽奖记录、中奖统计和奖品发放状态保持不变
+func purgeCompanyPII(db *gorm.DB, company *models.Company, dryRun bool) (*RetentionReport, error) {
+	report := &RetentionReport{CompanyID: company.ID, DryRun: dryRun, DueAt: retentionDueAt(company)}
+	if company.EventClosedAt == nil {
+		return report, errEventNotClosed
+	}
+
+	var users []models.User
+	if err := db.Select("id", "name", "phone", "username").Where("company_id = ?", company.ID).Find(&users).Error; err != nil {
+		return nil, err
+	}
+	report.Users = int64(len(users))
+	db.Model(&models.UserFieldValue{}).Where("company_id = ?", company.ID).Count(&report.FieldValues)
+	db.Model(&models.DrawRecord{}).Where("company_id = ?", company.ID).Count(&report.DrawRecords)
+
+	var recordIDs []int
+	if err := db.Model(&models.DrawRecord{}).Where("company_id = ?", company.ID).Pluck("id", &recordIDs).Error; err != nil {
+		return nil, err
+	}
+	logs, err := piiOperationLogs(db, company.ID, users, recordIDs)
+	if err != nil {
+		return nil, err
+	}
+	report.OperationLogs = int64(len(logs))
+
+	// 所有公司的导出（company_id = 0）同样包含该公司的个人信息
+	var jobs []models.ExportJob
+	if err := db.Where("company_id IN ?", []int{company.ID, 0}).Find(&jobs).Error; err != nil {
+		return nil, err
+	}
+	report.ExportJobs = int64(len(jobs))
+
+	if dryRun {
+		return report, nil
+	}
+
+	now := time.Now()
+	err = db.Transaction(func(tx *gorm.DB) error {
+		var locked models.Company
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&locked, company.ID).Error; err != nil {
+			return err
+		}
+
+		if err := tx.Model(&models.User{}).Where("company_id = ?", company.ID).Updates(map[string]interface{}{
+			"password":      "",
+			"phone":         "",
+			"phone_hash":    "",
+			"name_key":      "",
+			"name_pinyin":   "",
+			"registered_ip": "",
+			"device_hash":   "",
+			"external_id":   "",
+			"review_reason": "",
+		}).Error; err != nil {
+			return err
+		}
+		for _, user := range users {
+			if err := tx.Model(&models.User{}).Where("id = ?", user.ID).Updates(map[string]interface{}{
+				"name":     pseudonym(user.ID),
+				"username": fmt.Sprintf("anon_%d", user.ID),
+			}).Error; err != nil {
+				return err
+			}
+		}
+
+		if err := tx.Where("company_id = ?", company.ID).Delete(&models.UserFieldValue{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(&models.DrawRecord{}).Where("company_id = ?", company.ID).Updates(map[string]interface{}{
+			"ip":               "",
+			"recipient_name":   "",
+			"recipient_phone":  "",
+			"shipping_address": "",
+			"tracking_number":  "",
+			"fulfillment_note": "",
+		}).Error; err != nil {
+			return err
+		}
+
+		for _, log := range logs {
+			if err := tx.Model(&models.OperationLog{}).Where("id = ?", log.ID).Updates(map[string]interface{}{
+				"admin_name": log.AdminName,
+				"details":    log.Details,
+				"ip_address": log.IPAddress,
+				"user_agent": log.UserAgent,
+			}).Error; err != nil {
+				return err
+			}
+		}
+
+		if len(jobs) > 0 {
+			ids := make([]int, len(jobs))
+			for i, job := range jobs {
+				ids[i] = job.ID
+			}
+			if err := tx.Where("id IN ?", ids).Delete(&models.ExportJob{}).Error; err != nil {
+				return err
+			}
+		}
+
+		if err := tx.Model(&models.DirectorySync{}).Where("company_id = ?", company.ID).
+			Updates(map[string]interface{}{"enabled": false, "next_run_at": nil, "last_report": ""}).Error; err != nil {
+			return err
+		}
+		return tx.Model(&models.Company{}).Where("id = ?", company.ID).Update("pii_purged_at", now).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	company.PIIPurgedAt = &now
+
+	// 事务提交后删除导出文件，删除失败时文件仍会因无任务记录而无法下载
+	if store := storage.Default(); store != nil {
+		for _, job := range jobs {
+			if job.FileKey == "" {
+				continue
+			}
+			if err := store.Delete(context.Background(), job.FileKey); err != nil {
+				utils.WithFields(map[string]interface{}{"error": err, "key": job.FileKey}).Warn("删除导出文件失败")
+			}
+		}
+	}
+	return report, nil
+}
+
+// piiOperationLogs 查找包含参与者个人信息的操作日志，返回替换后的日志（只包含有变化的日志）
+// 范围：公司管理员的日志、以该公司、其参与者或抽奖记录为对象的日志（包括超级管理员的操作）
+func piiOperationLogs(db *gorm.DB, companyID int, users []models.User, recordIDs []int) ([]models.OperationLog, error) {
+	// 按长度从长到短替换，避免短姓名截断长姓名或手机号
+	type pair struct{ old, new string }
+	var pairs []pair
+	userIDs := make(map[uint]int, len(users))
+	userIDList := make([]int, 0, len(users))
+	for _, user := range users {
+		userIDs[uint(user.ID)] = user.ID
+		userIDList = append(userIDList, user.ID)
+		// 单字姓名过于常见，不替换
+		if utf8.RuneCountInString(user.Name) >= 2 {
+			pairs = append(pairs, pair{user.Name, pseudonym(user.ID)})
+		}
+		if len(user.Phone) >= 7 {
+			pairs = append(pairs, pair{user.Phone, piiRedacted}, pair{maskPhone(user.Phone), piiRedacted})
+		}
+		if user.Username != "" && user.Username != user.Phone && len(user.Username) >= 4 {
+			pairs = append(pairs, pair{user.Username, pseudonym(user.ID)})
+		}
+	}
+	sort.SliceStable(pairs, func(i, j int) bool { return len(pairs[i].old) > len(pairs[j].old) })
+	args := make([]string, 0, len(pairs)*2)
+	for _, p := range pairs {
+		args = append(args, p.old, p.new)
+	}
+	replacer := strings.NewReplacer(args...)
+
+	seen := map[uint]bool{}
+	var changed []models.OperationLog
+	scan := func(query *gorm.DB) error {
+		var logs []models.OperationLog
+		return query.FindInBatches(&logs, 500, func(_ *gorm.DB, _ int) error {
+			for _, log := range logs {
+				if seen[log.ID] {
+					continue
+				}
+				seen[log.ID] = true
+
+				updated := log
+				updated.Details = replacer.Replace(log.Details)
+				// 参与者本人的登录日志记录了参与者的用户名、IP 和设备
+				if log.Action == "login" && log.Resource == "user" && log.ResourceID != nil && log.AdminID == *log.ResourceID {
+					if id, ok := userIDs[log.AdminID]; ok {
+						updated.AdminName = pseudonym(id)
+						updated.IPAddress = ""
+						updated.UserAgent = ""
+					}
+				}
+				if updated != log {
+					changed = append(changed, updated)
+				}
+			}
+			return nil
+		}).Error
+	}
+
+	if err := scan(db.Where("company_id = ?", companyID)); err != nil {
+		return nil, err
+	}
+	if err := scan(db.Where("company_id <> ? AND resource = ? AND resource_id = ?", companyID, "company", companyID)); err != nil {
+		return nil, err
+	}
+	for resource, ids := range map[string][]int{"user": userIDList, "draw_record": recordIDs} {
+		for start := 0; start < len(ids); start += 500 {
+			end := start + 500
+			if end > len(ids) {
+				end = len(ids)
+			}
+			if err := scan(db.Where("company_id <> ? AND resource = ? AND resource_id IN ?", companyID, resource, ids[start:end])); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return changed, nil
+}
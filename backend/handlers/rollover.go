@@ -0,0 +1,456 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"lottery-system/config"
+	"lottery-system/models"
+	"lottery-system/utils"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// errEventAlreadyClosed 活动已结束（不能重复结束和回流）
+var errEventAlreadyClosed = errors.New("event already closed")
+
+// VoidDrawRecordRequest 作废中奖记录请求
+type VoidDrawRecordRequest struct {
+	Reason string `json:"reason"` // 作废原因（写入操作日志）
+}
+
+// RolloverItem 奖品回流报告条目
+type RolloverItem struct {
+	DrawRecordID  int    `json:"draw_record_id"`
+	UserID        int    `json:"user_id"`
+	UserName      string `json:"user_name"`
+	FromLevelID   int    `json:"from_level_id"`
+	FromLevelName string `json:"from_level_name"`
+	PrizeID       int    `json:"prize_id"`
+	PrizeName     string `json:"prize_name"`
+	ToLevelID     int    `json:"to_level_id,omitempty"`
+	ToLevelName   string `json:"to_level_name,omitempty"`
+	Reason        string `json:"reason"`                // 回流原因: voided, unclaimed, forfeited
+	Skipped       bool   `json:"skipped"`               // 是否跳过（未回流）
+	SkipReason    string `json:"skip_reason,omitempty"` // 跳过原因
+}
+
+// VoidDrawRecord 作废中奖记录（权限检查），作废的奖品在活动结束时按回流策略回流
+func VoidDrawRecord(c *gin.Context) {
+	record, ok := loadDrawRecordForAdmin(c)
+	if !ok {
+		return
+	}
+
+	var req VoidDrawRecordRequest
+	// 请求体可选
+	_ = c.ShouldBindJSON(&req)
+
+	if record.Status == models.DrawStatusVoided {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "该记录已作废"})
+		return
+	}
+	if !models.DrawStatusCanTransition(record.Status, models.DrawStatusVoided) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("当前状态(%s)不能作废", record.Status)})
+		return
+	}
+	if record.RolledOver {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "该记录的奖品已回流，无法作废"})
+		return
+	}
+
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&models.DrawRecord{}).
+			Where("id = ? AND status = ? AND rolled_over = ?", record.ID, record.Status, false).
+			Update("status", models.DrawStatusVoided)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return errStatusChanged
+		}
+		// 归还已分配的规格库存
+		return utils.ReleaseVariant(tx, record)
+	})
+	if err == errStatusChanged {
+		c.JSON(http.StatusConflict, gin.H{"error": errStatusChanged.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "操作失败，请稍后重试"})
+		return
+	}
+	record.Status = models.DrawStatusVoided
+
+	resourceID := uint(record.ID)
+	details := fmt.Sprintf("作废中奖记录: 记录ID %d", record.ID)
+	if req.Reason != "" {
+		details += fmt.Sprintf(" (原因: %s)", req.Reason)
+	}
+	LogOperation(c, "void", "draw_record", &resourceID, details)
+
+	c.JSON(http.StatusOK, newFulfillmentRecord(record))
+}
+
+// PreviewRollover 预览活动结束时的奖品回流（不修改数据）
+func PreviewRollover(c *gin.Context) {
+	companyID, ok := getScopedCompanyID(c, c.Query("company_id"))
+	if !ok {
+		return
+	}
+
+	items, err := planRollover(config.DB, companyID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "生成回流报告失败"})
+		return
+	}
+
+	c.JSON(http.StatusOK, buildRolloverReport(companyID, items, true))
+}
+
+// CloseEvent 结束活动并执行奖品回流（权限检查）
+func CloseEvent(c *gin.Context) {
+	id := c.Param("id")
+
+	var company models.Company
+	if err := config.DB.First(&company, id).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Company not found"})
+		return
+	}
+
+	if !canAccessCompany(c, company.ID) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Permission denied"})
+		return
+	}
+	if company.EventClosedAt != nil {
+		c.JSON(http.StatusConflict, gin.H{"error": "活动已结束", "error_code": "EVENT_ALREADY_CLOSED"})
+		return
+	}
+
+	var operatorID uint
+	if v, exists := c.Get("user_id"); exists {
+		if uid, ok := v.(int); ok {
+			operatorID = uint(uid)
+		}
+	}
+
+	var items []RolloverItem
+	closedAt := time.Now()
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		// 条件更新，防止重复结束活动导致重复回流
+		result := tx.Model(&company).Where("event_closed_at IS NULL").Update("event_closed_at", closedAt)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return errEventAlreadyClosed
+		}
+
+		planned, err := planRollover(tx, company.ID)
+		if err != nil {
+			return err
+		}
+
+		items, err = applyRollover(tx, company.ID, planned, operatorID)
+		return err
+	})
+	if errors.Is(err, errEventAlreadyClosed) {
+		c.JSON(http.StatusConflict, gin.H{"error": "活动已结束", "error_code": "EVENT_ALREADY_CLOSED"})
+		return
+	}
+	var budgetErr *prizeBudgetError
+	if errors.As(err, &budgetErr) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "结束活动失败: " + budgetErr.Error(), "error_code": "BUDGET_EXCEEDED"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "结束活动失败: " + err.Error()})
+		return
+	}
+
+	report := buildRolloverReport(company.ID, items, false)
+	report["closed_at"] = closedAt
+
+	broadcastStockChanged(company.ID, nil)
+
+	resourceID := uint(company.ID)
+	LogOperation(c, "close_event", "company", &resourceID, fmt.Sprintf("结束活动: %s，回流奖品 %d 件，跳过 %d 件",
+		company.Name, report["rolled_over"], report["skipped"]))
+
+	c.JSON(http.StatusOK, report)
+}
+
+// GetRollovers 获取奖品回流历史（权限隔离）
+func GetRollovers(c *gin.Context) {
+	companyID, ok := getScopedCompanyID(c, c.Query("company_id"))
+	if !ok {
+		return
+	}
+
+	var rollovers []models.PrizeRollover
+	config.DB.Where("company_id = ?", companyID).
+		Order("created_at DESC").
+		Find(&rollovers)
+
+	c.JSON(http.StatusOK, rollovers)
+}
+
+// loadDrawRecordForAdmin 根据路由参数加载抽奖记录并检查管理员权限
+func loadDrawRecordForAdmin(c *gin.Context) (*models.DrawRecord, bool) {
+	id := c.Param("id")
+
+	var record models.DrawRecord
+	if err := config.DB.First(&record, id).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "抽奖记录不存在"})
+		return nil, false
+	}
+
+	if !canAccessCompany(c, record.CompanyID) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Permission denied"})
+		return nil, false
+	}
+
+	return &record, true
+}
+
+// validateRolloverTarget 校验奖项的回流目标（必须是同公司的其他奖项）
+func validateRolloverTarget(level *models.PrizeLevel) error {
+	if level.RolloverLevelID == nil {
+		return nil
+	}
+	if *level.RolloverLevelID == level.ID {
+		return fmt.Errorf("回流目标不能是奖项本身")
+	}
+
+	var target models.PrizeLevel
+	if err := config.DB.First(&target, *level.RolloverLevelID).Error; err != nil {
+		return fmt.Errorf("回流目标奖项不存在")
+	}
+	if target.CompanyID != level.CompanyID {
+		return fmt.Errorf("回流目标奖项必须属于同一公司")
+	}
+	return nil
+}
+
+// planRollover 计算公司内需要回流的奖品（作废或未领取且尚未回流的记录）
+func planRollover(db *gorm.DB, companyID int) ([]RolloverItem, error) {
+	var levels []models.PrizeLevel
+	if err := db.Where("company_id = ?", companyID).Find(&levels).Error; err != nil {
+		return nil, err
+	}
+	levelMap := make(map[int]models.PrizeLevel, len(levels))
+	for _, level := range levels {
+		levelMap[level.ID] = level
+	}
+
+	var records []models.DrawRecord
+	if err := db.Where("company_id = ? AND rolled_over = ? AND status IN ?", companyID, false,
+		[]string{models.DrawStatusWon, models.DrawStatusForfeited, models.DrawStatusVoided}).
+		Preload("User").
+		Preload("Prize").
+		Order("id ASC").
+		Find(&records).Error; err != nil {
+		return nil, err
+	}
+
+	items := make([]RolloverItem, 0, len(records))
+	for _, record := range records {
+		item := RolloverItem{
+			DrawRecordID: record.ID,
+			UserID:       record.UserID,
+			UserName:     record.User.Name,
+			FromLevelID:  record.LevelID,
+			PrizeID:      record.PrizeID,
+			PrizeName:    record.Prize.Name,
+			Reason:       models.RolloverReasonUnclaimed,
+		}
+		switch record.Status {
+		case models.DrawStatusVoided:
+			item.Reason = models.RolloverReasonVoided
+		case models.DrawStatusForfeited:
+			item.Reason = models.RolloverReasonForfeited
+		}
+
+		level, exists := levelMap[record.LevelID]
+		switch {
+		case !exists:
+			item.Skipped, item.SkipReason = true, "原奖项不存在"
+		case record.Prize.ID == 0:
+			item.Skipped, item.SkipReason = true, "原奖品不存在"
+		case record.Prize.CodePool:
+			item.Skipped, item.SkipReason = true, "虚拟奖品兑换码已发放，不可回流"
+		case record.Prize.HasVariants:
+			item.Skipped, item.SkipReason = true, "多规格奖品不支持回流"
+		case level.RolloverLevelID == nil:
+			item.Skipped, item.SkipReason = true, "该奖项未配置回流策略"
+		}
+		if exists {
+			item.FromLevelName = level.Name
+		}
+
+		if !item.Skipped {
+			target, ok := levelMap[*level.RolloverLevelID]
+			if !ok || target.ID == level.ID {
+				item.Skipped, item.SkipReason = true, "回流目标奖项无效"
+			} else {
+				item.ToLevelID = target.ID
+				item.ToLevelName = target.Name
+			}
+		}
+
+		// 目标奖项已有的同名奖品不能直接增加库存时跳过
+		if !item.Skipped {
+			var targetPrize models.Prize
+			err := db.Where("level_id = ? AND name = ?", item.ToLevelID, record.Prize.Name).First(&targetPrize).Error
+			if err != nil && err != gorm.ErrRecordNotFound {
+				return nil, err
+			}
+			if err == nil {
+				if reason := rolloverTargetSkipReason(&targetPrize); reason != "" {
+					item.Skipped, item.SkipReason = true, reason
+				}
+			}
+		}
+
+		items = append(items, item)
+	}
+
+	return items, nil
+}
+
+// rolloverTargetSkipReason 目标奖品不能接收回流库存的原因，可以接收时返回空字符串
+func rolloverTargetSkipReason(target *models.Prize) string {
+	switch {
+	case target.CodePool:
+		return "目标奖项的同名奖品为虚拟奖品，库存由兑换码数量决定，不可回流"
+	case target.HasVariants:
+		return "目标奖项的同名奖品为多规格奖品，库存由规格库存决定，不可回流"
+	}
+	return ""
+}
+
+// applyRollover 在事务中执行回流：未领取的记录改为已放弃，原奖品扣减总库存和已发放数，目标奖项的同名奖品增加库存
+func applyRollover(tx *gorm.DB, companyID int, items []RolloverItem, operatorID uint) ([]RolloverItem, error) {
+	for i := range items {
+		item := &items[i]
+		if item.Skipped {
+			continue
+		}
+
+		// 标记记录已回流（条件更新，防止并发重复回流），未领取的记录同时改为已放弃，原中奖者不能再领取
+		result := tx.Model(&models.DrawRecord{}).
+			Where("id = ? AND rolled_over = ?", item.DrawRecordID, false).
+			Updates(map[string]interface{}{
+				"rolled_over": true,
+				"status": gorm.Expr("CASE WHEN status = ? THEN ? ELSE status END",
+					models.DrawStatusWon, models.DrawStatusForfeited),
+			})
+		if result.Error != nil {
+			return nil, result.Error
+		}
+		if result.RowsAffected == 0 {
+			item.Skipped, item.SkipReason = true, "记录已被其他操作回流"
+			continue
+		}
+
+		// 未领取的记录改为已放弃，归还已分配的规格库存（作废和放弃的记录在状态变更时已归还）
+		if item.Reason == models.RolloverReasonUnclaimed {
+			var record models.DrawRecord
+			if err := tx.First(&record, item.DrawRecordID).Error; err != nil {
+				return nil, err
+			}
+			if err := utils.ReleaseVariant(tx, &record); err != nil {
+				return nil, err
+			}
+		}
+
+		var source models.Prize
+		if err := tx.First(&source, item.PrizeID).Error; err != nil {
+			return nil, err
+		}
+		if source.UsedStock <= 0 || source.TotalStock <= 0 {
+			return nil, fmt.Errorf("奖品 %s 库存数据异常", source.Name)
+		}
+
+		if err := tx.Model(&source).Updates(map[string]interface{}{
+			"total_stock": gorm.Expr("total_stock - 1"),
+			"used_stock":  gorm.Expr("used_stock - 1"),
+		}).Error; err != nil {
+			return nil, err
+		}
+
+		// 目标奖项中查找同名奖品，不存在则创建
+		var target models.Prize
+		err := tx.Where("level_id = ? AND name = ?", item.ToLevelID, source.Name).First(&target).Error
+		if err == gorm.ErrRecordNotFound {
+			target = models.Prize{
+				LevelID:    item.ToLevelID,
+				Name:       source.Name,
+				Image:      source.Image,
+				Value:      source.Value,
+				Currency:   source.Currency,
+				TotalStock: 0,
+				UsedStock:  0,
+			}
+			err = tx.Create(&target).Error
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		// 目标奖品单价可能与原奖品不同，增加库存前检查预算
+		budgetCheck := target
+		budgetCheck.TotalStock++
+		if err := checkPrizeBudget(tx, companyID, &budgetCheck, target.ID); err != nil {
+			return nil, err
+		}
+
+		if err := tx.Model(&target).Update("total_stock", gorm.Expr("total_stock + 1")).Error; err != nil {
+			return nil, err
+		}
+
+		rollover := models.PrizeRollover{
+			CompanyID:    companyID,
+			DrawRecordID: item.DrawRecordID,
+			UserID:       item.UserID,
+			FromLevelID:  item.FromLevelID,
+			FromPrizeID:  source.ID,
+			ToLevelID:    item.ToLevelID,
+			ToPrizeID:    target.ID,
+			PrizeName:    source.Name,
+			Reason:       item.Reason,
+			OperatorID:   operatorID,
+		}
+		if err := tx.Create(&rollover).Error; err != nil {
+			return nil, err
+		}
+	}
+
+	return items, nil
+}
+
+// buildRolloverReport 汇总回流报告
+func buildRolloverReport(companyID int, items []RolloverItem, dryRun bool) gin.H {
+	rolledOver, skipped := 0, 0
+	byReason := map[string]int{}
+	for _, item := range items {
+		if item.Skipped {
+			skipped++
+			continue
+		}
+		rolledOver++
+		byReason[item.Reason]++
+	}
+
+	return gin.H{
+		"company_id":  companyID,
+		"dry_run":     dryRun,
+		"rolled_over": rolledOver,
+		"skipped":     skipped,
+		"by_reason":   byReason,
+		"items":       items,
+	}
+}
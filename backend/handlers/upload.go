@@ -0,0 +1,180 @@
+package handlers
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"lottery-system/config"
+	"lottery-system/storage"
+	"lottery-system/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// uploadURLPrefix 上传文件的访问路径前缀（位于 /api 下，与前端代理配置一致）
+const uploadURLPrefix = "/api/uploads/"
+
+// uploadKinds 允许的上传用途（作为对象键前缀）
+var uploadKinds = map[string]bool{
+	"prize": true, // 奖品图片
+	"logo":  true, // 公司 Logo
+}
+
+// UploadImage 上传图片（奖品图片/公司Logo）
+// 按文件内容识别类型，限制大小，原图等比缩小后生成缩略图，返回可直接写入 Prize.Image / Company.Logo 的 URL
+func UploadImage(c *gin.Context) {
+	kind := c.DefaultPostForm("kind", "prize")
+	if !uploadKinds[kind] {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的上传类型"})
+		return
+	}
+
+	store := storage.Default()
+	if store == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "文件存储未配置"})
+		return
+	}
+
+	maxBytes := int64(config.AppConfig.UploadMaxMB) << 20
+	if maxBytes <= 0 {
+		maxBytes = 5 << 20
+	}
+	// 额外预留 multipart 头部的空间
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxBytes+64*1024)
+
+	fileHeader, err := c.FormFile("file")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("请选择不超过 %dMB 的图片文件", maxBytes>>20)})
+		return
+	}
+	if fileHeader.Size > maxBytes {
+		c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": fmt.Sprintf("图片不能超过 %dMB", maxBytes>>20)})
+		return
+	}
+
+	file, err := fileHeader.Open()
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "读取文件失败"})
+		return
+	}
+	defer file.Close()
+
+	data, err := io.ReadAll(io.LimitReader(file, maxBytes+1))
+	if err != nil || int64(len(data)) > maxBytes {
+		c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": fmt.Sprintf("图片不能超过 %dMB", maxBytes>>20)})
+		return
+	}
+
+	if _, err := utils.SniffImageType(data); err != nil {
+		c.JSON(http.StatusUnsupportedMediaType, gin.H{"error": "仅支持 PNG、JPEG、GIF 格式的图片"})
+		return
+	}
+
+	img, err := utils.DecodeImage(data)
+	if err != nil {
+		if errors.Is(err, utils.ErrImageTooLarge) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "图片尺寸过大"})
+			return
+		}
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无法识别的图片文件"})
+		return
+	}
+
+	full, err := utils.ResizeImage(img, utils.ImageMaxSide)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "图片处理失败"})
+		return
+	}
+	thumb, err := utils.ResizeImage(img, utils.ThumbnailSide)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "图片处理失败"})
+		return
+	}
+
+	// 对象键基于内容哈希，同一图片重复上传得到相同地址，且可长期缓存
+	sum := sha256.Sum256(data)
+	base := fmt.Sprintf("%s/%s", kind, hex.EncodeToString(sum[:16]))
+	fullKey := base + full.Ext
+	thumbKey := base + "_thumb" + thumb.Ext
+
+	ctx := c.Request.Context()
+	if err := store.Put(ctx, fullKey, full.Data, full.ContentType); err != nil {
+		utils.WithFields(map[string]interface{}{"error": err, "key": fullKey}).Error("保存上传图片失败")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "保存图片失败"})
+		return
+	}
+	if err := store.Put(ctx, thumbKey, thumb.Data, thumb.ContentType); err != nil {
+		utils.WithFields(map[string]interface{}{"error": err, "key": thumbKey}).Error("保存缩略图失败")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "保存图片失败"})
+		return
+	}
+
+	LogOperation(c, "upload", "image", nil, fmt.Sprintf("上传图片: %s (%dx%d)", fullKey, full.Width, full.Height))
+
+	c.JSON(http.StatusCreated, gin.H{
+		"url":           uploadURLPrefix + fullKey,
+		"thumbnail_url": uploadURLPrefix + thumbKey,
+		"width":         full.Width,
+		"height":        full.Height,
+		"size":          len(full.Data),
+		"content_type":  full.ContentType,
+	})
+}
+
+// ServeUpload 读取上传的文件（公开访问），对象键基于内容哈希，可长期缓存
+func ServeUpload(c *gin.Context) {
+	key := strings.TrimPrefix(c.Param("filepath"), "/")
+	// 只公开图片上传目录，导出文件等私有对象需通过各自的接口下载
+	if kind, _, ok := strings.Cut(key, "/"); !ok || !uploadKinds[kind] {
+		c.Status(http.StatusNotFound)
+		return
+	}
+
+	store := storage.Default()
+	if store == nil {
+		c.Status(http.StatusNotFound)
+		return
+	}
+
+	reader, info, err := store.Get(c.Request.Context(), key)
+	if err != nil {
+		if errors.Is(err, storage.ErrNotFound) || errors.Is(err, storage.ErrInvalidKey) {
+			c.Status(http.StatusNotFound)
+			return
+		}
+		utils.WithFields(map[string]interface{}{"error": err, "key": key}).Error("读取上传文件失败")
+		c.Status(http.StatusBadGateway)
+		return
+	}
+	defer reader.Close()
+
+	etag := fmt.Sprintf("%q", info.ETag)
+	c.Header("Cache-Control", "public, max-age=31536000, immutable")
+	c.Header("ETag", etag)
+	c.Header("X-Content-Type-Options", "nosniff")
+	if !info.LastModified.IsZero() {
+		c.Header("Last-Modified", info.LastModified.UTC().Format(http.TimeFormat))
+	}
+
+	if match := c.GetHeader("If-None-Match"); match != "" && match == etag {
+		c.Status(http.StatusNotModified)
+		return
+	}
+
+	contentType := info.ContentType
+	if contentType == "" {
+		contentType = "application/octet-stream"
+	}
+	c.Header("Content-Type", contentType)
+	if info.Size > 0 {
+		c.Header("Content-Length", strconv.FormatInt(info.Size, 10))
+	}
+	c.Status(http.StatusOK)
+	io.Copy(c.Writer, reader)
+}
@@ -4,11 +4,13 @@
 	"encoding/json"
 	"fmt"
 	"net/http"
+	"net/url"
 	"strings"
 	"time"
 
 	"lottery-system/config"
 	"lottery-system/models"
+	"lottery-system/namematch"
 	"lottery-system/utils"
 
 	"github.com/gin-gonic/gin"
@@ -98,7 +100,7 @@ func CreateUser(c *gin.Context) {
 		query := config.DB.Where("company_id = ?", req.CompanyID).Where("username = ?", req.Username)
 
 		if req.Phone != "" {
-			query = query.Where("phone = ?", req.Phone)
+			query = query.Where("phone_hash = ?", models.PhoneIndex(req.Phone))
 		}
 
 		if err := query.Find(&existingUsers).Error; err == nil && len(existingUsers) > 0 {
@@ -142,14 +144,7 @@ func CreateUser(c *gin.Context) {
 		}
 	} else {
 		// 情况2：只提供了 name 和 phone -> 创建不可登录的用户（管理员添加）
-		// 自动生成 username（使用手机号或时间戳+随机数）
-		var username string
-		if req.Phone != "" {
-			username = req.Phone
-		} else {
-			username = fmt.Sprintf("u_%d_%d", time.Now().Unix(), utils.RandomInt(10000))
-		}
-
+		// username 留空，创建后自动生成 u_<ID>（不使用手机号）
 		// 生成随机密码（用户无法登录，但密码字段不能为空）
 		randomPassword := utils.GenerateRandomPassword(8)
 		hashedPassword, err := utils.HashPassword(randomPassword)
@@ -158,17 +153,8 @@ func CreateUser(c *gin.Context) {
 			return
 		}
 
-		// 检查用户名是否已存在
-		var existingUserCount int64
-		config.DB.Model(&models.User{}).Where("company_id = ? AND username = ?", req.CompanyID, username).Count(&existingUserCount)
-		if existingUserCount > 0 {
-			// 用户名重复，添加序号
-			username = fmt.Sprintf("%s_%d", username, existingUserCount+1)
-		}
-
 		user = models.User{
 			CompanyID: req.CompanyID,
-			Username:  username,
 			Password:  hashedPassword,
 			Role:      models.RoleUser,
 			Name:      req.Name,
@@ -191,6 +177,8 @@ func CreateUser(c *gin.Context) {
 	}
 	LogOperation(c, "create", "user", &resourceID, details)
 
+	broadcastParticipantRegistered(user.CompanyID, &user, 1)
+
 	// 如果用户名被修改了，返回提示
 	response := map[string]interface{}{
 		"id":        user.ID,
@@ -239,10 +227,6 @@ func BatchCreateUsers(c *gin.Context) {
 	var createdUsers []models.User
 	var failedUsers []string
 
-	// 用于批量导入时的用户名计数器
-	batchIndex := 0
-	baseTimestamp := time.Now().Unix()
-
 	for _, userStr := range req.Users {
 		// 解析格式: "姓名,手机号（可选）"
 		var name, phone string
@@ -268,28 +252,23 @@ func BatchCreateUsers(c *gin.Context) {
 			}
 		}
 
-		// 检查是否已存在（根据姓名和手机号）
+		// 检查是否已存在（根据姓名和手机号，姓名按繁简体、全半角、空格统一后比较）
 		var existingUser models.User
-		query := config.DB.Where("company_id = ? AND name = ?", req.CompanyID, name)
+		nameKey, _ := namematch.NameKeys(name)
+		query := config.DB.Where("company_id = ? AND (name = ? OR name_key = ?)", req.CompanyID, name, nameKey)
 		if phone != "" {
-			query = query.Where("phone = ?", phone)
+			query = query.Where("phone_hash = ?", models.PhoneIndex(phone))
 		}
 		if err := query.First(&existingUser).Error; err == nil {
 			failedUsers = append(failedUsers, name+" (已存在)")
 			continue
 		}
-
-		// 创建用户（自动生成 username 和 password）
-		// 生成 username：优先使用手机号，否则使用时间戳+批量索引
-		var username string
-		if phone != "" {
-			username = phone
-		} else {
-			// 使用批量索引确保唯一性
-			batchIndex++
-			username = fmt.Sprintf("u_%d_%d", baseTimestamp, batchIndex)
+		if duplicate := findLikelyDuplicate(req.CompanyID, name, phone); duplicate != nil {
+			failedUsers = append(failedUsers, fmt.Sprintf("%s (疑似与已有用户 %s 重复)", name, duplicate.Name))
+			continue
 		}
 
+		// 创建用户（username 创建后自动生成 u_<ID>，密码随机）
 		// 生成随机密码（用户无法登录，但密码字段不能为空）
 		randomPassword := utils.GenerateRandomPassword(8)
 		hashedPassword, err := utils.HashPassword(randomPassword)
@@ -298,17 +277,8 @@ func BatchCreateUsers(c *gin.Context) {
 			continue
 		}
 
-		// 检查用户名是否已存在
-		var existingUserCount int64
-		config.DB.Model(&models.User{}).Where("company_id = ? AND username = ?", req.CompanyID, username).Count(&existingUserCount)
-		if existingUserCount > 0 {
-			// 用户名重复，添加序号
-			username = fmt.Sprintf("%s_%d", username, existingUserCount+1)
-		}
-
 		user := models.User{
 			CompanyID: req.CompanyID,
-			Username:  username,
 			Password:  hashedPassword,
 			Role:      models.RoleUser,
 			Name:      name,
@@ -337,6 +307,8 @@ func BatchCreateUsers(c *gin.Context) {
 			resourceID = &rid
 		}
 		LogOperation(c, "create", "user", resourceID, details)
+
+		broadcastParticipantRegistered(req.CompanyID, nil, len(createdUsers))
 	}
 
 	c.JSON(http.StatusOK, gin.H{
@@ -350,9 +322,8 @@ func BatchCreateUsers(c *gin.Context) {
 // GetUsers 获取用户列表（权限隔离）
 func GetUsers(c *gin.Context) {
 	companyIDParam := c.Query("company_id")
-	hasDrawn := c.Query("has_drawn")
 
-	query := config.DB.Model(&models.User{})
+	query := applyUserFilters(config.DB.Model(&models.User{}), c.Request.URL.Query())
 
 	// 检查是否是超级管理员
 	isSuperAdmin, exists := c.Get("is_super_admin")
@@ -371,16 +342,47 @@ func GetUsers(c *gin.Context) {
 		}
 	}
 
-	if hasDrawn != "" {
-		query = query.Where("has_drawn = ?", hasDrawn)
+	// 按报名表单字段筛选：field.<key>=<value>，需确定公司
+	if filters := utils.FieldFiltersFromQuery(c.Request.URL.Query()); len(filters) > 0 {
+		companyID, ok := getScopedCompanyID(c, companyIDParam)
+		if !ok {
+			return
+		}
+		if err := utils.ValidateFieldFilters(filters); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		query = utils.ApplyFieldFilters(config.DB, query, companyID, filters)
 	}
 
 	var users []models.User
 	query.Order("id ASC").Find(&users)
+	utils.AttachUserFields(config.DB, users)
 
 	c.JSON(http.StatusOK, users)
 }
 
+// applyUserFilters 按查询参数筛选参与者（列表和导出共用）：has_drawn、waitlisted、review_status、deactivated
+// 报名表单字段筛选（field.<key>）需确定公司，由调用方处理
+func applyUserFilters(query *gorm.DB, params url.Values) *gorm.DB {
+	if hasDrawn := params.Get("has_drawn"); hasDrawn != "" {
+		query = query.Where("has_drawn = ?", hasDrawn)
+	}
+	if waitlisted := params.Get("waitlisted"); waitlisted != "" {
+		query = query.Where("waitlisted = ?", waitlisted)
+	}
+	if reviewStatus := params.Get("review_status"); reviewStatus != "" {
+		query = query.Where("review_status = ?", reviewStatus)
+	}
+	switch params.Get("deactivated") {
+	case "true":
+		query = query.Where("deactivated_at IS NOT NULL")
+	case "false":
+		query = query.Where("deactivated_at IS NULL")
+	}
+	return query
+}
+
 // DeleteUser 删除用户（权限检查）
 func DeleteUser(c *gin.Context) {
 	id := c.Param("id")
@@ -406,11 +408,17 @@ func DeleteUser(c *gin.Context) {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete user"})
 		return
 	}
+	config.DB.Where("user_id = ?", user.ID).Delete(&models.UserFieldValue{})
 
 	// 记录操作日志
 	resourceID := uint(user.ID)
 	LogOperation(c, "delete", "user", &resourceID, fmt.Sprintf("删除用户: %s (@%s)", user.Name, user.Username))
 
+	// 正式参与者被删除后，候补名单按顺序补位
+	if !user.Waitlisted {
+		promoteWaitlistAfterRemoval(user.CompanyID)
+	}
+
 	c.JSON(http.StatusOK, gin.H{"message": "User deleted successfully"})
 }
 
@@ -419,6 +427,8 @@ type UpdateUserRequest struct {
 	Name     string `json:"name"`
 	Phone    string `json:"phone"`
 	HasDrawn *bool  `json:"has_drawn"`
+
+	Fields map[string]string `json:"fields"` // 报名表单字段（传入时整体替换）
 }
 
 // UpdateUser 更新用户（权限检查）
@@ -457,6 +467,7 @@ func UpdateUser(c *gin.Context) {
 			return
 		}
 		updates["name"] = req.Name
+		updates["name_key"], updates["name_pinyin"] = namematch.NameKeys(req.Name)
 	}
 
 	if req.Phone != "" {
@@ -464,22 +475,52 @@ func UpdateUser(c *gin.Context) {
 			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 			return
 		}
-		updates["phone"] = req.Phone
+		if err := models.SetPhoneUpdate(updates, req.Phone); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "更新用户失败"})
+			return
+		}
 	}
 
 	if req.HasDrawn != nil {
 		updates["has_drawn"] = *req.HasDrawn
 	}
 
-	if len(updates) == 0 {
+	var answers map[string]string
+	if req.Fields != nil {
+		var err error
+		answers, err = utils.ValidateFormAnswers(activeRegistrationFields(user.CompanyID), req.Fields)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	if len(updates) == 0 && req.Fields == nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "没有要更新的字段"})
 		return
 	}
 
-	if err := config.DB.Model(&user).Updates(updates).Error; err != nil {
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		if len(updates) > 0 {
+			if err := tx.Model(&user).Updates(updates).Error; err != nil {
+				return err
+			}
+		}
+		if req.Fields != nil {
+			return utils.SaveUserFieldValues(tx, user.CompanyID, user.ID, answers)
+		}
+		return nil
+	})
+	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "更新失败"})
 		return
 	}
+	if req.Phone != "" {
+		user.Phone = req.Phone
+	}
+	users := []models.User{user}
+	utils.AttachUserFields(config.DB, users)
+	user = users[0]
 
 	// 记录操作日志
 	resourceID := uint(user.ID)
@@ -593,14 +634,48 @@ func ScanAddUser(c *gin.Context) {
 	}
 
 	// 解析二维码数据
-	// 支持两种格式：
+	// 签名格式: LQ1.<密钥ID>.<载荷>.<签名>（由服务端或生成工具签发，带有效期）
+	// 旧版明文格式（需开启 USER_QR_ALLOW_LEGACY）：
 	// 1. JSON格式: {"username":"zhangsan","name":"张三","phone":"13800138000"}
 	// 2. 简单格式: username:zhangsan,name:张三,phone:13800138000
 
 	var username, name, phone string
 
-	// 尝试解析为JSON
-	if strings.HasPrefix(req.QRCodeData, "{") {
+	if utils.IsSignedUserQR(req.QRCodeData) {
+		keyring, err := userQRKeyring()
+		if err != nil {
+			c.JSON(http.StatusServiceUnavailable, gin.H{"error": "二维码签名密钥配置错误"})
+			return
+		}
+
+		claims, err := keyring.Verify(req.QRCodeData, time.Now())
+		switch err {
+		case nil:
+		case utils.ErrUserQRExpired:
+			c.JSON(http.StatusBadRequest, gin.H{"error": "二维码已过期，请重新生成", "error_code": "QR_EXPIRED"})
+			return
+		default:
+			utils.NewSecurityLogger().LogSuspiciousActivity("user_qr_invalid_signature", "扫码添加用户的二维码签名无效", c.ClientIP())
+			c.JSON(http.StatusBadRequest, gin.H{"error": "二维码签名无效", "error_code": "QR_INVALID_SIGNATURE"})
+			return
+		}
+
+		if claims.CompanyID != companyID {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "二维码不属于当前公司", "error_code": "QR_COMPANY_MISMATCH"})
+			return
+		}
+
+		username = claims.Username
+		name = claims.Name
+		phone = claims.Phone
+	} else if !config.AppConfig.UserQRAllowLegacy {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":      "不支持未签名的旧版二维码，请使用系统生成的二维码",
+			"error_code": "LEGACY_QR_DISABLED",
+		})
+		return
+	} else if strings.HasPrefix(req.QRCodeData, "{") {
+		// 旧版 JSON 格式
 		var qrData map[string]string
 		if err := json.Unmarshal([]byte(req.QRCodeData), &qrData); err != nil {
 			c.JSON(http.StatusBadRequest, gin.H{"error": "二维码格式错误，无法解析JSON"})
@@ -636,6 +711,14 @@ func ScanAddUser(c *gin.Context) {
 		return
 	}
 
+	if !utils.IsSignedUserQR(req.QRCodeData) {
+		utils.WithFields(map[string]interface{}{
+			"username":   username,
+			"company_id": companyID,
+			"ip":         c.ClientIP(),
+		}).Warn("使用旧版未签名二维码添加用户")
+	}
+
 	if name == "" {
 		name = username // 如果没有姓名，使用用户名
 	}
@@ -647,7 +730,7 @@ func ScanAddUser(c *gin.Context) {
 
 	if phone != "" {
 		// 有手机号：检查 (username, phone) 组合
-		query = query.Where("phone = ?", phone)
+		query = query.Where("phone_hash = ?", models.PhoneIndex(phone))
 	}
 
 	if err := query.Find(&existingUsers).Error; err != nil && err != gorm.ErrRecordNotFound {
@@ -743,6 +826,8 @@ func ScanAddUser(c *gin.Context) {
 		"company_id": companyID,
 	}).Info("扫码添加用户成功")
 
+	broadcastParticipantRegistered(companyID, &user, 1)
+
 	c.JSON(http.StatusOK, gin.H{
 		"message": "添加用户成功",
 		"user": gin.H{
@@ -0,0 +1,525 @@
+package handlers
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"lottery-system/config"
+	"lottery-system/models"
+	"lottery-system/namematch"
+	"lottery-system/utils"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// 参与者导入限制
+const (
+	maxImportBytes      = 10 << 20 // 导入文件大小上限
+	maxImportRows       = 5000     // 导入行数上限（含表头）
+	importSampleRows    = 20       // 预览返回的示例行数
+	importFieldPrefix   = "field:" // 列映射中自定义字段的前缀，如 field:employee_id
+	importOnExistSkip   = "skip"   // 已存在的参与者跳过
+	importOnExistUpdate = "update" // 已存在的参与者更新映射的列
+)
+
+// 导入行处理结果
+const (
+	importActionCreate = "create"
+	importActionUpdate = "update"
+	importActionSkip   = "skip"
+	importActionError  = "error"
+)
+
+// ImportRowResult 导入文件中一行的校验结果
+type ImportRowResult struct {
+	Row      int               `json:"row"` // 表格中的行号（从 1 开始，含表头）
+	Action   string            `json:"action"`
+	Name     string            `json:"name,omitempty"`
+	Phone    string            `json:"phone,omitempty"`
+	UserID   int               `json:"user_id,omitempty"` // 匹配到的已有参与者
+	Errors   []string          `json:"errors,omitempty"`
+	Warnings []string          `json:"warnings,omitempty"`
+	Fields   map[string]string `json:"fields,omitempty"`
+}
+
+// ImportReport 导入校验报告
+type ImportReport struct {
+	Headers   []string          `json:"headers"`
+	Mapping   []string          `json:"mapping"`
+	FileHash  string            `json:"file_hash"` // 提交导入时回传，确保与预览的是同一个文件
+	TotalRows int               `json:"total_rows"`
+	Create    int               `json:"create"`
+	Update    int               `json:"update"`
+	Skip      int               `json:"skip"`
+	Invalid   int               `json:"invalid"`
+	Sample    []ImportRowResult `json:"sample"` // 前若干行的结果
+	Issues    []ImportRowResult `json:"issues"` // 所有有错误或警告的行
+}
+
+// importRequest 导入请求（multipart 表单）
+type importRequest struct {
+	companyID   int
+	rows        [][]string
+	fileHash    string
+	mapping     []string // 与列对齐：name, phone, field:<key>，空字符串表示忽略该列
+	onExisting  string
+	skipInvalid bool
+}
+
+// PreviewUserImport 预览导入参与者（权限检查，不写入数据）
+// multipart 参数：file（CSV/XLSX，首行为表头）、company_id、mapping（可选，JSON 数组，与列对齐）、on_existing（skip/update）
+// 未提供 mapping 时按表头自动推荐，返回逐行校验结果
+func PreviewUserImport(c *gin.Context) {
+	req, ok := parseImportRequest(c)
+	if !ok {
+		return
+	}
+	fields := activeRegistrationFields(req.companyID)
+
+	suggested := suggestImportMapping(req.rows[0], fields)
+	if req.mapping == nil {
+		req.mapping = suggested
+	}
+	if err := validateImportMapping(req.mapping, len(req.rows[0]), fields); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":             err.Error(),
+			"headers":           req.rows[0],
+			"suggested_mapping": suggested,
+			"fields":            fields,
+		})
+		return
+	}
+
+	report, _, err := analyzeImport(config.DB, req, fields)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "校验导入数据失败"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"report":            report,
+		"suggested_mapping": suggested,
+		"fields":            fields,
+	})
+}
+
+// ImportUsers 导入参与者（权限检查）
+// 参数同预览，mapping 必填；file_hash 可选，与预览返回的不一致时拒绝导入
+// 存在校验错误的行时整批不导入（skip_invalid=true 时跳过错误行），所有写入在同一事务中完成
+func ImportUsers(c *gin.Context) {
+	req, ok := parseImportRequest(c)
+	if !ok {
+		return
+	}
+	if req.mapping == nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "请指定列映射"})
+		return
+	}
+	if expected := c.PostForm("file_hash"); expected != "" && expected != req.fileHash {
+		c.JSON(http.StatusConflict, gin.H{"error": "文件与预览时不一致，请重新预览", "error_code": "IMPORT_FILE_CHANGED"})
+		return
+	}
+
+	fields := activeRegistrationFields(req.companyID)
+	if err := validateImportMapping(req.mapping, len(req.rows[0]), fields); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var report *ImportReport
+	var created []models.User
+	errInvalidRows := errors.New("invalid rows")
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		// 锁定公司记录，避免并发导入/报名重复创建
+		var company models.Company
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&company, req.companyID).Error; err != nil {
+			return err
+		}
+
+		var results []ImportRowResult
+		var err error
+		report, results, err = analyzeImport(tx, req, fields)
+		if err != nil {
+			return err
+		}
+		if report.Invalid > 0 && !req.skipInvalid {
+			return errInvalidRows
+		}
+
+		created, err = applyImport(tx, req.companyID, results)
+		return err
+	})
+	switch {
+	case errors.Is(err, errInvalidRows):
+		c.JSON(http.StatusUnprocessableEntity, gin.H{
+			"error":      fmt.Sprintf("有 %d 行数据校验失败，未导入任何数据", report.Invalid),
+			"error_code": "IMPORT_INVALID_ROWS",
+			"report":     report,
+		})
+		return
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Company not found"})
+		return
+	case err != nil:
+		utils.WithFields(map[string]interface{}{"error": err, "company_id": req.companyID}).Error("导入参与者失败")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "导入参与者失败"})
+		return
+	}
+
+	skipped := report.Skip + report.Invalid
+	resourceID := uint(req.companyID)
+	LogOperation(c, "import", "user", &resourceID,
+		fmt.Sprintf("导入参与者: 新增%d个, 更新%d个, 跳过%d个", report.Create, report.Update, skipped))
+	if len(created) > 0 {
+		broadcastParticipantRegistered(req.companyID, nil, len(created))
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"created": report.Create,
+		"updated": report.Update,
+		"skipped": skipped,
+		"report":  report,
+	})
+}
+
+// parseImportRequest 解析导入请求并读取表格
+func parseImportRequest(c *gin.Context) (*importRequest, bool) {
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxImportBytes+64*1024)
+
+	fileHeader, err := c.FormFile("file")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("请选择不超过 %dMB 的 CSV 或 XLSX 文件", maxImportBytes>>20)})
+		return nil, false
+	}
+
+	companyIDParam := c.PostForm("company_id")
+	if companyIDParam == "" {
+		companyIDParam = c.Query("company_id")
+	}
+	companyID, ok := getScopedCompanyID(c, companyIDParam)
+	if !ok {
+		return nil, false
+	}
+
+	req := &importRequest{
+		companyID:   companyID,
+		onExisting:  c.DefaultPostForm("on_existing", importOnExistSkip),
+		skipInvalid: c.PostForm("skip_invalid") == "true",
+	}
+	if req.onExisting != importOnExistSkip && req.onExisting != importOnExistUpdate {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "on_existing 只能是 skip 或 update"})
+		return nil, false
+	}
+	if raw := c.PostForm("mapping"); raw != "" {
+		if err := json.Unmarshal([]byte(raw), &req.mapping); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "mapping 应为 JSON 字符串数组"})
+			return nil, false
+		}
+		if req.mapping == nil {
+			req.mapping = []string{}
+		}
+	}
+
+	file, err := fileHeader.Open()
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "读取文件失败"})
+		return nil, false
+	}
+	defer file.Close()
+
+	data, err := io.ReadAll(io.LimitReader(file, maxImportBytes+1))
+	if err != nil || len(data) > maxImportBytes {
+		c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": fmt.Sprintf("文件不能超过 %dMB", maxImportBytes>>20)})
+		return nil, false
+	}
+
+	req.rows, err = utils.ReadSpreadsheet(fileHeader.Filename, data, maxImportRows)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return nil, false
+	}
+	if len(req.rows) < 2 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "文件中只有表头，没有参与者数据"})
+		return nil, false
+	}
+
+	sum := sha256.Sum256(data)
+	req.fileHash = hex.EncodeToString(sum[:])
+	return req, true
+}
+
+// suggestImportMapping 按表头推荐列映射（姓名、手机号、自定义字段名称或标识）
+func suggestImportMapping(headers []string, fields []models.RegistrationField) []string {
+	mapping := make([]string, len(headers))
+	used := map[string]bool{}
+	for i, header := range headers {
+		h := strings.ToLower(strings.TrimSpace(header))
+		target := ""
+		switch {
+		case h == "姓名" || h == "名字" || h == "name" || h == "参与者":
+			target = "name"
+		case strings.Contains(h, "手机") || strings.Contains(h, "电话") || h == "phone" || h == "mobile" || h == "tel":
+			target = "phone"
+		default:
+			for _, field := range fields {
+				if h == strings.ToLower(field.Label) || h == field.Key {
+					target = importFieldPrefix + field.Key
+					break
+				}
+			}
+		}
+		if target != "" && !used[target] {
+			mapping[i] = target
+			used[target] = true
+		}
+	}
+	return mapping
+}
+
+// validateImportMapping 校验列映射：必须映射姓名列，每个目标只能映射一列，自定义字段必须是启用的字段
+func validateImportMapping(mapping []string, columns int, fields []models.RegistrationField) error {
+	if len(mapping) > columns {
+		return fmt.Errorf("列映射数量（%d）超过文件列数（%d）", len(mapping), columns)
+	}
+	active := map[string]bool{}
+	for _, field := range fields {
+		active[field.Key] = true
+	}
+
+	used := map[string]bool{}
+	for i, target := range mapping {
+		switch {
+		case target == "":
+			continue
+		case target == "name" || target == "phone":
+		case strings.HasPrefix(target, importFieldPrefix) && active[strings.TrimPrefix(target, importFieldPrefix)]:
+		default:
+			return fmt.Errorf("第 %d 列的映射无效: %s", i+1, target)
+		}
+		if used[target] {
+			return fmt.Errorf("%s 被映射到多列", target)
+		}
+		used[target] = true
+	}
+	if !used["name"] {
+		return errors.New("请指定姓名列")
+	}
+	return nil
+}
+
+// analyzeImport 逐行校验导入数据并与已有参与者比对（不写入），返回报告和每行的结果
+// 已有参与者按规范化姓名+手机号匹配；文件中未填手机号时只按姓名匹配，匹配到多人时报错
+func analyzeImport(db *gorm.DB, req *importRequest, fields []models.RegistrationField) (*ImportReport, []ImportRowResult, error) {
+	var existing []models.User
+	if err := db.Select("id", "name", "phone").Where("company_id = ?", req.companyID).Find(&existing).Error; err != nil {
+		return nil, nil, err
+	}
+	byNamePhone := map[string][]*models.User{}
+	byName := map[string][]*models.User{}
+	byPhone := map[string][]*models.User{}
+	for i := range existing {
+		u := &existing[i]
+		keys := namematch.KeysOf(u.Name, u.Phone)
+		byName[keys.Name] = append(byName[keys.Name], u)
+		byNamePhone[keys.Name+"|"+keys.Phone] = append(byNamePhone[keys.Name+"|"+keys.Phone], u)
+		if keys.Phone != "" {
+			byPhone[keys.Phone] = append(byPhone[keys.Phone], u)
+		}
+	}
+
+	// 更新已有参与者时，未映射的字段保留原值后再整体校验
+	var existingFields map[int]map[string]string
+	if req.onExisting == importOnExistUpdate {
+		var values []models.UserFieldValue
+		if err := db.Where("company_id = ?", req.companyID).Find(&values).Error; err != nil {
+			return nil, nil, err
+		}
+		existingFields = map[int]map[string]string{}
+		for _, v := range values {
+			if existingFields[v.UserID] == nil {
+				existingFields[v.UserID] = map[string]string{}
+			}
+			existingFields[v.UserID][v.FieldKey] = v.Value
+		}
+	}
+
+	report := &ImportReport{Headers: req.rows[0], Mapping: req.mapping, FileHash: req.fileHash, TotalRows: len(req.rows) - 1}
+	results := make([]ImportRowResult, 0, len(req.rows)-1)
+	seen := map[string]int{}
+
+	for i, row := range req.rows[1:] {
+		result := ImportRowResult{Row: i + 2}
+		input := map[string]string{}
+		mappedFields := map[string]bool{}
+		for col, target := range req.mapping {
+			value := ""
+			if col < len(row) {
+				value = row[col]
+			}
+			switch {
+			case target == "name":
+				result.Name = value
+			case target == "phone":
+				result.Phone = namematch.NormalizePhone(value)
+				if value != "" && result.Phone == "" {
+					result.Errors = append(result.Errors, "手机号格式错误")
+				}
+			case strings.HasPrefix(target, importFieldPrefix):
+				key := strings.TrimPrefix(target, importFieldPrefix)
+				input[key] = value
+				mappedFields[key] = true
+			}
+		}
+
+		if err := utils.ValidateName(result.Name); err != nil {
+			result.Errors = append(result.Errors, err.Error())
+		}
+		if result.Phone != "" {
+			if err := utils.ValidatePhone(result.Phone); err != nil {
+				result.Errors = append(result.Errors, err.Error())
+			}
+		}
+
+		keys := namematch.KeysOf(result.Name, result.Phone)
+		rowKey := keys.Name + "|" + keys.Phone
+		if first, ok := seen[rowKey]; ok && keys.Name != "" {
+			result.Errors = append(result.Errors, fmt.Sprintf("与第 %d 行重复", first))
+		} else {
+			seen[rowKey] = result.Row
+		}
+
+		var match *models.User
+		if result.Phone != "" {
+			if users := byNamePhone[rowKey]; len(users) > 0 {
+				match = users[0]
+			} else if users := byNamePhone[keys.Name+"|"]; len(users) == 1 && len(byName[keys.Name]) == 1 {
+				// 已有参与者未填手机号且姓名唯一，视为同一人（更新时补充手机号）
+				match = users[0]
+			}
+		} else if users := byName[keys.Name]; len(users) == 1 {
+			match = users[0]
+		} else if len(users) > 1 {
+			result.Errors = append(result.Errors, fmt.Sprintf("已有 %d 位同名参与者，请填写手机号区分", len(users)))
+		}
+
+		switch {
+		case match != nil && req.onExisting == importOnExistSkip:
+			result.Action = importActionSkip
+			result.UserID = match.ID
+		case match != nil:
+			result.Action = importActionUpdate
+			result.UserID = match.ID
+			merged := map[string]string{}
+			for key, value := range existingFields[match.ID] {
+				merged[key] = value
+			}
+			for key := range mappedFields {
+				merged[key] = input[key]
+			}
+			input = merged
+		default:
+			result.Action = importActionCreate
+			for _, u := range byPhone[keys.Phone] {
+				if score, _ := namematch.Match(keys, namematch.KeysOf(u.Name, u.Phone)); score >= likelyDuplicateScore {
+					result.Warnings = append(result.Warnings, fmt.Sprintf("疑似与已有参与者 %s (ID %d) 重复", u.Name, u.ID))
+					break
+				}
+			}
+		}
+
+		if result.Action != importActionSkip {
+			answers, err := utils.ValidateFormAnswers(fields, input)
+			if err != nil {
+				result.Errors = append(result.Errors, err.Error())
+			}
+			result.Fields = answers
+		}
+
+		if len(result.Errors) > 0 {
+			result.Action = importActionError
+		}
+		switch result.Action {
+		case importActionCreate:
+			report.Create++
+		case importActionUpdate:
+			report.Update++
+		case importActionSkip:
+			report.Skip++
+		default:
+			report.Invalid++
+		}
+
+		results = append(results, result)
+		if len(report.Sample) < importSampleRows {
+			report.Sample = append(report.Sample, result)
+		}
+		if len(result.Errors) > 0 || len(result.Warnings) > 0 {
+			report.Issues = append(report.Issues, result)
+		}
+	}
+	return report, results, nil
+}
+
+// applyImport 在事务中写入校验通过的行，返回新创建的参与者
+// 新参与者不设密码（与扫码报名一致，无法登录），用户名创建后自动生成；更新时只补充空手机号并覆盖映射的字段
+func applyImport(tx *gorm.DB, companyID int, results []ImportRowResult) ([]models.User, error) {
+	var created []models.User
+	var createdFields []map[string]string
+
+	for _, result := range results {
+		switch result.Action {
+		case importActionCreate:
+			created = append(created, models.User{
+				CompanyID: companyID,
+				Password:  "",
+				Role:      models.RoleUser,
+				Name:      result.Name,
+				Phone:     result.Phone,
+			})
+			createdFields = append(createdFields, result.Fields)
+		case importActionUpdate:
+			if result.Phone != "" {
+				updates := map[string]interface{}{}
+				if err := models.SetPhoneUpdate(updates, result.Phone); err != nil {
+					return nil, err
+				}
+				if err := tx.Model(&models.User{}).
+					Where("id = ? AND (phone = ? OR phone IS NULL)", result.UserID, "").
+					Updates(updates).Error; err != nil {
+					return nil, err
+				}
+			}
+			if err := utils.SaveUserFieldValues(tx, companyID, result.UserID, result.Fields); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	if len(created) == 0 {
+		return nil, nil
+	}
+	if err := tx.CreateInBatches(&created, 200).Error; err != nil {
+		return nil, err
+	}
+
+	var values []models.UserFieldValue
+	for i, user := range created {
+		for key, value := range createdFields[i] {
+			values = append(values, models.UserFieldValue{CompanyID: companyID, UserID: user.ID, FieldKey: key, Value: value})
+		}
+	}
+	if len(values) > 0 {
+		if err := tx.CreateInBatches(&values, 500).Error; err != nil {
+			return nil, err
+		}
+	}
+	return created, nil
+}
@@ -0,0 +1,130 @@
+package handlers
+
+import (
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"lottery-system/config"
+	"lottery-system/models"
+	"lottery-system/utils"
+
+	"github.com/gin-gonic/gin"
+	"github.com/skip2/go-qrcode"
+)
+
+// GenerateUserQRRequest 生成用户二维码请求
+type GenerateUserQRRequest struct {
+	CompanyID int    `json:"company_id"` // 仅超级管理员需要指定
+	Username  string `json:"username" binding:"required"`
+	Name      string `json:"name"`
+	Phone     string `json:"phone"`
+	TTLHours  int    `json:"ttl_hours"` // 有效期（小时），为空使用默认配置
+}
+
+var (
+	userQRKeyringOnce sync.Once
+	userQRKeys        *utils.UserQRKeyring
+	userQRKeysErr     error
+)
+
+// GenerateUserQR 为用户签发带签名和有效期的扫码添加二维码（权限隔离）
+func GenerateUserQR(c *gin.Context) {
+	var req GenerateUserQRRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "请求参数格式错误"})
+		return
+	}
+
+	companyID, ok := getScopedCompanyID(c, fmt.Sprint(req.CompanyID))
+	if !ok {
+		return
+	}
+
+	req.Username = strings.TrimSpace(req.Username)
+	req.Name = strings.TrimSpace(req.Name)
+	if req.Username == "" || len(req.Username) > 100 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "用户名长度应为1-100个字符"})
+		return
+	}
+	if req.Name != "" {
+		if err := utils.ValidateName(req.Name); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "姓名格式错误: " + err.Error()})
+			return
+		}
+	}
+	if req.Phone != "" {
+		if err := utils.ValidatePhone(req.Phone); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	ttl := req.TTLHours
+	if ttl <= 0 {
+		ttl = config.AppConfig.UserQRTTLHours
+	}
+	if ttl <= 0 || ttl > 24*30 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "有效期应为1-720小时"})
+		return
+	}
+
+	var company models.Company
+	if err := config.DB.First(&company, companyID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Company not found"})
+		return
+	}
+
+	keyring, err := userQRKeyring()
+	if err != nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "二维码签名密钥配置错误"})
+		return
+	}
+
+	now := time.Now()
+	claims := utils.UserQRClaims{
+		CompanyID: company.ID,
+		Username:  req.Username,
+		Name:      req.Name,
+		Phone:     req.Phone,
+		IssuedAt:  now.Unix(),
+		ExpiresAt: now.Add(time.Duration(ttl) * time.Hour).Unix(),
+	}
+	token, err := keyring.Sign(claims)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "生成二维码失败"})
+		return
+	}
+	png, err := qrcode.Encode(token, qrcode.Medium, 256)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "生成二维码失败"})
+		return
+	}
+
+	LogOperation(c, "generate_user_qr", "user", nil, fmt.Sprintf("生成用户二维码: %s (%s)", req.Username, company.Name))
+
+	c.JSON(http.StatusOK, gin.H{
+		"qr_code_data": token,
+		"qr_code":      fmt.Sprintf("data:image/png;base64,%s", base64.StdEncoding.EncodeToString(png)),
+		"key_id":       keyring.ActiveKeyID(),
+		"expires_at":   time.Unix(claims.ExpiresAt, 0),
+	})
+}
+
+// userQRKeyring 获取用户二维码签名密钥环（首次调用时解析配置）
+func userQRKeyring() (*utils.UserQRKeyring, error) {
+	userQRKeyringOnce.Do(func() {
+		userQRKeys, userQRKeysErr = utils.ParseUserQRKeyring(
+			config.AppConfig.UserQRKeys,
+			config.AppConfig.UserQRActiveKey,
+			utils.DeriveKey("user-qr:"+config.AppConfig.JWTSecret),
+		)
+		if userQRKeysErr != nil {
+			utils.WithFields(map[string]interface{}{"error": userQRKeysErr}).Error("用户二维码签名密钥配置错误")
+		}
+	})
+	return userQRKeys, userQRKeysErr
+}
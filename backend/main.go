@@ -3,9 +3,15 @@
 import (
 	"context"
 	"log"
+	"lottery-system/challenge"
 	"lottery-system/config"
+	"lottery-system/handlers"
 	"lottery-system/middleware"
+	"lottery-system/otp"
+	"lottery-system/realtime"
 	"lottery-system/router"
+	"lottery-system/sms"
+	"lottery-system/storage"
 	"lottery-system/utils"
 	"math/rand"
 	"time"
@@ -54,6 +60,77 @@ func main() {
 		log.Printf("✅ 内存限流器已初始化（%d req/sec, %d burst）", config.AppConfig.RateLimitRPS, config.AppConfig.RateLimitBurst)
 	}
 
+	// 初始化实时事件广播（启用 Redis 时跨实例分发）
+	if redisClient != nil {
+		hub := realtime.NewRedisHub(redisClient)
+		go hub.Run(context.Background())
+		realtime.SetDefault(hub)
+		log.Println("✅ 实时事件广播已启用（Redis pub/sub）")
+	} else {
+		realtime.SetDefault(realtime.NewMemoryHub())
+		log.Println("ℹ️  实时事件广播使用进程内模式")
+	}
+
+	// 初始化上传文件存储
+	store, err := storage.New(storage.Options{
+		Driver:      config.AppConfig.StorageDriver,
+		LocalDir:    config.AppConfig.UploadDir,
+		S3Endpoint:  config.AppConfig.S3Endpoint,
+		S3Region:    config.AppConfig.S3Region,
+		S3Bucket:    config.AppConfig.S3Bucket,
+		S3AccessKey: config.AppConfig.S3AccessKey,
+		S3SecretKey: config.AppConfig.S3SecretKey,
+	})
+	if err != nil {
+		log.Fatalf("❌ 初始化文件存储失败: %v", err)
+	}
+	storage.SetDefault(store)
+	log.Printf("✅ 文件存储已初始化（%s）", config.AppConfig.StorageDriver)
+
+	// 初始化短信发送和验证码存储（启用 Redis 时验证码存储在 Redis）
+	sender, err := sms.New(sms.Options{
+		Driver:       config.AppConfig.SMSDriver,
+		FilePath:     config.AppConfig.SMSFilePath,
+		WebhookURL:   config.AppConfig.SMSWebhookURL,
+		WebhookToken: config.AppConfig.SMSWebhookToken,
+	})
+	if err != nil {
+		log.Fatalf("❌ 初始化短信发送失败: %v", err)
+	}
+	sms.SetDefault(sender)
+	if redisClient != nil {
+		otp.SetDefault(otp.NewRedisStore(redisClient))
+	} else {
+		dbStore := otp.NewDBStore(config.DB)
+		go dbStore.RunCleanup(context.Background(), 10*time.Minute)
+		otp.SetDefault(dbStore)
+	}
+	switch config.AppConfig.SMSDriver {
+	case "":
+		log.Println("⚠️  未配置短信驱动（SMS_DRIVER），手机号验证码不可用")
+	case "log", "file":
+		log.Printf("⚠️  短信验证码已初始化（%s），验证码将明文记录，仅用于本地开发测试", config.AppConfig.SMSDriver)
+	default:
+		log.Printf("✅ 短信验证码已初始化（%s）", config.AppConfig.SMSDriver)
+	}
+
+	// 注册报名人机验证（图形验证码 / 工作量证明）
+	challengeKey := utils.DeriveKey("challenge:" + config.AppConfig.JWTSecret)
+	challenge.Register(challenge.NewCaptchaProvider(challengeKey, challenge.DefaultTTL))
+	challenge.Register(challenge.NewPoWProvider(challengeKey, config.AppConfig.ChallengePoWDifficulty, challenge.DefaultTTL))
+
+	// 启动逾期未选择奖品规格的自动分配任务
+	go utils.RunVariantFallbackWorker(config.DB, time.Minute)
+
+	// 启动后台导出任务
+	go handlers.RunExportWorker(30 * time.Second)
+
+	// 启动参与者目录定时同步
+	go handlers.RunDirectorySyncWorker(time.Minute)
+
+	// 启动个人信息保留期限定时清理
+	go handlers.RunRetentionWorker(time.Hour)
+
 	// 设置路由（自动应用中间件和限流）
 	r := router.SetupRouter()
 
@@ -5,6 +5,7 @@
 	"strings"
 
 	"lottery-system/config"
+	"lottery-system/models"
 	"lottery-system/utils"
 
 	"github.com/gin-gonic/gin"
@@ -21,7 +22,8 @@ func AuthMiddleware() gin.HandlerFunc {
 
 		tokenString := strings.Replace(authHeader, "Bearer ", "", 1)
 		claims, err := utils.ValidateToken(tokenString, config.AppConfig.JWTSecret)
-		if err != nil {
+		if err != nil || claims.Scope != "" {
+			// 参与者范围的token不能访问管理后台
 			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid token"})
 			c.Abort()
 			return
@@ -69,14 +71,9 @@ func UserAuthMiddleware() gin.HandlerFunc {
 		}
 
 		// 先尝试作为用户验证
-		var user struct {
-			ID        int
-			CompanyID int
-			Phone     string
-			Name      string
-			HasDrawn  bool
-		}
-		userErr := config.DB.Table("users").
+		// 使用用户模型读取，手机号自动解密
+		var user models.User
+		userErr := config.DB.Model(&models.User{}).
 			Select("id, company_id, phone, name, has_drawn").
 			Where("id = ?", claims.UserID).
 			First(&user).Error
@@ -89,10 +86,18 @@ func UserAuthMiddleware() gin.HandlerFunc {
 			c.Set("company_id", user.CompanyID)
 			c.Set("has_drawn", user.HasDrawn)
 			c.Set("is_admin", false)
+			c.Set("token_scope", claims.Scope)
 			c.Next()
 			return
 		}
 
+		// 参与者token只对应用户，不回退到管理员
+		if claims.Scope == utils.TokenScopeParticipant {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "User not found"})
+			c.Abort()
+			return
+		}
+
 		// 如果不是用户，尝试作为管理员验证
 		var admin struct {
 			ID           int
@@ -120,6 +125,22 @@ func UserAuthMiddleware() gin.HandlerFunc {
 	}
 }
 
+// RejectParticipantToken 拒绝参与者范围的token（只允许访问本人的只读和领奖接口）
+// 需挂在 UserAuthMiddleware 之后
+func RejectParticipantToken() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if c.GetString("token_scope") == utils.TokenScopeParticipant {
+			c.JSON(http.StatusForbidden, gin.H{
+				"error":      "参与者登录无权访问该接口",
+				"error_code": "PARTICIPANT_SCOPE",
+			})
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
+
 func CORSMiddleware() gin.HandlerFunc {
 	return func(c *gin.Context) {
 		origin := c.Request.Header.Get("Origin")
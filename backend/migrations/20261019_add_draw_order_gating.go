@@ -0,0 +1,50 @@
+package migrations
+
+import (
+	"log"
+
+	"lottery-system/models"
+
+	"gorm.io/gorm"
+)
+
+// Migration20261019AddDrawOrderGating 添加抽奖顺序（公司）和奖项开放状态字段
+type Migration20261019AddDrawOrderGating struct{}
+
+// Name 返回迁移名称
+func (m *Migration20261019AddDrawOrderGating) Name() string {
+	return "20261019_add_draw_order_gating"
+}
+
+// Up 执行迁移
+func (m *Migration20261019AddDrawOrderGating) Up(tx *gorm.DB) error {
+	log.Println("  → 检查 companies.draw_order 字段...")
+	if !tx.Migrator().HasColumn(&models.Company{}, "DrawOrder") {
+		if err := tx.Migrator().AddColumn(&models.Company{}, "DrawOrder"); err != nil {
+			return err
+		}
+		log.Println("  ✓ 添加 draw_order 字段成功")
+	} else {
+		log.Println("  ℹ️  draw_order 字段已存在")
+	}
+
+	log.Println("  → 检查 prize_levels.draw_state 字段...")
+	if !tx.Migrator().HasColumn(&models.PrizeLevel{}, "DrawState") {
+		if err := tx.Migrator().AddColumn(&models.PrizeLevel{}, "DrawState"); err != nil {
+			return err
+		}
+		log.Println("  ✓ 添加 draw_state 字段成功")
+	} else {
+		log.Println("  ℹ️  draw_state 字段已存在")
+	}
+
+	return nil
+}
+
+// Down 回滚迁移
+func (m *Migration20261019AddDrawOrderGating) Down(tx *gorm.DB) error {
+	log.Println("  → 删除抽奖顺序相关字段...")
+	tx.Migrator().DropColumn(&models.Company{}, "DrawOrder")
+	tx.Migrator().DropColumn(&models.PrizeLevel{}, "DrawState")
+	return nil
+}
@@ -0,0 +1,57 @@
+package migrations
+
+import (
+	"log"
+
+	"lottery-system/models"
+
+	"gorm.io/gorm"
+)
+
+// Migration20261020AddPrizeFinance 添加奖品价值、公司预算和个税档位字段
+type Migration20261020AddPrizeFinance struct{}
+
+// Name 返回迁移名称
+func (m *Migration20261020AddPrizeFinance) Name() string {
+	return "20261020_add_prize_finance"
+}
+
+// Up 执行迁移
+func (m *Migration20261020AddPrizeFinance) Up(tx *gorm.DB) error {
+	columns := []struct {
+		model  interface{}
+		field  string
+		column string
+	}{
+		{&models.Prize{}, "Value", "prizes.value"},
+		{&models.Prize{}, "Currency", "prizes.currency"},
+		{&models.Company{}, "PrizeBudget", "companies.prize_budget"},
+		{&models.Company{}, "BudgetCurrency", "companies.budget_currency"},
+		{&models.Company{}, "TaxBrackets", "companies.tax_brackets"},
+	}
+
+	for _, col := range columns {
+		log.Printf("  → 检查 %s 字段...", col.column)
+		if tx.Migrator().HasColumn(col.model, col.field) {
+			log.Printf("  ℹ️  %s 字段已存在", col.column)
+			continue
+		}
+		if err := tx.Migrator().AddColumn(col.model, col.field); err != nil {
+			return err
+		}
+		log.Printf("  ✓ 添加 %s 字段成功", col.column)
+	}
+
+	return nil
+}
+
+// Down 回滚迁移
+func (m *Migration20261020AddPrizeFinance) Down(tx *gorm.DB) error {
+	log.Println("  → 删除财务相关字段...")
+	tx.Migrator().DropColumn(&models.Prize{}, "Value")
+	tx.Migrator().DropColumn(&models.Prize{}, "Currency")
+	tx.Migrator().DropColumn(&models.Company{}, "PrizeBudget")
+	tx.Migrator().DropColumn(&models.Company{}, "BudgetCurrency")
+	tx.Migrator().DropColumn(&models.Company{}, "TaxBrackets")
+	return nil
+}
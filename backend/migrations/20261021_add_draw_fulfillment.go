@@ -0,0 +1,54 @@
+package migrations
+
+import (
+	"log"
+
+	"lottery-system/models"
+
+	"gorm.io/gorm"
+)
+
+// Migration20261021AddDrawFulfillment 添加中奖记录发放流程字段（收货信息、快递单号等）
+type Migration20261021AddDrawFulfillment struct{}
+
+// Name 返回迁移名称
+func (m *Migration20261021AddDrawFulfillment) Name() string {
+	return "20261021_add_draw_fulfillment"
+}
+
+// fulfillmentFields 发放流程新增字段
+var fulfillmentFields = []string{
+	"RecipientName",
+	"RecipientPhone",
+	"ShippingAddress",
+	"Courier",
+	"TrackingNumber",
+	"FulfillmentNote",
+	"ShippedAt",
+	"CompletedAt",
+}
+
+// Up 执行迁移
+func (m *Migration20261021AddDrawFulfillment) Up(tx *gorm.DB) error {
+	for _, field := range fulfillmentFields {
+		log.Printf("  → 检查 draw_records.%s 字段...", field)
+		if tx.Migrator().HasColumn(&models.DrawRecord{}, field) {
+			log.Printf("  ℹ️  %s 字段已存在", field)
+			continue
+		}
+		if err := tx.Migrator().AddColumn(&models.DrawRecord{}, field); err != nil {
+			return err
+		}
+		log.Printf("  ✓ 添加 %s 字段成功", field)
+	}
+	return nil
+}
+
+// Down 回滚迁移
+func (m *Migration20261021AddDrawFulfillment) Down(tx *gorm.DB) error {
+	log.Println("  → 删除发放流程相关字段...")
+	for _, field := range fulfillmentFields {
+		tx.Migrator().DropColumn(&models.DrawRecord{}, field)
+	}
+	return nil
+}
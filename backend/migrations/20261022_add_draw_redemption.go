@@ -0,0 +1,45 @@
+package migrations
+
+import (
+	"log"
+
+	"lottery-system/models"
+
+	"gorm.io/gorm"
+)
+
+// Migration20261022AddDrawRedemption 添加中奖记录现场核销字段
+type Migration20261022AddDrawRedemption struct{}
+
+// Name 返回迁移名称
+func (m *Migration20261022AddDrawRedemption) Name() string {
+	return "20261022_add_draw_redemption"
+}
+
+// redemptionFields 核销新增字段
+var redemptionFields = []string{"RedemptionNonce", "RedeemedAt", "RedeemedBy"}
+
+// Up 执行迁移
+func (m *Migration20261022AddDrawRedemption) Up(tx *gorm.DB) error {
+	for _, field := range redemptionFields {
+		log.Printf("  → 检查 draw_records.%s 字段...", field)
+		if tx.Migrator().HasColumn(&models.DrawRecord{}, field) {
+			log.Printf("  ℹ️  %s 字段已存在", field)
+			continue
+		}
+		if err := tx.Migrator().AddColumn(&models.DrawRecord{}, field); err != nil {
+			return err
+		}
+		log.Printf("  ✓ 添加 %s 字段成功", field)
+	}
+	return nil
+}
+
+// Down 回滚迁移
+func (m *Migration20261022AddDrawRedemption) Down(tx *gorm.DB) error {
+	log.Println("  → 删除核销相关字段...")
+	for _, field := range redemptionFields {
+		tx.Migrator().DropColumn(&models.DrawRecord{}, field)
+	}
+	return nil
+}
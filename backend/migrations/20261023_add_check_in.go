@@ -0,0 +1,63 @@
+package migrations
+
+import (
+	"log"
+
+	"lottery-system/models"
+
+	"gorm.io/gorm"
+)
+
+// Migration20261023AddCheckIn 添加现场签到字段（公司签到配置、用户签到状态）
+type Migration20261023AddCheckIn struct{}
+
+// Name 返回迁移名称
+func (m *Migration20261023AddCheckIn) Name() string {
+	return "20261023_add_check_in"
+}
+
+// checkInCompanyFields 公司签到配置字段
+var checkInCompanyFields = []string{"CheckInRequired", "CheckInCutoffAt"}
+
+// checkInUserFields 用户签到状态字段
+var checkInUserFields = []string{"CheckedInAt", "CheckInMethod", "CheckedInBy"}
+
+// Up 执行迁移
+func (m *Migration20261023AddCheckIn) Up(tx *gorm.DB) error {
+	for _, field := range checkInCompanyFields {
+		log.Printf("  → 检查 companies.%s 字段...", field)
+		if tx.Migrator().HasColumn(&models.Company{}, field) {
+			log.Printf("  ℹ️  %s 字段已存在", field)
+			continue
+		}
+		if err := tx.Migrator().AddColumn(&models.Company{}, field); err != nil {
+			return err
+		}
+		log.Printf("  ✓ 添加 %s 字段成功", field)
+	}
+
+	for _, field := range checkInUserFields {
+		log.Printf("  → 检查 users.%s 字段...", field)
+		if tx.Migrator().HasColumn(&models.User{}, field) {
+			log.Printf("  ℹ️  %s 字段已存在", field)
+			continue
+		}
+		if err := tx.Migrator().AddColumn(&models.User{}, field); err != nil {
+			return err
+		}
+		log.Printf("  ✓ 添加 %s 字段成功", field)
+	}
+	return nil
+}
+
+// Down 回滚迁移
+func (m *Migration20261023AddCheckIn) Down(tx *gorm.DB) error {
+	log.Println("  → 删除签到相关字段...")
+	for _, field := range checkInCompanyFields {
+		tx.Migrator().DropColumn(&models.Company{}, field)
+	}
+	for _, field := range checkInUserFields {
+		tx.Migrator().DropColumn(&models.User{}, field)
+	}
+	return nil
+}
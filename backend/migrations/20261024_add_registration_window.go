@@ -0,0 +1,65 @@
+package migrations
+
+import (
+	"log"
+
+	"lottery-system/models"
+
+	"gorm.io/gorm"
+)
+
+// Migration20261024AddRegistrationWindow 添加报名时间窗口、人数上限和候补名单字段
+type Migration20261024AddRegistrationWindow struct{}
+
+// Name 返回迁移名称
+func (m *Migration20261024AddRegistrationWindow) Name() string {
+	return "20261024_add_registration_window"
+}
+
+// registrationCompanyFields 公司报名配置字段
+var registrationCompanyFields = []string{
+	"RegistrationOpensAt", "RegistrationClosesAt", "RegistrationClosed", "MaxParticipants", "WaitlistEnabled",
+}
+
+// Up 执行迁移
+func (m *Migration20261024AddRegistrationWindow) Up(tx *gorm.DB) error {
+	for _, field := range registrationCompanyFields {
+		log.Printf("  → 检查 companies.%s 字段...", field)
+		if tx.Migrator().HasColumn(&models.Company{}, field) {
+			log.Printf("  ℹ️  %s 字段已存在", field)
+			continue
+		}
+		if err := tx.Migrator().AddColumn(&models.Company{}, field); err != nil {
+			return err
+		}
+		log.Printf("  ✓ 添加 %s 字段成功", field)
+	}
+
+	log.Println("  → 检查 users.waitlisted 字段...")
+	if !tx.Migrator().HasColumn(&models.User{}, "Waitlisted") {
+		if err := tx.Migrator().AddColumn(&models.User{}, "Waitlisted"); err != nil {
+			return err
+		}
+		log.Println("  ✓ 添加 waitlisted 字段成功")
+	} else {
+		log.Println("  ℹ️  waitlisted 字段已存在")
+	}
+	if !tx.Migrator().HasIndex(&models.User{}, "Waitlisted") {
+		if err := tx.Migrator().CreateIndex(&models.User{}, "Waitlisted"); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Down 回滚迁移
+func (m *Migration20261024AddRegistrationWindow) Down(tx *gorm.DB) error {
+	log.Println("  → 删除报名配置相关字段...")
+	for _, field := range registrationCompanyFields {
+		tx.Migrator().DropColumn(&models.Company{}, field)
+	}
+	tx.Migrator().DropIndex(&models.User{}, "Waitlisted")
+	tx.Migrator().DropColumn(&models.User{}, "Waitlisted")
+	return nil
+}
@@ -0,0 +1,37 @@
+package migrations
+
+import (
+	"log"
+
+	"lottery-system/models"
+
+	"gorm.io/gorm"
+)
+
+// Migration20261025AddPhoneOTP 添加报名短信验证码开关
+type Migration20261025AddPhoneOTP struct{}
+
+// Name 返回迁移名称
+func (m *Migration20261025AddPhoneOTP) Name() string {
+	return "20261025_add_phone_otp"
+}
+
+// Up 执行迁移
+func (m *Migration20261025AddPhoneOTP) Up(tx *gorm.DB) error {
+	log.Println("  → 检查 companies.phone_otp_required 字段...")
+	if tx.Migrator().HasColumn(&models.Company{}, "PhoneOTPRequired") {
+		log.Println("  ℹ️  phone_otp_required 字段已存在")
+		return nil
+	}
+	if err := tx.Migrator().AddColumn(&models.Company{}, "PhoneOTPRequired"); err != nil {
+		return err
+	}
+	log.Println("  ✓ 添加 phone_otp_required 字段成功")
+	return nil
+}
+
+// Down 回滚迁移
+func (m *Migration20261025AddPhoneOTP) Down(tx *gorm.DB) error {
+	log.Println("  → 删除 companies.phone_otp_required 字段...")
+	return tx.Migrator().DropColumn(&models.Company{}, "PhoneOTPRequired")
+}
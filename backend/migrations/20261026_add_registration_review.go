@@ -0,0 +1,71 @@
+package migrations
+
+import (
+	"log"
+
+	"lottery-system/models"
+
+	"gorm.io/gorm"
+)
+
+// Migration20261026AddRegistrationReview 添加报名人机验证配置和批量报名审核字段
+type Migration20261026AddRegistrationReview struct{}
+
+// Name 返回迁移名称
+func (m *Migration20261026AddRegistrationReview) Name() string {
+	return "20261026_add_registration_review"
+}
+
+// registrationReviewUserFields 用户报名来源和审核字段
+var registrationReviewUserFields = []string{"RegisteredIP", "DeviceHash", "ReviewStatus", "ReviewReason"}
+
+// registrationReviewIndexes 报名风控统计和审核列表使用的索引
+var registrationReviewIndexes = []string{"RegisteredIP", "DeviceHash", "ReviewStatus"}
+
+// Up 执行迁移
+func (m *Migration20261026AddRegistrationReview) Up(tx *gorm.DB) error {
+	log.Println("  → 检查 companies.challenge_mode 字段...")
+	if tx.Migrator().HasColumn(&models.Company{}, "ChallengeMode") {
+		log.Println("  ℹ️  challenge_mode 字段已存在")
+	} else {
+		if err := tx.Migrator().AddColumn(&models.Company{}, "ChallengeMode"); err != nil {
+			return err
+		}
+		log.Println("  ✓ 添加 challenge_mode 字段成功")
+	}
+
+	for _, field := range registrationReviewUserFields {
+		log.Printf("  → 检查 users.%s 字段...", field)
+		if tx.Migrator().HasColumn(&models.User{}, field) {
+			log.Printf("  ℹ️  %s 字段已存在", field)
+			continue
+		}
+		if err := tx.Migrator().AddColumn(&models.User{}, field); err != nil {
+			return err
+		}
+		log.Printf("  ✓ 添加 %s 字段成功", field)
+	}
+
+	for _, field := range registrationReviewIndexes {
+		if tx.Migrator().HasIndex(&models.User{}, field) {
+			continue
+		}
+		if err := tx.Migrator().CreateIndex(&models.User{}, field); err != nil {
+			return err
+		}
+		log.Printf("  ✓ 创建 users.%s 索引成功", field)
+	}
+	return nil
+}
+
+// Down 回滚迁移
+func (m *Migration20261026AddRegistrationReview) Down(tx *gorm.DB) error {
+	log.Println("  → 删除报名审核相关字段...")
+	for _, field := range registrationReviewIndexes {
+		tx.Migrator().DropIndex(&models.User{}, field)
+	}
+	for _, field := range registrationReviewUserFields {
+		tx.Migrator().DropColumn(&models.User{}, field)
+	}
+	return tx.Migrator().DropColumn(&models.Company{}, "ChallengeMode")
+}
@@ -0,0 +1,71 @@
+package migrations
+
+import (
+	"log"
+
+	"lottery-system/models"
+	"lottery-system/namematch"
+
+	"gorm.io/gorm"
+)
+
+// Migration20261027AddNameKeys 添加用户姓名比对键（疑似重复检测）并为已有用户计算
+type Migration20261027AddNameKeys struct{}
+
+// Name 返回迁移名称
+func (m *Migration20261027AddNameKeys) Name() string {
+	return "20261027_add_name_keys"
+}
+
+// nameKeyFields 用户姓名比对键字段
+var nameKeyFields = []string{"NameKey", "NamePinyin"}
+
+// Up 执行迁移
+func (m *Migration20261027AddNameKeys) Up(tx *gorm.DB) error {
+	for _, field := range nameKeyFields {
+		log.Printf("  → 检查 users.%s 字段...", field)
+		if !tx.Migrator().HasColumn(&models.User{}, field) {
+			if err := tx.Migrator().AddColumn(&models.User{}, field); err != nil {
+				return err
+			}
+			log.Printf("  ✓ 添加 %s 字段成功", field)
+		}
+		if !tx.Migrator().HasIndex(&models.User{}, field) {
+			if err := tx.Migrator().CreateIndex(&models.User{}, field); err != nil {
+				return err
+			}
+		}
+	}
+
+	log.Println("  → 计算已有用户的姓名比对键...")
+	var users []models.User
+	updated := 0
+	result := tx.Select("id", "name").
+		Where("(name_key = ? OR name_key IS NULL) AND name <> ?", "", "").
+		FindInBatches(&users, 500, func(batch *gorm.DB, _ int) error {
+			for _, user := range users {
+				nameKey, namePinyin := namematch.NameKeys(user.Name)
+				if err := batch.Model(&models.User{}).Where("id = ?", user.ID).
+					UpdateColumns(map[string]interface{}{"name_key": nameKey, "name_pinyin": namePinyin}).Error; err != nil {
+					return err
+				}
+				updated++
+			}
+			return nil
+		})
+	if result.Error != nil {
+		return result.Error
+	}
+	log.Printf("  ✓ 已更新 %d 个用户", updated)
+	return nil
+}
+
+// Down 回滚迁移
+func (m *Migration20261027AddNameKeys) Down(tx *gorm.DB) error {
+	log.Println("  → 删除用户姓名比对键字段...")
+	for _, field := range nameKeyFields {
+		tx.Migrator().DropIndex(&models.User{}, field)
+		tx.Migrator().DropColumn(&models.User{}, field)
+	}
+	return nil
+}
@@ -0,0 +1,51 @@
+package migrations
+
+import (
+	"log"
+
+	"lottery-system/models"
+
+	"gorm.io/gorm"
+)
+
+// Migration20261028AddDirectorySync 添加目录同步的用户唯一标识和停用字段
+type Migration20261028AddDirectorySync struct{}
+
+// Name 返回迁移名称
+func (m *Migration20261028AddDirectorySync) Name() string {
+	return "20261028_add_directory_sync"
+}
+
+// directorySyncUserFields 目录同步用户字段（均需索引）
+var directorySyncUserFields = []string{"ExternalID", "DeactivatedAt"}
+
+// Up 执行迁移
+func (m *Migration20261028AddDirectorySync) Up(tx *gorm.DB) error {
+	for _, field := range directorySyncUserFields {
+		log.Printf("  → 检查 users.%s 字段...", field)
+		if tx.Migrator().HasColumn(&models.User{}, field) {
+			log.Printf("  ℹ️  %s 字段已存在", field)
+		} else {
+			if err := tx.Migrator().AddColumn(&models.User{}, field); err != nil {
+				return err
+			}
+			log.Printf("  ✓ 添加 %s 字段成功", field)
+		}
+		if !tx.Migrator().HasIndex(&models.User{}, field) {
+			if err := tx.Migrator().CreateIndex(&models.User{}, field); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// Down 回滚迁移
+func (m *Migration20261028AddDirectorySync) Down(tx *gorm.DB) error {
+	log.Println("  → 删除目录同步字段...")
+	for _, field := range directorySyncUserFields {
+		tx.Migrator().DropIndex(&models.User{}, field)
+		tx.Migrator().DropColumn(&models.User{}, field)
+	}
+	return nil
+}
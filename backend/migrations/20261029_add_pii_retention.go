@@ -0,0 +1,45 @@
+package migrations
+
+import (
+	"log"
+
+	"lottery-system/models"
+
+	"gorm.io/gorm"
+)
+
+// Migration20261029AddPIIRetention 添加公司个人信息保留期限配置
+type Migration20261029AddPIIRetention struct{}
+
+// Name 返回迁移名称
+func (m *Migration20261029AddPIIRetention) Name() string {
+	return "20261029_add_pii_retention"
+}
+
+// piiRetentionCompanyFields 公司个人信息保留字段
+var piiRetentionCompanyFields = []string{"RetentionDays", "PIIPurgedAt"}
+
+// Up 执行迁移
+func (m *Migration20261029AddPIIRetention) Up(tx *gorm.DB) error {
+	for _, field := range piiRetentionCompanyFields {
+		log.Printf("  → 检查 companies.%s 字段...", field)
+		if tx.Migrator().HasColumn(&models.Company{}, field) {
+			log.Printf("  ℹ️  %s 字段已存在", field)
+			continue
+		}
+		if err := tx.Migrator().AddColumn(&models.Company{}, field); err != nil {
+			return err
+		}
+		log.Printf("  ✓ 添加 %s 字段成功", field)
+	}
+	return nil
+}
+
+// Down 回滚迁移
+func (m *Migration20261029AddPIIRetention) Down(tx *gorm.DB) error {
+	log.Println("  → 删除个人信息保留字段...")
+	for _, field := range piiRetentionCompanyFields {
+		tx.Migrator().DropColumn(&models.Company{}, field)
+	}
+	return nil
+}
@@ -0,0 +1,61 @@
+package migrations
+
+import (
+	"log"
+
+	"lottery-system/models"
+
+	"gorm.io/gorm"
+)
+
+// Migration20261030EncryptUserPhones 用户手机号加密存储：添加盲索引字段，分批加密已有手机号
+type Migration20261030EncryptUserPhones struct{}
+
+// Name 返回迁移名称
+func (m *Migration20261030EncryptUserPhones) Name() string {
+	return "20261030_encrypt_user_phones"
+}
+
+// Up 执行迁移
+func (m *Migration20261030EncryptUserPhones) Up(tx *gorm.DB) error {
+	log.Println("  → 检查 users.phone_hash 字段...")
+	if !tx.Migrator().HasColumn(&models.User{}, "PhoneHash") {
+		if err := tx.Migrator().AddColumn(&models.User{}, "PhoneHash"); err != nil {
+			return err
+		}
+		log.Println("  ✓ 添加 phone_hash 字段成功")
+	}
+	if !tx.Migrator().HasIndex(&models.User{}, "PhoneHash") {
+		if err := tx.Migrator().CreateIndex(&models.User{}, "PhoneHash"); err != nil {
+			return err
+		}
+	}
+
+	// 扩大字段长度以容纳密文，明文索引不再使用
+	log.Println("  → 修改 users.phone 字段长度...")
+	if err := tx.Migrator().AlterColumn(&models.User{}, "Phone"); err != nil {
+		return err
+	}
+	if tx.Migrator().HasIndex(&models.User{}, "idx_users_phone") {
+		if err := tx.Migrator().DropIndex(&models.User{}, "idx_users_phone"); err != nil {
+			return err
+		}
+		log.Println("  ✓ 删除手机号明文索引")
+	}
+
+	log.Println("  → 加密已有用户手机号...")
+	count, err := models.EncryptUserPhones(tx, 500, false)
+	if err != nil {
+		return err
+	}
+	log.Printf("  ✓ 已加密 %d 个用户的手机号", count)
+	return nil
+}
+
+// Down 回滚迁移（已加密的手机号不会解密还原）
+func (m *Migration20261030EncryptUserPhones) Down(tx *gorm.DB) error {
+	log.Println("  → 删除手机号盲索引字段...")
+	tx.Migrator().DropIndex(&models.User{}, "PhoneHash")
+	tx.Migrator().DropColumn(&models.User{}, "PhoneHash")
+	return nil
+}
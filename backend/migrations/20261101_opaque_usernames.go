@@ -0,0 +1,54 @@
+package migrations
+
+import (
+	"log"
+
+	"lottery-system/models"
+
+	"gorm.io/gorm"
+)
+
+// Migration20261101OpaqueUsernames 以手机号作为用户名的参与者改为系统生成的用户名（u_<ID>）
+type Migration20261101OpaqueUsernames struct{}
+
+// Name 返回迁移名称
+func (m *Migration20261101OpaqueUsernames) Name() string {
+	return "20261101_opaque_usernames"
+}
+
+// Up 执行迁移
+func (m *Migration20261101OpaqueUsernames) Up(tx *gorm.DB) error {
+	log.Println("  → 替换使用手机号的用户名...")
+
+	total, lastID := 0, 0
+	for {
+		// 手机号字段读取时自动解密
+		var users []models.User
+		if err := tx.Select("id", "username", "phone").Where("id > ?", lastID).Order("id ASC").Limit(500).Find(&users).Error; err != nil {
+			return err
+		}
+		if len(users) == 0 {
+			break
+		}
+
+		for _, user := range users {
+			if !models.IsPhoneUsername(user.Username, user.Phone) {
+				continue
+			}
+			if err := tx.Model(&models.User{}).Where("id = ?", user.ID).
+				UpdateColumn("username", models.OpaqueUsername(user.ID)).Error; err != nil {
+				return err
+			}
+			total++
+		}
+		lastID = users[len(users)-1].ID
+	}
+
+	log.Printf("  ✓ 已替换 %d 个用户名", total)
+	return nil
+}
+
+// Down 回滚迁移（无需操作，不再恢复以手机号作为用户名）
+func (m *Migration20261101OpaqueUsernames) Down(tx *gorm.DB) error {
+	return nil
+}
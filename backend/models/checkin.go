@@ -0,0 +1,8 @@
+package models
+
+// 签到方式常量定义
+const (
+	CheckInMethodPersonalQR = "personal_qr" // 工作人员/自助机扫描个人签到码
+	CheckInMethodVenueQR    = "venue_qr"    // 参与者扫描现场轮换二维码
+	CheckInMethodManual     = "manual"      // 管理员手动签到
+)
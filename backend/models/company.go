@@ -13,7 +13,7 @@ type Company struct {
 	Name       string `gorm:"type:varchar(100);not null" json:"name"`                // 公司名称
 	Logo       string `gorm:"type:varchar(255)" json:"logo"`                         // Logo URL
 	ThemeColor string `gorm:"type:varchar(20);default:'#00fff5'" json:"theme_color"` // 主题颜色
-	BgColor    string `gorm:"type:varchar(20);default:'#0a0f14'" json:"bg_color"`      // 背景颜色
+	BgColor    string `gorm:"type:varchar(20);default:'#0a0f14'" json:"bg_color"`    // 背景颜色
 
 	// 文案配置
 	Title          string `gorm:"type:varchar(100);default:'幸运抽奖'" json:"title"`           // 系统标题
@@ -28,7 +28,34 @@ type Company struct {
 	ContactPhone string `gorm:"type:varchar(20)" json:"contact_phone"`  // 联系电话
 	ContactEmail string `gorm:"type:varchar(100)" json:"contact_email"` // 联系邮箱
 
-	IsActive  bool      `gorm:"default:true" json:"is_active"` // 是否启用
+	// 抽奖顺序: none, desc(按sort_order从大到小), asc(按sort_order从小到大)
+	DrawOrder string `gorm:"type:varchar(10);not null;default:'none'" json:"draw_order"`
+
+	// 财务配置
+	PrizeBudget    float64 `gorm:"type:decimal(14,2);not null;default:0" json:"prize_budget"`     // 奖品预算上限（0 表示不限制）
+	BudgetCurrency string  `gorm:"type:varchar(3);not null;default:'CNY'" json:"budget_currency"` // 预算币种
+	TaxBrackets    string  `gorm:"type:text" json:"tax_brackets"`                                 // 个税代扣税率档位（JSON），为空使用默认税率
+
+	// 签到配置
+	CheckInRequired bool       `gorm:"default:false" json:"check_in_required"` // 是否只有已签到用户可参与抽奖
+	CheckInCutoffAt *time.Time `json:"check_in_cutoff_at,omitempty"`           // 签到截止时间（迟到截止），为空表示不限制
+
+	// 报名配置（扫码自助注册）
+	RegistrationOpensAt  *time.Time `json:"registration_opens_at,omitempty"`                   // 报名开始时间，为空表示立即开放
+	RegistrationClosesAt *time.Time `json:"registration_closes_at,omitempty"`                  // 报名截止时间，为空表示不限制
+	RegistrationClosed   bool       `gorm:"default:false" json:"registration_closed"`          // 管理员手动关闭报名
+	MaxParticipants      int        `gorm:"type:integer;default:0" json:"max_participants"`    // 参与人数上限（0 表示不限制）
+	WaitlistEnabled      bool       `gorm:"default:false" json:"waitlist_enabled"`             // 满员后是否进入候补名单
+	PhoneOTPRequired     bool       `gorm:"default:false" json:"phone_otp_required"`           // 报名时是否必须通过短信验证码验证手机号
+	ChallengeMode        string     `gorm:"type:varchar(20);default:''" json:"challenge_mode"` // 报名人机验证方式：空（不验证）、captcha、pow
+
+	IsActive      bool       `gorm:"default:true" json:"is_active"` // 是否启用
+	EventClosedAt *time.Time `json:"event_closed_at,omitempty"`     // 活动结束时间（结束时执行奖品回流）
+
+	// 个人信息保留期限（活动结束 N 天后匿名化参与者姓名、手机号等个人信息，保留统计和中奖记录）
+	RetentionDays int        `gorm:"type:integer;not null;default:0" json:"retention_days"` // 0 表示不自动清理
+	PIIPurgedAt   *time.Time `gorm:"column:pii_purged_at" json:"pii_purged_at,omitempty"`   // 个人信息匿名化时间
+
 	CreatedAt time.Time `json:"created_at"`
 	UpdatedAt time.Time `json:"updated_at"`
 }
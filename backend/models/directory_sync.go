@@ -0,0 +1,58 @@
+package models
+
+import "time"
+
+// 目录同步数据源
+const (
+	DirectorySourceLDAP = "ldap" // LDAP / Active Directory
+	DirectorySourceFile = "csv"  // HR 系统投放的 CSV/XLSX 文件
+)
+
+// 目录同步结果
+const (
+	DirectorySyncSuccess = "success"
+	DirectorySyncFailed  = "failed"
+	DirectorySyncSkipped = "skipped" // 数据源内容与上次同步相同
+)
+
+// DirectorySync 公司参与者目录同步配置（从员工目录定时同步参与者名单，每个公司一个）
+type DirectorySync struct {
+	ID                int    `gorm:"type:integer;primarykey" json:"id"`
+	CompanyID         int    `gorm:"type:integer;not null;uniqueIndex" json:"company_id"`
+	SourceType        string `gorm:"type:varchar(10);not null" json:"source_type"` // ldap, csv
+	Enabled           bool   `gorm:"not null" json:"enabled"`                      // 是否定时同步（关闭时仍可手动同步）
+	IntervalMinutes   int    `gorm:"type:integer;not null" json:"interval_minutes"`
+	DeactivateMissing bool   `gorm:"not null" json:"deactivate_missing"` // 停用目录中已不存在的用户
+
+	// LDAP 连接
+	ServerURL     string `gorm:"type:varchar(255)" json:"server_url"` // ldap://host:389 或 ldaps://host:636
+	StartTLS      bool   `gorm:"not null" json:"start_tls"`
+	SkipTLSVerify bool   `gorm:"not null" json:"skip_tls_verify"`
+	BindDN        string `gorm:"type:varchar(255)" json:"bind_dn"`
+	BindPassword  string `gorm:"type:varchar(512)" json:"-"` // 加密存储
+	BaseDN        string `gorm:"type:varchar(255)" json:"base_dn"`
+	Filter        string `gorm:"type:varchar(1000)" json:"filter"`
+
+	// 文件投放（文件放在 DIRECTORY_DROP_DIR/<公司代码>/ 目录下）
+	FileName string `gorm:"type:varchar(255)" json:"file_name"` // 为空时读取目录中最新的文件
+
+	// 属性映射（LDAP 属性名或表格列名）
+	IDAttribute    string `gorm:"type:varchar(100);not null" json:"id_attribute"`
+	NameAttribute  string `gorm:"type:varchar(100);not null" json:"name_attribute"`
+	PhoneAttribute string `gorm:"type:varchar(100)" json:"phone_attribute"`
+	FieldMapping   string `gorm:"type:text" json:"-"` // JSON：报名表单字段 key → 属性名
+
+	NextRunAt  *time.Time `gorm:"index" json:"next_run_at,omitempty"`
+	LastRunAt  *time.Time `json:"last_run_at,omitempty"`
+	LastStatus string     `gorm:"type:varchar(20)" json:"last_status,omitempty"`
+	LastError  string     `gorm:"type:varchar(500)" json:"last_error,omitempty"`
+	LastReport string     `gorm:"type:text" json:"-"`        // 最近一次同步报告（JSON）
+	LastDigest string     `gorm:"type:varchar(64)" json:"-"` // 最近一次成功同步的数据源内容摘要
+	CreatedAt  time.Time  `json:"created_at"`
+	UpdatedAt  time.Time  `json:"updated_at"`
+}
+
+// TableName 指定表名
+func (DirectorySync) TableName() string {
+	return "directory_syncs"
+}
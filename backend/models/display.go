@@ -0,0 +1,51 @@
+package models
+
+import (
+	"time"
+)
+
+// 大屏会话状态
+const (
+	DisplayStatusWaiting = "waiting" // 等待遥控端配对
+	DisplayStatusPaired  = "paired"  // 已配对
+	DisplayStatusClosed  = "closed"  // 已关闭
+)
+
+// 大屏遥控指令
+const (
+	DisplayCommandStartSpin = "start_spin" // 开始滚动动画
+	DisplayCommandStop      = "stop"       // 停止滚动
+	DisplayCommandReveal    = "reveal"     // 公布中奖结果
+	DisplayCommandNextLevel = "next_level" // 切换到下一个奖项
+)
+
+// DisplaySession 大屏展示会话：大屏注册后显示配对码，管理员在手机上配对后遥控大屏
+type DisplaySession struct {
+	ID                int        `gorm:"type:integer;primarykey" json:"id"`
+	CompanyID         int        `gorm:"type:integer;not null;index" json:"company_id"`
+	Name              string     `gorm:"type:varchar(100)" json:"name"`                             // 大屏名称（如 "主会场投影"）
+	PairingCode       string     `gorm:"type:varchar(10);not null;index" json:"-"`                  // 配对码（仅注册时返回给大屏）
+	TokenHash         string     `gorm:"type:varchar(64);not null" json:"-"`                        // 大屏访问凭证摘要
+	Status            string     `gorm:"type:varchar(20);not null;default:'waiting'" json:"status"` // 状态: waiting, paired, closed
+	ControllerAdminID *int       `gorm:"type:integer" json:"controller_admin_id,omitempty"`         // 配对的遥控管理员
+	PairingExpiresAt  time.Time  `json:"pairing_expires_at"`                                        // 配对码过期时间
+	PairedAt          *time.Time `json:"paired_at,omitempty"`
+	LastSeenAt        *time.Time `json:"last_seen_at,omitempty"` // 大屏最后连接时间
+	CreatedAt         time.Time  `json:"created_at"`
+	UpdatedAt         time.Time  `json:"updated_at"`
+}
+
+// TableName 指定表名
+func (DisplaySession) TableName() string {
+	return "display_sessions"
+}
+
+// DisplayCommandIsValid 检查遥控指令是否有效
+func DisplayCommandIsValid(command string) bool {
+	switch command {
+	case DisplayCommandStartSpin, DisplayCommandStop, DisplayCommandReveal, DisplayCommandNextLevel:
+		return true
+	default:
+		return false
+	}
+}
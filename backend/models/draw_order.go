@@ -0,0 +1,29 @@
+package models
+
+// 抽奖顺序常量定义（基于 PrizeLevel.SortOrder）
+const (
+	DrawOrderNone = "none" // 不限制抽奖顺序
+	DrawOrderDesc = "desc" // 按 sort_order 从大到小抽（如 三等奖 → 二等奖 → 一等奖）
+	DrawOrderAsc  = "asc"  // 按 sort_order 从小到大抽
+)
+
+// 奖项抽奖状态常量定义
+const (
+	LevelDrawStateOpen   = "open"   // 开放抽奖
+	LevelDrawStateClosed = "closed" // 已关闭（不可抽奖，且不再阻塞后续奖项）
+)
+
+// DrawOrderIsValid 检查抽奖顺序配置是否有效
+func DrawOrderIsValid(order string) bool {
+	switch order {
+	case DrawOrderNone, DrawOrderDesc, DrawOrderAsc:
+		return true
+	default:
+		return false
+	}
+}
+
+// DrawOrderEnforced 检查是否启用了抽奖顺序限制
+func DrawOrderEnforced(order string) bool {
+	return order == DrawOrderDesc || order == DrawOrderAsc
+}
@@ -0,0 +1,47 @@
+package models
+
+// 抽奖记录状态常量定义（兑奖/发放流程）
+// won → claimed → shipped/picked_up → completed，won/claimed 可转为 forfeited（放弃）或 voided（作废）
+const (
+	DrawStatusWon       = "won"       // 已中奖，待领取
+	DrawStatusClaimed   = "claimed"   // 已领取（已确认兑奖/已提交收货信息）
+	DrawStatusShipped   = "shipped"   // 已发货
+	DrawStatusPickedUp  = "picked_up" // 已现场自提
+	DrawStatusCompleted = "completed" // 已完成（已签收）
+	DrawStatusForfeited = "forfeited" // 已放弃（中奖者放弃或逾期未领）
+	DrawStatusVoided    = "voided"    // 已作废
+)
+
+// drawStatusTransitions 允许的状态流转
+var drawStatusTransitions = map[string][]string{
+	DrawStatusWon:      {DrawStatusClaimed, DrawStatusForfeited, DrawStatusVoided},
+	DrawStatusClaimed:  {DrawStatusShipped, DrawStatusPickedUp, DrawStatusForfeited, DrawStatusVoided},
+	DrawStatusShipped:  {DrawStatusCompleted},
+	DrawStatusPickedUp: {DrawStatusCompleted},
+}
+
+// DrawStatusIsValid 检查抽奖记录状态是否有效
+func DrawStatusIsValid(status string) bool {
+	switch status {
+	case DrawStatusWon, DrawStatusClaimed, DrawStatusShipped, DrawStatusPickedUp,
+		DrawStatusCompleted, DrawStatusForfeited, DrawStatusVoided:
+		return true
+	default:
+		return false
+	}
+}
+
+// DrawStatusCanTransition 检查抽奖记录能否从 from 状态流转到 to 状态
+func DrawStatusCanTransition(from, to string) bool {
+	for _, next := range drawStatusTransitions[from] {
+		if next == to {
+			return true
+		}
+	}
+	return false
+}
+
+// DrawStatusesReleased 奖品未实际发放、库存可回流的状态
+func DrawStatusesReleased() []string {
+	return []string{DrawStatusForfeited, DrawStatusVoided}
+}
@@ -0,0 +1,18 @@
+package models
+
+import "time"
+
+// DuplicateDismissal 管理员确认不是重复的参与者对（不再出现在疑似重复列表中）
+type DuplicateDismissal struct {
+	ID          int       `gorm:"type:integer;primarykey" json:"id"`
+	CompanyID   int       `gorm:"type:integer;not null;index" json:"company_id"`
+	UserAID     int       `gorm:"type:integer;not null;uniqueIndex:idx_duplicate_pair,priority:1" json:"user_a_id"` // 较小的用户ID
+	UserBID     int       `gorm:"type:integer;not null;uniqueIndex:idx_duplicate_pair,priority:2" json:"user_b_id"` // 较大的用户ID
+	DismissedBy int       `gorm:"type:integer" json:"dismissed_by"`                                                 // 操作的管理员ID
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+// TableName 指定表名
+func (DuplicateDismissal) TableName() string {
+	return "duplicate_dismissals"
+}
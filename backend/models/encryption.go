@@ -0,0 +1,140 @@
+package models
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+
+	"lottery-system/fieldcrypt"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/schema"
+)
+
+// ErrFieldCryptNotConfigured 未配置字段加密密钥环
+var ErrFieldCryptNotConfigured = errors.New("field encryption keyring not configured")
+
+func init() {
+	schema.RegisterSerializer("encrypted", EncryptedSerializer{})
+}
+
+// EncryptedSerializer 字段加密序列化器（gorm:"serializer:encrypted"），写入时加密，读取时解密
+// 注意：只对结构体的创建、保存和查询生效，map 更新和查询条件需使用 SetPhoneUpdate、PhoneIndex
+type EncryptedSerializer struct{}
+
+// Scan 解密数据库中的值
+func (EncryptedSerializer) Scan(ctx context.Context, field *schema.Field, dst reflect.Value, dbValue interface{}) error {
+	var value string
+	switch v := dbValue.(type) {
+	case nil:
+		return nil
+	case string:
+		value = v
+	case []byte:
+		value = string(v)
+	default:
+		return fmt.Errorf("unsupported encrypted value type %T", dbValue)
+	}
+
+	if fieldcrypt.IsEncrypted(value) {
+		ring := fieldcrypt.Default()
+		if ring == nil {
+			return ErrFieldCryptNotConfigured
+		}
+		plaintext, err := ring.Decrypt(value)
+		if err != nil {
+			return err
+		}
+		value = plaintext
+	}
+	return field.Set(ctx, dst, value)
+}
+
+// Value 加密字段值
+func (EncryptedSerializer) Value(ctx context.Context, field *schema.Field, dst reflect.Value, fieldValue interface{}) (interface{}, error) {
+	value, _ := fieldValue.(string)
+	return encryptValue(value)
+}
+
+func encryptValue(value string) (string, error) {
+	if value == "" {
+		return "", nil
+	}
+	ring := fieldcrypt.Default()
+	if ring == nil {
+		return "", ErrFieldCryptNotConfigured
+	}
+	return ring.Encrypt(value)
+}
+
+// PhoneIndex 返回手机号的盲索引，用于按手机号等值查找（Where("phone_hash = ?", PhoneIndex(phone))）
+func PhoneIndex(phone string) string {
+	if phone == "" {
+		return ""
+	}
+	return fieldcrypt.Default().BlindIndex(phone)
+}
+
+// SetPhoneUpdate 将加密后的手机号和盲索引写入 map 更新（map 更新不经过序列化器）
+// 密文以表达式写入，Model(&user).Updates 不会把密文回写到结构体，结构体中的 Phone 需由调用方更新
+func SetPhoneUpdate(updates map[string]interface{}, phone string) error {
+	encrypted, err := encryptValue(phone)
+	if err != nil {
+		return err
+	}
+	updates["phone"] = gorm.Expr("?", encrypted)
+	updates["phone_hash"] = PhoneIndex(phone)
+	return nil
+}
+
+// EncryptUserPhones 分批加密用户手机号：加密尚未加密的明文，rewrap 为 true 时同时用当前密钥重新加密其他密钥加密的值，
+// 并重新计算盲索引，返回处理的用户数
+func EncryptUserPhones(db *gorm.DB, batchSize int, rewrap bool) (int, error) {
+	ring := fieldcrypt.Default()
+	if ring == nil {
+		return 0, ErrFieldCryptNotConfigured
+	}
+
+	// 直接读写原始列，绕过序列化器
+	type phoneRow struct {
+		ID    int
+		Phone string
+	}
+	pattern := fieldcrypt.Prefix + "%"
+	if rewrap {
+		pattern = fieldcrypt.Prefix + ring.ActiveKeyID() + ".%"
+	}
+
+	total, lastID := 0, 0
+	for {
+		var rows []phoneRow
+		if err := db.Table("users").Select("id", "phone").
+			Where("id > ? AND phone <> ? AND phone IS NOT NULL AND phone NOT LIKE ?", lastID, "", pattern).
+			Order("id ASC").Limit(batchSize).Scan(&rows).Error; err != nil {
+			return total, err
+		}
+		if len(rows) == 0 {
+			return total, nil
+		}
+
+		for _, row := range rows {
+			phone, err := ring.Decrypt(row.Phone)
+			if err != nil {
+				return total, fmt.Errorf("user %d: %w", row.ID, err)
+			}
+			encrypted, err := ring.Encrypt(phone)
+			if err != nil {
+				return total, err
+			}
+			if err := db.Table("users").Where("id = ?", row.ID).UpdateColumns(map[string]interface{}{
+				"phone":      encrypted,
+				"phone_hash": ring.BlindIndex(phone),
+			}).Error; err != nil {
+				return total, err
+			}
+		}
+		total += len(rows)
+		lastID = rows[len(rows)-1].ID
+	}
+}
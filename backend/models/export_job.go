@@ -0,0 +1,42 @@
+package models
+
+import "time"
+
+// 导出任务状态
+const (
+	ExportStatusPending = "pending" // 排队中
+	ExportStatusRunning = "running" // 生成中
+	ExportStatusDone    = "done"    // 已完成，可下载
+	ExportStatusFailed  = "failed"  // 失败
+)
+
+// 导出内容
+const (
+	ExportKindDrawRecords = "draw_records" // 中奖记录
+	ExportKindUsers       = "users"        // 参与者
+)
+
+// ExportJob 后台导出任务（数据量较大的导出在后台生成，完成后下载）
+type ExportJob struct {
+	ID         int        `gorm:"type:integer;primarykey" json:"id"`
+	CompanyID  int        `gorm:"type:integer;not null;default:0;index" json:"company_id"` // 0 表示所有公司（仅超级管理员）
+	AdminID    int        `gorm:"type:integer;not null;index" json:"admin_id"`             // 发起导出的管理员
+	Kind       string     `gorm:"type:varchar(20);not null" json:"kind"`
+	Format     string     `gorm:"type:varchar(10);not null" json:"format"` // csv, xlsx, pdf
+	Params     string     `gorm:"type:text" json:"params"`                 // 筛选条件（URL 查询字符串）
+	Status     string     `gorm:"type:varchar(20);not null;default:'pending';index" json:"status"`
+	RowCount   int        `gorm:"type:integer;default:0" json:"row_count"`
+	FileKey    string     `gorm:"type:varchar(255)" json:"-"` // 导出文件在存储中的对象键
+	FileSize   int64      `gorm:"default:0" json:"file_size"`
+	Error      string     `gorm:"type:varchar(255)" json:"error,omitempty"`
+	StartedAt  *time.Time `json:"started_at,omitempty"`
+	FinishedAt *time.Time `json:"finished_at,omitempty"`
+	ExpiresAt  *time.Time `gorm:"index" json:"expires_at,omitempty"` // 导出文件过期时间，过期后删除
+	CreatedAt  time.Time  `json:"created_at"`
+	UpdatedAt  time.Time  `json:"updated_at"`
+}
+
+// TableName 指定表名
+func (ExportJob) TableName() string {
+	return "export_jobs"
+}
@@ -0,0 +1,49 @@
+package models
+
+import (
+	"encoding/json"
+	"errors"
+	"sort"
+)
+
+// DefaultCurrency 默认币种
+const DefaultCurrency = "CNY"
+
+// TaxBracket 个人所得税代扣税率档位（速算扣除数法）
+// 奖品价值超过 Above 时适用该档：应纳税额 = 价值 × Rate - Deduction
+type TaxBracket struct {
+	Above     float64 `json:"above"`     // 起征点（不含）
+	Rate      float64 `json:"rate"`      // 税率，如 0.2 表示 20%
+	Deduction float64 `json:"deduction"` // 速算扣除数
+}
+
+// DefaultTaxBrackets 默认税率：偶然所得按 20% 比例税率代扣
+var DefaultTaxBrackets = []TaxBracket{
+	{Above: 0, Rate: 0.2, Deduction: 0},
+}
+
+// ParseTaxBrackets 解析公司配置的税率档位（JSON），为空时返回默认税率
+func ParseTaxBrackets(raw string) ([]TaxBracket, error) {
+	if raw == "" {
+		return DefaultTaxBrackets, nil
+	}
+
+	var brackets []TaxBracket
+	if err := json.Unmarshal([]byte(raw), &brackets); err != nil {
+		return nil, errors.New("税率档位格式错误")
+	}
+	if len(brackets) == 0 {
+		return DefaultTaxBrackets, nil
+	}
+
+	for _, b := range brackets {
+		if b.Above < 0 || b.Rate < 0 || b.Rate > 1 || b.Deduction < 0 {
+			return nil, errors.New("税率档位数值无效")
+		}
+	}
+
+	sort.Slice(brackets, func(i, j int) bool {
+		return brackets[i].Above < brackets[j].Above
+	})
+	return brackets, nil
+}
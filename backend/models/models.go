@@ -7,6 +7,8 @@
 	"strings"
 	"time"
 
+	"lottery-system/namematch"
+
 	"gorm.io/gorm"
 )
 
@@ -25,45 +27,137 @@ type Admin struct {
 
 // User 用户模型
 type User struct {
-	ID        int       `gorm:"type:integer;primarykey" json:"id"`
-	CompanyID int       `gorm:"type:integer;not null;index" json:"company_id"` // 所属公司
-	Company   Company   `gorm:"foreignKey:CompanyID" json:"company,omitempty"`
-	Username  string    `gorm:"type:varchar(100);not null;index" json:"username"` // 允许重名
-	Password  string    `gorm:"type:varchar(255);not null" json:"-"`
-	Role      string    `gorm:"type:varchar(50);not null;default:'user';index" json:"role"` // 角色: user
-	Name      string    `gorm:"type:varchar(100)" json:"name"`
-	Phone     string    `gorm:"type:varchar(20);index" json:"phone"` // 手机号（可选，用于区分重名用户）
-	HasDrawn  bool      `gorm:"default:false" json:"has_drawn"`
+	ID        int     `gorm:"type:integer;primarykey" json:"id"`
+	CompanyID int     `gorm:"type:integer;not null;index" json:"company_id"` // 所属公司
+	Company   Company `gorm:"foreignKey:CompanyID" json:"company,omitempty"`
+	Username  string  `gorm:"type:varchar(100);not null;index" json:"username"` // 允许重名
+	Password  string  `gorm:"type:varchar(255);not null" json:"-"`
+	Role      string  `gorm:"type:varchar(50);not null;default:'user';index" json:"role"` // 角色: user
+	Name      string  `gorm:"type:varchar(100)" json:"name"`
+	Phone     string  `gorm:"type:varchar(255);serializer:encrypted" json:"phone"` // 手机号（可选，用于区分重名用户；加密存储）
+	PhoneHash string  `gorm:"type:varchar(64);index" json:"-"`                     // 手机号盲索引（按手机号查找时使用 PhoneIndex）
+	HasDrawn  bool    `gorm:"default:false" json:"has_drawn"`
+
+	// 疑似重复检测（创建时自动计算，修改姓名时需同步更新）
+	NameKey    string `gorm:"type:varchar(100);index" json:"-"` // 规范化姓名（繁简体、全半角、空格统一）
+	NamePinyin string `gorm:"type:varchar(255);index" json:"-"` // 姓名拼音
+
+	// 现场签到（公司开启签到后，只有已签到的用户才能参与抽奖）
+	CheckedInAt   *time.Time `json:"checked_in_at,omitempty"`                           // 签到时间
+	CheckInMethod string     `gorm:"type:varchar(20)" json:"check_in_method,omitempty"` // 签到方式: personal_qr, venue_qr, manual
+	CheckedInBy   *int       `gorm:"type:integer" json:"checked_in_by,omitempty"`       // 扫码/手动签到的管理员ID
+
+	// 候补名单（报名满员后进入候补，不参与抽奖，有空位时按报名顺序转正）
+	Waitlisted bool `gorm:"default:false;index" json:"waitlisted"`
+
+	// 报名风控（扫码报名时记录来源，短时间内同一 IP/设备批量报名时标记待审核）
+	RegisteredIP string `gorm:"type:varchar(45);index" json:"registered_ip,omitempty"`            // 报名时的客户端IP
+	DeviceHash   string `gorm:"type:varchar(64);index" json:"-"`                                  // 客户端设备标识摘要
+	ReviewStatus string `gorm:"type:varchar(20);default:'';index" json:"review_status,omitempty"` // flagged, approved, rejected
+	ReviewReason string `gorm:"type:varchar(255)" json:"review_reason,omitempty"`
+
+	// 目录同步（从 LDAP/HR 名单同步的用户记录目录中的唯一标识，从目录中移除后停用，停用的用户不参与抽奖）
+	ExternalID    string     `gorm:"type:varchar(128);index" json:"external_id,omitempty"`
+	DeactivatedAt *time.Time `gorm:"index" json:"deactivated_at,omitempty"`
+
+	Fields map[string]string `gorm:"-" json:"fields,omitempty"` // 报名表单自定义字段（从 user_field_values 加载）
+
 	CreatedAt time.Time `json:"created_at"`
 	UpdatedAt time.Time `json:"updated_at"`
 }
 
+// BeforeCreate 创建用户前计算姓名比对键
+func (u *User) BeforeCreate(tx *gorm.DB) error {
+	u.NameKey, u.NamePinyin = namematch.NameKeys(u.Name)
+	return nil
+}
+
+// AfterCreate 未指定用户名时按 ID 生成不含个人信息的用户名
+func (u *User) AfterCreate(tx *gorm.DB) error {
+	if u.Username != "" {
+		return nil
+	}
+	u.Username = OpaqueUsername(u.ID)
+	return tx.Model(u).UpdateColumn("username", u.Username).Error
+}
+
+// OpaqueUsername 系统生成的用户名（u_<ID>），不使用手机号等个人信息
+func OpaqueUsername(id int) string {
+	return fmt.Sprintf("u_%d", id)
+}
+
+// IsPhoneUsername 用户名是否为手机号，或重名时追加序号的 "<手机号>_<序号>"（旧版创建规则）
+func IsPhoneUsername(username, phone string) bool {
+	if phone == "" || !strings.HasPrefix(username, phone) {
+		return false
+	}
+	suffix := strings.TrimPrefix(username, phone)
+	if suffix == "" {
+		return true
+	}
+	if len(suffix) < 2 || suffix[0] != '_' {
+		return false
+	}
+	for _, r := range suffix[1:] {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// BeforeSave 保存用户前计算手机号盲索引
+func (u *User) BeforeSave(tx *gorm.DB) error {
+	u.PhoneHash = PhoneIndex(u.Phone)
+	return nil
+}
+
 // PrizeLevel 奖项等级（一等奖、二等奖等）
 type PrizeLevel struct {
-	ID          int       `gorm:"type:integer;primarykey" json:"id"`
-	CompanyID   int       `gorm:"type:integer;not null;index" json:"company_id"` // 所属公司
-	Company     Company   `gorm:"foreignKey:CompanyID" json:"company,omitempty"`
-	Name        string    `gorm:"type:varchar(50);not null" json:"name"`
-	Description string    `gorm:"type:varchar(200)" json:"description"`
-	Probability float64   `gorm:"type:real;not null" json:"probability"`
-	TotalStock  int       `gorm:"type:integer;not null" json:"total_stock"`
-	UsedStock   int       `gorm:"type:integer;default:0" json:"used_stock"`
-	SortOrder   int       `gorm:"type:integer;default:0" json:"sort_order"`
-	IsActive    bool      `gorm:"default:true" json:"is_active"`
-	CreatedAt   time.Time `json:"created_at"`
-	UpdatedAt   time.Time `json:"updated_at"`
+	ID          int     `gorm:"type:integer;primarykey" json:"id"`
+	CompanyID   int     `gorm:"type:integer;not null;index" json:"company_id"` // 所属公司
+	Company     Company `gorm:"foreignKey:CompanyID" json:"company,omitempty"`
+	Name        string  `gorm:"type:varchar(50);not null" json:"name"`
+	Description string  `gorm:"type:varchar(200)" json:"description"`
+	Probability float64 `gorm:"type:real;not null" json:"probability"`
+	TotalStock  int     `gorm:"type:integer;not null" json:"total_stock"`
+	UsedStock   int     `gorm:"type:integer;default:0" json:"used_stock"`
+	SortOrder   int     `gorm:"type:integer;default:0" json:"sort_order"`
+	IsActive    bool    `gorm:"default:true" json:"is_active"`
+
+	// 回流策略：活动结束时未领取/作废的奖品库存转入的目标奖项（后续轮次或大奖池），null表示不回流
+	RolloverLevelID *int `gorm:"type:integer" json:"rollover_level_id,omitempty"`
+
+	// 抽奖状态: open, closed（关闭后不可抽奖，按顺序抽奖时视为已完成）
+	DrawState string `gorm:"type:varchar(20);not null;default:'open'" json:"draw_state"`
+
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
 }
 
 // Prize 具体奖品
 type Prize struct {
-	ID         int       `gorm:"type:integer;primarykey" json:"id"`
-	LevelID    int       `gorm:"type:integer;not null" json:"level_id"`
-	Name       string    `gorm:"type:varchar(100);not null" json:"name"`
-	Image      string    `gorm:"type:varchar(255)" json:"image"`
-	TotalStock int       `gorm:"type:integer;not null;default:0" json:"total_stock"` // 奖品总库存
-	UsedStock  int       `gorm:"type:integer;default:0" json:"used_stock"`           // 已使用库存
-	CreatedAt  time.Time `json:"created_at"`
-	UpdatedAt  time.Time `json:"updated_at"`
+	ID         int    `gorm:"type:integer;primarykey" json:"id"`
+	LevelID    int    `gorm:"type:integer;not null" json:"level_id"`
+	Name       string `gorm:"type:varchar(100);not null" json:"name"`
+	Image      string `gorm:"type:varchar(255)" json:"image"`
+	TotalStock int    `gorm:"type:integer;not null;default:0" json:"total_stock"` // 奖品总库存
+	UsedStock  int    `gorm:"type:integer;default:0" json:"used_stock"`           // 已使用库存
+
+	// 奖品价值（财务对账及个税代扣）
+	Value    float64 `gorm:"type:decimal(12,2);not null;default:0" json:"value"`     // 单件价值
+	Currency string  `gorm:"type:varchar(3);not null;default:'CNY'" json:"currency"` // 币种
+
+	// 兑换码池（虚拟奖品）：启用后库存等于兑换码数量，中奖时自动分配一个未使用的兑换码
+	CodePool           bool `gorm:"default:false" json:"code_pool"`
+	CodeAlertThreshold int  `gorm:"type:integer;default:10" json:"code_alert_threshold"` // 剩余兑换码低于该值时告警
+
+	// 多规格奖品：库存等于各规格库存之和，中奖者在截止时间内选择规格，逾期自动分配
+	HasVariants      bool `gorm:"default:false" json:"has_variants"`
+	VariantPickHours int  `gorm:"type:integer;default:24" json:"variant_pick_hours"` // 中奖后选择规格的时限（小时）
+
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
 }
 
 // DrawRecord 抽奖记录
@@ -78,8 +172,37 @@ type DrawRecord struct {
 	PrizeID   int        `json:"prize_id"`
 	Prize     Prize      `gorm:"foreignKey:PrizeID" json:"prize,omitempty"`
 	IP        string     `gorm:"type:varchar(50)" json:"ip"`
-	CreatedAt time.Time  `json:"created_at"`
-	UpdatedAt time.Time  `json:"updated_at"`
+
+	Status     string     `gorm:"type:varchar(20);not null;default:'won';index" json:"status"` // 状态: won, claimed, shipped, picked_up, completed, forfeited, voided
+	ClaimedAt  *time.Time `json:"claimed_at,omitempty"`                                        // 领取时间
+	RolledOver bool       `gorm:"default:false" json:"rolled_over"`                            // 库存是否已回流
+
+	// 发放信息（收件人、地址、单号和备注只通过管理端接口返回，公开和参与者接口不返回）
+	RecipientName   string     `gorm:"type:varchar(100)" json:"-"`                // 收件人
+	RecipientPhone  string     `gorm:"type:varchar(20)" json:"-"`                 // 收件人电话
+	ShippingAddress string     `gorm:"type:varchar(500)" json:"-"`                // 收货地址
+	Courier         string     `gorm:"type:varchar(50)" json:"courier,omitempty"` // 快递公司
+	TrackingNumber  string     `gorm:"type:varchar(100)" json:"-"`                // 快递单号
+	FulfillmentNote string     `gorm:"type:varchar(500)" json:"-"`                // 发放备注
+	ShippedAt       *time.Time `json:"shipped_at,omitempty"`                      // 发货/自提时间
+	CompletedAt     *time.Time `json:"completed_at,omitempty"`                    // 完成时间
+
+	// 现场领奖核销
+	RedemptionNonce string     `gorm:"type:varchar(32)" json:"-"`                 // 核销二维码随机数（参与签名）
+	RedeemedAt      *time.Time `json:"redeemed_at,omitempty"`                     // 核销时间
+	RedeemedBy      *int       `gorm:"type:integer" json:"redeemed_by,omitempty"` // 核销操作的管理员ID
+
+	VariantID           *int          `gorm:"type:integer" json:"variant_id,omitempty"` // 中奖者选择/分配的规格
+	Variant             *PrizeVariant `gorm:"foreignKey:VariantID" json:"variant,omitempty"`
+	VariantDeadline     *time.Time    `json:"variant_deadline,omitempty"`                 // 选择规格的截止时间
+	VariantAutoAssigned bool          `gorm:"default:false" json:"variant_auto_assigned"` // 是否逾期自动分配
+
+	PrizeCode       string `gorm:"-" json:"prize_code,omitempty"`       // 虚拟奖品兑换码（仅中奖者本人查询时返回）
+	RedemptionToken string `gorm:"-" json:"redemption_token,omitempty"` // 领奖核销凭证（仅中奖者本人查询时返回）
+	RedemptionQR    string `gorm:"-" json:"redemption_qr,omitempty"`    // 领奖核销二维码（data URL）
+
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
 }
 
 // AutoMigrate 自动迁移数据库表（仅在表结构变化时执行）
@@ -114,6 +237,17 @@ func AutoMigrate(db *gorm.DB) error {
 		&Prize{},
 		&DrawRecord{},
 		&OperationLog{},
+		&PrizeRollover{},
+		&PrizeCode{},
+		&PrizeVariant{},
+		&DisplaySession{},
+		&RegistrationField{},
+		&UserFieldValue{},
+		&PhoneOTP{},
+		&OTPCounter{},
+		&DuplicateDismissal{},
+		&ExportJob{},
+		&DirectorySync{},
 	); err != nil {
 		return fmt.Errorf("failed to run auto migration: %w", err)
 	}
@@ -146,6 +280,8 @@ func computeModelsHash() string {
 	// 这里我们用模型名称列表作为简化版本
 	models := []string{
 		"Company", "Admin", "User", "PrizeLevel", "Prize", "DrawRecord", "OperationLog",
+		"PrizeRollover", "PrizeCode", "PrizeVariant", "DisplaySession", "RegistrationField", "UserFieldValue",
+		"PhoneOTP", "OTPCounter", "DuplicateDismissal", "ExportJob", "DirectorySync",
 	}
 
 	// TODO: 未来可以使用反射获取实际的结构信息
@@ -0,0 +1,22 @@
+package models
+
+import "time"
+
+// PhoneOTP 手机验证码（未启用 Redis 时存储在数据库）
+// Key 由用途、公司和手机号组成，同一 Key 只保留最新的验证码
+type PhoneOTP struct {
+	ID        int       `gorm:"type:integer;primarykey" json:"id"`
+	Key       string    `gorm:"column:otp_key;type:varchar(128);uniqueIndex;not null" json:"-"`
+	CodeHash  string    `gorm:"type:varchar(64);not null" json:"-"` // 验证码 HMAC 摘要，不保存明文
+	Attempts  int       `gorm:"type:integer;not null;default:0" json:"attempts"`
+	ExpiresAt time.Time `gorm:"index;not null" json:"expires_at"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// OTPCounter 验证码发送/校验频率计数（固定时间窗口，未启用 Redis 时使用）
+type OTPCounter struct {
+	ID        int       `gorm:"type:integer;primarykey" json:"id"`
+	Key       string    `gorm:"column:counter_key;type:varchar(128);uniqueIndex;not null" json:"-"`
+	Count     int       `gorm:"column:hits;type:integer;not null;default:0" json:"count"`
+	ExpiresAt time.Time `gorm:"index;not null" json:"expires_at"`
+}
@@ -0,0 +1,22 @@
+package models
+
+import (
+	"time"
+)
+
+// PrizeCode 虚拟奖品兑换码（如电子礼品卡），兑换码加密存储
+type PrizeCode struct {
+	ID           int        `gorm:"type:integer;primarykey" json:"id"`
+	PrizeID      int        `gorm:"type:integer;not null;index;uniqueIndex:idx_prize_code_hash" json:"prize_id"`
+	CodeHash     string     `gorm:"type:varchar(64);not null;uniqueIndex:idx_prize_code_hash" json:"-"` // 兑换码摘要（用于去重）
+	Ciphertext   string     `gorm:"type:text;not null" json:"-"`                                        // 加密后的兑换码
+	Masked       string     `gorm:"type:varchar(50)" json:"masked"`                                     // 脱敏显示，如 ****WXYZ
+	DrawRecordID *int       `gorm:"type:integer;index" json:"draw_record_id,omitempty"`                 // 分配给的抽奖记录，null表示未分配
+	AssignedAt   *time.Time `json:"assigned_at,omitempty"`
+	CreatedAt    time.Time  `json:"created_at"`
+}
+
+// TableName 指定表名
+func (PrizeCode) TableName() string {
+	return "prize_codes"
+}
@@ -0,0 +1,22 @@
+package models
+
+import (
+	"time"
+)
+
+// PrizeVariant 奖品规格（如尺码、颜色），每个规格独立库存
+type PrizeVariant struct {
+	ID         int       `gorm:"type:integer;primarykey" json:"id"`
+	PrizeID    int       `gorm:"type:integer;not null;index" json:"prize_id"`
+	Name       string    `gorm:"type:varchar(100);not null" json:"name"` // 规格名称，如 "L / 黑色"
+	TotalStock int       `gorm:"type:integer;not null;default:0" json:"total_stock"`
+	UsedStock  int       `gorm:"type:integer;default:0" json:"used_stock"` // 已被中奖者选择/分配的数量
+	SortOrder  int       `gorm:"type:integer;default:0" json:"sort_order"`
+	CreatedAt  time.Time `json:"created_at"`
+	UpdatedAt  time.Time `json:"updated_at"`
+}
+
+// TableName 指定表名
+func (PrizeVariant) TableName() string {
+	return "prize_variants"
+}
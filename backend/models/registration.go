@@ -0,0 +1,18 @@
+package models
+
+// 报名状态常量定义（扫码自助注册页面根据状态展示提示）
+const (
+	RegistrationStatusOpen     = "open"     // 开放报名
+	RegistrationStatusNotOpen  = "not_open" // 尚未开始
+	RegistrationStatusEnded    = "ended"    // 已过截止时间
+	RegistrationStatusClosed   = "closed"   // 管理员已关闭报名
+	RegistrationStatusFull     = "full"     // 名额已满
+	RegistrationStatusWaitlist = "waitlist" // 名额已满，可报名候补
+)
+
+// 报名审核状态常量定义（批量报名风控）
+const (
+	ReviewStatusFlagged  = "flagged"  // 疑似批量报名，待管理员审核（仍可参与抽奖）
+	ReviewStatusApproved = "approved" // 审核通过
+	ReviewStatusRejected = "rejected" // 审核拒绝，不参与抽奖
+)
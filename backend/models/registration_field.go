@@ -0,0 +1,98 @@
+package models
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"time"
+)
+
+// 报名表单字段类型
+const (
+	FieldTypeText   = "text"   // 文本（可设置正则校验）
+	FieldTypeNumber = "number" // 数字
+	FieldTypeSelect = "select" // 单选（从选项中选择）
+	FieldTypeEmail  = "email"  // 邮箱
+	FieldTypeDate   = "date"   // 日期（YYYY-MM-DD）
+)
+
+// FieldTypeIsValid 检查字段类型是否有效
+func FieldTypeIsValid(fieldType string) bool {
+	switch fieldType {
+	case FieldTypeText, FieldTypeNumber, FieldTypeSelect, FieldTypeEmail, FieldTypeDate:
+		return true
+	default:
+		return false
+	}
+}
+
+// FieldOptions 单选字段的选项列表（以 JSON 数组存储）
+type FieldOptions []string
+
+// Value 实现 driver.Valuer
+func (o FieldOptions) Value() (driver.Value, error) {
+	if len(o) == 0 {
+		return "", nil
+	}
+	data, err := json.Marshal([]string(o))
+	return string(data), err
+}
+
+// Scan 实现 sql.Scanner
+func (o *FieldOptions) Scan(value interface{}) error {
+	var data []byte
+	switch v := value.(type) {
+	case nil:
+		*o = nil
+		return nil
+	case []byte:
+		data = v
+	case string:
+		data = []byte(v)
+	default:
+		return errors.New("unsupported field options type")
+	}
+	if len(data) == 0 {
+		*o = nil
+		return nil
+	}
+	return json.Unmarshal(data, (*[]string)(o))
+}
+
+// RegistrationField 公司自定义的报名表单字段（如工号、部门、桌号、餐饮偏好）
+type RegistrationField struct {
+	ID        int          `gorm:"type:integer;primarykey" json:"id"`
+	CompanyID int          `gorm:"type:integer;not null;uniqueIndex:idx_company_field_key" json:"company_id"`
+	Key       string       `gorm:"column:field_key;type:varchar(50);not null;uniqueIndex:idx_company_field_key" json:"key"` // 字段标识，如 employee_id
+	Label     string       `gorm:"type:varchar(100);not null" json:"label"`                                                 // 显示名称，如 "工号"
+	Type      string       `gorm:"type:varchar(20);not null;default:'text'" json:"type"`
+	Required  bool         `gorm:"default:false" json:"required"`
+	Pattern   string       `gorm:"type:varchar(255)" json:"pattern,omitempty"` // 文本字段的正则校验
+	Options   FieldOptions `gorm:"type:text" json:"options,omitempty"`         // 单选字段的选项
+	MaxLength int          `gorm:"type:integer;default:100" json:"max_length"`
+	SortOrder int          `gorm:"type:integer;default:0" json:"sort_order"`
+	IsActive  bool         `gorm:"default:true" json:"is_active"`
+	CreatedAt time.Time    `json:"created_at"`
+	UpdatedAt time.Time    `json:"updated_at"`
+}
+
+// TableName 指定表名
+func (RegistrationField) TableName() string {
+	return "registration_fields"
+}
+
+// UserFieldValue 参与者填写的报名表单字段值（每个字段一行，便于按字段筛选）
+type UserFieldValue struct {
+	ID        int       `gorm:"type:integer;primarykey" json:"id"`
+	CompanyID int       `gorm:"type:integer;not null;index:idx_field_value,priority:1" json:"company_id"`
+	UserID    int       `gorm:"type:integer;not null;index" json:"user_id"`
+	FieldKey  string    `gorm:"type:varchar(50);not null;index:idx_field_value,priority:2" json:"field_key"`
+	Value     string    `gorm:"type:varchar(255);index:idx_field_value,priority:3" json:"value"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// TableName 指定表名
+func (UserFieldValue) TableName() string {
+	return "user_field_values"
+}
@@ -0,0 +1,33 @@
+package models
+
+import (
+	"time"
+)
+
+// 奖品回流原因
+const (
+	RolloverReasonVoided    = "voided"    // 中奖记录已作废
+	RolloverReasonUnclaimed = "unclaimed" // 活动结束时仍未领取
+	RolloverReasonForfeited = "forfeited" // 中奖者已放弃
+)
+
+// PrizeRollover 奖品回流记录（活动结束时未领取/作废奖品的库存流转明细）
+type PrizeRollover struct {
+	ID           int       `gorm:"type:integer;primarykey" json:"id"`
+	CompanyID    int       `gorm:"type:integer;not null;index" json:"company_id"`
+	DrawRecordID int       `gorm:"type:integer;not null;index" json:"draw_record_id"` // 来源抽奖记录
+	UserID       int       `gorm:"type:integer;not null" json:"user_id"`              // 原中奖用户
+	FromLevelID  int       `gorm:"type:integer;not null" json:"from_level_id"`
+	FromPrizeID  int       `gorm:"type:integer;not null" json:"from_prize_id"`
+	ToLevelID    int       `gorm:"type:integer;not null" json:"to_level_id"`
+	ToPrizeID    int       `gorm:"type:integer;not null" json:"to_prize_id"`
+	PrizeName    string    `gorm:"type:varchar(100)" json:"prize_name"`     // 奖品名称（冗余字段，便于报表）
+	Reason       string    `gorm:"type:varchar(20);not null" json:"reason"` // 回流原因: voided, unclaimed, forfeited
+	OperatorID   uint      `gorm:"type:integer" json:"operator_id"`         // 执行回流的管理员ID
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+// TableName 指定表名
+func (PrizeRollover) TableName() string {
+	return "prize_rollovers"
+}
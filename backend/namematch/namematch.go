@@ -0,0 +1,285 @@
+// Package namematch 提供参与者疑似重复检测：手机号规范化、中文姓名繁简/全半角/拼音规范化和编辑距离比对
+// 不依赖外部词库，拼音和繁简对照表只收录姓名常用字，未收录的字按原字比对
+package namematch
+
+import (
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// 疑似重复原因
+const (
+	ReasonSamePhone   = "same_phone"   // 手机号相同
+	ReasonSameName    = "same_name"    // 规范化后姓名相同（含繁简体、全半角、空格和间隔号差异）
+	ReasonSamePinyin  = "same_pinyin"  // 姓名同音（如 张伟/张玮）
+	ReasonSimilarName = "similar_name" // 姓名相近（错别字、多字少字）
+)
+
+// 疑似重复得分（0-100），手机号不同时扣分
+const (
+	scoreSamePhone      = 60
+	scoreSameName       = 40
+	scoreSamePinyin     = 35
+	scoreSimilarName    = 25
+	penaltyPhoneDiffers = 30
+
+	// DefaultMinScore 默认列出的最低得分：姓名同音即列出，仅姓名相近需同时手机号相同
+	DefaultMinScore = scoreSamePinyin
+)
+
+var (
+	pinyinOf     = map[rune]string{}
+	simplifiedOf = map[rune]rune{}
+)
+
+func init() {
+	for _, line := range strings.Split(pinyinTable, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) != 2 {
+			continue
+		}
+		for _, r := range fields[1] {
+			pinyinOf[r] = fields[0]
+		}
+	}
+	for _, pair := range strings.Fields(traditionalPairs) {
+		runes := []rune(pair)
+		if len(runes) == 2 {
+			simplifiedOf[runes[0]] = runes[1]
+		}
+	}
+}
+
+// NormalizeName 规范化姓名：全角转半角、去除空白和间隔号、英文转小写、繁体转简体
+func NormalizeName(name string) string {
+	var b strings.Builder
+	for _, r := range name {
+		switch {
+		case r == 0x3000:
+			continue
+		case r >= 0xFF01 && r <= 0xFF5E: // 全角 ASCII
+			r -= 0xFEE0
+		}
+		if unicode.IsSpace(r) || strings.ContainsRune("·・•.-_'", r) {
+			continue
+		}
+		if s, ok := simplifiedOf[r]; ok {
+			r = s
+		}
+		b.WriteRune(unicode.ToLower(r))
+	}
+	return b.String()
+}
+
+// Pinyin 返回规范化姓名的拼音（不带声调，音节以空格分隔），姓名中没有已收录的汉字时返回空字符串
+func Pinyin(normalized string) string {
+	var tokens []string
+	var other strings.Builder
+	found := false
+	for _, r := range normalized {
+		py, ok := pinyinOf[r]
+		if !ok {
+			other.WriteRune(r)
+			continue
+		}
+		if other.Len() > 0 {
+			tokens = append(tokens, other.String())
+			other.Reset()
+		}
+		tokens = append(tokens, py)
+		found = true
+	}
+	if !found {
+		return ""
+	}
+	if other.Len() > 0 {
+		tokens = append(tokens, other.String())
+	}
+	return strings.Join(tokens, " ")
+}
+
+// NameKeys 返回姓名的规范化键和拼音键（存储在用户表，用于报名时按索引查找疑似重复）
+func NameKeys(name string) (string, string) {
+	normalized := NormalizeName(name)
+	return normalized, Pinyin(normalized)
+}
+
+// NormalizePhone 规范化手机号：只保留数字，去掉 +86/0086 国家码
+func NormalizePhone(phone string) string {
+	var b strings.Builder
+	for _, r := range phone {
+		if r >= '0' && r <= '9' {
+			b.WriteRune(r)
+		}
+	}
+	digits := b.String()
+	for _, prefix := range []string{"0086", "86"} {
+		if len(digits) == len(prefix)+11 && strings.HasPrefix(digits, prefix) {
+			return digits[len(prefix):]
+		}
+	}
+	return digits
+}
+
+// EditDistance 计算两个字符串的编辑距离（按字符）
+func EditDistance(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+	prev := make([]int, len(rb)+1)
+	curr := make([]int, len(rb)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(ra); i++ {
+		curr[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			curr[j] = min3(prev[j]+1, curr[j-1]+1, prev[j-1]+cost)
+		}
+		prev, curr = curr, prev
+	}
+	return prev[len(rb)]
+}
+
+// Keys 参与者的比对键
+type Keys struct {
+	Name   string // 规范化姓名
+	Pinyin string // 姓名拼音
+	Phone  string // 规范化手机号
+}
+
+// KeysOf 计算参与者的比对键
+func KeysOf(name, phone string) Keys {
+	normalized, pinyin := NameKeys(name)
+	return Keys{Name: normalized, Pinyin: pinyin, Phone: NormalizePhone(phone)}
+}
+
+// Match 比对两个参与者，返回疑似重复得分（0-100）和原因
+func Match(a, b Keys) (int, []string) {
+	score := 0
+	var reasons []string
+
+	if a.Phone != "" && b.Phone != "" {
+		if a.Phone == b.Phone {
+			score += scoreSamePhone
+			reasons = append(reasons, ReasonSamePhone)
+		} else {
+			score -= penaltyPhoneDiffers
+		}
+	}
+
+	switch {
+	case a.Name == "" || b.Name == "":
+	case a.Name == b.Name:
+		score += scoreSameName
+		reasons = append(reasons, ReasonSameName)
+	case a.Pinyin != "" && a.Pinyin == b.Pinyin:
+		score += scoreSamePinyin
+		reasons = append(reasons, ReasonSamePinyin)
+	case similarNames(a.Name, b.Name):
+		score += scoreSimilarName
+		reasons = append(reasons, ReasonSimilarName)
+	}
+
+	if score < 0 {
+		score = 0
+	}
+	if score > 100 {
+		score = 100
+	}
+	return score, reasons
+}
+
+// similarNames 姓名是否相近：较短的姓名不超过 6 个字时相差 1 个字，更长的姓名（如英文名）相差不超过 2 个字符
+// 两个双字姓名相差一字通常是不同的人（如 王明/王红），不视为相近
+func similarNames(a, b string) bool {
+	la, lb := len([]rune(a)), len([]rune(b))
+	shorter := la
+	if lb < shorter {
+		shorter = lb
+	}
+	switch {
+	case shorter < 2 || la+lb < 5:
+		return false
+	case shorter <= 6:
+		return EditDistance(a, b) <= 1
+	default:
+		return EditDistance(a, b) <= 2
+	}
+}
+
+// Candidate 参与检测的参与者
+type Candidate struct {
+	ID   int
+	Keys Keys
+}
+
+// Pair 疑似重复的参与者对（AID < BID）
+type Pair struct {
+	AID     int
+	BID     int
+	Score   int
+	Reasons []string
+}
+
+// FindPairs 查找得分不低于 minScore 的疑似重复对，按得分从高到低排序
+// 只比对手机号、规范化姓名或拼音相同的参与者（分组后组内比对，避免全量两两比较）
+func FindPairs(candidates []Candidate, minScore int) []Pair {
+	groups := map[string][]int{}
+	for i, c := range candidates {
+		for _, key := range []string{"p:" + c.Keys.Phone, "n:" + c.Keys.Name, "y:" + c.Keys.Pinyin} {
+			if len(key) > 2 {
+				groups[key] = append(groups[key], i)
+			}
+		}
+	}
+
+	seen := map[[2]int]bool{}
+	var pairs []Pair
+	for _, members := range groups {
+		for x := 0; x < len(members); x++ {
+			for y := x + 1; y < len(members); y++ {
+				a, b := candidates[members[x]], candidates[members[y]]
+				if a.ID == b.ID {
+					continue
+				}
+				if a.ID > b.ID {
+					a, b = b, a
+				}
+				id := [2]int{a.ID, b.ID}
+				if seen[id] {
+					continue
+				}
+				seen[id] = true
+
+				if score, reasons := Match(a.Keys, b.Keys); score >= minScore && len(reasons) > 0 {
+					pairs = append(pairs, Pair{AID: a.ID, BID: b.ID, Score: score, Reasons: reasons})
+				}
+			}
+		}
+	}
+
+	sort.Slice(pairs, func(i, j int) bool {
+		if pairs[i].Score != pairs[j].Score {
+			return pairs[i].Score > pairs[j].Score
+		}
+		if pairs[i].AID != pairs[j].AID {
+			return pairs[i].AID < pairs[j].AID
+		}
+		return pairs[i].BID < pairs[j].BID
+	})
+	return pairs
+}
+
+func min3(a, b, c int) int {
+	if b < a {
+		a = b
+	}
+	if c < a {
+		a = c
+	}
+	return a
+}
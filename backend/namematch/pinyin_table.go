@@ -0,0 +1,331 @@
+package namematch
+
+// pinyinTable 常用姓氏和名字用字的拼音（不带声调，多音字取作姓名时的常用读音，如 单 shan、区 ou、朴 piao）
+// 每行格式：拼音 汉字...；未收录的字在比对时保留原字
+const pinyinTable = `
+a 阿
+ai 艾爱蔼
+an 安岸按庵
+ang 昂
+ao 敖奥傲澳
+ba 巴八霸
+bai 白百柏佰
+ban 班斑般盘办
+bang 邦帮
+bao 包宝保鲍葆豹
+bei 贝北蓓倍
+ben 本奔
+bi 毕碧璧必弼比壁闭笔
+bian 边卞扁变编
+biao 彪标
+bin 彬斌滨宾
+bing 冰兵炳秉丙
+bo 波博伯勃泊渤薄
+bu 卜步布补
+cai 才蔡彩财采
+can 灿参
+cang 苍仓
+cao 曹草操
+ce 策
+cen 岑
+chai 柴
+chan 婵蝉禅产
+chang 常昌畅长唱嫦倡
+chao 超朝潮巢晁
+che 车彻澈
+chen 陈晨辰臣沉琛宸谌郴尘
+cheng 程成承诚城澄乘呈橙铖称
+chi 池驰迟赤弛
+chong 崇冲充宠
+chu 楚初储褚础出处
+chuan 川传船
+chuang 闯
+chun 春纯淳醇
+ci 慈词瓷辞赐
+cong 从丛聪葱
+cui 崔翠萃璀
+cun 存村
+da 达大
+dai 戴代黛岱带
+dan 丹旦淡但聃诞
+dang 党当
+dao 道导稻
+de 德得
+deng 邓登灯
+di 狄迪笛帝棣第荻邸
+dian 典电殿甸点
+diao 刁
+ding 丁定鼎顶
+dong 东董冬栋洞
+dou 窦豆
+du 杜都督笃度渡
+duan 段端
+dui 对队
+dun 敦顿
+duo 朵多铎
+e 鄂娥峨
+en 恩
+er 尔二洱
+fa 法发
+fan 范凡樊帆繁番梵
+fang 方房芳放舫昉
+fei 费飞菲斐霏妃
+fen 芬汾奋粉
+feng 冯凤峰丰风锋枫封逢奉沣
+fu 付傅符福富甫扶伏芙馥复夫孚赋辅
+gai 盖
+gan 甘干淦赣
+gang 刚钢港岗冈纲
+gao 高郜皋杲
+ge 葛戈歌格阁革
+gei 给
+gen 根
+geng 耿庚更
+gong 龚宫弓公功巩恭贡
+gou 苟勾构
+gu 谷顾古固鼓辜
+guan 关管冠观官贯馆
+guang 光广
+gui 桂贵归瑰圭
+guo 郭国果过
+ha 哈
+hai 海亥
+han 韩汉涵寒翰含晗函瀚罕邯
+hang 杭航
+hao 郝浩昊豪皓好灏号颢
+he 何贺和河荷禾赫鹤合
+heng 衡恒亨横
+hong 洪红宏鸿弘虹泓
+hou 侯后厚
+hu 胡呼湖虎护瑚扈
+hua 华花化骅桦画
+huai 怀淮
+huan 桓欢环焕寰还
+huang 黄皇凰煌璜晃
+hui 惠辉慧会晖卉蕙徽回汇绘荟
+huo 霍火或
+ji 纪吉季姬冀嵇籍及基济骥佶继计际绩玑级记
+jia 贾佳家嘉加甲夹郏
+jian 简建剑健坚鉴俭监见涧菅键间
+jiang 江蒋姜将疆绛降匠讲
+jiao 焦娇蛟皎姣教骄
+jie 杰洁节捷婕介界颉揭结阶
+jin 金晋锦靳进津瑾谨今劲近堇尽缙
+jing 景京静晶敬菁婧靖精经荆井竞璟镜境
+jiong 炯迥
+jiu 九久玖酒
+ju 居菊巨聚举鞠琚局炬驹
+juan 娟鹃卷绢
+jue 珏觉爵决
+jun 军君俊峻骏钧均隽郡珺竣浚
+kai 开凯恺楷锴铠
+kan 阚侃看
+kang 康亢抗慷
+kao 考
+ke 柯克可科珂轲恪
+kong 孔空
+kou 寇口
+kuai 蒯快
+kuan 宽
+kuang 匡况邝旷
+kui 奎魁葵夔隗
+kun 昆坤琨锟
+kuo 阔扩
+lai 赖来莱徕
+lan 兰蓝岚澜斓览栏
+lang 郎朗浪琅
+lao 劳老
+le 乐
+lei 雷蕾磊垒镭
+leng 冷
+li 李黎丽立力利礼理莉厉励俐郦栗里梨璃荔历莅离
+lian 连廉莲涟练联恋炼链
+liang 梁良亮凉粱靓量两
+liao 廖辽寥聊燎
+lie 烈列
+lin 林琳霖麟淋临蔺璘邻
+ling 凌玲灵岭令铃龄翎陵羚菱苓伶零领
+liu 刘柳留流浏琉六
+long 龙隆珑泷
+lou 娄楼
+lu 卢陆鲁路露鹿禄芦璐录潞炉泸麓逯庐
+luan 栾峦鸾
+lun 伦仑论轮
+luo 罗骆洛落珞络
+lv 吕律绿旅侣履闾
+ma 马麻玛
+mai 麦迈买
+man 满曼蔓漫蛮
+mang 芒莽
+mao 毛茅茂冒卯懋贸
+mei 梅美媚玫眉每湄楣妹
+men 门
+meng 孟蒙萌梦猛盟
+mi 米宓密蜜弥觅
+mian 勉绵冕
+miao 苗妙淼缪渺
+min 闵敏民珉旻岷玟闽
+ming 明鸣铭茗名命
+mo 莫墨默漠茉沫磨末
+mou 牟谋
+mu 穆木慕沐牧睦幕母牡
+na 那娜纳
+nai 乃奈耐
+nan 南楠男
+neng 能
+ni 倪妮霓尼泥旎
+nian 年念
+niao 鸟
+nie 聂涅
+ning 宁凝柠
+niu 牛钮纽
+nong 农浓
+nuo 诺糯
+ou 欧区鸥偶藕
+pan 潘盼攀磐畔
+pang 庞旁
+pei 裴培佩沛珮
+peng 彭鹏蓬朋澎芃
+pi 皮丕
+piao 朴飘
+pin 品频
+ping 平萍屏坪苹瓶评
+po 珀坡
+pu 蒲浦溥普璞濮
+qi 齐祁戚琪奇启其琦祺麒棋骐淇岐起绮旗企杞七綦漆亓气
+qian 钱谦倩乾千茜黔潜芊虔前迁浅骞
+qiang 强蔷墙羌锵
+qiao 乔桥巧俏侨谯翘
+qin 秦钦琴勤芹沁亲
+qing 庆清青晴卿倾情擎箐磬轻请
+qiong 琼穹
+qiu 邱秋丘仇球求裘酋
+qu 曲屈瞿渠趣衢
+quan 全权泉铨荃诠劝
+que 雀阙确却
+qun 群裙
+ran 冉然苒燃
+rang 让壤
+rao 饶绕
+ren 任仁人忍韧壬
+ri 日
+rong 荣容蓉融戎溶熔榕绒嵘
+rou 柔
+ru 茹如汝儒入孺
+ruan 阮
+rui 瑞锐睿芮蕊
+run 润闰
+ruo 若
+sa 萨飒
+sai 赛
+san 三伞
+sang 桑
+se 色瑟
+sen 森
+sha 沙莎纱
+shan 单山珊杉姗善闪陕衫擅扇
+shang 尚商上裳
+shao 邵少韶绍劭
+she 佘舍社涉射设
+shen 沈申深神慎莘审绅燊伸身
+sheng 盛胜生圣升晟声笙绳
+shi 石史施时师世诗士实仕识始拾适示市式狮视释
+shou 寿首守收授
+shu 舒书蜀叔树淑殊述曙束姝疏抒暑术枢
+shuai 帅
+shuang 双爽霜
+shui 水
+shun 顺舜
+shuo 硕朔说
+si 司思斯丝四嗣泗寺似
+song 宋松颂嵩淞送
+su 苏素肃粟宿速溯酥
+sui 隋岁穗遂随睢
+sun 孙笋
+suo 索锁
+ta 塔
+tai 台太泰邰汰
+tan 谈谭覃坦檀昙潭探
+tang 唐汤堂棠塘糖
+tao 陶涛桃韬滔焘淘
+te 特
+teng 腾滕藤
+ti 提梯缇体题
+tian 田天甜恬添
+tiao 调
+tie 铁
+ting 婷庭廷亭霆挺听汀葶
+tong 童佟同彤通桐瞳铜统潼仝
+tou 头
+tu 涂屠图土途
+tuan 团
+tuo 拓托妥驼脱
+wa 娃瓦
+wan 万婉宛晚菀皖湾丸完琬碗
+wang 王汪旺望往网
+wei 魏韦卫危伟威薇维玮巍蔚炜微为唯尉惟纬苇娓违位
+wen 温文闻雯稳问汶纹
+weng 翁
+wo 沃卧
+wu 吴武伍巫乌邬毋午五舞悟务梧雾坞
+xi 席奚西希喜熙曦溪夕锡习晰玺禧惜息羲犀僖兮熹悉袭昔细
+xia 夏霞侠峡遐暇下
+xian 冼鲜仙先贤显娴宪献弦咸闲现县线羡衔纤险
+xiang 向项香祥翔湘相享想襄骧响乡详
+xiao 萧肖晓小孝笑霄潇啸筱效校宵骁
+xie 谢解协燮谐携鞋写斜泄邪
+xin 辛新欣心信鑫馨昕芯忻歆
+xing 邢星兴行幸杏型醒形刑惺
+xiong 熊雄胸兄
+xiu 修秀袖岫绣羞
+xu 徐许胥须续旭序绪栩煦虚叙婿蓄絮需诩
+xuan 宣轩萱玄璇选旋炫绚暄喧铉
+xue 薛雪学血
+xun 荀寻勋逊讯迅浔询训循珣洵
+ya 亚雅娅鸭牙芽涯崖压
+yan 严颜闫阎燕言晏妍岩艳焰琰彦雁炎延研砚宴衍嫣焱烟演鄢验
+yang 杨阳羊洋扬仰养央漾样炀旸
+yao 姚尧瑶耀遥药要摇窑谣垚曜钥
+ye 叶业野烨晔夜页也冶耶
+yi 易伊依仪怡宜奕逸亿艺意义益毅羿翊忆轶乙一以弈颐漪熠懿倚沂祎苡议翼已移遗谊裔溢屹邑衣医译
+yin 尹殷银音印因隐寅茵吟饮荫胤引阴
+ying 应英莹颖营迎樱盈影鹰婴瑛滢萤赢映硬缨莺荧楹
+yong 永勇雍泳咏涌庸拥用镛
+you 尤游友有优幽佑悠又由油右犹攸祐
+yu 于余俞虞鱼禹宇玉雨羽语育裕钰昱煜予渝愉瑜榆郁誉遇御豫毓域屿妤娱芋浴愈欲狱寓喻瑀彧於与
+yuan 袁元原源远苑园缘圆媛渊沅援愿员院垣辕鸳
+yue 岳越悦月跃粤阅玥钺约
+yun 云韵运芸允匀昀耘筠蕴孕郧恽赟
+za 杂
+zai 宰在载
+zan 赞咱簪昝
+zang 臧藏
+zao 早枣造灶澡藻
+ze 泽则责择仄
+zeng 曾增赠
+zha 查扎札闸渣诈
+zhai 翟宅寨摘斋
+zhan 詹展湛战占站栈斩绽瞻毡沾
+zhang 张章彰漳璋樟仗丈涨掌帐障
+zhao 赵招昭照兆钊肇召诏沼
+zhe 哲浙喆折者蔗辙赭遮
+zhen 甄真珍振震贞祯臻镇桢针侦阵枕诊斟帧榛圳
+zheng 郑正征政铮争整证筝峥拯蒸症挣
+zhi 支志智芝致治植知直之枝执止纸指旨质稚至制织值址挚置秩只汁峙职趾帜炙郅祉陟芷
+zhong 钟仲中忠种众终衷重
+zhou 周舟州洲宙昼骤皱粥轴肘咒纣
+zhu 朱祝诸竹珠柱助主注住筑铸驻著株猪烛煮嘱瞩蛛逐竺贮
+zhuan 专砖转撰赚篆
+zhuang 庄装壮状幢撞妆桩
+zhui 追坠缀锥赘
+zhun 准谆
+zhuo 卓桌灼茁浊琢啄酌拙濯
+zi 子紫梓姿资自滋孜仔籽兹咨字姊
+zong 宗综棕踪总纵粽
+zou 邹走奏揍
+zu 祖组租足族阻卒
+zuan 钻纂
+zui 最醉罪嘴
+zun 尊遵
+zuo 左作佐坐座做昨祚柞
+`
@@ -0,0 +1,29 @@
+package namematch
+
+// traditionalPairs 姓名常用繁体字与简体字对照（繁体在前），比对前统一转为简体
+const traditionalPairs = `
+張张 陳陈 劉刘 黃黄 楊杨 趙赵 吳吴 鄭郑 謝谢 馬马 許许 鄧邓 馮冯 蔣蒋 葉叶 蘇苏 盧卢 羅罗 韓韩 錢钱
+孫孙 龍龙 萬万 顧顾 賴赖 蕭萧 譚谭 閻阎 陸陆 鍾钟 鐘钟 嚴严 賈贾 魯鲁 鄒邹 湯汤 範范 龔龚 歐欧 莊庄
+鄔邬 賀贺 關关 聶聂 畢毕 溫温 紀纪 鮑鲍 華华 韋韦 衛卫 喬乔 鄺邝 區区 寧宁 龐庞 齊齐 費费 單单 閔闵
+藍蓝 駱骆 項项 習习 樂乐 壽寿 塗涂 嶽岳 遲迟 顏颜 樓楼 時时 賽赛 婁娄 竇窦 欒栾 諸诸 偉伟 強强 軍军
+麗丽 紅红 鳳凤 飛飞 鵬鹏 輝辉 傑杰 劍剑 濤涛 靜静 穎颖 藝艺 瑩莹 嬌娇 潔洁 曉晓 東东 國国 勝胜 貴贵
+寶宝 義义 廣广 慶庆 榮荣 興兴 順顺 倫伦 詩诗 語语 譽誉 亞亚 雲云 風风 蘭兰 鶯莺 鳴鸣 綺绮 綠绿 銀银
+鋒锋 鐵铁 鋼钢 錦锦 長长 開开 達达 進进 運运 遠远 連连 邁迈 陽阳 雙双 電电 靈灵 韻韵 頌颂 願愿 驍骁
+騰腾 驊骅 鴻鸿 鶴鹤 麥麦 黨党 齡龄 婭娅 嫻娴 嬋婵 嬰婴 學学 實实 寬宽 對对 尋寻 將将 嵐岚 巖岩 帥帅
+師师 彌弥 歸归 徹彻 憶忆 懷怀 戰战 擇择 斕斓 曄晔 暉晖 書书 會会 楨桢 樺桦 樹树 橋桥 檸柠 權权 歡欢
+歲岁 氣气 漢汉 潤润 澤泽 濱滨 瀟潇 瀾澜 灣湾 燁烨 燦灿 爾尔 獻献 玨珏 現现 瑋玮 瑤瑶 璣玑 璽玺 瓊琼
+產产 畫画 發发 眾众 碩硕 禮礼 禎祯 穩稳 競竞 筆笔 約约 純纯 紗纱 紹绍 結结 絲丝 經经 維维 綿绵 緣缘
+編编 練练 縣县 總总 繼继 續续 羨羡 聖圣 聰聪 聲声 聯联 肅肃 脫脱 臨临 與与 舉举 艷艳 豔艳 萊莱 葦苇
+蓮莲 蕓芸 薈荟 蘆芦 處处 號号 裝装 覺觉 親亲 觀观 訓训 託托 記记 設设 詠咏 誠诚 誼谊 諾诺 謙谦 謹谨
+證证 譯译 讓让 豐丰 貝贝 財财 貞贞 賓宾 賢贤 賜赐 贊赞 贇赟 躍跃 軒轩 輕轻 轉转 農农 邊边 鄉乡 醫医
+釗钊 鈞钧 鈴铃 銘铭 錚铮 錫锡 鎮镇 鏡镜 閃闪 閏闰 閣阁 闖闯 際际 雋隽 雜杂 離离 頂顶 須须 頤颐 頻频
+題题 顯显 颯飒 飄飘 駒驹 騏骐 驥骥 體体 髮发 鬱郁 鷹鹰 堯尧 塵尘 壯壮 夢梦 奮奋 審审 寵宠 岡冈 崢峥
+嶸嵘 巒峦 廬庐 彥彦 徵征 復复 恆恒 悅悦 愛爱 應应 擁拥 敘叙 暢畅 曆历 棟栋 構构 標标 樞枢 樣样 橫横
+櫻樱 欄栏 沖冲 決决 況况 淵渊 淺浅 湧涌 滿满 漣涟 濃浓 燈灯 爭争 狀状 獅狮 瓏珑 當当 盡尽 祿禄 禪禅
+稱称 築筑 簡简 納纳 紋纹 級级 細细 紳绅 組组 絡络 給给 統统 綜综 綱纲 網网 緒绪 緯纬 縉缙 織织 繡绣
+纖纤 翹翘 聞闻 職职 臺台 蒼苍 蔭荫 薔蔷 薩萨 蘊蕴 蟬蝉 術术 補补 見见 視视 覽览 詢询 誕诞 說说 調调
+談谈 請请 論论 謀谋 講讲 識识 護护 變变 貢贡 貫贯 貿贸 資资 賦赋 贏赢 輔辅 輪轮 辦办 迴回 遊游 過过
+選选 遺遗 釋释 銓铨 銳锐 錄录 鍵键 鎧铠 鑄铸 門门 閑闲 間间 閩闽 閱阅 闊阔 陣阵 陰阴 隊队 階阶 隨随
+險险 隱隐 霧雾 韜韬 響响 頓顿 領领 頡颉 頭头 顥颢 餘余 館馆 馳驰 駿骏 騫骞 驕骄 驗验 魚鱼 鮮鲜 鳥鸟
+鵑鹃 鸞鸾 點点 齋斋
+`
@@ -0,0 +1,141 @@
+package otp
+
+import (
+	"context"
+	"crypto/hmac"
+	"errors"
+	"time"
+
+	"lottery-system/models"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// errCounterConflict 并发创建计数记录多次冲突
+var errCounterConflict = errors.New("otp counter conflict")
+
+// DBStore 基于数据库的验证码存储（单实例或未启用 Redis 时使用）
+type DBStore struct {
+	db *gorm.DB
+}
+
+// NewDBStore 创建数据库验证码存储
+func NewDBStore(db *gorm.DB) *DBStore {
+	return &DBStore{db: db}
+}
+
+// Save 保存验证码摘要（同一 key 覆盖旧记录）
+func (s *DBStore) Save(ctx context.Context, key, codeHash string, ttl time.Duration) error {
+	now := time.Now()
+	record := models.PhoneOTP{
+		Key:       key,
+		CodeHash:  codeHash,
+		ExpiresAt: now.Add(ttl),
+		CreatedAt: now,
+	}
+	return s.db.WithContext(ctx).Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "otp_key"}},
+		DoUpdates: clause.AssignmentColumns([]string{"code_hash", "attempts", "expires_at", "created_at"}),
+	}).Create(&record).Error
+}
+
+// Check 校验验证码：先条件更新尝试次数（并发请求不会超过上限），再比对摘要
+func (s *DBStore) Check(ctx context.Context, key, codeHash string, maxAttempts int) error {
+	db := s.db.WithContext(ctx)
+
+	result := db.Model(&models.PhoneOTP{}).
+		Where("otp_key = ? AND expires_at > ? AND attempts < ?", key, time.Now(), maxAttempts).
+		UpdateColumn("attempts", gorm.Expr("attempts + 1"))
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrNotFound
+	}
+
+	var record models.PhoneOTP
+	if err := db.Where("otp_key = ?", key).First(&record).Error; err != nil {
+		return ErrNotFound
+	}
+
+	if hmac.Equal([]byte(record.CodeHash), []byte(codeHash)) {
+		// 条件删除保证验证码只能使用一次
+		deleted := db.Where("id = ? AND code_hash = ?", record.ID, record.CodeHash).Delete(&models.PhoneOTP{})
+		if deleted.Error != nil {
+			return deleted.Error
+		}
+		if deleted.RowsAffected == 0 {
+			return ErrNotFound
+		}
+		return nil
+	}
+
+	if record.Attempts >= maxAttempts {
+		db.Delete(&record)
+		return ErrTooManyAttempts
+	}
+	return ErrMismatch
+}
+
+// Delete 删除验证码
+func (s *DBStore) Delete(ctx context.Context, key string) error {
+	return s.db.WithContext(ctx).Where("otp_key = ?", key).Delete(&models.PhoneOTP{}).Error
+}
+
+// Incr 固定窗口计数：窗口内加一，窗口过期后重置为 1
+func (s *DBStore) Incr(ctx context.Context, key string, window time.Duration) (int, time.Duration, error) {
+	db := s.db.WithContext(ctx)
+
+	for i := 0; i < 3; i++ {
+		now := time.Now()
+
+		result := db.Model(&models.OTPCounter{}).
+			Where("counter_key = ? AND expires_at > ?", key, now).
+			UpdateColumn("hits", gorm.Expr("hits + 1"))
+		if result.Error != nil {
+			return 0, 0, result.Error
+		}
+		if result.RowsAffected == 0 {
+			// 窗口已过期：重置计数
+			result = db.Model(&models.OTPCounter{}).
+				Where("counter_key = ? AND expires_at <= ?", key, now).
+				Updates(map[string]interface{}{"hits": 1, "expires_at": now.Add(window)})
+			if result.Error != nil {
+				return 0, 0, result.Error
+			}
+		}
+		if result.RowsAffected == 0 {
+			// 首次计数；并发创建冲突时重试
+			counter := models.OTPCounter{Key: key, Count: 1, ExpiresAt: now.Add(window)}
+			if err := db.Create(&counter).Error; err != nil {
+				continue
+			}
+			return 1, window, nil
+		}
+
+		var counter models.OTPCounter
+		if err := db.Where("counter_key = ?", key).First(&counter).Error; err != nil {
+			return 0, 0, err
+		}
+		return counter.Count, time.Until(counter.ExpiresAt), nil
+	}
+	return 0, 0, errCounterConflict
+}
+
+// RunCleanup 定期删除过期的验证码和计数记录，ctx 取消时退出
+func (s *DBStore) RunCleanup(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			now := time.Now()
+			s.db.Where("expires_at <= ?", now).Delete(&models.PhoneOTP{})
+			s.db.Where("expires_at <= ?", now).Delete(&models.OTPCounter{})
+		}
+	}
+}
@@ -0,0 +1,96 @@
+package otp
+
+import (
+	"context"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// redisKeyPrefix Redis 键前缀
+const redisKeyPrefix = "lottery:otp:"
+
+// checkScript 原子地累加尝试次数并比对验证码摘要
+// 返回 1 成功，0 不匹配，-1 不存在或已过期，-2 尝试次数用尽
+var checkScript = redis.NewScript(`
+	local code = redis.call('HGET', KEYS[1], 'code')
+	if not code then
+		return -1
+	end
+	local attempts = redis.call('HINCRBY', KEYS[1], 'attempts', 1)
+	if code == ARGV[1] then
+		redis.call('DEL', KEYS[1])
+		return 1
+	end
+	if attempts >= tonumber(ARGV[2]) then
+		redis.call('DEL', KEYS[1])
+		return -2
+	end
+	return 0
+`)
+
+// incrScript 固定窗口计数：首次计数时设置过期时间，返回 {计数, 剩余毫秒}
+var incrScript = redis.NewScript(`
+	local n = redis.call('INCR', KEYS[1])
+	if n == 1 then
+		redis.call('PEXPIRE', KEYS[1], ARGV[1])
+	end
+	return {n, redis.call('PTTL', KEYS[1])}
+`)
+
+// RedisStore 基于 Redis 的验证码存储（多实例部署时共享）
+type RedisStore struct {
+	client *redis.Client
+}
+
+// NewRedisStore 创建 Redis 验证码存储
+func NewRedisStore(client *redis.Client) *RedisStore {
+	return &RedisStore{client: client}
+}
+
+// Save 保存验证码摘要（覆盖旧验证码并重置尝试次数）
+func (s *RedisStore) Save(ctx context.Context, key, codeHash string, ttl time.Duration) error {
+	redisKey := redisKeyPrefix + "code:" + key
+	pipe := s.client.TxPipeline()
+	pipe.Del(ctx, redisKey)
+	pipe.HSet(ctx, redisKey, "code", codeHash, "attempts", 0)
+	pipe.PExpire(ctx, redisKey, ttl)
+	_, err := pipe.Exec(ctx)
+	return err
+}
+
+// Check 校验验证码
+func (s *RedisStore) Check(ctx context.Context, key, codeHash string, maxAttempts int) error {
+	result, err := checkScript.Run(ctx, s.client, []string{redisKeyPrefix + "code:" + key}, codeHash, maxAttempts).Int()
+	if err != nil {
+		return err
+	}
+	switch result {
+	case 1:
+		return nil
+	case -1:
+		return ErrNotFound
+	case -2:
+		return ErrTooManyAttempts
+	default:
+		return ErrMismatch
+	}
+}
+
+// Delete 删除验证码
+func (s *RedisStore) Delete(ctx context.Context, key string) error {
+	return s.client.Del(ctx, redisKeyPrefix+"code:"+key).Err()
+}
+
+// Incr 固定窗口计数
+func (s *RedisStore) Incr(ctx context.Context, key string, window time.Duration) (int, time.Duration, error) {
+	values, err := incrScript.Run(ctx, s.client, []string{redisKeyPrefix + "count:" + key}, window.Milliseconds()).Int64Slice()
+	if err != nil {
+		return 0, 0, err
+	}
+	ttl := time.Duration(values[1]) * time.Millisecond
+	if ttl < 0 {
+		ttl = window
+	}
+	return int(values[0]), ttl, nil
+}
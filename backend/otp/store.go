@@ -0,0 +1,47 @@
+// Package otp 提供短信验证码和发送频率计数的存储，启用 Redis 时存储在 Redis，否则存储在数据库
+package otp
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+// 验证码校验错误
+var (
+	ErrNotFound        = errors.New("otp not found or expired")
+	ErrMismatch        = errors.New("otp mismatch")
+	ErrTooManyAttempts = errors.New("otp attempts exceeded")
+)
+
+// Store 验证码存储接口（验证码只保存摘要）
+type Store interface {
+	// Save 保存验证码摘要，覆盖同一 key 的旧验证码并重置尝试次数
+	Save(ctx context.Context, key, codeHash string, ttl time.Duration) error
+	// Check 校验验证码，成功后删除（一次性）；累计错误 maxAttempts 次后验证码作废
+	Check(ctx context.Context, key, codeHash string, maxAttempts int) error
+	// Delete 删除验证码（如短信发送失败时）
+	Delete(ctx context.Context, key string) error
+	// Incr 在固定时间窗口内计数加一，返回当前计数和窗口剩余时间（用于频率限制）
+	Incr(ctx context.Context, key string, window time.Duration) (int, time.Duration, error)
+}
+
+var (
+	defaultStore Store
+	storeMu      sync.RWMutex
+)
+
+// SetDefault 设置全局验证码存储
+func SetDefault(s Store) {
+	storeMu.Lock()
+	defer storeMu.Unlock()
+	defaultStore = s
+}
+
+// Default 获取全局验证码存储
+func Default() Store {
+	storeMu.RLock()
+	defer storeMu.RUnlock()
+	return defaultStore
+}
@@ -0,0 +1,157 @@
+// Package realtime 提供按公司划分的实时事件广播（大屏抽奖直播）
+// 启用 Redis 时通过 pub/sub 在多个实例间分发，否则使用进程内 Hub
+package realtime
+
+import (
+	"encoding/json"
+	"sync"
+	"time"
+)
+
+// 事件类型
+const (
+	EventDrawStarted           = "draw_started"           // 开始抽奖
+	EventWinnersRevealed       = "winners_revealed"       // 公布中奖者
+	EventStockChanged          = "stock_changed"          // 奖品库存变化
+	EventParticipantRegistered = "participant_registered" // 新参与者加入
+	EventParticipantCheckedIn  = "participant_checked_in" // 参与者签到
+
+	// 大屏遥控事件（只投递给指定大屏）
+	EventDisplayPaired  = "display_paired"  // 遥控端已配对
+	EventDisplayCommand = "display_command" // 遥控指令
+	EventDisplayClosed  = "display_closed"  // 会话已关闭
+)
+
+// subscriberBuffer 每个订阅者的缓冲事件数，消费过慢时丢弃新事件，避免阻塞广播
+const subscriberBuffer = 64
+
+// Event 实时事件
+type Event struct {
+	Type      string          `json:"type"`
+	CompanyID int             `json:"company_id"`
+	DisplayID int             `json:"display_id,omitempty"` // 非 0 时为发给指定大屏的定向事件
+	Data      json.RawMessage `json:"data"`
+	Time      time.Time       `json:"time"`
+}
+
+// Hub 事件广播中心
+type Hub interface {
+	// Publish 向公司的所有订阅者广播事件
+	Publish(event Event) error
+	// Subscribe 订阅公司的事件，使用完毕后必须调用 Close
+	Subscribe(companyID int) *Subscription
+}
+
+// Subscription 事件订阅
+type Subscription struct {
+	C <-chan Event
+
+	once  sync.Once
+	close func()
+}
+
+// Close 取消订阅
+func (s *Subscription) Close() {
+	s.once.Do(s.close)
+}
+
+// MemoryHub 进程内事件广播中心
+type MemoryHub struct {
+	mu          sync.RWMutex
+	subscribers map[int]map[chan Event]struct{}
+}
+
+// NewMemoryHub 创建进程内事件广播中心
+func NewMemoryHub() *MemoryHub {
+	return &MemoryHub{subscribers: make(map[int]map[chan Event]struct{})}
+}
+
+// Publish 向本进程内的订阅者广播事件
+func (h *MemoryHub) Publish(event Event) error {
+	h.deliver(event)
+	return nil
+}
+
+// Subscribe 订阅公司的事件
+func (h *MemoryHub) Subscribe(companyID int) *Subscription {
+	ch := make(chan Event, subscriberBuffer)
+
+	h.mu.Lock()
+	if h.subscribers[companyID] == nil {
+		h.subscribers[companyID] = make(map[chan Event]struct{})
+	}
+	h.subscribers[companyID][ch] = struct{}{}
+	h.mu.Unlock()
+
+	return &Subscription{
+		C: ch,
+		close: func() {
+			h.mu.Lock()
+			delete(h.subscribers[companyID], ch)
+			if len(h.subscribers[companyID]) == 0 {
+				delete(h.subscribers, companyID)
+			}
+			h.mu.Unlock()
+			close(ch)
+		},
+	}
+}
+
+// SubscriberCount 获取公司当前的订阅者数量
+func (h *MemoryHub) SubscriberCount(companyID int) int {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return len(h.subscribers[companyID])
+}
+
+// deliver 将事件投递给本进程内的订阅者（非阻塞）
+func (h *MemoryHub) deliver(event Event) {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	for ch := range h.subscribers[event.CompanyID] {
+		select {
+		case ch <- event:
+		default:
+			// 订阅者消费过慢，丢弃事件（大屏可通过轮询接口补齐）
+		}
+	}
+}
+
+var (
+	defaultHub Hub = NewMemoryHub()
+	hubMu      sync.RWMutex
+)
+
+// SetDefault 设置全局事件广播中心
+func SetDefault(h Hub) {
+	hubMu.Lock()
+	defer hubMu.Unlock()
+	defaultHub = h
+}
+
+// Default 获取全局事件广播中心
+func Default() Hub {
+	hubMu.RLock()
+	defer hubMu.RUnlock()
+	return defaultHub
+}
+
+// Publish 使用全局广播中心发布公司事件
+func Publish(companyID int, eventType string, data interface{}) error {
+	return PublishToDisplay(companyID, 0, eventType, data)
+}
+
+// PublishToDisplay 使用全局广播中心发布只投递给指定大屏的事件
+func PublishToDisplay(companyID, displayID int, eventType string, data interface{}) error {
+	payload, err := json.Marshal(data)
+	if err != nil {
+		return err
+	}
+	return Default().Publish(Event{
+		Type:      eventType,
+		CompanyID: companyID,
+		DisplayID: displayID,
+		Data:      payload,
+		Time:      time.Now(),
+	})
+}
@@ -0,0 +1,72 @@
+package realtime
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// redisChannelPrefix Redis 频道前缀，每个公司一个频道
+const redisChannelPrefix = "lottery:live:"
+
+// RedisHub 基于 Redis pub/sub 的事件广播中心
+// 发布的事件先写入 Redis，再由各实例的订阅协程投递给本地订阅者
+type RedisHub struct {
+	client *redis.Client
+	local  *MemoryHub
+}
+
+// NewRedisHub 创建 Redis 事件广播中心，需要调用 Run 启动订阅
+func NewRedisHub(client *redis.Client) *RedisHub {
+	return &RedisHub{client: client, local: NewMemoryHub()}
+}
+
+// Publish 通过 Redis 广播事件（所有实例的订阅者都会收到）
+func (h *RedisHub) Publish(event Event) error {
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	if err := h.client.Publish(ctx, fmt.Sprintf("%s%d", redisChannelPrefix, event.CompanyID), payload).Err(); err != nil {
+		// Redis 不可用时降级为仅本实例广播
+		h.local.deliver(event)
+		return err
+	}
+	return nil
+}
+
+// Subscribe 订阅公司的事件
+func (h *RedisHub) Subscribe(companyID int) *Subscription {
+	return h.local.Subscribe(companyID)
+}
+
+// Run 订阅 Redis 频道并投递给本地订阅者，ctx 取消时退出；连接断开后自动重连
+func (h *RedisHub) Run(ctx context.Context) {
+	for {
+		pubsub := h.client.PSubscribe(ctx, redisChannelPrefix+"*")
+		ch := pubsub.Channel()
+
+		for msg := range ch {
+			var event Event
+			if err := json.Unmarshal([]byte(msg.Payload), &event); err != nil {
+				continue
+			}
+			h.local.deliver(event)
+		}
+		pubsub.Close()
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(3 * time.Second):
+			log.Println("⚠️  实时事件 Redis 订阅已断开，正在重连...")
+		}
+	}
+}
@@ -52,7 +52,7 @@ func (r *UserRepository) FindByUsername(username string, companyID int) (*models
 // FindByPhone finds a user by phone number and company ID
 func (r *UserRepository) FindByPhone(phone string, companyID int) (*models.User, error) {
 	var user models.User
-	err := config.DB.Where("phone = ? AND company_id = ?", phone, companyID).
+	err := config.DB.Where("phone_hash = ? AND company_id = ?", models.PhoneIndex(phone), companyID).
 		Preload("Company").
 		First(&user).Error
 	if err != nil {
@@ -144,7 +144,7 @@ func (r *UserRepository) ExistsByUsername(username string, companyID int) (bool,
 func (r *UserRepository) ExistsByPhone(phone string, companyID int) (bool, error) {
 	var count int64
 	err := config.DB.Model(&models.User{}).
-		Where("phone = ? AND company_id = ?", phone, companyID).
+		Where("phone_hash = ? AND company_id = ?", models.PhoneIndex(phone), companyID).
 		Count(&count).Error
 	return count > 0, err
 }
@@ -53,6 +53,32 @@ func setupUserAPIRoutes(r *gin.Engine) {
 		api.GET("/company-info", handlers.GetCompanyInfo)         // 获取公司信息
 		api.POST("/self-register", handlers.UserSelfRegister)     // 用户自助注册
 
+		// 报名手机号短信验证（公司开启时报名前需先验证）
+		api.POST("/self-register/otp", handlers.SendRegistrationOTP)
+		api.POST("/self-register/otp/verify", handlers.VerifyRegistrationOTP)
+
+		// 报名人机验证（公司开启时报名和发送报名验证码前需先完成）
+		api.GET("/challenge", handlers.GetRegistrationChallenge)
+
+		// 上传的图片（公开，长期缓存）
+		api.GET("/uploads/*filepath", handlers.ServeUpload)
+		api.HEAD("/uploads/*filepath", handlers.ServeUpload)
+
+		// 大屏实时事件流（SSE，支持 access_token 参数认证）
+		api.GET("/live", middleware.QueryTokenMiddleware(), middleware.UserAuthMiddleware(), middleware.RejectParticipantToken(), handlers.LiveEvents)
+
+		// 大屏展示会话（大屏端使用注册时返回的 token 认证）
+		api.POST("/displays", handlers.RegisterDisplay)
+		api.GET("/displays/:id", handlers.GetDisplayStatus)
+		api.GET("/displays/:id/stream", handlers.DisplayStream)
+		api.GET("/displays/:id/checkin-qr", handlers.GetDisplayCheckInQR)
+
+		// 参与者登录（手机验证码 / 一次性登录链接），签发只能访问本人信息的参与者token
+		api.POST("/participant/login/otp", handlers.SendParticipantLoginOTP)
+		api.POST("/participant/login/verify", handlers.ParticipantLoginByOTP)
+		api.POST("/participant/login/link", handlers.SendParticipantLoginLink)
+		api.POST("/participant/login/link/verify", handlers.ParticipantLoginByLink)
+
 		// 需要用户认证的接口
 		userAuth := api.Group("")
 		userAuth.Use(middleware.UserAuthMiddleware())
@@ -62,17 +88,31 @@ func setupUserAPIRoutes(r *gin.Engine) {
 
 			// 用户信息
 			userAuth.GET("/user", handlers.GetUserInfo)
-			userAuth.POST("/user/change-password", handlers.ChangeUserPassword)
+			userAuth.PUT("/user/profile", handlers.UpdateMyProfile) // 参与者修改个人资料
 
 			// 奖品相关
 			userAuth.GET("/prize-levels", handlers.GetActivePrizeLevels)
 
-			// 抽奖相关
-			userAuth.POST("/draw", handlers.Draw)
+			// 我的奖品和领奖
 			userAuth.GET("/my-prize", handlers.GetMyPrize)
+			userAuth.GET("/my-prize/variants", handlers.GetMyPrizeVariants)
+			userAuth.POST("/my-prize/variant", handlers.ChooseMyPrizeVariant)
+			userAuth.PUT("/my-prize/shipping", handlers.SubmitShippingInfo)
+			userAuth.GET("/my-checkin", handlers.GetMyCheckIn)         // 个人签到码
+			userAuth.POST("/checkin/venue", handlers.CheckInByVenueQR) // 扫描现场签到码
 			userAuth.GET("/user-stats", handlers.GetUserStats)
-			userAuth.GET("/draw-records", handlers.GetDrawRecordsPublic)
-			userAuth.GET("/available-users", handlers.GetAvailableUsersPublic)
+
+			// 以下接口不对参与者token开放
+			full := userAuth.Group("")
+			full.Use(middleware.RejectParticipantToken())
+			{
+				full.POST("/user/change-password", handlers.ChangeUserPassword)
+
+				// 抽奖相关
+				full.POST("/draw", handlers.Draw)
+				full.GET("/draw-records", handlers.GetDrawRecordsPublic)
+				full.GET("/available-users", handlers.GetAvailableUsersPublic)
+			}
 		}
 	}
 }
@@ -102,7 +142,10 @@ func setupAdminAPIRoutes(r *gin.Engine) {
 			auth.GET("/users", handlers.GetUsers)
 			auth.POST("/users", handlers.CreateUser)
 			auth.POST("/users/batch", handlers.BatchCreateUsers)
+			auth.POST("/users/import/preview", handlers.PreviewUserImport) // 导入参与者预览（CSV/XLSX）
+			auth.POST("/users/import", handlers.ImportUsers)
 			auth.POST("/users/scan-add", handlers.ScanAddUser) // 扫码添加用户
+			auth.POST("/user-qr", handlers.GenerateUserQR)     // 签发扫码添加用户的签名二维码
 			auth.PUT("/users/:id", handlers.UpdateUser)
 			auth.DELETE("/users/:id", handlers.DeleteUser)
 
@@ -111,6 +154,8 @@ func setupAdminAPIRoutes(r *gin.Engine) {
 			auth.POST("/companies", handlers.CreateCompany)
 			auth.PUT("/companies/:id", handlers.UpdateCompany)
 			auth.DELETE("/companies/:id", handlers.DeleteCompany)
+			auth.GET("/companies/:id/archive", handlers.ExportCompanyArchive) // 导出公司备份归档（zip）
+			auth.POST("/companies/import", handlers.ImportCompanyArchive)     // 从归档导入为新公司（迁移/恢复）
 			auth.GET("/company-stats", handlers.GetCompanyStats)
 
 			// 奖项等级管理
@@ -118,6 +163,8 @@ func setupAdminAPIRoutes(r *gin.Engine) {
 			auth.GET("/prize-levels", handlers.GetPrizeLevels)
 			auth.PUT("/prize-levels/:id", handlers.UpdatePrizeLevel)
 			auth.DELETE("/prize-levels/:id", handlers.DeletePrizeLevel)
+			auth.PUT("/prize-levels/:id/open", handlers.OpenPrizeLevel)   // 开放奖项抽奖
+			auth.PUT("/prize-levels/:id/close", handlers.ClosePrizeLevel) // 关闭奖项抽奖
 
 			// 奖品管理
 			auth.GET("/prizes/all", handlers.GetAllPrizes)
@@ -126,10 +173,91 @@ func setupAdminAPIRoutes(r *gin.Engine) {
 			auth.PUT("/prizes/:id", handlers.UpdatePrize)
 			auth.DELETE("/prizes/:id", handlers.DeletePrize)
 
+			// 图片上传（奖品图片、公司Logo）
+			auth.POST("/uploads/image", handlers.UploadImage)
+
+			// 虚拟奖品兑换码池
+			auth.POST("/prizes/:id/codes", handlers.ImportPrizeCodes)
+			auth.GET("/prizes/:id/codes", handlers.GetPrizeCodes)
+			auth.GET("/prize-codes/alerts", handlers.GetPrizeCodeAlerts)
+
+			// 奖品规格（尺码/颜色）
+			auth.POST("/prizes/:id/variants", handlers.CreatePrizeVariant)
+			auth.GET("/prizes/:id/variants", handlers.GetPrizeVariants)
+			auth.PUT("/prize-variants/:id", handlers.UpdatePrizeVariant)
+			auth.DELETE("/prize-variants/:id", handlers.DeletePrizeVariant)
+
 			// 抽奖记录和统计
 			auth.GET("/draw-records", handlers.GetDrawRecords)
+			auth.PUT("/draw-records/:id/void", handlers.VoidDrawRecord)
+			auth.PUT("/draw-records/:id/status", handlers.UpdateDrawRecordStatus)
+			auth.PUT("/fulfillment/bulk-status", handlers.BulkUpdateDrawRecordStatus)
+			auth.GET("/fulfillment/shipping-export", handlers.ExportShippingCSV)
+			auth.POST("/redemptions/scan", handlers.RedeemPrize) // 领奖台扫码核销
 			auth.GET("/stats", handlers.GetStats)
 
+			// 现场签到
+			auth.POST("/checkins/scan", handlers.ScanCheckIn) // 签到台扫描个人签到码
+			auth.GET("/checkin/venue-qr", handlers.GetVenueCheckInQR)
+			auth.GET("/checkin/stats", handlers.GetCheckInStats)
+			auth.POST("/users/:id/checkin", handlers.ManualCheckIn)
+			auth.DELETE("/users/:id/checkin", handlers.CancelCheckIn)
+			auth.PUT("/companies/:id/checkin", handlers.UpdateCheckInSettings) // 签到配置（是否必须签到、迟到截止时间）
+
+			// 大屏遥控
+			auth.GET("/displays", handlers.GetDisplays)
+			auth.POST("/displays/pair", handlers.PairDisplay)
+			auth.POST("/displays/:id/commands", handlers.SendDisplayCommand)
+			auth.DELETE("/displays/:id", handlers.CloseDisplay)
+
+			// 奖品回流（活动结束时未领取/作废奖品）
+			auth.GET("/rollover/preview", handlers.PreviewRollover)
+			auth.GET("/rollovers", handlers.GetRollovers)
+			auth.POST("/companies/:id/close-event", handlers.CloseEvent)
+
+			// 财务：奖品预算与个税代扣报表
+			auth.PUT("/companies/:id/finance", handlers.UpdateCompanyFinance)
+			auth.GET("/finance/report", handlers.GetFinanceReport)
+
+			// 参与者目录同步（LDAP / HR 名单文件）
+			auth.GET("/companies/:id/directory-sync", handlers.GetDirectorySync)
+			auth.PUT("/companies/:id/directory-sync", handlers.UpdateDirectorySync)
+			auth.DELETE("/companies/:id/directory-sync", handlers.DeleteDirectorySync)
+			auth.POST("/companies/:id/directory-sync/run", handlers.RunDirectorySync) // dry_run=true 预览
+
+			// 个人信息保留期限（活动结束后匿名化参与者个人信息）
+			auth.GET("/companies/:id/retention", handlers.GetRetention)
+			auth.PUT("/companies/:id/retention", handlers.UpdateRetention)
+			auth.POST("/companies/:id/retention/purge", handlers.PurgeCompanyPII) // dry_run=true 预览
+
+			// 报名配置（报名时间窗口、人数上限、候补名单）
+			auth.PUT("/companies/:id/registration", handlers.UpdateRegistrationSettings)
+
+			// 参与者胸牌 PDF（A4 网格 / 标签打印机）
+			auth.GET("/badges/pdf", handlers.GenerateBadgesPDF)
+
+			// 导出中奖名单/参与者（CSV/XLSX/PDF，数据量较大时转为后台任务）
+			auth.GET("/exports/draw-records", handlers.ExportDrawRecords)
+			auth.GET("/exports/users", handlers.ExportUsers)
+			auth.GET("/export-jobs", handlers.GetExportJobs)
+			auth.GET("/export-jobs/:id", handlers.GetExportJob)
+			auth.GET("/export-jobs/:id/download", handlers.DownloadExportJob)
+
+			// 报名表单自定义字段
+			auth.GET("/registration-fields", handlers.GetRegistrationFields)
+			auth.POST("/registration-fields", handlers.CreateRegistrationField)
+			auth.PUT("/registration-fields/:id", handlers.UpdateRegistrationField)
+			auth.DELETE("/registration-fields/:id", handlers.DeleteRegistrationField)
+
+			// 报名审核（疑似批量报名）
+			auth.GET("/registration-reviews", handlers.GetRegistrationReviews)
+			auth.POST("/registration-reviews", handlers.ReviewRegistrations)
+
+			// 疑似重复参与者（审核列表、标记不是重复、合并）
+			auth.GET("/duplicates", handlers.GetDuplicateCandidates)
+			auth.POST("/duplicates/dismiss", handlers.DismissDuplicate)
+			auth.POST("/duplicates/merge", handlers.MergeUsers)
+
 			// 操作日志（仅超级管理员）
 			auth.GET("/operation-logs", handlers.GetOperationLogs)
 			auth.GET("/operation-stats", handlers.GetOperationStats)
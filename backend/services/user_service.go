@@ -275,7 +275,9 @@ func (s *UserService) UpdateUser(userID int, req *UpdateUserRequest) (*models.Us
 		if err := validators.ValidatePhone(req.Phone); err != nil {
 			return nil, err
 		}
-		updates["phone"] = req.Phone
+		if err := models.SetPhoneUpdate(updates, req.Phone); err != nil {
+			return nil, err
+		}
 	}
 
 	if req.HasDrawn != nil {
@@ -0,0 +1,112 @@
+// Package sms 提供短信发送抽象，支持日志输出、本地文件和 HTTP 网关实现
+package sms
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"sync"
+	"time"
+)
+
+// Sender 短信发送接口
+type Sender interface {
+	// Send 向手机号发送短信
+	Send(ctx context.Context, phone, message string) error
+}
+
+// Options 短信发送配置
+type Options struct {
+	Driver string // log, file, webhook
+
+	// 本地文件（开发测试用，每条短信一行 JSON）
+	FilePath string
+
+	// HTTP 短信网关（POST JSON: {"phone": "...", "message": "..."}）
+	WebhookURL   string
+	WebhookToken string // 以 Bearer 方式放入 Authorization 头，可为空
+}
+
+// New 根据配置创建短信发送器，未配置驱动时返回 nil
+func New(opts Options) (Sender, error) {
+	switch opts.Driver {
+	case "":
+		return nil, nil
+	case "log":
+		return LogSender{}, nil
+	case "file":
+		return NewFileSender(opts.FilePath), nil
+	case "webhook":
+		return NewWebhookSender(opts.WebhookURL, opts.WebhookToken)
+	default:
+		return nil, fmt.Errorf("unknown sms driver: %s", opts.Driver)
+	}
+}
+
+var (
+	defaultSender Sender // 未配置驱动时为 nil
+	senderMu      sync.RWMutex
+)
+
+// SetDefault 设置全局短信发送器
+func SetDefault(s Sender) {
+	senderMu.Lock()
+	defer senderMu.Unlock()
+	defaultSender = s
+}
+
+// Default 获取全局短信发送器，未配置时返回 nil
+func Default() Sender {
+	senderMu.RLock()
+	defer senderMu.RUnlock()
+	return defaultSender
+}
+
+// LogSender 将短信内容输出到日志（本地开发使用，不实际发送，验证码会明文写入日志）
+type LogSender struct{}
+
+// Send 输出短信到日志
+func (LogSender) Send(ctx context.Context, phone, message string) error {
+	log.Printf("📱 [SMS] %s: %s", phone, message)
+	return nil
+}
+
+// FileSender 将短信追加写入本地文件（本地测试和自动化测试读取验证码）
+type FileSender struct {
+	mu   sync.Mutex
+	path string
+}
+
+// NewFileSender 创建文件短信发送器
+func NewFileSender(path string) *FileSender {
+	if path == "" {
+		path = "sms_outbox.log"
+	}
+	return &FileSender{path: path}
+}
+
+// Send 追加一行 JSON 到文件
+func (s *FileSender) Send(ctx context.Context, phone, message string) error {
+	line, err := json.Marshal(map[string]interface{}{
+		"time":    time.Now().Format(time.RFC3339),
+		"phone":   phone,
+		"message": message,
+	})
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	f, err := os.OpenFile(s.path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0600)
+	if err != nil {
+		return fmt.Errorf("open sms outbox: %w", err)
+	}
+	defer f.Close()
+
+	_, err = f.Write(append(line, '\n'))
+	return err
+}
@@ -0,0 +1,65 @@
+package sms
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+// WebhookSender 通过 HTTP 网关发送短信（对接自建或第三方短信服务的转发接口）
+type WebhookSender struct {
+	url    string
+	token  string
+	client *http.Client
+}
+
+// NewWebhookSender 创建 HTTP 网关短信发送器
+func NewWebhookSender(endpoint, token string) (*WebhookSender, error) {
+	if endpoint == "" {
+		return nil, errors.New("sms webhook url is required")
+	}
+	u, err := url.Parse(endpoint)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return nil, fmt.Errorf("invalid sms webhook url: %s", endpoint)
+	}
+	return &WebhookSender{
+		url:    endpoint,
+		token:  token,
+		client: &http.Client{Timeout: 10 * time.Second},
+	}, nil
+}
+
+// Send 向网关 POST 短信内容，非 2xx 响应视为发送失败
+func (s *WebhookSender) Send(ctx context.Context, phone, message string) error {
+	body, err := json.Marshal(map[string]string{"phone": phone, "message": message})
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if s.token != "" {
+		req.Header.Set("Authorization", "Bearer "+s.token)
+	}
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		detail, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("sms gateway returned %d: %s", resp.StatusCode, bytes.TrimSpace(detail))
+	}
+	return nil
+}
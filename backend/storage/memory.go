@@ -0,0 +1,80 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"crypto/md5"
+	"encoding/hex"
+	"io"
+	"sync"
+	"time"
+)
+
+// memoryObject 内存中的对象
+type memoryObject struct {
+	data []byte
+	info ObjectInfo
+}
+
+// MemoryStorage 内存存储（用于测试和本地开发，进程重启后数据丢失）
+type MemoryStorage struct {
+	mu      sync.RWMutex
+	objects map[string]memoryObject
+}
+
+// NewMemoryStorage 创建内存存储
+func NewMemoryStorage() *MemoryStorage {
+	return &MemoryStorage{objects: make(map[string]memoryObject)}
+}
+
+// Put 保存对象
+func (s *MemoryStorage) Put(ctx context.Context, key string, data []byte, contentType string) error {
+	if err := ValidateKey(key); err != nil {
+		return err
+	}
+
+	sum := md5.Sum(data)
+	copied := append([]byte(nil), data...)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.objects[key] = memoryObject{
+		data: copied,
+		info: ObjectInfo{
+			Key:          key,
+			Size:         int64(len(copied)),
+			ContentType:  contentType,
+			ETag:         hex.EncodeToString(sum[:]),
+			LastModified: time.Now(),
+		},
+	}
+	return nil
+}
+
+// Get 读取对象
+func (s *MemoryStorage) Get(ctx context.Context, key string) (io.ReadCloser, *ObjectInfo, error) {
+	if err := ValidateKey(key); err != nil {
+		return nil, nil, err
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	obj, ok := s.objects[key]
+	if !ok {
+		return nil, nil, ErrNotFound
+	}
+	info := obj.info
+	return io.NopCloser(bytes.NewReader(obj.data)), &info, nil
+}
+
+// Delete 删除对象
+func (s *MemoryStorage) Delete(ctx context.Context, key string) error {
+	if err := ValidateKey(key); err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.objects, key)
+	return nil
+}
@@ -0,0 +1,90 @@
+// Package storage 提供上传文件的存储抽象，支持本地磁盘、S3 兼容对象存储和内存实现
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+// ErrNotFound 对象不存在
+var ErrNotFound = errors.New("object not found")
+
+// ErrInvalidKey 对象键非法（为空、包含 .. 或以 / 开头）
+var ErrInvalidKey = errors.New("invalid object key")
+
+// ObjectInfo 对象元信息
+type ObjectInfo struct {
+	Key          string
+	Size         int64
+	ContentType  string
+	ETag         string
+	LastModified time.Time
+}
+
+// Storage 文件存储接口
+type Storage interface {
+	// Put 保存对象，已存在时覆盖
+	Put(ctx context.Context, key string, data []byte, contentType string) error
+	// Get 读取对象，调用方负责关闭返回的 ReadCloser
+	Get(ctx context.Context, key string) (io.ReadCloser, *ObjectInfo, error)
+	// Delete 删除对象，不存在时不报错
+	Delete(ctx context.Context, key string) error
+}
+
+// Options 存储配置
+type Options struct {
+	Driver string // local, s3, memory
+
+	// 本地磁盘
+	LocalDir string
+
+	// S3 兼容对象存储（AWS S3、MinIO 等）
+	S3Endpoint  string // 如 https://s3.amazonaws.com 或 http://localhost:9000
+	S3Region    string
+	S3Bucket    string
+	S3AccessKey string
+	S3SecretKey string
+}
+
+var defaultStorage Storage
+
+// New 根据配置创建存储实例
+func New(opts Options) (Storage, error) {
+	switch opts.Driver {
+	case "", "local":
+		return NewLocalStorage(opts.LocalDir)
+	case "s3":
+		return NewS3Storage(opts.S3Endpoint, opts.S3Region, opts.S3Bucket, opts.S3AccessKey, opts.S3SecretKey)
+	case "memory":
+		return NewMemoryStorage(), nil
+	default:
+		return nil, fmt.Errorf("unknown storage driver: %s", opts.Driver)
+	}
+}
+
+// SetDefault 设置全局默认存储
+func SetDefault(s Storage) {
+	defaultStorage = s
+}
+
+// Default 获取全局默认存储
+func Default() Storage {
+	return defaultStorage
+}
+
+// ValidateKey 校验对象键，防止路径穿越
+func ValidateKey(key string) error {
+	if key == "" || strings.HasPrefix(key, "/") || strings.Contains(key, "\\") {
+		return ErrInvalidKey
+	}
+	for _, part := range strings.Split(key, "/") {
+		if part == "" || part == "." || part == ".." {
+			return ErrInvalidKey
+		}
+	}
+	return nil
+}
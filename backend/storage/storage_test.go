@@ -0,0 +1,264 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestValidateKey(t *testing.T) {
+	valid := []string{"a.png", "prizes/2026/abc.jpg", "logo..png"}
+	for _, key := range valid {
+		if err := ValidateKey(key); err != nil {
+			t.Errorf("ValidateKey(%q) = %v, want nil", key, err)
+		}
+	}
+
+	invalid := []string{"", "/etc/passwd", "../a.png", "a/../../b", "a//b", "a/./b", "a/", `a\b`}
+	for _, key := range invalid {
+		if err := ValidateKey(key); !errors.Is(err, ErrInvalidKey) {
+			t.Errorf("ValidateKey(%q) = %v, want ErrInvalidKey", key, err)
+		}
+	}
+}
+
+func TestNewUnknownDriver(t *testing.T) {
+	if _, err := New(Options{Driver: "ftp"}); err == nil {
+		t.Fatal("expected error for unknown driver")
+	}
+	s, err := New(Options{Driver: "memory"})
+	if err != nil {
+		t.Fatalf("New(memory) error: %v", err)
+	}
+	if _, ok := s.(*MemoryStorage); !ok {
+		t.Fatalf("New(memory) = %T, want *MemoryStorage", s)
+	}
+}
+
+func TestMemoryStorage(t *testing.T) {
+	testStorage(t, NewMemoryStorage())
+}
+
+func TestMemoryStorageCopiesData(t *testing.T) {
+	s := NewMemoryStorage()
+	ctx := context.Background()
+
+	data := []byte("original")
+	if err := s.Put(ctx, "copy.txt", data, "text/plain"); err != nil {
+		t.Fatalf("Put error: %v", err)
+	}
+	copy(data, "modified")
+
+	if got := readObject(t, s, "copy.txt"); got != "original" {
+		t.Fatalf("stored data changed with caller buffer: %q", got)
+	}
+}
+
+func TestMemoryStorageETag(t *testing.T) {
+	s := NewMemoryStorage()
+	ctx := context.Background()
+
+	if err := s.Put(ctx, "a.txt", []byte("hello"), "text/plain"); err != nil {
+		t.Fatalf("Put error: %v", err)
+	}
+	_, info, err := s.Get(ctx, "a.txt")
+	if err != nil {
+		t.Fatalf("Get error: %v", err)
+	}
+	// md5("hello")
+	if info.ETag != "5d41402abc4b2a76b9719d911017c592" {
+		t.Fatalf("ETag = %q", info.ETag)
+	}
+}
+
+func TestMemoryStorageConcurrent(t *testing.T) {
+	s := NewMemoryStorage()
+	ctx := context.Background()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			key := "concurrent/" + string(rune('a'+i)) + ".txt"
+			if err := s.Put(ctx, key, []byte(key), "text/plain"); err != nil {
+				t.Errorf("Put error: %v", err)
+				return
+			}
+			if rc, _, err := s.Get(ctx, key); err == nil {
+				rc.Close()
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	if len(s.objects) != 20 {
+		t.Fatalf("stored %d objects, want 20", len(s.objects))
+	}
+}
+
+func TestLocalStorage(t *testing.T) {
+	s, err := NewLocalStorage(t.TempDir())
+	if err != nil {
+		t.Fatalf("NewLocalStorage error: %v", err)
+	}
+	testStorage(t, s)
+}
+
+func TestS3Storage(t *testing.T) {
+	server := httptest.NewServer(newFakeS3(t, "uploads"))
+	defer server.Close()
+
+	s, err := NewS3Storage(server.URL, "", "uploads", "access", "secret")
+	if err != nil {
+		t.Fatalf("NewS3Storage error: %v", err)
+	}
+	testStorage(t, s)
+}
+
+func TestNewS3StorageValidation(t *testing.T) {
+	if _, err := NewS3Storage("", "", "bucket", "", ""); err == nil {
+		t.Error("expected error for empty endpoint")
+	}
+	if _, err := NewS3Storage("http://localhost:9000", "", "", "", ""); err == nil {
+		t.Error("expected error for empty bucket")
+	}
+	if _, err := NewS3Storage("not a url", "", "bucket", "", ""); err == nil {
+		t.Error("expected error for endpoint without host")
+	}
+}
+
+// testStorage 各存储实现共用的行为测试
+func testStorage(t *testing.T, s Storage) {
+	t.Helper()
+	ctx := context.Background()
+
+	if _, _, err := s.Get(ctx, "missing/file.png"); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("Get missing = %v, want ErrNotFound", err)
+	}
+
+	if err := s.Put(ctx, "prizes/2026/a.png", []byte("first"), "image/png"); err != nil {
+		t.Fatalf("Put error: %v", err)
+	}
+	if got := readObject(t, s, "prizes/2026/a.png"); got != "first" {
+		t.Fatalf("Get = %q, want %q", got, "first")
+	}
+
+	_, info, err := s.Get(ctx, "prizes/2026/a.png")
+	if err != nil {
+		t.Fatalf("Get error: %v", err)
+	}
+	if info.Key != "prizes/2026/a.png" || info.Size != int64(len("first")) {
+		t.Fatalf("info = %+v", info)
+	}
+	if info.ContentType != "image/png" {
+		t.Fatalf("ContentType = %q, want image/png", info.ContentType)
+	}
+	if info.ETag == "" {
+		t.Fatal("ETag is empty")
+	}
+
+	// 覆盖已有对象
+	if err := s.Put(ctx, "prizes/2026/a.png", []byte("second"), "image/png"); err != nil {
+		t.Fatalf("Put overwrite error: %v", err)
+	}
+	if got := readObject(t, s, "prizes/2026/a.png"); got != "second" {
+		t.Fatalf("Get after overwrite = %q, want %q", got, "second")
+	}
+
+	if err := s.Delete(ctx, "prizes/2026/a.png"); err != nil {
+		t.Fatalf("Delete error: %v", err)
+	}
+	if _, _, err := s.Get(ctx, "prizes/2026/a.png"); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("Get after delete = %v, want ErrNotFound", err)
+	}
+	// 删除不存在的对象不报错
+	if err := s.Delete(ctx, "prizes/2026/a.png"); err != nil {
+		t.Fatalf("Delete missing error: %v", err)
+	}
+
+	// 非法对象键
+	for _, key := range []string{"../escape.png", "/abs.png", ""} {
+		if err := s.Put(ctx, key, []byte("x"), "image/png"); !errors.Is(err, ErrInvalidKey) {
+			t.Errorf("Put(%q) = %v, want ErrInvalidKey", key, err)
+		}
+		if _, _, err := s.Get(ctx, key); !errors.Is(err, ErrInvalidKey) {
+			t.Errorf("Get(%q) = %v, want ErrInvalidKey", key, err)
+		}
+		if err := s.Delete(ctx, key); !errors.Is(err, ErrInvalidKey) {
+			t.Errorf("Delete(%q) = %v, want ErrInvalidKey", key, err)
+		}
+	}
+}
+
+func readObject(t *testing.T, s Storage, key string) string {
+	t.Helper()
+	rc, _, err := s.Get(context.Background(), key)
+	if err != nil {
+		t.Fatalf("Get(%q) error: %v", key, err)
+	}
+	defer rc.Close()
+	data, err := io.ReadAll(rc)
+	if err != nil {
+		t.Fatalf("read %q error: %v", key, err)
+	}
+	return string(data)
+}
+
+// newFakeS3 模拟 S3 path-style 接口（只校验签名头格式，不验证签名值）
+func newFakeS3(t *testing.T, bucket string) http.Handler {
+	type object struct {
+		data        []byte
+		contentType string
+		modified    time.Time
+	}
+	var mu sync.Mutex
+	objects := make(map[string]object)
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		auth := r.Header.Get("Authorization")
+		if !strings.HasPrefix(auth, "AWS4-HMAC-SHA256 Credential=access/") || r.Header.Get("X-Amz-Date") == "" {
+			t.Errorf("request without SigV4 headers: %q", auth)
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+
+		prefix := "/" + bucket + "/"
+		if !strings.HasPrefix(r.URL.Path, prefix) {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		key := strings.TrimPrefix(r.URL.Path, prefix)
+
+		mu.Lock()
+		defer mu.Unlock()
+		switch r.Method {
+		case http.MethodPut:
+			data, _ := io.ReadAll(r.Body)
+			objects[key] = object{data: data, contentType: r.Header.Get("Content-Type"), modified: time.Now()}
+			w.Header().Set("ETag", `"fake-etag"`)
+			w.WriteHeader(http.StatusOK)
+		case http.MethodGet:
+			obj, ok := objects[key]
+			if !ok {
+				w.WriteHeader(http.StatusNotFound)
+				return
+			}
+			w.Header().Set("Content-Type", obj.contentType)
+			w.Header().Set("ETag", `"fake-etag"`)
+			w.Header().Set("Last-Modified", obj.modified.UTC().Format(http.TimeFormat))
+			w.Write(obj.data)
+		case http.MethodDelete:
+			delete(objects, key)
+			w.WriteHeader(http.StatusNoContent)
+		default:
+			w.WriteHeader(http.StatusMethodNotAllowed)
+		}
+	})
+}
@@ -129,7 +129,7 @@ func main() {
 		query := config.DB.Where("company_id = ?", companyID).Where("username = ?", username)
 
 		if phone != "" {
-			query = query.Where("phone = ?", phone)
+			query = query.Where("phone_hash = ?", models.PhoneIndex(phone))
 		}
 
 		if err := query.Find(&existingUsers).Error; err == nil && len(existingUsers) > 0 {
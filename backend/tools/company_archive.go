@@ -0,0 +1,143 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"time"
+
+	"lottery-system/archive"
+	"lottery-system/config"
+	"lottery-system/models"
+	"lottery-system/storage"
+)
+
+func main() {
+	if len(os.Args) < 3 || (os.Args[1] != "export" && os.Args[1] != "import") {
+		fmt.Println("======================================")
+		fmt.Println("📦 公司备份与迁移工具")
+		fmt.Println("======================================")
+		fmt.Println("")
+		fmt.Println("用法:")
+		fmt.Println("  go run company_archive.go export <公司代码> [输出文件]")
+		fmt.Println("  go run company_archive.go import <归档文件> [新公司代码] [fail|rename]")
+		fmt.Println("")
+		fmt.Println("示例:")
+		fmt.Println("  go run company_archive.go export acme")
+		fmt.Println("  go run company_archive.go import company_acme_20261019.zip")
+		fmt.Println("  go run company_archive.go import company_acme_20261019.zip acme-test rename")
+		fmt.Println("")
+		fmt.Println("说明:")
+		fmt.Println("  归档包含公司设置、奖项、奖品、报名字段、参与者、中奖记录、操作日志和图片")
+		fmt.Println("  不包含管理员和参与者密码、兑换码，导入后管理员需重置密码")
+		fmt.Println("  公司代码已存在时默认失败，rename 会自动改为 acme-2 等")
+		fmt.Println("======================================")
+		return
+	}
+
+	config.LoadConfig()
+	config.InitDB()
+
+	store, err := storage.New(storage.Options{
+		Driver:      config.AppConfig.StorageDriver,
+		LocalDir:    config.AppConfig.UploadDir,
+		S3Endpoint:  config.AppConfig.S3Endpoint,
+		S3Region:    config.AppConfig.S3Region,
+		S3Bucket:    config.AppConfig.S3Bucket,
+		S3AccessKey: config.AppConfig.S3AccessKey,
+		S3SecretKey: config.AppConfig.S3SecretKey,
+	})
+	if err != nil {
+		fmt.Printf("⚠️  文件存储不可用，归档将不包含图片: %v\n", err)
+		store = nil
+	}
+	// 需与服务端上传文件的 URL 前缀一致
+	opts := archive.Options{Store: store, AssetPrefix: "/api/uploads/"}
+
+	if os.Args[1] == "export" {
+		exportCompany(os.Args[2], opts)
+	} else {
+		importCompany(os.Args[2], opts)
+	}
+}
+
+func exportCompany(companyCode string, opts archive.Options) {
+	var company models.Company
+	if err := config.DB.Where("code = ?", companyCode).First(&company).Error; err != nil {
+		fmt.Printf("❌ 公司不存在: %s\n", companyCode)
+		os.Exit(1)
+	}
+
+	filename := fmt.Sprintf("company_%s_%s.zip", company.Code, time.Now().Format("20060102"))
+	if len(os.Args) > 3 {
+		filename = os.Args[3]
+	}
+	file, err := os.Create(filename)
+	if err != nil {
+		fmt.Printf("❌ 创建文件失败: %v\n", err)
+		os.Exit(1)
+	}
+
+	manifest, err := archive.Export(context.Background(), config.DB, company.ID, file, opts)
+	if err == nil {
+		err = file.Close()
+	} else {
+		file.Close()
+	}
+	if err != nil {
+		os.Remove(filename)
+		fmt.Printf("❌ 导出失败: %v\n", err)
+		os.Exit(1)
+	}
+
+	fmt.Println("\n======================================")
+	fmt.Printf("✅ 导出成功！\n")
+	fmt.Printf("   公司: %s (%s)\n", company.Name, company.Code)
+	fmt.Printf("   参与者: %d\n", manifest.Counts["users"])
+	fmt.Printf("   中奖记录: %d\n", manifest.Counts["draw_records"])
+	fmt.Printf("   图片: %d\n", len(manifest.Assets))
+	fmt.Printf("\n📁 文件保存: %s\n", filename)
+	fmt.Println("======================================")
+}
+
+func importCompany(filename string, opts archive.Options) {
+	file, err := os.Open(filename)
+	if err != nil {
+		fmt.Printf("❌ 打开文件失败: %v\n", err)
+		os.Exit(1)
+	}
+	defer file.Close()
+	info, err := file.Stat()
+	if err != nil {
+		fmt.Printf("❌ 读取文件失败: %v\n", err)
+		os.Exit(1)
+	}
+
+	importOpts := archive.ImportOptions{Options: opts, OnConflict: archive.ConflictFail}
+	if len(os.Args) > 3 {
+		importOpts.Code = os.Args[3]
+	}
+	if len(os.Args) > 4 {
+		importOpts.OnConflict = os.Args[4]
+	}
+	if importOpts.OnConflict != archive.ConflictFail && importOpts.OnConflict != archive.ConflictRename {
+		fmt.Printf("❌ 无效的冲突处理方式: %s（可选 fail 或 rename）\n", importOpts.OnConflict)
+		os.Exit(1)
+	}
+
+	result, err := archive.Import(context.Background(), config.DB, file, info.Size(), importOpts)
+	if err != nil {
+		fmt.Printf("❌ 导入失败: %v\n", err)
+		os.Exit(1)
+	}
+
+	fmt.Println("\n======================================")
+	fmt.Printf("✅ 导入成功！\n")
+	fmt.Printf("   公司代码: %s (ID: %d)\n", result.Code, result.CompanyID)
+	fmt.Printf("   参与者: %d\n", result.Counts["users"])
+	fmt.Printf("   中奖记录: %d\n", result.Counts["draw_records"])
+	for _, warning := range result.Warnings {
+		fmt.Printf("⚠️  %s\n", warning)
+	}
+	fmt.Println("======================================")
+}
@@ -0,0 +1,44 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"lottery-system/config"
+	"lottery-system/fieldcrypt"
+	"lottery-system/models"
+)
+
+func main() {
+	if len(os.Args) > 2 || (len(os.Args) == 2 && os.Args[1] != "rewrap") {
+		fmt.Println("======================================")
+		fmt.Println("🔐 参与者手机号加密工具")
+		fmt.Println("======================================")
+		fmt.Println("")
+		fmt.Println("用法:")
+		fmt.Println("  go run encrypt_phones.go          加密尚未加密的手机号")
+		fmt.Println("  go run encrypt_phones.go rewrap   同时用当前密钥重新加密旧密钥加密的手机号")
+		fmt.Println("")
+		fmt.Println("说明:")
+		fmt.Println("  服务启动时的数据库迁移会自动加密已有手机号，本工具用于迁移失败后重试和密钥轮换")
+		fmt.Println("  轮换密钥：在 PHONE_KEYS 中添加新密钥并设置 PHONE_ACTIVE_KEY，重启服务后运行 rewrap，")
+		fmt.Println("  完成后即可从 PHONE_KEYS 中删除旧密钥")
+		fmt.Println("======================================")
+		return
+	}
+	rewrap := len(os.Args) == 2
+
+	config.LoadConfig()
+	config.InitDB()
+
+	ring := fieldcrypt.Default()
+	fmt.Printf("🔑 当前密钥: %s（密钥环: %s）\n", ring.ActiveKeyID(), strings.Join(ring.KeyIDs(), ","))
+
+	count, err := models.EncryptUserPhones(config.DB, 500, rewrap)
+	if err != nil {
+		fmt.Printf("❌ 加密失败（已处理 %d 个用户）: %v\n", count, err)
+		os.Exit(1)
+	}
+	fmt.Printf("✅ 已处理 %d 个用户的手机号\n", count)
+}
@@ -0,0 +1,115 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"time"
+
+	"lottery-system/config"
+	"lottery-system/models"
+	"lottery-system/storage"
+	"lottery-system/utils"
+)
+
+func main() {
+	if len(os.Args) < 2 {
+		fmt.Println("======================================")
+		fmt.Println("🪪 参与者胸牌生成工具")
+		fmt.Println("======================================")
+		fmt.Println("")
+		fmt.Println("用法:")
+		fmt.Println("  go run generate_badges.go <公司代码> [a4|label] [输出文件]")
+		fmt.Println("")
+		fmt.Println("示例:")
+		fmt.Println("  go run generate_badges.go acme")
+		fmt.Println("  go run generate_badges.go acme label acme_labels.pdf")
+		fmt.Println("")
+		fmt.Println("说明:")
+		fmt.Println("  a4: A4 纸每页 8 个胸牌（90×60mm，带裁切线）")
+		fmt.Println("  label: 标签打印机每页 1 个胸牌（4×3 英寸）")
+		fmt.Println("  胸牌二维码为个人签到码，需与服务端使用相同的 JWT_SECRET")
+		fmt.Println("======================================")
+		return
+	}
+
+	config.LoadConfig()
+	config.InitDB()
+
+	companyCode := os.Args[1]
+	layout := utils.BadgeLayoutA4
+	if len(os.Args) > 2 {
+		layout = os.Args[2]
+	}
+	if layout != utils.BadgeLayoutA4 && layout != utils.BadgeLayoutLabel {
+		fmt.Printf("❌ 无效的排版方式: %s（可选 a4 或 label）\n", layout)
+		os.Exit(1)
+	}
+
+	var company models.Company
+	if err := config.DB.Where("code = ?", companyCode).First(&company).Error; err != nil {
+		fmt.Printf("❌ 公司不存在: %s\n", companyCode)
+		os.Exit(1)
+	}
+
+	filename := fmt.Sprintf("badges_%s_%s.pdf", company.Code, time.Now().Format("20060102"))
+	if len(os.Args) > 3 {
+		filename = os.Args[3]
+	}
+
+	// 候补用户未获得参与资格，不生成胸牌
+	var users []models.User
+	config.DB.Where("company_id = ? AND waitlisted = ?", company.ID, false).Order("id ASC").Find(&users)
+	if len(users) == 0 {
+		fmt.Println("❌ 该公司没有参与者")
+		os.Exit(1)
+	}
+
+	key := utils.DeriveKey("checkin:" + config.AppConfig.JWTSecret)
+	badges := make([]utils.Badge, 0, len(users))
+	for _, user := range users {
+		badges = append(badges, utils.Badge{
+			Name:      user.Name,
+			QRContent: utils.SignCheckInToken(company.ID, user.ID, key),
+		})
+	}
+
+	opts := utils.BadgeSheetOptions{Layout: layout, Title: company.Name}
+
+	// 读取已上传的公司 Logo
+	if company.Logo != "" {
+		store, err := storage.New(storage.Options{
+			Driver:      config.AppConfig.StorageDriver,
+			LocalDir:    config.AppConfig.UploadDir,
+			S3Endpoint:  config.AppConfig.S3Endpoint,
+			S3Region:    config.AppConfig.S3Region,
+			S3Bucket:    config.AppConfig.S3Bucket,
+			S3AccessKey: config.AppConfig.S3AccessKey,
+			S3SecretKey: config.AppConfig.S3SecretKey,
+		})
+		if err == nil {
+			opts.Logo, err = utils.LoadStoredImage(context.Background(), store, company.Logo, "/api/uploads/", 5<<20)
+		}
+		if err != nil {
+			fmt.Printf("⚠️  读取公司Logo失败，胸牌将不包含Logo: %v\n", err)
+		}
+	}
+
+	pdf, err := utils.RenderBadgeSheet(badges, opts)
+	if err != nil {
+		fmt.Printf("❌ 生成胸牌失败: %v\n", err)
+		os.Exit(1)
+	}
+	if err := os.WriteFile(filename, pdf, 0644); err != nil {
+		fmt.Printf("❌ 保存文件失败: %v\n", err)
+		os.Exit(1)
+	}
+
+	fmt.Println("\n======================================")
+	fmt.Printf("✅ 胸牌生成成功！\n")
+	fmt.Printf("   公司: %s (%s)\n", company.Name, company.Code)
+	fmt.Printf("   人数: %d\n", len(badges))
+	fmt.Printf("   排版: %s\n", layout)
+	fmt.Printf("\n📁 文件保存: %s\n", filename)
+	fmt.Println("======================================")
+}
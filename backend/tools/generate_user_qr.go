@@ -1,90 +1,114 @@
 package main
 
 import (
-	"encoding/json"
 	"fmt"
 	"os"
+	"time"
+
+	"lottery-system/config"
+	"lottery-system/models"
+	"lottery-system/utils"
 
 	"github.com/skip2/go-qrcode"
 )
 
-type UserInfo struct {
-	Username string `json:"username"`
-	Name     string `json:"name"`
-	Phone    string `json:"phone"`
-}
-
 func main() {
-	if len(os.Args) < 3 {
+	if len(os.Args) < 4 {
 		fmt.Println("======================================")
 		fmt.Println("📋 用户二维码生成工具")
 		fmt.Println("======================================")
 		fmt.Println("")
 		fmt.Println("用法:")
-		fmt.Println("  go run generate_user_qr.go <用户名> <姓名> [手机号]")
+		fmt.Println("  go run generate_user_qr.go <公司代码> <用户名> <姓名> [手机号]")
 		fmt.Println("")
 		fmt.Println("示例:")
-		fmt.Println("  go run generate_user_qr.go zhangsan 张三")
-		fmt.Println("  go run generate_user_qr.go lisi 李四 13800138000")
+		fmt.Println("  go run generate_user_qr.go acme zhangsan 张三")
+		fmt.Println("  go run generate_user_qr.go acme lisi 李四 13800138000")
 		fmt.Println("")
+		fmt.Println("说明:")
+		fmt.Println("  二维码使用 USER_QR_KEYS 中的当前密钥签名（未配置时从 JWT_SECRET 派生），")
+		fmt.Println("  有效期由 USER_QR_TTL_HOURS 控制，需与服务端使用相同配置")
 		fmt.Println("======================================")
 		return
 	}
 
-	username := os.Args[1]
-	name := os.Args[2]
+	config.LoadConfig()
+	config.InitDB()
+
+	companyCode := os.Args[1]
+	username := os.Args[2]
+	name := os.Args[3]
 	phone := ""
-	if len(os.Args) > 3 {
-		phone = os.Args[3]
+	if len(os.Args) > 4 {
+		phone = os.Args[4]
 	}
 
-	// 创建用户信息
-	userInfo := UserInfo{
-		Username: username,
-		Name:     name,
-		Phone:    phone,
+	var company models.Company
+	if err := config.DB.Where("code = ?", companyCode).First(&company).Error; err != nil {
+		fmt.Printf("❌ 公司不存在: %s\n", companyCode)
+		os.Exit(1)
 	}
 
-	// 转换为JSON
-	jsonData, err := json.Marshal(userInfo)
-	if err != nil {
-		fmt.Printf("❌ JSON编码失败: %v\n", err)
-		return
+	if phone != "" {
+		if err := utils.ValidatePhone(phone); err != nil {
+			fmt.Printf("❌ 手机号格式错误: %v\n", err)
+			os.Exit(1)
+		}
 	}
 
-	// 生成二维码
-	qrCode, err := qrcode.Encode(string(jsonData), qrcode.Medium)
+	keyring, err := utils.ParseUserQRKeyring(
+		config.AppConfig.UserQRKeys,
+		config.AppConfig.UserQRActiveKey,
+		utils.DeriveKey("user-qr:"+config.AppConfig.JWTSecret),
+	)
 	if err != nil {
-		fmt.Printf("❌ 二维码生成失败: %v\n", err)
-		return
+		fmt.Printf("❌ 签名密钥配置错误: %v\n", err)
+		os.Exit(1)
 	}
 
-	// 打印二维码（ASCII版本）
-	qrCodeString := string(qrCode)
-	fmt.Println("\n" + qrCodeString)
+	ttl := config.AppConfig.UserQRTTLHours
+	if ttl <= 0 {
+		ttl = 72
+	}
+	now := time.Now()
+	expiresAt := now.Add(time.Duration(ttl) * time.Hour)
+
+	// 生成签名二维码内容
+	token, err := keyring.Sign(utils.UserQRClaims{
+		CompanyID: company.ID,
+		Username:  username,
+		Name:      name,
+		Phone:     phone,
+		IssuedAt:  now.Unix(),
+		ExpiresAt: expiresAt.Unix(),
+	})
+	if err != nil {
+		fmt.Printf("❌ 签名失败: %v\n", err)
+		os.Exit(1)
+	}
 
 	// 保存到文件
 	filename := fmt.Sprintf("%s_qrcode.png", username)
-	err = qrcode.WriteFile(filename, qrcode.Medium, []byte(string(jsonData)))
-	if err != nil {
+	if err := qrcode.WriteFile(token, qrcode.Medium, 256, filename); err != nil {
 		fmt.Printf("❌ 保存二维码失败: %v\n", err)
-		return
+		os.Exit(1)
 	}
 
 	fmt.Println("\n======================================")
 	fmt.Printf("✅ 二维码生成成功！\n")
 	fmt.Printf("📝 用户信息:\n")
+	fmt.Printf("   公司: %s (%s)\n", company.Name, company.Code)
 	fmt.Printf("   用户名: %s\n", username)
 	fmt.Printf("   姓名: %s\n", name)
 	if phone != "" {
 		fmt.Printf("   手机: %s\n", phone)
 	}
+	fmt.Printf("   签名密钥: %s\n", keyring.ActiveKeyID())
+	fmt.Printf("   有效期至: %s\n", expiresAt.Format("2006-01-02 15:04"))
 	fmt.Printf("\n📁 文件保存: %s\n", filename)
-	fmt.Printf("\n📱 二维码内容(JSON):\n")
-	fmt.Printf("   %s\n", string(jsonData))
 	fmt.Println("\n💡 使用方法:")
 	fmt.Println("   1. 将二维码图片发送给用户")
 	fmt.Println("   2. 在抽奖页面点击'扫码添加用户'")
-	fmt.Println("   3. 扫描二维码即可添加用户到抽奖池")
+	fmt.Println("   3. 扫描二维码即可添加用户到抽奖池（过期后需重新生成）")
 	fmt.Println("======================================")
 }
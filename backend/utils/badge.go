@@ -0,0 +1,212 @@
+package utils
+
+import (
+	"context"
+	"errors"
+	"image"
+	"io"
+	"strings"
+
+	"lottery-system/storage"
+
+	"github.com/skip2/go-qrcode"
+)
+
+// 胸牌排版方式
+const (
+	BadgeLayoutA4    = "a4"    // A4 纸网格排版（2 列 × 4 行，带裁切线）
+	BadgeLayoutLabel = "label" // 标签打印机（每页一个胸牌，页面尺寸等于标签尺寸）
+)
+
+// 胸牌尺寸（毫米）
+const (
+	badgeA4Width      = 90.0
+	badgeA4Height     = 60.0
+	badgeA4Columns    = 2
+	badgeA4Rows       = 4
+	badgeA4Gap        = 5.0
+	badgeLabelWidth   = 101.6 // 4 英寸
+	badgeLabelHeight  = 76.2  // 3 英寸
+	badgePadding      = 4.0
+	badgeHeaderHeight = 11.0
+	badgeLogoMaxPx    = 400 // 嵌入 PDF 前将 Logo 缩小到该尺寸以内
+)
+
+// Badge 单个胸牌内容
+type Badge struct {
+	Name      string // 姓名（大号字体）
+	Subtitle  string // 副标题（如部门、桌号）
+	QRContent string // 个人二维码内容
+}
+
+// BadgeSheetOptions 胸牌文档选项
+type BadgeSheetOptions struct {
+	Layout        string      // a4, label
+	Title         string      // 页眉文字（公司名称）
+	Logo          image.Image // 公司 Logo，可为空
+	LabelWidthMM  float64     // 标签宽度（毫米），为 0 使用默认 4×3 英寸
+	LabelHeightMM float64     // 标签高度（毫米）
+}
+
+// ErrInvalidBadgeLayout 不支持的排版方式或标签尺寸
+var ErrInvalidBadgeLayout = errors.New("invalid badge layout")
+
+// RenderBadgeSheet 将胸牌批量排版为分页 PDF
+func RenderBadgeSheet(badges []Badge, opts BadgeSheetOptions) ([]byte, error) {
+	var doc *PDFDocument
+	var badgeW, badgeH float64
+	perPage := 1
+
+	switch opts.Layout {
+	case BadgeLayoutA4, "":
+		doc = NewPDFDocument(A4Width, A4Height)
+		badgeW, badgeH = badgeA4Width*MMToPt, badgeA4Height*MMToPt
+		perPage = badgeA4Columns * badgeA4Rows
+	case BadgeLayoutLabel:
+		w, h := opts.LabelWidthMM, opts.LabelHeightMM
+		if w == 0 && h == 0 {
+			w, h = badgeLabelWidth, badgeLabelHeight
+		}
+		if w < 50 || w > 210 || h < 40 || h > 297 {
+			return nil, ErrInvalidBadgeLayout
+		}
+		badgeW, badgeH = w*MMToPt, h*MMToPt
+		doc = NewPDFDocument(badgeW, badgeH)
+	default:
+		return nil, ErrInvalidBadgeLayout
+	}
+
+	var logo *PDFImage
+	if opts.Logo != nil {
+		logo = doc.AddImage(shrinkImage(opts.Logo, badgeLogoMaxPx))
+	}
+
+	// A4 网格在页面内居中
+	gap := badgeA4Gap * MMToPt
+	gridW := float64(badgeA4Columns)*badgeW + float64(badgeA4Columns-1)*gap
+	gridH := float64(badgeA4Rows)*badgeH + float64(badgeA4Rows-1)*gap
+	originX := (A4Width - gridW) / 2
+	originY := (A4Height - gridH) / 2
+
+	var page *PDFPage
+	for i, badge := range badges {
+		slot := i % perPage
+		if slot == 0 {
+			page = doc.AddPage()
+		}
+
+		x, y := 0.0, 0.0
+		if opts.Layout != BadgeLayoutLabel {
+			col := slot % badgeA4Columns
+			row := slot / badgeA4Columns
+			x = originX + float64(col)*(badgeW+gap)
+			y = originY + float64(row)*(badgeH+gap)
+			page.Rect(x, y, badgeW, badgeH, 0.3, 180, true)
+		}
+
+		if err := drawBadge(doc, page, badge, opts.Title, logo, x, y, badgeW, badgeH); err != nil {
+			return nil, err
+		}
+	}
+
+	if doc.PageCount() == 0 {
+		doc.AddPage()
+	}
+	return doc.Bytes()
+}
+
+// drawBadge 在 (x, y) 处绘制单个胸牌：页眉（Logo + 公司名称）、左侧姓名和副标题、右侧个人二维码
+func drawBadge(doc *PDFDocument, page *PDFPage, badge Badge, title string, logo *PDFImage, x, y, w, h float64) error {
+	pad := badgePadding * MMToPt
+	headerH := badgeHeaderHeight * MMToPt
+
+	// 页眉
+	textX := x + pad
+	if logo != nil {
+		lw, lh := logo.Size()
+		drawH := headerH - 2
+		drawW := drawH * float64(lw) / float64(lh)
+		if maxW := w * 0.35; drawW > maxW {
+			drawW = maxW
+			drawH = drawW * float64(lh) / float64(lw)
+		}
+		page.Image(logo, x+pad, y+pad+(headerH-drawH)/2, drawW, drawH)
+		textX += drawW + 2*MMToPt
+	}
+	if title != "" {
+		text, size := FitText(title, x+w-pad-textX, 10, 6)
+		page.SetFillColor(80, 80, 80)
+		page.Text(textX, y+pad+headerH/2+size*0.35, size, text)
+	}
+	page.FillRect(x+pad, y+pad+headerH, w-2*pad, 0.8, 200, 200, 200)
+
+	// 右侧二维码
+	bodyY := y + pad + headerH + pad
+	qrSize := y + h - pad - bodyY
+	if badge.QRContent != "" {
+		qr, err := qrcode.New(badge.QRContent, qrcode.Medium)
+		if err != nil {
+			return err
+		}
+		page.Image(doc.AddBitmap(qr.Bitmap()), x+w-pad-qrSize, bodyY, qrSize, qrSize)
+	} else {
+		qrSize = 0
+	}
+
+	// 左侧姓名和副标题
+	textW := w - 3*pad - qrSize
+	page.SetFillColor(0, 0, 0)
+	name, nameSize := FitText(badge.Name, textW, 26, 12)
+	nameY := bodyY + qrSize/2
+	if qrSize == 0 {
+		nameY = bodyY + (y+h-pad-bodyY)/2
+	}
+	page.Text(x+pad, nameY, nameSize, name)
+
+	if badge.Subtitle != "" {
+		subtitle, subSize := FitText(badge.Subtitle, textW, 11, 7)
+		page.SetFillColor(90, 90, 90)
+		page.Text(x+pad, nameY+subSize+6, subSize, subtitle)
+	}
+
+	return nil
+}
+
+// shrinkImage 等比缩小图片使最长边不超过 maxSide
+func shrinkImage(img image.Image, maxSide int) image.Image {
+	bounds := img.Bounds()
+	w, h := bounds.Dx(), bounds.Dy()
+	if w <= maxSide && h <= maxSide {
+		return img
+	}
+	if w >= h {
+		h = max1(h * maxSide / w)
+		w = maxSide
+	} else {
+		w = max1(w * maxSide / h)
+		h = maxSide
+	}
+	return boxResize(img, w, h)
+}
+
+// LoadStoredImage 读取已上传到存储中的图片（如公司 Logo），url 必须以 prefix 开头，不访问外部地址
+func LoadStoredImage(ctx context.Context, store storage.Storage, url, prefix string, maxBytes int64) (image.Image, error) {
+	if store == nil || !strings.HasPrefix(url, prefix) {
+		return nil, storage.ErrNotFound
+	}
+
+	reader, _, err := store.Get(ctx, strings.TrimPrefix(url, prefix))
+	if err != nil {
+		return nil, err
+	}
+	defer reader.Close()
+
+	data, err := io.ReadAll(io.LimitReader(reader, maxBytes+1))
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(data)) > maxBytes {
+		return nil, ErrImageTooLarge
+	}
+	return DecodeImage(data)
+}
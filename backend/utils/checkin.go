@@ -0,0 +1,84 @@
+package utils
+
+import (
+	"crypto/hmac"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// 签到凭证前缀（带版本号）
+const (
+	checkInTokenPrefix = "CI1" // 个人签到码
+	venueTokenPrefix   = "CV1" // 现场轮换签到码
+)
+
+// VenueCheckInWindow 现场签到码轮换周期
+const VenueCheckInWindow = 30 * time.Second
+
+// ErrInvalidCheckInToken 签到凭证格式错误、签名无效或已过期
+var ErrInvalidCheckInToken = errors.New("invalid check-in token")
+
+// SignCheckInToken 生成个人签到码：CI1.<公司ID>.<用户ID>.<HMAC签名>
+func SignCheckInToken(companyID, userID int, key []byte) string {
+	payload := fmt.Sprintf("%s.%d.%d", checkInTokenPrefix, companyID, userID)
+	return payload + "." + tokenSignature(payload, key)
+}
+
+// VerifyCheckInToken 校验个人签到码，返回公司ID和用户ID
+func VerifyCheckInToken(token string, key []byte) (int, int, error) {
+	ids, err := verifySignedIDs(token, checkInTokenPrefix, key)
+	if err != nil {
+		return 0, 0, err
+	}
+	return ids[0], ids[1], nil
+}
+
+// SignVenueCheckInToken 生成当前周期的现场签到码：CV1.<公司ID>.<周期序号>.<HMAC签名>
+// 返回签到码及其失效时间
+func SignVenueCheckInToken(companyID int, now time.Time, key []byte) (string, time.Time) {
+	window := now.Unix() / int64(VenueCheckInWindow/time.Second)
+	payload := fmt.Sprintf("%s.%d.%d", venueTokenPrefix, companyID, window)
+	expiresAt := time.Unix((window+1)*int64(VenueCheckInWindow/time.Second), 0)
+	return payload + "." + tokenSignature(payload, key), expiresAt
+}
+
+// VerifyVenueCheckInToken 校验现场签到码，返回公司ID
+// 接受当前周期和上一周期的签到码，容忍扫码到提交之间的延迟
+func VerifyVenueCheckInToken(token string, now time.Time, key []byte) (int, error) {
+	ids, err := verifySignedIDs(token, venueTokenPrefix, key)
+	if err != nil {
+		return 0, err
+	}
+
+	current := int(now.Unix() / int64(VenueCheckInWindow/time.Second))
+	if ids[1] != current && ids[1] != current-1 {
+		return 0, ErrInvalidCheckInToken
+	}
+	return ids[0], nil
+}
+
+// verifySignedIDs 校验 <前缀>.<ID>.<ID>.<签名> 格式的凭证，返回两个ID
+func verifySignedIDs(token, prefix string, key []byte) ([2]int, error) {
+	var ids [2]int
+	parts := strings.Split(strings.TrimSpace(token), ".")
+	if len(parts) != 4 || parts[0] != prefix {
+		return ids, ErrInvalidCheckInToken
+	}
+
+	payload := strings.Join(parts[:3], ".")
+	if !hmac.Equal([]byte(parts[3]), []byte(tokenSignature(payload, key))) {
+		return ids, ErrInvalidCheckInToken
+	}
+
+	for i := 0; i < 2; i++ {
+		n, err := strconv.Atoi(parts[i+1])
+		if err != nil || n <= 0 {
+			return ids, ErrInvalidCheckInToken
+		}
+		ids[i] = n
+	}
+	return ids, nil
+}
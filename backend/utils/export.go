@@ -0,0 +1,340 @@
+package utils
+
+import (
+	"archive/zip"
+	"bufio"
+	"encoding/csv"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// 导出文件格式
+const (
+	ExportFormatCSV  = "csv"
+	ExportFormatXLSX = "xlsx"
+	ExportFormatPDF  = "pdf"
+)
+
+// ErrInvalidExportFormat 不支持的导出格式
+var ErrInvalidExportFormat = errors.New("导出格式只能是 csv、xlsx 或 pdf")
+
+// ExportContentType 返回导出格式对应的 Content-Type
+func ExportContentType(format string) string {
+	switch format {
+	case ExportFormatXLSX:
+		return "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"
+	case ExportFormatPDF:
+		return "application/pdf"
+	default:
+		return "text/csv; charset=utf-8"
+	}
+}
+
+// TableWriter 逐行写出表格，Close 时写入文件结尾（不关闭底层 io.Writer）
+type TableWriter interface {
+	WriteRow(cells []string) error
+	Close() error
+}
+
+// NewTableWriter 创建指定格式的表格写出器并写入表头
+// CSV 和 XLSX 边查询边写出；PDF 需要分页排版，在 Close 时一次性生成
+func NewTableWriter(format string, w io.Writer, title string, headers []string) (TableWriter, error) {
+	var tw TableWriter
+	switch format {
+	case ExportFormatCSV:
+		tw = newCSVTableWriter(w)
+	case ExportFormatXLSX:
+		xw, err := newXLSXTableWriter(w, headers)
+		if err != nil {
+			return nil, err
+		}
+		tw = xw
+	case ExportFormatPDF:
+		return &pdfTableWriter{w: w, title: title, headers: headers}, nil
+	default:
+		return nil, ErrInvalidExportFormat
+	}
+	if err := tw.WriteRow(headers); err != nil {
+		return nil, err
+	}
+	return tw, nil
+}
+
+// csvTableWriter CSV 写出（带 UTF-8 BOM，Excel 可直接打开中文）
+type csvTableWriter struct {
+	w *csv.Writer
+}
+
+func newCSVTableWriter(w io.Writer) *csvTableWriter {
+	io.WriteString(w, "\xef\xbb\xbf")
+	return &csvTableWriter{w: csv.NewWriter(w)}
+}
+
+func (t *csvTableWriter) WriteRow(cells []string) error {
+	escaped := make([]string, len(cells))
+	for i, cell := range cells {
+		// 防止以 = + - @ 开头的内容在 Excel 中被当作公式执行
+		if cell != "" && strings.ContainsRune("=+-@\t\r", rune(cell[0])) {
+			cell = "'" + cell
+		}
+		escaped[i] = cell
+	}
+	return t.w.Write(escaped)
+}
+
+func (t *csvTableWriter) Close() error {
+	t.w.Flush()
+	return t.w.Error()
+}
+
+// xlsxTableWriter XLSX 写出（单个工作表，单元格使用内联字符串，无需共享字符串表即可流式写出）
+type xlsxTableWriter struct {
+	zw    *zip.Writer
+	sheet *bufio.Writer
+	rows  int
+}
+
+func newXLSXTableWriter(w io.Writer, headers []string) (*xlsxTableWriter, error) {
+	zw := zip.NewWriter(w)
+	parts := []struct{ name, body string }{
+		{"[Content_Types].xml", `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Types xmlns="http://schemas.openxmlformats.org/package/2006/content-types">` +
+			`<Default Extension="rels" ContentType="application/vnd.openxmlformats-package.relationships+xml"/>` +
+			`<Default Extension="xml" ContentType="application/xml"/>` +
+			`<Override PartName="/xl/workbook.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.sheet.main+xml"/>` +
+			`<Override PartName="/xl/worksheets/sheet1.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.worksheet+xml"/>` +
+			`<Override PartName="/xl/styles.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.styles+xml"/>` +
+			`</Types>`},
+		{"_rels/.rels", `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">` +
+			`<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/officeDocument" Target="xl/workbook.xml"/>` +
+			`</Relationships>`},
+		{"xl/workbook.xml", `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<workbook xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships">` +
+			`<sheets><sheet name="Sheet1" sheetId="1" r:id="rId1"/></sheets></workbook>`},
+		{"xl/_rels/workbook.xml.rels", `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">` +
+			`<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/worksheet" Target="worksheets/sheet1.xml"/>` +
+			`<Relationship Id="rId2" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/styles" Target="styles.xml"/>` +
+			`</Relationships>`},
+		// 样式 0 为默认，样式 1 为加粗（表头）
+		{"xl/styles.xml", `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<styleSheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main">` +
+			`<fonts count="2"><font><sz val="11"/><name val="Calibri"/></font><font><b/><sz val="11"/><name val="Calibri"/></font></fonts>` +
+			`<fills count="2"><fill><patternFill patternType="none"/></fill><fill><patternFill patternType="gray125"/></fill></fills>` +
+			`<borders count="1"><border><left/><right/><top/><bottom/><diagonal/></border></borders>` +
+			`<cellStyleXfs count="1"><xf numFmtId="0" fontId="0" fillId="0" borderId="0"/></cellStyleXfs>` +
+			`<cellXfs count="2"><xf numFmtId="0" fontId="0" fillId="0" borderId="0" xfId="0"/><xf numFmtId="0" fontId="1" fillId="0" borderId="0" xfId="0" applyFont="1"/></cellXfs>` +
+			`</styleSheet>`},
+	}
+	for _, part := range parts {
+		f, err := zw.Create(part.name)
+		if err != nil {
+			return nil, err
+		}
+		if _, err := io.WriteString(f, part.body); err != nil {
+			return nil, err
+		}
+	}
+
+	// 工作表必须最后写入（zip 同时只能写一个文件），表头冻结，列宽按表头估算
+	f, err := zw.Create("xl/worksheets/sheet1.xml")
+	if err != nil {
+		return nil, err
+	}
+	sheet := bufio.NewWriter(f)
+	sheet.WriteString(`<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main">`)
+	sheet.WriteString(`<sheetViews><sheetView workbookViewId="0"><pane ySplit="1" topLeftCell="A2" activePane="bottomLeft" state="frozen"/></sheetView></sheetViews>`)
+	if len(headers) > 0 {
+		sheet.WriteString("<cols>")
+		for i, header := range headers {
+			width := TextWidth(header, 2) + 4
+			if width < 12 {
+				width = 12
+			}
+			fmt.Fprintf(sheet, `<col min="%d" max="%d" width="%.0f" customWidth="1"/>`, i+1, i+1, width)
+		}
+		sheet.WriteString("</cols>")
+	}
+	sheet.WriteString("<sheetData>")
+
+	return &xlsxTableWriter{zw: zw, sheet: sheet}, nil
+}
+
+func (t *xlsxTableWriter) WriteRow(cells []string) error {
+	t.rows++
+	style := ""
+	if t.rows == 1 {
+		style = ` s="1"`
+	}
+	fmt.Fprintf(t.sheet, `<row r="%d">`, t.rows)
+	for i, cell := range cells {
+		if cell == "" {
+			continue
+		}
+		fmt.Fprintf(t.sheet, `<c r="%s%d" t="inlineStr"%s><is><t xml:space="preserve">`, xlsxColumnName(i), t.rows, style)
+		if err := xml.EscapeText(t.sheet, []byte(stripInvalidXMLChars(cell))); err != nil {
+			return err
+		}
+		t.sheet.WriteString(`</t></is></c>`)
+	}
+	_, err := t.sheet.WriteString("</row>")
+	return err
+}
+
+func (t *xlsxTableWriter) Close() error {
+	t.sheet.WriteString("</sheetData></worksheet>")
+	if err := t.sheet.Flush(); err != nil {
+		return err
+	}
+	return t.zw.Close()
+}
+
+// xlsxColumnName 将从 0 开始的列号转换为列字母（0 → A，26 → AA）
+func xlsxColumnName(col int) string {
+	name := ""
+	for col >= 0 {
+		name = string(rune('A'+col%26)) + name
+		col = col/26 - 1
+	}
+	return name
+}
+
+// stripInvalidXMLChars 去除 XML 1.0 不允许的控制字符
+func stripInvalidXMLChars(s string) string {
+	return strings.Map(func(r rune) rune {
+		if r == '\t' || r == '\n' || r == '\r' || (r >= 0x20 && r != utf8.RuneError) {
+			return r
+		}
+		return -1
+	}, s)
+}
+
+// PDF 表格排版参数（pt）
+const (
+	pdfTableMargin      = 36
+	pdfTableFontSize    = 9
+	pdfTableRowHeight   = 18
+	pdfTableTitleSize   = 16
+	pdfTableMinColumn   = 36
+	pdfTableMaxColumn   = 220
+	pdfTableCellPadding = 4
+)
+
+// pdfTableWriter PDF 表格写出：缓存所有行，Close 时按列内容分配列宽并分页（每页重复表头，页脚显示页码）
+type pdfTableWriter struct {
+	w       io.Writer
+	title   string
+	headers []string
+	rows    [][]string
+}
+
+func (t *pdfTableWriter) WriteRow(cells []string) error {
+	t.rows = append(t.rows, append([]string(nil), cells...))
+	return nil
+}
+
+func (t *pdfTableWriter) Close() error {
+	// 列数较多时使用横向 A4
+	width, height := A4Width, A4Height
+	if len(t.headers) > 6 {
+		width, height = A4Height, A4Width
+	}
+	doc := NewPDFDocument(width, height)
+	widths := t.columnWidths(width - 2*pdfTableMargin)
+
+	tableTop := float64(pdfTableMargin + pdfTableTitleSize + 20)
+	perPage := int((height-tableTop-pdfTableMargin-20)/pdfTableRowHeight) - 1
+	pages := (len(t.rows) + perPage - 1) / perPage
+	if pages == 0 {
+		pages = 1
+	}
+
+	generated := "导出时间: " + time.Now().Format("2006-01-02 15:04")
+	for p := 0; p < pages; p++ {
+		page := doc.AddPage()
+		page.Text(pdfTableMargin, pdfTableMargin+pdfTableTitleSize, pdfTableTitleSize, t.title)
+		page.Text(width-pdfTableMargin-TextWidth(generated, 8), pdfTableMargin+pdfTableTitleSize, 8, generated)
+
+		y := tableTop
+		page.FillRect(pdfTableMargin, y, width-2*pdfTableMargin, pdfTableRowHeight, 230, 230, 230)
+		t.drawRow(page, t.headers, widths, y)
+
+		end := (p + 1) * perPage
+		if end > len(t.rows) {
+			end = len(t.rows)
+		}
+		for i, row := range t.rows[p*perPage : end] {
+			y += pdfTableRowHeight
+			if i%2 == 1 {
+				page.FillRect(pdfTableMargin, y, width-2*pdfTableMargin, pdfTableRowHeight, 246, 246, 246)
+			}
+			t.drawRow(page, row, widths, y)
+		}
+		page.Rect(pdfTableMargin, tableTop, width-2*pdfTableMargin, y+pdfTableRowHeight-tableTop, 0.5, 160, false)
+
+		footer := fmt.Sprintf("共 %d 条　第 %d / %d 页", len(t.rows), p+1, pages)
+		page.Text((width-TextWidth(footer, 8))/2, height-pdfTableMargin/2, 8, footer)
+	}
+
+	data, err := doc.Bytes()
+	if err != nil {
+		return err
+	}
+	_, err = t.w.Write(data)
+	return err
+}
+
+// drawRow 绘制一行，超出列宽的内容缩小字号或截断
+func (t *pdfTableWriter) drawRow(page *PDFPage, cells []string, widths []float64, y float64) {
+	x := float64(pdfTableMargin)
+	for i, w := range widths {
+		if i < len(cells) && cells[i] != "" {
+			text, size := FitText(cells[i], w-2*pdfTableCellPadding, pdfTableFontSize, 7)
+			page.Text(x+pdfTableCellPadding, y+pdfTableRowHeight/2+size/2-1, size, text)
+		}
+		x += w
+	}
+}
+
+// columnWidths 按各列最长内容的比例分配列宽（每列不小于最小宽度）
+func (t *pdfTableWriter) columnWidths(total float64) []float64 {
+	n := len(t.headers)
+	if n == 0 {
+		return nil
+	}
+	natural := make([]float64, n)
+	for i, header := range t.headers {
+		natural[i] = TextWidth(header, pdfTableFontSize)
+	}
+	for _, row := range t.rows {
+		for i := 0; i < n && i < len(row); i++ {
+			if w := TextWidth(row[i], pdfTableFontSize); w > natural[i] {
+				natural[i] = w
+			}
+		}
+	}
+
+	sum := 0.0
+	for i := range natural {
+		natural[i] += 2 * pdfTableCellPadding
+		if natural[i] < pdfTableMinColumn {
+			natural[i] = pdfTableMinColumn
+		}
+		if natural[i] > pdfTableMaxColumn {
+			natural[i] = pdfTableMaxColumn
+		}
+		sum += natural[i]
+	}
+	widths := make([]float64, n)
+	for i := range natural {
+		widths[i] = natural[i] * total / sum
+	}
+	return widths
+}
@@ -0,0 +1,223 @@
+package utils
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"lottery-system/models"
+
+	"gorm.io/gorm"
+)
+
+// 报名表单字段限制
+const (
+	FieldValueMaxLength = 255 // 字段值最大长度（与 user_field_values.value 列一致）
+	MaxFieldFilters     = 10  // 单次查询最多的字段筛选条件
+)
+
+var (
+	fieldKeyRegex = regexp.MustCompile(`^[a-z][a-z0-9_]{0,49}$`)
+	emailRegex    = regexp.MustCompile(`^[^@\s]+@[^@\s]+\.[^@\s]+$`)
+)
+
+// ValidateFieldDefinition 校验报名表单字段定义
+func ValidateFieldDefinition(field *models.RegistrationField) error {
+	if !fieldKeyRegex.MatchString(field.Key) {
+		return errors.New("字段标识只能包含小写字母、数字和下划线，且以字母开头")
+	}
+	field.Label = strings.TrimSpace(field.Label)
+	if field.Label == "" || utf8.RuneCountInString(field.Label) > 100 {
+		return errors.New("字段名称长度应为1-100个字符")
+	}
+	if !models.FieldTypeIsValid(field.Type) {
+		return errors.New("无效的字段类型")
+	}
+	if field.MaxLength <= 0 || field.MaxLength > FieldValueMaxLength {
+		field.MaxLength = 100
+	}
+
+	if field.Pattern != "" {
+		if field.Type != models.FieldTypeText {
+			return errors.New("只有文本字段可以设置正则校验")
+		}
+		if _, err := regexp.Compile(field.Pattern); err != nil {
+			return errors.New("正则表达式格式错误")
+		}
+	}
+
+	if field.Type == models.FieldTypeSelect {
+		if len(field.Options) == 0 {
+			return errors.New("单选字段至少需要一个选项")
+		}
+		seen := make(map[string]bool)
+		for i, option := range field.Options {
+			option = strings.TrimSpace(option)
+			if option == "" || utf8.RuneCountInString(option) > FieldValueMaxLength {
+				return errors.New("选项不能为空且不能超过255个字符")
+			}
+			if seen[option] {
+				return fmt.Errorf("选项重复: %s", option)
+			}
+			seen[option] = true
+			field.Options[i] = option
+		}
+	} else {
+		field.Options = nil
+	}
+
+	return nil
+}
+
+// ValidateFormAnswers 按公司表单定义校验参与者填写的字段值，返回规范化后的答案
+// 只保留启用字段的答案，未定义的字段忽略
+func ValidateFormAnswers(fields []models.RegistrationField, input map[string]string) (map[string]string, error) {
+	answers := make(map[string]string)
+	for _, field := range fields {
+		if !field.IsActive {
+			continue
+		}
+
+		value := strings.TrimSpace(input[field.Key])
+		if value == "" {
+			if field.Required {
+				return nil, fmt.Errorf("%s不能为空", field.Label)
+			}
+			continue
+		}
+
+		if utf8.RuneCountInString(value) > field.MaxLength {
+			return nil, fmt.Errorf("%s不能超过%d个字符", field.Label, field.MaxLength)
+		}
+
+		switch field.Type {
+		case models.FieldTypeNumber:
+			n, err := strconv.ParseFloat(value, 64)
+			if err != nil {
+				return nil, fmt.Errorf("%s必须是数字", field.Label)
+			}
+			value = strconv.FormatFloat(n, 'f', -1, 64)
+		case models.FieldTypeSelect:
+			valid := false
+			for _, option := range field.Options {
+				if option == value {
+					valid = true
+					break
+				}
+			}
+			if !valid {
+				return nil, fmt.Errorf("%s的选项无效", field.Label)
+			}
+		case models.FieldTypeEmail:
+			if !emailRegex.MatchString(value) {
+				return nil, fmt.Errorf("%s格式错误", field.Label)
+			}
+		case models.FieldTypeDate:
+			if _, err := time.Parse("2006-01-02", value); err != nil {
+				return nil, fmt.Errorf("%s应为 YYYY-MM-DD 格式的日期", field.Label)
+			}
+		default:
+			if field.Pattern != "" {
+				re, err := regexp.Compile(field.Pattern)
+				if err != nil || !re.MatchString(value) {
+					return nil, fmt.Errorf("%s格式错误", field.Label)
+				}
+			}
+		}
+
+		answers[field.Key] = value
+	}
+	return answers, nil
+}
+
+// SaveUserFieldValues 在事务中保存参与者的表单字段值（覆盖已有的值）
+func SaveUserFieldValues(tx *gorm.DB, companyID, userID int, answers map[string]string) error {
+	if err := tx.Where("user_id = ?", userID).Delete(&models.UserFieldValue{}).Error; err != nil {
+		return err
+	}
+	if len(answers) == 0 {
+		return nil
+	}
+
+	values := make([]models.UserFieldValue, 0, len(answers))
+	for key, value := range answers {
+		values = append(values, models.UserFieldValue{
+			CompanyID: companyID,
+			UserID:    userID,
+			FieldKey:  key,
+			Value:     value,
+		})
+	}
+	return tx.Create(&values).Error
+}
+
+// AttachUserFields 为用户列表加载表单字段值
+func AttachUserFields(db *gorm.DB, users []models.User) {
+	if len(users) == 0 {
+		return
+	}
+
+	ids := make([]int, len(users))
+	index := make(map[int]int, len(users))
+	for i, user := range users {
+		ids[i] = user.ID
+		index[user.ID] = i
+	}
+
+	// 分批查询，避免 IN 参数过多
+	for start := 0; start < len(ids); start += 1000 {
+		end := start + 1000
+		if end > len(ids) {
+			end = len(ids)
+		}
+
+		var values []models.UserFieldValue
+		db.Where("user_id IN ?", ids[start:end]).Find(&values)
+		for _, v := range values {
+			user := &users[index[v.UserID]]
+			if user.Fields == nil {
+				user.Fields = make(map[string]string)
+			}
+			user.Fields[v.FieldKey] = v.Value
+		}
+	}
+}
+
+// ValidateFieldFilters 校验字段筛选条件
+func ValidateFieldFilters(filters map[string]string) error {
+	if len(filters) > MaxFieldFilters {
+		return fmt.Errorf("最多支持%d个字段筛选条件", MaxFieldFilters)
+	}
+	for key := range filters {
+		if !fieldKeyRegex.MatchString(key) {
+			return fmt.Errorf("无效的筛选字段: %s", key)
+		}
+	}
+	return nil
+}
+
+// ApplyFieldFilters 按表单字段值筛选用户（精确匹配，多个条件为且关系），调用前需先校验筛选条件
+func ApplyFieldFilters(db *gorm.DB, query *gorm.DB, companyID int, filters map[string]string) *gorm.DB {
+	for key, value := range filters {
+		sub := db.Model(&models.UserFieldValue{}).
+			Select("user_id").
+			Where("company_id = ? AND field_key = ? AND value = ?", companyID, key, strings.TrimSpace(value))
+		query = query.Where("id IN (?)", sub)
+	}
+	return query
+}
+
+// FieldFiltersFromQuery 从查询参数中提取字段筛选条件（field.<key>=<value>）
+func FieldFiltersFromQuery(params map[string][]string) map[string]string {
+	filters := make(map[string]string)
+	for name, values := range params {
+		if key := strings.TrimPrefix(name, "field."); key != name && len(values) > 0 {
+			filters[key] = values[0]
+		}
+	}
+	return filters
+}
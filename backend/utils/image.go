@@ -0,0 +1,154 @@
+package utils
+
+import (
+	"bytes"
+	"errors"
+	"image"
+	"image/color"
+	_ "image/gif" // 注册 GIF 解码器
+	"image/jpeg"
+	"image/png"
+	"net/http"
+)
+
+// 图片处理限制
+const (
+	MaxImagePixels = 40000000 // 解码前检查像素总数，防止解压炸弹
+	ImageMaxSide   = 1600     // 原图最长边（超过则缩小）
+	ThumbnailSide  = 320      // 缩略图最长边
+)
+
+// ErrUnsupportedImage 不支持的图片格式
+var ErrUnsupportedImage = errors.New("unsupported image type")
+
+// ErrImageTooLarge 图片像素数过大
+var ErrImageTooLarge = errors.New("image dimensions too large")
+
+// allowedImageTypes 允许上传的图片类型（按文件内容识别）
+var allowedImageTypes = map[string]bool{
+	"image/png":  true,
+	"image/jpeg": true,
+	"image/gif":  true,
+}
+
+// ProcessedImage 处理后的图片
+type ProcessedImage struct {
+	Data        []byte
+	ContentType string
+	Ext         string
+	Width       int
+	Height      int
+}
+
+// SniffImageType 根据文件内容识别图片类型，不信任客户端提供的 Content-Type
+func SniffImageType(data []byte) (string, error) {
+	contentType := http.DetectContentType(data)
+	if !allowedImageTypes[contentType] {
+		return "", ErrUnsupportedImage
+	}
+	return contentType, nil
+}
+
+// DecodeImage 解码图片（先检查尺寸，避免超大图片耗尽内存）
+func DecodeImage(data []byte) (image.Image, error) {
+	cfg, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, ErrUnsupportedImage
+	}
+	if cfg.Width <= 0 || cfg.Height <= 0 || cfg.Width*cfg.Height > MaxImagePixels {
+		return nil, ErrImageTooLarge
+	}
+
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, ErrUnsupportedImage
+	}
+	return img, nil
+}
+
+// ResizeImage 等比缩小图片使最长边不超过 maxSide，并重新编码
+// 不透明图片编码为 JPEG，带透明通道的编码为 PNG（GIF 只保留第一帧）
+func ResizeImage(img image.Image, maxSide int) (*ProcessedImage, error) {
+	bounds := img.Bounds()
+	w, h := bounds.Dx(), bounds.Dy()
+	if w > maxSide || h > maxSide {
+		if w >= h {
+			h = max1(h * maxSide / w)
+			w = maxSide
+		} else {
+			w = max1(w * maxSide / h)
+			h = maxSide
+		}
+		img = boxResize(img, w, h)
+	}
+
+	var buf bytes.Buffer
+	if imageHasAlpha(img) {
+		if err := png.Encode(&buf, img); err != nil {
+			return nil, err
+		}
+		return &ProcessedImage{Data: buf.Bytes(), ContentType: "image/png", Ext: ".png", Width: w, Height: h}, nil
+	}
+
+	if err := jpeg.Encode(&buf, img, &jpeg.Options{Quality: 85}); err != nil {
+		return nil, err
+	}
+	return &ProcessedImage{Data: buf.Bytes(), ContentType: "image/jpeg", Ext: ".jpg", Width: w, Height: h}, nil
+}
+
+// boxResize 区域平均法缩小图片（仅用于缩小）
+func boxResize(src image.Image, w, h int) *image.NRGBA {
+	bounds := src.Bounds()
+	sw, sh := bounds.Dx(), bounds.Dy()
+	dst := image.NewNRGBA(image.Rect(0, 0, w, h))
+
+	for y := 0; y < h; y++ {
+		y0 := bounds.Min.Y + y*sh/h
+		y1 := bounds.Min.Y + (y+1)*sh/h
+		if y1 <= y0 {
+			y1 = y0 + 1
+		}
+		for x := 0; x < w; x++ {
+			x0 := bounds.Min.X + x*sw/w
+			x1 := bounds.Min.X + (x+1)*sw/w
+			if x1 <= x0 {
+				x1 = x0 + 1
+			}
+
+			var r, g, b, a, n uint64
+			for sy := y0; sy < y1; sy++ {
+				for sx := x0; sx < x1; sx++ {
+					c := color.NRGBAModel.Convert(src.At(sx, sy)).(color.NRGBA)
+					// 按透明度加权，避免透明像素的颜色渗入边缘
+					r += uint64(c.R) * uint64(c.A)
+					g += uint64(c.G) * uint64(c.A)
+					b += uint64(c.B) * uint64(c.A)
+					a += uint64(c.A)
+					n++
+				}
+			}
+
+			var px color.NRGBA
+			if a > 0 {
+				px = color.NRGBA{R: uint8(r / a), G: uint8(g / a), B: uint8(b / a), A: uint8(a / n)}
+			}
+			dst.SetNRGBA(x, y, px)
+		}
+	}
+	return dst
+}
+
+// imageHasAlpha 检查图片是否包含透明像素
+func imageHasAlpha(img image.Image) bool {
+	if o, ok := img.(interface{ Opaque() bool }); ok {
+		return !o.Opaque()
+	}
+	return true
+}
+
+func max1(v int) int {
+	if v < 1 {
+		return 1
+	}
+	return v
+}
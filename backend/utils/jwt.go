@@ -7,9 +7,13 @@
 	"github.com/golang-jwt/jwt/v4"
 )
 
+// TokenScopeParticipant 参与者登录凭证（手机验证码/登录链接签发），只能访问本人的只读和领奖接口
+const TokenScopeParticipant = "participant"
+
 type Claims struct {
 	UserID   int    `json:"user_id"`
 	Username string `json:"username"`
+	Scope    string `json:"scope,omitempty"` // 为空表示完整权限（管理员/用户名密码登录）
 	jwt.RegisteredClaims
 }
 
@@ -29,8 +33,26 @@ func GenerateToken(userID int, username string, jwtSecret string, expirationHour
 }
 
 // GenerateUserToken 生成用户token（与管理员token使用相同的逻辑）
-func GenerateUserToken(userID int, phone string, jwtSecret string, expirationHours int64) (string, error) {
-	return GenerateToken(userID, phone, jwtSecret, expirationHours)
+func GenerateUserToken(userID int, username string, jwtSecret string, expirationHours int64) (string, error) {
+	return GenerateToken(userID, username, jwtSecret, expirationHours)
+}
+
+// GenerateParticipantToken 生成参与者范围的token
+func GenerateParticipantToken(userID int, username string, jwtSecret string, expiration time.Duration) (string, time.Time, error) {
+	expiresAt := time.Now().Add(expiration)
+	claims := Claims{
+		UserID:   userID,
+		Username: username,
+		Scope:    TokenScopeParticipant,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ExpiresAt: jwt.NewNumericDate(expiresAt),
+			IssuedAt:  jwt.NewNumericDate(time.Now()),
+		},
+	}
+
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+	signed, err := token.SignedString([]byte(jwtSecret))
+	return signed, expiresAt, err
 }
 
 // ValidateToken 验证JWT token
@@ -3,6 +3,7 @@
 import (
 	"fmt"
 	"math/rand"
+	"time"
 
 	"lottery-system/models"
 
@@ -97,11 +98,25 @@ func DrawLotteryWithLevel(db *gorm.DB, user *models.User, ip string, companyID i
 		IP:        ip,
 	}
 
+	// 多规格奖品：中奖者需在截止时间内选择规格
+	if selectedPrize.HasVariants {
+		deadline := VariantDeadline(&selectedPrize, time.Now())
+		record.VariantDeadline = &deadline
+	}
+
 	if err := tx.Create(record).Error; err != nil {
 		tx.Rollback()
 		return nil, err
 	}
 
+	// 虚拟奖品：分配一个兑换码
+	if selectedPrize.CodePool {
+		if err := AssignPrizeCode(tx, selectedPrize.ID, record.ID); err != nil {
+			tx.Rollback()
+			return nil, err
+		}
+	}
+
 	// 更新奖品库存
 	if err := tx.Model(&selectedPrize).Update("used_stock", selectedPrize.UsedStock+1).Error; err != nil {
 		tx.Rollback()
@@ -119,6 +134,10 @@ func DrawLotteryWithLevel(db *gorm.DB, user *models.User, ip string, companyID i
 		return nil, err
 	}
 
+	if selectedPrize.CodePool {
+		CheckPrizeCodePool(db, &selectedPrize)
+	}
+
 	// 加载关联数据
 	db.Preload("Level").Preload("Prize").First(record, record.ID)
 
@@ -140,9 +159,9 @@ func DrawLottery(db *gorm.DB, user *models.User, ip string, companyID int) (*mod
 	if err := tx.Raw(`
 		SELECT p.* FROM prizes p
 		INNER JOIN prize_levels l ON p.level_id = l.id
-		WHERE l.company_id = ? AND l.is_active = ? AND p.used_stock < p.total_stock
+		WHERE l.company_id = ? AND l.is_active = ? AND l.draw_state = ? AND p.used_stock < p.total_stock
 		ORDER BY l.sort_order ASC, p.id ASC
-	`, companyID, true).Find(&availablePrizes).Error; err != nil {
+	`, companyID, true, models.LevelDrawStateOpen).Find(&availablePrizes).Error; err != nil {
 		tx.Rollback()
 		return nil, err
 	}
@@ -172,11 +191,25 @@ func DrawLottery(db *gorm.DB, user *models.User, ip string, companyID int) (*mod
 		IP:        ip,
 	}
 
+	// 多规格奖品：中奖者需在截止时间内选择规格
+	if selectedPrize.HasVariants {
+		deadline := VariantDeadline(&selectedPrize, time.Now())
+		record.VariantDeadline = &deadline
+	}
+
 	if err := tx.Create(record).Error; err != nil {
 		tx.Rollback()
 		return nil, err
 	}
 
+	// 虚拟奖品：分配一个兑换码
+	if selectedPrize.CodePool {
+		if err := AssignPrizeCode(tx, selectedPrize.ID, record.ID); err != nil {
+			tx.Rollback()
+			return nil, err
+		}
+	}
+
 	// 更新奖品库存
 	if err := tx.Model(&selectedPrize).Update("used_stock", selectedPrize.UsedStock+1).Error; err != nil {
 		tx.Rollback()
@@ -194,6 +227,10 @@ func DrawLottery(db *gorm.DB, user *models.User, ip string, companyID int) (*mod
 		return nil, err
 	}
 
+	if selectedPrize.CodePool {
+		CheckPrizeCodePool(db, &selectedPrize)
+	}
+
 	// 加载关联数据
 	db.Preload("Level").Preload("Prize").First(record, record.ID)
 
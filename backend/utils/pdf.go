@@ -0,0 +1,312 @@
+package utils
+
+import (
+	"bytes"
+	"compress/zlib"
+	"fmt"
+	"image"
+	"image/color"
+	"strings"
+	"unicode/utf16"
+)
+
+// 简易 PDF 生成（用于胸牌等打印文档）
+// 中文使用 PDF 阅读器内置的 STSong-Light 字体（Adobe-GB1，不嵌入字体文件），坐标单位为 pt，原点在页面左上角
+
+// MMToPt 毫米转换为 pt
+const MMToPt = 72 / 25.4
+
+// 常用纸张尺寸（pt）
+const (
+	A4Width  = 210 * MMToPt
+	A4Height = 297 * MMToPt
+)
+
+// PDFDocument PDF 文档
+type PDFDocument struct {
+	width, height float64
+	pages         []*PDFPage
+	images        []*PDFImage
+}
+
+// PDFPage PDF 页面
+type PDFPage struct {
+	doc     *PDFDocument
+	content bytes.Buffer
+	images  map[string]bool
+}
+
+// PDFImage 已添加到文档的图片（可在多个页面重复使用）
+type PDFImage struct {
+	name       string
+	width      int
+	height     int
+	colorSpace string
+	data       []byte // 未压缩的像素数据
+}
+
+// NewPDFDocument 创建指定页面尺寸（pt）的 PDF 文档
+func NewPDFDocument(width, height float64) *PDFDocument {
+	return &PDFDocument{width: width, height: height}
+}
+
+// AddPage 添加新页面
+func (d *PDFDocument) AddPage() *PDFPage {
+	page := &PDFPage{doc: d, images: make(map[string]bool)}
+	d.pages = append(d.pages, page)
+	return page
+}
+
+// PageCount 返回页数
+func (d *PDFDocument) PageCount() int {
+	return len(d.pages)
+}
+
+// AddImage 添加图片（透明部分按白色背景合成），灰度图使用 DeviceGray 以减小体积
+func (d *PDFDocument) AddImage(img image.Image) *PDFImage {
+	bounds := img.Bounds()
+	w, h := bounds.Dx(), bounds.Dy()
+
+	gray := true
+	rgb := make([]byte, 0, w*h*3)
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		for x := bounds.Min.X; x < bounds.Max.X; x++ {
+			c := color.NRGBAModel.Convert(img.At(x, y)).(color.NRGBA)
+			r := blendWhite(c.R, c.A)
+			g := blendWhite(c.G, c.A)
+			b := blendWhite(c.B, c.A)
+			if r != g || g != b {
+				gray = false
+			}
+			rgb = append(rgb, r, g, b)
+		}
+	}
+
+	pdfImg := &PDFImage{
+		name:       fmt.Sprintf("Im%d", len(d.images)+1),
+		width:      w,
+		height:     h,
+		colorSpace: "DeviceRGB",
+		data:       rgb,
+	}
+	if gray {
+		grayData := make([]byte, w*h)
+		for i := range grayData {
+			grayData[i] = rgb[i*3]
+		}
+		pdfImg.colorSpace = "DeviceGray"
+		pdfImg.data = grayData
+	}
+
+	d.images = append(d.images, pdfImg)
+	return pdfImg
+}
+
+// AddBitmap 添加黑白点阵图（如二维码模块），每个点一个像素，绘制时不做插值
+func (d *PDFDocument) AddBitmap(bitmap [][]bool) *PDFImage {
+	h := len(bitmap)
+	w := 0
+	if h > 0 {
+		w = len(bitmap[0])
+	}
+
+	data := make([]byte, 0, w*h)
+	for _, row := range bitmap {
+		for x := 0; x < w; x++ {
+			if x < len(row) && row[x] {
+				data = append(data, 0)
+			} else {
+				data = append(data, 255)
+			}
+		}
+	}
+
+	pdfImg := &PDFImage{
+		name:       fmt.Sprintf("Im%d", len(d.images)+1),
+		width:      w,
+		height:     h,
+		colorSpace: "DeviceGray",
+		data:       data,
+	}
+	d.images = append(d.images, pdfImg)
+	return pdfImg
+}
+
+// Size 返回图片像素尺寸
+func (img *PDFImage) Size() (int, int) {
+	return img.width, img.height
+}
+
+// Text 在 (x, y) 处绘制文字，y 为文字基线距页面顶部的距离
+func (p *PDFPage) Text(x, y, size float64, s string) {
+	fmt.Fprintf(&p.content, "BT /F1 %.2f Tf 1 0 0 1 %.2f %.2f Tm <%s> Tj ET\n",
+		size, x, p.doc.height-y, encodeUCS2Hex(s))
+}
+
+// SetFillColor 设置填充颜色（文字颜色），取值 0-255
+func (p *PDFPage) SetFillColor(r, g, b uint8) {
+	fmt.Fprintf(&p.content, "%.3f %.3f %.3f rg\n", float64(r)/255, float64(g)/255, float64(b)/255)
+}
+
+// Rect 绘制矩形边框，(x, y) 为左上角；dashed 为 true 时绘制虚线（裁切线）
+func (p *PDFPage) Rect(x, y, w, h, lineWidth float64, gray uint8, dashed bool) {
+	p.content.WriteString("q\n")
+	fmt.Fprintf(&p.content, "%.2f w %.3f G\n", lineWidth, float64(gray)/255)
+	if dashed {
+		p.content.WriteString("[3 2] 0 d\n")
+	}
+	fmt.Fprintf(&p.content, "%.2f %.2f %.2f %.2f re S\nQ\n", x, p.doc.height-y-h, w, h)
+}
+
+// FillRect 填充矩形，(x, y) 为左上角
+func (p *PDFPage) FillRect(x, y, w, h float64, r, g, b uint8) {
+	fmt.Fprintf(&p.content, "q %.3f %.3f %.3f rg %.2f %.2f %.2f %.2f re f Q\n",
+		float64(r)/255, float64(g)/255, float64(b)/255, x, p.doc.height-y-h, w, h)
+}
+
+// Image 在 (x, y)（左上角）处按 w × h 绘制图片
+func (p *PDFPage) Image(img *PDFImage, x, y, w, h float64) {
+	p.images[img.name] = true
+	fmt.Fprintf(&p.content, "q %.2f 0 0 %.2f %.2f %.2f cm /%s Do Q\n", w, h, x, p.doc.height-y-h, img.name)
+}
+
+// TextWidth 估算文字宽度：ASCII 字符为半角，其余为全角
+func TextWidth(s string, size float64) float64 {
+	width := 0.0
+	for _, r := range s {
+		if r < 0x80 {
+			width += 0.5
+		} else {
+			width += 1
+		}
+	}
+	return width * size
+}
+
+// FitText 缩小字号使文字不超过 maxWidth，达到最小字号仍超出时截断并添加省略号
+func FitText(s string, maxWidth, size, minSize float64) (string, float64) {
+	for size > minSize && TextWidth(s, size) > maxWidth {
+		size -= 0.5
+	}
+	if TextWidth(s, size) <= maxWidth {
+		return s, size
+	}
+
+	runes := []rune(s)
+	for len(runes) > 0 && TextWidth(string(runes)+"…", size) > maxWidth {
+		runes = runes[:len(runes)-1]
+	}
+	return string(runes) + "…", size
+}
+
+// Bytes 输出 PDF 文件内容
+func (d *PDFDocument) Bytes() ([]byte, error) {
+	var buf bytes.Buffer
+	var offsets []int
+
+	// 对象编号：1 目录，2 页面树，3-5 字体，之后依次为图片和各页面（页面对象 + 内容流）
+	const fontObjects = 3
+	firstImage := 3 + fontObjects
+	firstPage := firstImage + len(d.images)
+
+	writeObj := func(body string) {
+		offsets = append(offsets, buf.Len())
+		fmt.Fprintf(&buf, "%d 0 obj\n%s\nendobj\n", len(offsets), body)
+	}
+	writeStream := func(dict string, data []byte) error {
+		compressed, err := deflate(data)
+		if err != nil {
+			return err
+		}
+		offsets = append(offsets, buf.Len())
+		fmt.Fprintf(&buf, "%d 0 obj\n<< %s /Filter /FlateDecode /Length %d >>\nstream\n", len(offsets), dict, len(compressed))
+		buf.Write(compressed)
+		buf.WriteString("\nendstream\nendobj\n")
+		return nil
+	}
+
+	buf.WriteString("%PDF-1.4\n%\xE2\xE3\xCF\xD3\n")
+
+	kids := make([]string, len(d.pages))
+	for i := range d.pages {
+		kids[i] = fmt.Sprintf("%d 0 R", firstPage+i*2)
+	}
+
+	writeObj("<< /Type /Catalog /Pages 2 0 R >>")
+	writeObj(fmt.Sprintf("<< /Type /Pages /Kids [%s] /Count %d /MediaBox [0 0 %.2f %.2f] >>",
+		strings.Join(kids, " "), len(d.pages), d.width, d.height))
+
+	// 中文字体：STSong-Light + UniGB-UCS2-H 编码（由阅读器提供字形）
+	writeObj("<< /Type /Font /Subtype /Type0 /BaseFont /STSong-Light /Encoding /UniGB-UCS2-H /DescendantFonts [4 0 R] >>")
+	writeObj("<< /Type /Font /Subtype /CIDFontType0 /BaseFont /STSong-Light " +
+		"/CIDSystemInfo << /Registry (Adobe) /Ordering (GB1) /Supplement 2 >> " +
+		"/FontDescriptor 5 0 R /DW 1000 /W [1 95 500] >>")
+	writeObj("<< /Type /FontDescriptor /FontName /STSong-Light /Flags 6 /FontBBox [-25 -254 1000 880] " +
+		"/ItalicAngle 0 /Ascent 880 /Descent -120 /CapHeight 880 /StemV 93 >>")
+
+	for _, img := range d.images {
+		dict := fmt.Sprintf("/Type /XObject /Subtype /Image /Width %d /Height %d /ColorSpace /%s /BitsPerComponent 8 /Interpolate false",
+			img.width, img.height, img.colorSpace)
+		if err := writeStream(dict, img.data); err != nil {
+			return nil, err
+		}
+	}
+
+	for i, page := range d.pages {
+		var xobjects []string
+		for j, img := range d.images {
+			if page.images[img.name] {
+				xobjects = append(xobjects, fmt.Sprintf("/%s %d 0 R", img.name, firstImage+j))
+			}
+		}
+		resources := "/Font << /F1 3 0 R >>"
+		if len(xobjects) > 0 {
+			resources += fmt.Sprintf(" /XObject << %s >>", strings.Join(xobjects, " "))
+		}
+
+		writeObj(fmt.Sprintf("<< /Type /Page /Parent 2 0 R /Resources << %s >> /Contents %d 0 R >>",
+			resources, firstPage+i*2+1))
+		if err := writeStream("", page.content.Bytes()); err != nil {
+			return nil, err
+		}
+	}
+
+	xref := buf.Len()
+	fmt.Fprintf(&buf, "xref\n0 %d\n0000000000 65535 f \n", len(offsets)+1)
+	for _, offset := range offsets {
+		fmt.Fprintf(&buf, "%010d 00000 n \n", offset)
+	}
+	fmt.Fprintf(&buf, "trailer\n<< /Size %d /Root 1 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(offsets)+1, xref)
+
+	return buf.Bytes(), nil
+}
+
+// encodeUCS2Hex 将文字编码为 UCS-2 大端十六进制字符串（基本平面以外的字符替换为问号）
+func encodeUCS2Hex(s string) string {
+	var sb strings.Builder
+	for _, r := range s {
+		if r > 0xFFFF || utf16.IsSurrogate(r) {
+			r = '?'
+		}
+		fmt.Fprintf(&sb, "%04X", r)
+	}
+	return sb.String()
+}
+
+// blendWhite 将带透明度的颜色分量合成到白色背景
+func blendWhite(v, alpha uint8) uint8 {
+	return uint8((int(v)*int(alpha) + 255*(255-int(alpha))) / 255)
+}
+
+// deflate 使用 zlib 压缩数据（PDF FlateDecode）
+func deflate(data []byte) ([]byte, error) {
+	var buf bytes.Buffer
+	w := zlib.NewWriter(&buf)
+	if _, err := w.Write(data); err != nil {
+		return nil, err
+	}
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
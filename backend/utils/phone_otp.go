@@ -0,0 +1,67 @@
+package utils
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"math/big"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// phoneVerifiedPrefix 手机号验证凭证前缀（带版本号）：PV1.<公司ID>.<手机号>.<过期时间戳>.<HMAC签名>
+const phoneVerifiedPrefix = "PV1"
+
+// OTPCodeLength 短信验证码位数
+const OTPCodeLength = 6
+
+// ErrInvalidPhoneToken 手机号验证凭证无效、已过期或与公司/手机号不匹配
+var ErrInvalidPhoneToken = errors.New("invalid phone verification token")
+
+// GenerateOTPCode 生成随机数字验证码
+func GenerateOTPCode() (string, error) {
+	max := big.NewInt(1)
+	for i := 0; i < OTPCodeLength; i++ {
+		max.Mul(max, big.NewInt(10))
+	}
+	n, err := rand.Int(rand.Reader, max)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%0*d", OTPCodeLength, n), nil
+}
+
+// HashOTPCode 计算验证码摘要（绑定验证码用途键，存储中不保存明文）
+func HashOTPCode(otpKey, code string, key []byte) string {
+	return tokenSignature(otpKey+":"+code, key)
+}
+
+// SignPhoneVerification 签发手机号验证凭证（验证码校验通过后返回，报名时提交）
+func SignPhoneVerification(companyID int, phone string, expiresAt time.Time, key []byte) string {
+	payload := fmt.Sprintf("%s.%d.%s.%d", phoneVerifiedPrefix, companyID, phone, expiresAt.Unix())
+	return payload + "." + tokenSignature(payload, key)
+}
+
+// VerifyPhoneVerification 校验手机号验证凭证是否由本系统签发、未过期且与公司和手机号一致
+func VerifyPhoneVerification(token string, companyID int, phone string, now time.Time, key []byte) error {
+	parts := strings.Split(strings.TrimSpace(token), ".")
+	if len(parts) != 5 || parts[0] != phoneVerifiedPrefix {
+		return ErrInvalidPhoneToken
+	}
+
+	payload := strings.Join(parts[:4], ".")
+	if !hmac.Equal([]byte(parts[4]), []byte(tokenSignature(payload, key))) {
+		return ErrInvalidPhoneToken
+	}
+
+	if parts[1] != strconv.Itoa(companyID) || parts[2] != phone {
+		return ErrInvalidPhoneToken
+	}
+	expiresAt, err := strconv.ParseInt(parts[3], 10, 64)
+	if err != nil || now.Unix() > expiresAt {
+		return ErrInvalidPhoneToken
+	}
+	return nil
+}
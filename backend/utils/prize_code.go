@@ -0,0 +1,91 @@
+package utils
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"lottery-system/models"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// ErrPrizeCodePoolExhausted 兑换码池已无可分配的兑换码
+var ErrPrizeCodePoolExhausted = errors.New("prize code pool exhausted")
+
+// AssignPrizeCode 在事务中为抽奖记录分配一个未使用的兑换码（原子操作）
+func AssignPrizeCode(tx *gorm.DB, prizeID, recordID int) error {
+	// 并发抽奖时可能与其他事务争抢同一兑换码，条件更新失败则重试
+	for attempt := 0; attempt < 3; attempt++ {
+		var code models.PrizeCode
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("prize_id = ? AND draw_record_id IS NULL", prizeID).
+			Order("id ASC").
+			First(&code).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrPrizeCodePoolExhausted
+		}
+		if err != nil {
+			return err
+		}
+
+		result := tx.Model(&models.PrizeCode{}).
+			Where("id = ? AND draw_record_id IS NULL", code.ID).
+			Updates(map[string]interface{}{
+				"draw_record_id": recordID,
+				"assigned_at":    time.Now(),
+			})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 1 {
+			return nil
+		}
+	}
+
+	return fmt.Errorf("failed to assign prize code for prize %d", prizeID)
+}
+
+// PrizeCodeHash 计算兑换码摘要（用于去重），使用从加密密钥派生的 HMAC 密钥，数据库泄露时无法离线穷举兑换码
+func PrizeCodeHash(code string, key []byte) string {
+	return HMACString(code, DeriveKey("prize-code-hash:"+string(key)))
+}
+
+// MaskPrizeCode 兑换码脱敏显示（只保留末尾字符）
+func MaskPrizeCode(code string) string {
+	runes := []rune(code)
+	switch {
+	case len(runes) <= 4:
+		return "****"
+	case len(runes) <= 8:
+		return "****" + string(runes[len(runes)-2:])
+	default:
+		return "****" + string(runes[len(runes)-4:])
+	}
+}
+
+// CountUnassignedPrizeCodes 统计奖品未分配的兑换码数量
+func CountUnassignedPrizeCodes(db *gorm.DB, prizeID int) int64 {
+	var count int64
+	db.Model(&models.PrizeCode{}).
+		Where("prize_id = ? AND draw_record_id IS NULL", prizeID).
+		Count(&count)
+	return count
+}
+
+// CheckPrizeCodePool 检查兑换码池余量，低于告警阈值时记录告警日志
+func CheckPrizeCodePool(db *gorm.DB, prize *models.Prize) {
+	remaining := CountUnassignedPrizeCodes(db, prize.ID)
+	if remaining > int64(prize.CodeAlertThreshold) {
+		return
+	}
+
+	WithFields(map[string]interface{}{
+		"event":     "prize_code_pool_low",
+		"prize_id":  prize.ID,
+		"prize":     prize.Name,
+		"remaining": remaining,
+		"threshold": prize.CodeAlertThreshold,
+	}).Warn("奖品兑换码余量不足")
+}
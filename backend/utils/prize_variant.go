@@ -0,0 +1,114 @@
+package utils
+
+import (
+	"errors"
+	"time"
+
+	"lottery-system/models"
+
+	"gorm.io/gorm"
+)
+
+// ErrVariantOutOfStock 所选规格已无库存
+var ErrVariantOutOfStock = errors.New("variant out of stock")
+
+// ErrVariantAlreadyChosen 中奖记录已选择规格
+var ErrVariantAlreadyChosen = errors.New("variant already chosen")
+
+// VariantDeadline 计算中奖后选择规格的截止时间
+func VariantDeadline(prize *models.Prize, wonAt time.Time) time.Time {
+	hours := prize.VariantPickHours
+	if hours <= 0 {
+		hours = 24
+	}
+	return wonAt.Add(time.Duration(hours) * time.Hour)
+}
+
+// AssignVariant 在事务中为中奖记录分配规格（扣减规格库存，原子操作）
+func AssignVariant(tx *gorm.DB, recordID, variantID int, auto bool) error {
+	result := tx.Model(&models.PrizeVariant{}).
+		Where("id = ? AND used_stock < total_stock", variantID).
+		Update("used_stock", gorm.Expr("used_stock + 1"))
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrVariantOutOfStock
+	}
+
+	result = tx.Model(&models.DrawRecord{}).
+		Where("id = ? AND variant_id IS NULL", recordID).
+		Updates(map[string]interface{}{
+			"variant_id":            variantID,
+			"variant_auto_assigned": auto,
+		})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrVariantAlreadyChosen
+	}
+	return nil
+}
+
+// ReleaseVariant 在事务中归还中奖记录已分配的规格库存，记录作废或放弃（进入 DrawStatusesReleased）时调用
+func ReleaseVariant(tx *gorm.DB, record *models.DrawRecord) error {
+	if record.VariantID == nil {
+		return nil
+	}
+	return tx.Model(&models.PrizeVariant{}).
+		Where("id = ? AND used_stock > 0", *record.VariantID).
+		Update("used_stock", gorm.Expr("used_stock - 1")).Error
+}
+
+// AssignOverdueVariants 为逾期未选择规格的中奖记录自动分配剩余库存最多的规格
+func AssignOverdueVariants(db *gorm.DB) (int, error) {
+	var records []models.DrawRecord
+	if err := db.Where("variant_id IS NULL AND variant_deadline IS NOT NULL AND variant_deadline < ? AND status NOT IN ?",
+		time.Now(), models.DrawStatusesReleased()).
+		Order("id ASC").
+		Find(&records).Error; err != nil {
+		return 0, err
+	}
+
+	assigned := 0
+	for _, record := range records {
+		err := db.Transaction(func(tx *gorm.DB) error {
+			var variant models.PrizeVariant
+			if err := tx.Where("prize_id = ? AND used_stock < total_stock", record.PrizeID).
+				Order("(total_stock - used_stock) DESC, sort_order ASC, id ASC").
+				First(&variant).Error; err != nil {
+				return err
+			}
+			return AssignVariant(tx, record.ID, variant.ID, true)
+		})
+		if err != nil {
+			WithFields(map[string]interface{}{
+				"error":          err,
+				"draw_record_id": record.ID,
+				"prize_id":       record.PrizeID,
+			}).Warn("逾期规格自动分配失败")
+			continue
+		}
+		assigned++
+	}
+
+	return assigned, nil
+}
+
+// RunVariantFallbackWorker 定时执行逾期规格自动分配（在独立 goroutine 中运行）
+func RunVariantFallbackWorker(db *gorm.DB, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		assigned, err := AssignOverdueVariants(db)
+		if err != nil {
+			WithFields(map[string]interface{}{"error": err}).Error("逾期规格自动分配任务失败")
+			continue
+		}
+		if assigned > 0 {
+			WithFields(map[string]interface{}{"assigned": assigned}).Info("已为逾期未选择规格的中奖者自动分配规格")
+		}
+	}
+}
@@ -0,0 +1,55 @@
+package utils
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// redemptionTokenPrefix 核销凭证前缀（带版本号，便于以后更换格式）
+const redemptionTokenPrefix = "LR1"
+
+// ErrInvalidRedemptionToken 核销凭证格式错误或签名无效
+var ErrInvalidRedemptionToken = errors.New("invalid redemption token")
+
+// NewRedemptionNonce 生成核销凭证随机数
+func NewRedemptionNonce() (string, error) {
+	return RandomHex(16)
+}
+
+// SignRedemptionToken 生成中奖记录的核销凭证：LR1.<记录ID>.<随机数>.<HMAC签名>
+func SignRedemptionToken(recordID int, nonce string, key []byte) string {
+	payload := fmt.Sprintf("%s.%d.%s", redemptionTokenPrefix, recordID, nonce)
+	return payload + "." + tokenSignature(payload, key)
+}
+
+// VerifyRedemptionToken 校验核销凭证签名，返回中奖记录ID和随机数
+func VerifyRedemptionToken(token string, key []byte) (int, string, error) {
+	parts := strings.Split(strings.TrimSpace(token), ".")
+	if len(parts) != 4 || parts[0] != redemptionTokenPrefix {
+		return 0, "", ErrInvalidRedemptionToken
+	}
+
+	payload := strings.Join(parts[:3], ".")
+	expected := tokenSignature(payload, key)
+	if !hmac.Equal([]byte(parts[3]), []byte(expected)) {
+		return 0, "", ErrInvalidRedemptionToken
+	}
+
+	recordID, err := strconv.Atoi(parts[1])
+	if err != nil || recordID <= 0 {
+		return 0, "", ErrInvalidRedemptionToken
+	}
+	return recordID, parts[2], nil
+}
+
+// tokenSignature 计算凭证的 HMAC-SHA256 签名（base64url，无填充），领奖核销、签到、验证码等凭证共用
+func tokenSignature(payload string, key []byte) string {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(payload))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,266 @@
+package utils
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/csv"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// 表格读取错误
+var (
+	ErrUnsupportedSpreadsheet = errors.New("仅支持 CSV 和 XLSX 格式的文件")
+	ErrSpreadsheetEncoding    = errors.New("CSV 文件不是 UTF-8 编码，请在 Excel 中另存为“CSV UTF-8”格式")
+	ErrSpreadsheetEmpty       = errors.New("文件中没有数据")
+)
+
+// maxXLSXPartBytes XLSX 中单个 XML 部件解压后的大小上限（防止压缩炸弹）
+const maxXLSXPartBytes = 64 << 20
+
+// ReadSpreadsheet 读取 CSV 或 XLSX（第一个工作表）文件，返回去除首尾空白后的所有非空行
+// 按文件内容识别格式（XLSX 为 zip 压缩包），超过 maxRows 行时返回错误
+func ReadSpreadsheet(filename string, data []byte, maxRows int) ([][]string, error) {
+	var rows [][]string
+	var err error
+	switch {
+	case bytes.HasPrefix(data, []byte("PK\x03\x04")):
+		rows, err = readXLSX(data)
+	case strings.EqualFold(path.Ext(filename), ".xls"):
+		return nil, ErrUnsupportedSpreadsheet
+	default:
+		rows, err = readCSV(data)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	cleaned := rows[:0]
+	for _, row := range rows {
+		empty := true
+		for i := range row {
+			row[i] = strings.TrimSpace(row[i])
+			if row[i] != "" {
+				empty = false
+			}
+		}
+		if !empty {
+			cleaned = append(cleaned, row)
+		}
+	}
+	if len(cleaned) == 0 {
+		return nil, ErrSpreadsheetEmpty
+	}
+	if maxRows > 0 && len(cleaned) > maxRows {
+		return nil, fmt.Errorf("文件行数超过上限（最多 %d 行）", maxRows)
+	}
+	return cleaned, nil
+}
+
+// readCSV 读取 UTF-8 CSV（自动去除 BOM，表头中没有逗号时识别分号或制表符分隔）
+func readCSV(data []byte) ([][]string, error) {
+	data = bytes.TrimPrefix(data, []byte("\xef\xbb\xbf"))
+	if !utf8.Valid(data) {
+		return nil, ErrSpreadsheetEncoding
+	}
+
+	reader := csv.NewReader(bytes.NewReader(data))
+	reader.FieldsPerRecord = -1
+	reader.LazyQuotes = true
+	firstLine := string(data)
+	if i := strings.IndexByte(firstLine, '\n'); i >= 0 {
+		firstLine = firstLine[:i]
+	}
+	if !strings.Contains(firstLine, ",") {
+		if strings.Contains(firstLine, "\t") {
+			reader.Comma = '\t'
+		} else if strings.Contains(firstLine, ";") {
+			reader.Comma = ';'
+		}
+	}
+
+	rows, err := reader.ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("CSV 格式错误: %w", err)
+	}
+	return rows, nil
+}
+
+// xlsxWorkbook 工作簿（只读取工作表列表）
+type xlsxWorkbook struct {
+	Sheets []struct {
+		Name string `xml:"name,attr"`
+		RID  string `xml:"http://schemas.openxmlformats.org/officeDocument/2006/relationships id,attr"`
+	} `xml:"sheets>sheet"`
+}
+
+// xlsxRelationships 工作簿关系（工作表 ID 到文件路径）
+type xlsxRelationships struct {
+	Items []struct {
+		ID     string `xml:"Id,attr"`
+		Target string `xml:"Target,attr"`
+	} `xml:"Relationship"`
+}
+
+// xlsxText 文本（普通文本或富文本片段，忽略注音）
+type xlsxText struct {
+	T    string `xml:"t"`
+	Runs []struct {
+		T string `xml:"t"`
+	} `xml:"r"`
+}
+
+func (t xlsxText) String() string {
+	if len(t.Runs) == 0 {
+		return t.T
+	}
+	var b strings.Builder
+	b.WriteString(t.T)
+	for _, r := range t.Runs {
+		b.WriteString(r.T)
+	}
+	return b.String()
+}
+
+// xlsxSharedStrings 共享字符串表
+type xlsxSharedStrings struct {
+	Items []xlsxText `xml:"si"`
+}
+
+// xlsxWorksheet 工作表数据
+type xlsxWorksheet struct {
+	Rows []struct {
+		Cells []struct {
+			Ref    string   `xml:"r,attr"`
+			Type   string   `xml:"t,attr"`
+			Value  string   `xml:"v"`
+			Inline xlsxText `xml:"is"`
+		} `xml:"c"`
+	} `xml:"sheetData>row"`
+}
+
+// readXLSX 读取 XLSX 第一个工作表的单元格文本
+func readXLSX(data []byte) ([][]string, error) {
+	zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return nil, ErrUnsupportedSpreadsheet
+	}
+	files := make(map[string]*zip.File, len(zr.File))
+	for _, f := range zr.File {
+		files[f.Name] = f
+	}
+
+	var workbook xlsxWorkbook
+	if err := decodeXLSXPart(files, "xl/workbook.xml", &workbook); err != nil || len(workbook.Sheets) == 0 {
+		return nil, ErrUnsupportedSpreadsheet
+	}
+
+	sheetPath := "xl/worksheets/sheet1.xml"
+	var rels xlsxRelationships
+	if err := decodeXLSXPart(files, "xl/_rels/workbook.xml.rels", &rels); err == nil {
+		for _, rel := range rels.Items {
+			if rel.ID == workbook.Sheets[0].RID {
+				if strings.HasPrefix(rel.Target, "/") {
+					sheetPath = strings.TrimPrefix(rel.Target, "/")
+				} else {
+					sheetPath = path.Join("xl", rel.Target)
+				}
+				break
+			}
+		}
+	}
+
+	var shared xlsxSharedStrings
+	if _, ok := files["xl/sharedStrings.xml"]; ok {
+		if err := decodeXLSXPart(files, "xl/sharedStrings.xml", &shared); err != nil {
+			return nil, fmt.Errorf("读取 XLSX 共享字符串失败: %w", err)
+		}
+	}
+
+	var sheet xlsxWorksheet
+	if err := decodeXLSXPart(files, sheetPath, &sheet); err != nil {
+		return nil, fmt.Errorf("读取 XLSX 工作表失败: %w", err)
+	}
+
+	rows := make([][]string, 0, len(sheet.Rows))
+	for _, r := range sheet.Rows {
+		var row []string
+		for i, cell := range r.Cells {
+			col := i
+			if cell.Ref != "" {
+				if c, ok := xlsxColumnIndex(cell.Ref); ok {
+					col = c
+				}
+			}
+			if col > 1000 {
+				continue
+			}
+			for len(row) <= col {
+				row = append(row, "")
+			}
+
+			switch cell.Type {
+			case "s":
+				if idx, err := strconv.Atoi(cell.Value); err == nil && idx >= 0 && idx < len(shared.Items) {
+					row[col] = shared.Items[idx].String()
+				}
+			case "inlineStr":
+				row[col] = cell.Inline.String()
+			case "b":
+				row[col] = map[string]string{"1": "TRUE", "0": "FALSE"}[cell.Value]
+			case "str", "e":
+				row[col] = cell.Value
+			default:
+				row[col] = formatXLSXNumber(cell.Value)
+			}
+		}
+		rows = append(rows, row)
+	}
+	return rows, nil
+}
+
+// decodeXLSXPart 解析 XLSX 中的 XML 部件
+func decodeXLSXPart(files map[string]*zip.File, name string, v interface{}) error {
+	f, ok := files[name]
+	if !ok {
+		return fmt.Errorf("missing %s", name)
+	}
+	rc, err := f.Open()
+	if err != nil {
+		return err
+	}
+	defer rc.Close()
+	return xml.NewDecoder(io.LimitReader(rc, maxXLSXPartBytes)).Decode(v)
+}
+
+// xlsxColumnIndex 将单元格引用（如 "AB12"）转换为从 0 开始的列号
+func xlsxColumnIndex(ref string) (int, bool) {
+	col := 0
+	n := 0
+	for _, r := range ref {
+		if r < 'A' || r > 'Z' {
+			break
+		}
+		col = col*26 + int(r-'A'+1)
+		n++
+	}
+	return col - 1, n > 0
+}
+
+// formatXLSXNumber 数字单元格转文本：整数不带小数点，科学计数法展开（如手机号 1.38E+10）
+func formatXLSXNumber(v string) string {
+	if !strings.ContainsAny(v, "Ee.") {
+		return v
+	}
+	f, err := strconv.ParseFloat(v, 64)
+	if err != nil {
+		return v
+	}
+	return strconv.FormatFloat(f, 'f', -1, 64)
+}
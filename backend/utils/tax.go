@@ -0,0 +1,36 @@
+package utils
+
+import (
+	"math"
+
+	"lottery-system/models"
+)
+
+// RoundMoney 金额保留两位小数（四舍五入）
+func RoundMoney(amount float64) float64 {
+	return math.Round(amount*100) / 100
+}
+
+// CalculateWithholding 按税率档位计算奖品价值应代扣的个人所得税
+// 适用价值所在的最高档位，结果不小于 0
+func CalculateWithholding(value float64, brackets []models.TaxBracket) float64 {
+	if value <= 0 {
+		return 0
+	}
+
+	var matched *models.TaxBracket
+	for i := range brackets {
+		if value > brackets[i].Above {
+			matched = &brackets[i]
+		}
+	}
+	if matched == nil {
+		return 0
+	}
+
+	tax := value*matched.Rate - matched.Deduction
+	if tax < 0 {
+		return 0
+	}
+	return RoundMoney(tax)
+}
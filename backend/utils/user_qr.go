@@ -0,0 +1,139 @@
+package utils
+
+import (
+	"crypto/hmac"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// userQRPrefix 签名用户二维码前缀（带版本号）：LQ1.<密钥ID>.<载荷>.<HMAC签名>
+const userQRPrefix = "LQ1"
+
+// 用户二维码校验错误
+var (
+	ErrUserQRInvalid    = errors.New("invalid user qr code")
+	ErrUserQRExpired    = errors.New("user qr code expired")
+	ErrUserQRUnknownKey = errors.New("user qr code signed with unknown key")
+)
+
+// UserQRClaims 用户二维码载荷
+type UserQRClaims struct {
+	CompanyID int    `json:"cid"`
+	Username  string `json:"u"`
+	Name      string `json:"n,omitempty"`
+	Phone     string `json:"p,omitempty"`
+	IssuedAt  int64  `json:"iat"`
+	ExpiresAt int64  `json:"exp"`
+}
+
+// UserQRKeyring 用户二维码签名密钥环
+// 新二维码使用当前密钥签名，校验时接受密钥环中所有密钥，轮换时保留旧密钥直到旧二维码过期
+type UserQRKeyring struct {
+	keys   map[string][]byte
+	active string
+}
+
+// ParseUserQRKeyring 解析密钥配置：<密钥ID>:<密钥>,<密钥ID>:<密钥>
+// active 为签名使用的密钥ID，为空时使用第一个；spec 为空时使用 fallback 作为唯一密钥（ID 为 k0）
+func ParseUserQRKeyring(spec, active string, fallback []byte) (*UserQRKeyring, error) {
+	ring := &UserQRKeyring{keys: make(map[string][]byte)}
+
+	spec = strings.TrimSpace(spec)
+	if spec == "" {
+		ring.keys["k0"] = fallback
+		ring.active = "k0"
+		return ring, nil
+	}
+
+	for _, entry := range strings.Split(spec, ",") {
+		parts := strings.SplitN(strings.TrimSpace(entry), ":", 2)
+		if len(parts) != 2 || parts[0] == "" || len(parts[1]) < 16 {
+			return nil, fmt.Errorf("invalid user qr key entry %q (expected <id>:<secret of at least 16 chars>)", entry)
+		}
+		if strings.ContainsAny(parts[0], ".") {
+			return nil, fmt.Errorf("user qr key id %q must not contain '.'", parts[0])
+		}
+		if _, exists := ring.keys[parts[0]]; exists {
+			return nil, fmt.Errorf("duplicate user qr key id %q", parts[0])
+		}
+		ring.keys[parts[0]] = DeriveKey(parts[1])
+		if ring.active == "" {
+			ring.active = parts[0]
+		}
+	}
+
+	if active != "" {
+		if _, ok := ring.keys[active]; !ok {
+			return nil, fmt.Errorf("active user qr key %q not found", active)
+		}
+		ring.active = active
+	}
+	return ring, nil
+}
+
+// ActiveKeyID 返回当前签名使用的密钥ID
+func (r *UserQRKeyring) ActiveKeyID() string {
+	return r.active
+}
+
+// KeyIDs 返回密钥环中所有密钥ID（用于日志和状态展示）
+func (r *UserQRKeyring) KeyIDs() []string {
+	ids := make([]string, 0, len(r.keys))
+	for id := range r.keys {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+// Sign 使用当前密钥签发用户二维码
+func (r *UserQRKeyring) Sign(claims UserQRClaims) (string, error) {
+	data, err := json.Marshal(claims)
+	if err != nil {
+		return "", err
+	}
+	payload := fmt.Sprintf("%s.%s.%s", userQRPrefix, r.active, base64.RawURLEncoding.EncodeToString(data))
+	return payload + "." + tokenSignature(payload, r.keys[r.active]), nil
+}
+
+// Verify 校验用户二维码的签名和有效期
+func (r *UserQRKeyring) Verify(token string, now time.Time) (*UserQRClaims, error) {
+	parts := strings.Split(strings.TrimSpace(token), ".")
+	if len(parts) != 4 || parts[0] != userQRPrefix {
+		return nil, ErrUserQRInvalid
+	}
+
+	key, ok := r.keys[parts[1]]
+	if !ok {
+		return nil, ErrUserQRUnknownKey
+	}
+
+	payload := strings.Join(parts[:3], ".")
+	if !hmac.Equal([]byte(parts[3]), []byte(tokenSignature(payload, key))) {
+		return nil, ErrUserQRInvalid
+	}
+
+	data, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, ErrUserQRInvalid
+	}
+	var claims UserQRClaims
+	if err := json.Unmarshal(data, &claims); err != nil || claims.CompanyID <= 0 || claims.Username == "" {
+		return nil, ErrUserQRInvalid
+	}
+
+	if claims.ExpiresAt > 0 && now.Unix() > claims.ExpiresAt {
+		return &claims, ErrUserQRExpired
+	}
+	return &claims, nil
+}
+
+// IsSignedUserQR 判断二维码内容是否为签名格式（否则视为旧版明文格式）
+func IsSignedUserQR(data string) bool {
+	return strings.HasPrefix(strings.TrimSpace(data), userQRPrefix+".")
+}